@@ -0,0 +1,22 @@
+package api
+
+import "time"
+
+// AdminStatsResponse describes the running server, for the admin api
+type AdminStatsResponse struct {
+	StartedAt     time.Time             `json:"started_at"`
+	UptimeSeconds float64               `json:"uptime_seconds"`
+	GoVersion     string                `json:"go_version"`
+	Goroutines    int                   `json:"goroutines"`
+	Memory        MemoryStatsResponse   `json:"memory"`
+	Keyspace      KeyspaceStatsResponse `json:"keyspace"`
+}
+
+// MemoryStatsResponse is a summary of the go runtime's memory statistics
+type MemoryStatsResponse struct {
+	AllocBytes      uint64 `json:"alloc_bytes"`
+	TotalAllocBytes uint64 `json:"total_alloc_bytes"`
+	SysBytes        uint64 `json:"sys_bytes"`
+	HeapObjects     uint64 `json:"heap_objects"`
+	GCCycles        uint32 `json:"gc_cycles"`
+}
@@ -0,0 +1,29 @@
+package api
+
+import "time"
+
+// ListAuditEntriesResponse is a page of the audit log, oldest first.
+// NextCursor fetches the next page, and is omitted on the last page.
+type ListAuditEntriesResponse struct {
+	Entries    []AuditEntryResponse `json:"entries"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
+// AuditEntryResponse is who changed what, when and from where
+type AuditEntryResponse struct {
+	ID          int64                          `json:"id"`
+	Action      string                         `json:"action"`
+	Actor       string                         `json:"actor"`
+	WorkspaceID string                         `json:"workspace_id,omitempty"`
+	Target      string                         `json:"target,omitempty"`
+	IP          string                         `json:"ip,omitempty"`
+	RequestID   string                         `json:"request_id,omitempty"`
+	Changes     map[string]AuditChangeResponse `json:"changes,omitempty"`
+	CreatedAt   time.Time                      `json:"created_at"`
+}
+
+// AuditChangeResponse is the value of a field before and after a change
+type AuditChangeResponse struct {
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
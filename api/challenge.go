@@ -0,0 +1,13 @@
+package api
+
+import "time"
+
+// ChallengeResponse is a proof of work challenge, which anonymous requests solve before creating links.
+// A solution is a nonce, such that the SHA-256 hash of "<challenge>:<nonce>" starts with Difficulty zero bits.
+// The challenge and nonce are sent in the X-Challenge and X-Challenge-Solution headers.
+type ChallengeResponse struct {
+	Challenge  string    `json:"challenge"`
+	Algorithm  string    `json:"algorithm"`
+	Difficulty int       `json:"difficulty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
@@ -0,0 +1,35 @@
+package api
+
+import "time"
+
+// DomainRequest represents the expected request body when adding a domain to a workspace, or changing its settings.
+// The name is only read when adding a domain.
+type DomainRequest struct {
+	Name            string `json:"name"`
+	DefaultRedirect string `json:"default_redirect"`
+	NotFoundUrl     string `json:"not_found_url"`
+}
+
+// DomainResponse is a short domain of a workspace. Until it's verified, it includes the TXT record that verifies it.
+type DomainResponse struct {
+	Name            string              `json:"name"`
+	WorkspaceID     string              `json:"workspace_id"`
+	Verified        bool                `json:"verified"`
+	VerifiedAt      *time.Time          `json:"verified_at,omitempty"`
+	Verification    *VerificationRecord `json:"verification,omitempty"`
+	DefaultRedirect string              `json:"default_redirect,omitempty"`
+	NotFoundUrl     string              `json:"not_found_url,omitempty"`
+	CreatedAt       time.Time           `json:"created_at"`
+}
+
+// VerificationRecord is the DNS record that proves a workspace controls a domain.
+type VerificationRecord struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ListDomainsResponse is a list of the domains of a workspace
+type ListDomainsResponse struct {
+	Domains []DomainResponse `json:"domains"`
+}
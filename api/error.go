@@ -18,6 +18,10 @@ const (
 	BadRequest           ErrorType = "BAD_REQUEST"
 	PayloadTooLarge      ErrorType = "PAYLOAD_TOO_LARGE"
 	TooManyRequests      ErrorType = "TOO_MANY_REQUESTS"
+	Unauthorized         ErrorType = "UNAUTHORIZED"
+	Forbidden            ErrorType = "FORBIDDEN"
+	Conflict             ErrorType = "CONFLICT"
+	Gone                 ErrorType = "GONE"
 )
 
 // ApiError is a custom error for the application.
@@ -96,6 +100,14 @@ func (ae *ApiError) Status() int {
 		return http.StatusRequestEntityTooLarge
 	case TooManyRequests:
 		return http.StatusTooManyRequests
+	case Unauthorized:
+		return http.StatusUnauthorized
+	case Forbidden:
+		return http.StatusForbidden
+	case Conflict:
+		return http.StatusConflict
+	case Gone:
+		return http.StatusGone
 	default:
 		return http.StatusInternalServerError
 	}
@@ -162,13 +174,63 @@ func NewRequestPayloadTooLarge(code, msg string, opts ...ErrorOption) *ApiError
 	return ae
 }
 
-func NewTooManyRequests() *ApiError {
-	return &ApiError{
+// NewUnauthorized is used when returning a HTTP Status 401 error to the client.
+func NewUnauthorized(code, msg string, opts ...ErrorOption) *ApiError {
+	ae := &ApiError{
+		Type:    Unauthorized,
+		Code:    code,
+		Message: msg,
+	}
+	applyErrorOptions(ae, opts...)
+	return ae
+}
+
+// NewForbidden is used when returning a HTTP Status 403 error to the client.
+// Unlike NewUnauthorized, the client is known, but isn't allowed to do what it asked.
+func NewForbidden(code, msg string, opts ...ErrorOption) *ApiError {
+	ae := &ApiError{
+		Type:    Forbidden,
+		Code:    code,
+		Message: msg,
+	}
+	applyErrorOptions(ae, opts...)
+	return ae
+}
+
+// NewConflict is used when returning a HTTP Status 409 error to the client,
+// when a request conflicts with the current state of a resource.
+func NewConflict(code, msg string, opts ...ErrorOption) *ApiError {
+	ae := &ApiError{
+		Type:    Conflict,
+		Code:    code,
+		Message: msg,
+	}
+	applyErrorOptions(ae, opts...)
+	return ae
+}
+
+// NewTooManyRequests is used when returning a HTTP Status 429 error to the client,
+// when it has gone over a rate limit or quota.
+func NewTooManyRequests(code, msg string, opts ...ErrorOption) *ApiError {
+	ae := &ApiError{
 		Type:    TooManyRequests,
-		Code:    "api/too-many-requests",
-		Message: "You are sending too many requests to the server.",
-		Action:  "C'mon buddy, please slow down. You are limited to 1 request/second.",
+		Code:    code,
+		Message: msg,
+	}
+	applyErrorOptions(ae, opts...)
+	return ae
+}
+
+// NewGone is used when returning a HTTP Status 410 error to the client,
+// when a resource exists, but has deliberately been made unavailable.
+func NewGone(code, msg string, opts ...ErrorOption) *ApiError {
+	ae := &ApiError{
+		Type:    Gone,
+		Code:    code,
+		Message: msg,
 	}
+	applyErrorOptions(ae, opts...)
+	return ae
 }
 
 // applyErrorOptions is a helper function to apply any options in our error factories
@@ -0,0 +1,20 @@
+package api
+
+// ImportResponse reports on every row of an import
+type ImportResponse struct {
+	Created     int                 `json:"created"`
+	Overwritten int                 `json:"overwritten"`
+	Renamed     int                 `json:"renamed"`
+	Skipped     int                 `json:"skipped"`
+	Failed      int                 `json:"failed"`
+	Rows        []ImportRowResponse `json:"rows"`
+}
+
+// ImportRowResponse is the outcome of importing a single row
+type ImportRowResponse struct {
+	Row           int    `json:"row"`
+	Token         string `json:"token,omitempty"`
+	OriginalToken string `json:"original_token,omitempty"`
+	Status        string `json:"status"`
+	Error         string `json:"error,omitempty"`
+}
@@ -0,0 +1,32 @@
+package api
+
+import "time"
+
+// CreateKeyRequest represents the expected request body when creating an api key.
+// Keys are created for the default workspace without a workspace id, and with the default scopes without any scopes.
+type CreateKeyRequest struct {
+	Name        string   `json:"name"`
+	WorkspaceID string   `json:"workspace_id"`
+	Scopes      []string `json:"scopes"`
+}
+
+// KeyResponse is an api key, without the key itself
+type KeyResponse struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	WorkspaceID string     `json:"workspace_id"`
+	Scopes      []string   `json:"scopes"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+}
+
+// CreateKeyResponse is a created api key. Key is only ever returned here, as only its hash is stored.
+type CreateKeyResponse struct {
+	KeyResponse
+	Key string `json:"key"`
+}
+
+// ListKeysResponse is every api key, including revoked ones
+type ListKeysResponse struct {
+	Keys []KeyResponse `json:"keys"`
+}
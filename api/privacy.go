@@ -0,0 +1,63 @@
+package api
+
+import "time"
+
+// StartPrivacyJobRequest starts exporting or erasing everything tied to an api key or user, for a data protection request.
+type StartPrivacyJobRequest struct {
+	Kind    string `json:"kind"`    // export or erase
+	Subject string `json:"subject"` // the id of the api key or user
+}
+
+// PrivacyJobResponse is how far a privacy job has got. An export's data is fetched from ExportUrl once it succeeds.
+type PrivacyJobResponse struct {
+	ID           string     `json:"id"`
+	Kind         string     `json:"kind"`
+	Subject      string     `json:"subject"`
+	Status       string     `json:"status"`
+	Error        string     `json:"error,omitempty"`
+	RequestedBy  string     `json:"requested_by"`
+	Links        int        `json:"links"`
+	AuditEntries int        `json:"audit_entries"`
+	ExportUrl    string     `json:"export_url,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+}
+
+// SubjectExportResponse is everything tied to an api key or user: the links they own, with their visits,
+// the audit entries they made or that target them, and the key or user itself with its memberships and quota usage.
+type SubjectExportResponse struct {
+	Subject      string                 `json:"subject"`
+	JobID        string                 `json:"job_id"`
+	ExportedAt   time.Time              `json:"exported_at"`
+	Links        []ExportedLinkResponse `json:"links"`
+	AuditEntries []AuditEntryResponse   `json:"audit_entries"`
+	ApiKey       *KeyResponse           `json:"api_key,omitempty"`
+	User         *ExportedUserResponse  `json:"user,omitempty"`
+	Memberships  []MembershipResponse   `json:"memberships"`
+	QuotaUsage   []QuotaUsageResponse   `json:"quota_usage"`
+}
+
+// ExportedUserResponse is the user in a subject's export
+type ExportedUserResponse struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// QuotaUsageResponse is how many links a subject created in a quota period, such as "day:2023-04-01"
+type QuotaUsageResponse struct {
+	Period string `json:"period"`
+	Used   int    `json:"used"`
+}
+
+// ExportedLinkResponse is a link in a subject's export
+type ExportedLinkResponse struct {
+	Token       string    `json:"token"`
+	Domain      string    `json:"domain,omitempty"`
+	TargetUrl   string    `json:"target_url"`
+	Visits      int       `json:"visits"`
+	CreatedAt   time.Time `json:"created_at"`
+	WorkspaceID string    `json:"workspace_id"`
+	Disabled    bool      `json:"disabled,omitempty"`
+}
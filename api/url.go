@@ -1,5 +1,7 @@
 package api
 
+import "time"
+
 // CreateUrlRequest represents the expected request body
 // when either shortening or lengthening a url
 type CreateUrlRequest struct {
@@ -12,8 +14,48 @@ type UrlResponse struct {
 	Token     string `json:"token"`
 	TargetUrl string `json:"target_url"`
 	QRCode    string `json:"qr_code"`
+	Domain    string `json:"domain,omitempty"`    // the short domain the url is served from, omitted for the default domain
+	ShortUrl  string `json:"short_url,omitempty"` // omitted for the default domain when server.base_url isn't set
+}
+
+// UpdateUrlRequest represents the expected request body when changing the target of a url
+type UpdateUrlRequest struct {
+	Url string `json:"url"`
 }
 
 type UrlVisitsResponse struct {
 	Visits int `json:"visits"`
 }
+
+// KeyspaceStatsResponse describes how full the keyspace
+// of the token length currently being generated is
+type KeyspaceStatsResponse struct {
+	TokenLength   int     `json:"token_length"`
+	Links         int     `json:"links"`
+	Capacity      float64 `json:"capacity"`
+	Utilisation   float64 `json:"utilisation"`
+	Attempts      int     `json:"attempts"`
+	Collisions    int     `json:"collisions"`
+	CollisionRate float64 `json:"collision_rate"`
+}
+
+// ListUrlsResponse is a page of urls.
+// NextCursor fetches the next page, and is omitted on the last page.
+type ListUrlsResponse struct {
+	Urls       []UrlDetailsResponse `json:"urls"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
+// UrlDetailsResponse is a url, with its visits, when it was created, the id of the api key that created it,
+// the workspace it belongs to, the short domain it's served from, and whether it has been disabled
+type UrlDetailsResponse struct {
+	Token       string    `json:"token"`
+	Domain      string    `json:"domain,omitempty"`
+	ShortUrl    string    `json:"short_url,omitempty"`
+	TargetUrl   string    `json:"target_url"`
+	Visits      int       `json:"visits"`
+	CreatedAt   time.Time `json:"created_at"`
+	Owner       string    `json:"owner,omitempty"`
+	WorkspaceID string    `json:"workspace_id,omitempty"`
+	Disabled    bool      `json:"disabled,omitempty"`
+}
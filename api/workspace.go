@@ -0,0 +1,39 @@
+package api
+
+import "time"
+
+// CreateWorkspaceRequest represents the expected request body when creating a workspace
+type CreateWorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+// WorkspaceResponse is a workspace
+type WorkspaceResponse struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AddMemberRequest represents the expected request body when adding a member to a workspace
+type AddMemberRequest struct {
+	UserID string `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+// UpdateMemberRequest represents the expected request body when changing the role of a member
+type UpdateMemberRequest struct {
+	Role string `json:"role"`
+}
+
+// MembershipResponse is the role a user has in a workspace
+type MembershipResponse struct {
+	WorkspaceID string    `json:"workspace_id"`
+	UserID      string    `json:"user_id"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ListMembershipsResponse is a list of memberships, either of a user or of a workspace
+type ListMembershipsResponse struct {
+	Memberships []MembershipResponse `json:"memberships"`
+}
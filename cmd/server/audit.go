@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/auth"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/handler"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/Jaytpa01/url-shortener-api/internal/service"
+	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+// cliContext returns a copy of ctx acting in the workspace as the cli, which has direct access to the database,
+// so acts as the admin. Changes it makes are audited with "cli" as their actor.
+func cliContext(ctx context.Context, workspaceID string) context.Context {
+	return auth.NewContext(ctx, &auth.Principal{Name: "cli", Admin: true, WorkspaceID: workspaceID})
+}
+
+func auditCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Reads the audit log of who changed links, keys, workspaces and config.",
+	}
+
+	var (
+		database, format, output string
+		action, since, until     string
+		filter                   repository.AuditFilter
+	)
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Exports the audit log, oldest first.",
+		Long: "export writes every audit entry matching the filters, as JSON lines or CSV. " +
+			"The database is given as driver:dsn, using the same drivers and DSNs as the database config. Only sqlite and postgres store the audit log.",
+		Example: "url-shortener-api audit export -d sqlite:db/url.db --target abcdef --since 2023-04-01T00:00:00Z --format csv -o audit.csv",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filter.Action = entity.AuditAction(action)
+			for flag, value := range map[string]string{"since": since, "until": until} {
+				if value == "" {
+					continue
+				}
+
+				t, err := time.Parse(time.RFC3339, value)
+				if err != nil {
+					return fmt.Errorf("--%s (%s) isn't an RFC 3339 time", flag, value)
+				}
+
+				if flag == "since" {
+					filter.Since = t
+				} else {
+					filter.Until = t
+				}
+			}
+
+			repo, err := openEndpoint(database)
+			if err != nil {
+				return fmt.Errorf("couldn't open database: %w", err)
+			}
+			defer closeRepository(repo)
+
+			auditRepo, err := repository.NewAuditRepository(repo)
+			if err != nil {
+				return fmt.Errorf("%w, use sqlite or postgres", err)
+			}
+
+			audit := service.NewAuditService(&service.AuditConfig{
+				Logger:    logger.NewApiLogger("production"),
+				AuditRepo: auditRepo,
+			})
+
+			out := cmd.OutOrStdout()
+			if output != "" {
+				f, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				out = f
+			}
+
+			w, err := newAuditWriter(out, format)
+			if err != nil {
+				return err
+			}
+
+			// the cli acts as the admin, so it can export every workspace
+			ctx := cliContext(cmd.Context(), "")
+			exported, cursor := 0, ""
+			for {
+				entries, next, err := audit.ListAuditEntries(ctx, filter, cursor, service.MAX_LIST_LIMIT)
+				if err != nil {
+					return err
+				}
+
+				for _, entry := range entries {
+					if err := w.Write(entry); err != nil {
+						return err
+					}
+				}
+				exported += len(entries)
+
+				if next == "" {
+					break
+				}
+				cursor = next
+			}
+
+			if err := w.Flush(); err != nil {
+				return err
+			}
+
+			if output != "" {
+				fmt.Fprintf(cmd.OutOrStdout(), "Exported %d audit entries to %s\n", exported, output)
+			}
+			return nil
+		},
+	}
+	exportCmd.Flags().StringVarP(&database, "database", "d", "", "Database the audit log is stored in, as driver:dsn.")
+	exportCmd.MarkFlagRequired("database")
+	exportCmd.Flags().StringVar(&format, "format", "json", "Format to export in: json (one entry per line) or csv.")
+	exportCmd.Flags().StringVarP(&output, "output", "o", "", "File to export to, instead of stdout.")
+	exportCmd.Flags().StringVar(&action, "action", "", "Only entries with this action, such as link.retarget.")
+	exportCmd.Flags().StringVar(&filter.Actor, "actor", "", "Only entries made by this api key or user id, admin, cli, server or anonymous.")
+	exportCmd.Flags().StringVar(&filter.WorkspaceID, "workspace", "", "Only entries in this workspace.")
+	exportCmd.Flags().StringVar(&filter.Target, "target", "", "Only entries changing this token, key id, workspace id or user id.")
+	exportCmd.Flags().StringVar(&since, "since", "", "Only entries at or after this RFC 3339 time.")
+	exportCmd.Flags().StringVar(&until, "until", "", "Only entries before this RFC 3339 time.")
+
+	cmd.AddCommand(exportCmd)
+	return cmd
+}
+
+// auditWriter writes audit entries as JSON lines, in the same shape as GET /audit, or as CSV.
+type auditWriter struct {
+	json *json.Encoder
+	csv  *csv.Writer
+}
+
+// newAuditWriter returns an auditWriter for the format, writing the CSV header straight away.
+func newAuditWriter(w io.Writer, format string) (*auditWriter, error) {
+	switch format {
+	case "json":
+		return &auditWriter{json: json.NewEncoder(w)}, nil
+	case "csv":
+		cw := csv.NewWriter(w)
+		return &auditWriter{csv: cw}, cw.Write([]string{"id", "created_at", "action", "actor", "workspace_id", "target", "ip", "request_id", "changes"})
+	default:
+		return nil, fmt.Errorf("unknown format (%s), expected json or csv", format)
+	}
+}
+
+func (a *auditWriter) Write(entry entity.AuditEntry) error {
+	if a.json != nil {
+		return a.json.Encode(handler.AuditEntryResponse(entry))
+	}
+
+	// the changes are written as the JSON they're stored as
+	changes, err := entry.Changes.Value()
+	if err != nil {
+		return err
+	}
+
+	return a.csv.Write([]string{
+		strconv.FormatInt(entry.ID, 10), entry.CreatedAt.Format(time.RFC3339Nano), string(entry.Action), entry.Actor,
+		entry.WorkspaceID, entry.Target, entry.IP, entry.RequestID, changes.(string),
+	})
+}
+
+func (a *auditWriter) Flush() error {
+	if a.csv == nil {
+		return nil
+	}
+
+	a.csv.Flush()
+	return a.csv.Error()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/backup"
+	"github.com/spf13/cobra"
+)
+
+func backupCmd() *cobra.Command {
+	var (
+		database string
+		output   string
+		compress bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Backs up an SQLite database.",
+		Long: "backup takes a consistent snapshot of an SQLite database with SQLite's online backup API, so it is safe to run while the server is writing to it. " +
+			"A manifest with the backup's checksum and schema version is written next to it, at <output>.manifest.json.",
+		Example: "url-shortener-api backup -d db/url.db -o backups/url.db.gz --gzip",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manifest, err := backup.Backup(cmd.Context(), database, output, compress)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Backed up %s to %s (%d bytes, schema version %d, sha256 %s)\n",
+				database, output, manifest.Size, manifest.SchemaVersion, manifest.SHA256)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&database, "database", "d", "", "Path of the SQLite database to back up.")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Path to write the backup to.")
+	cmd.Flags().BoolVarP(&compress, "gzip", "z", false, "Gzip the backup.")
+	cmd.MarkFlagRequired("database")
+	cmd.MarkFlagRequired("output")
+
+	return cmd
+}
+
+func restoreCmd() *cobra.Command {
+	var (
+		database string
+		input    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Restores an SQLite database from a backup.",
+		Long: "restore checks a backup against its manifest, and that its schema version isn't newer than this binary's migrations, " +
+			"then copies it over the database with SQLite's online backup API. The database isn't touched if any check fails.",
+		Example: "url-shortener-api restore -i backups/url.db.gz -d db/url.db",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manifest, err := backup.Restore(cmd.Context(), input, database)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Restored %s from %s (schema version %d, taken at %s)\n",
+				database, input, manifest.SchemaVersion, manifest.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&database, "database", "d", "", "Path of the SQLite database to restore to.")
+	cmd.Flags().StringVarP(&input, "input", "i", "", "Path of the backup to restore.")
+	cmd.MarkFlagRequired("database")
+	cmd.MarkFlagRequired("input")
+
+	return cmd
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/bench"
+	"github.com/spf13/cobra"
+)
+
+func benchCmd() *cobra.Command {
+	var (
+		opts bench.Options
+		mix  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Load tests a running server.",
+		Long: "bench sends a mix of shorten, redirect and visits requests to a running server, at a fixed rate or concurrency, " +
+			"and reports latency percentiles, error counts by type and throughput. Requests refused with a 401 or 403 are counted apart from other errors.",
+		Example: "url-shortener-api bench -t http://localhost:8080 -d 30s -r 100 -m shorten=1,redirect=8,visits=1",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			weights, err := bench.ParseMix(mix)
+			if err != nil {
+				return err
+			}
+			opts.Mix = weights
+
+			report, err := bench.Run(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+
+			return report.Print(cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Target, "target", "t", "", "Base URL of the server to load test.")
+	cmd.Flags().DurationVarP(&opts.Duration, "duration", "d", 30*time.Second, "How long to send requests for.")
+	cmd.Flags().IntVarP(&opts.Rate, "rate", "r", 0, "Requests per second to send. If 0, requests are sent as fast as the concurrency allows.")
+	cmd.Flags().IntVarP(&opts.Concurrency, "concurrency", "c", 10, "Maximum number of requests in flight.")
+	cmd.Flags().StringVarP(&mix, "mix", "m", "shorten=1,redirect=8,visits=1", "Relative weights of each request type.")
+	cmd.Flags().StringVarP(&opts.Url, "url", "u", "https://example.com", "URL to shorten in shorten requests.")
+	cmd.Flags().IntVarP(&opts.Seed, "seed", "s", 10, "Number of links to create before the run.")
+	cmd.Flags().StringVarP(&opts.Key, "key", "k", "", "API key to send as a bearer token with every request, to measure per key limits and quotas.")
+	cmd.MarkFlagRequired("target")
+
+	return cmd
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/importer"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/Jaytpa01/url-shortener-api/internal/service"
+	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+func importCmd() *cobra.Command {
+	var (
+		database  string
+		file      string
+		format    string
+		conflict  string
+		workspace string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Imports urls from CSV, JSON or bookmark HTML.",
+		Long: "import creates urls from a CSV, JSON or Netscape bookmark HTML file, keeping their tokens, targets, created dates and visits. " +
+			"Exports from YOURLS, Bitly and Kutt are recognised by their field names. Urls without a token are given a generated one. " +
+			"The database is given as driver:dsn, using the same drivers and DSNs as the database config.",
+		Example: "url-shortener-api import -d sqlite:db/url.db -f yourls.csv --conflict rename",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := importer.ParseFormat(format)
+			if err != nil {
+				return err
+			}
+
+			c, err := importer.ParseConflict(conflict)
+			if err != nil {
+				return err
+			}
+
+			in, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer in.Close()
+
+			records, err := importer.Parse(in, f)
+			if err != nil {
+				return err
+			}
+
+			repo, err := openEndpoint(database)
+			if err != nil {
+				return fmt.Errorf("couldn't open database: %w", err)
+			}
+			defer closeRepository(repo)
+
+			// imports are audited, if the database can store the audit log
+			auditRepo, _ := repository.NewAuditRepository(repo)
+
+			urlService := service.NewUrlService(&service.Config{
+				Logger:    logger.NewApiLogger("production"),
+				UrlRepo:   repo,
+				AuditRepo: auditRepo,
+			})
+
+			report, err := urlService.ImportUrls(cliContext(cmd.Context(), workspace), records, c)
+			if err != nil {
+				return err
+			}
+
+			return printImportReport(cmd, report)
+		},
+	}
+
+	cmd.Flags().StringVarP(&database, "database", "d", "", "Database to import into, as driver:dsn.")
+	cmd.Flags().StringVarP(&file, "file", "f", "", "File to import.")
+	cmd.Flags().StringVar(&format, "format", string(importer.FormatAuto), "Format of the file: auto, csv, json or bookmarks.")
+	cmd.Flags().StringVar(&conflict, "conflict", string(importer.ConflictSkip), "What to do when a token already exists: skip, overwrite or rename.")
+	cmd.Flags().StringVar(&workspace, "workspace", entity.DEFAULT_WORKSPACE, "Workspace to import the urls into.")
+	cmd.MarkFlagRequired("database")
+	cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+// printImportReport prints the totals of an import, and every row that failed or was renamed.
+func printImportReport(cmd *cobra.Command, report *importer.Report) error {
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Created %d, overwritten %d, renamed %d, skipped %d, failed %d\n",
+		report.Created, report.Overwritten, report.Renamed, report.Skipped, report.Failed)
+
+	if report.Failed == 0 && report.Renamed == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(out)
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "ROW\tTOKEN\tSTATUS\tDETAIL\n")
+	for _, row := range report.Rows {
+		switch row.Status {
+		case importer.StatusFailed:
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", row.Row, row.Token, row.Status, row.Error)
+		case importer.StatusRenamed:
+			fmt.Fprintf(tw, "%d\t%s\t%s\twas %s\n", row.Row, row.Token, row.Status, row.OriginalToken)
+		}
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/Jaytpa01/url-shortener-api/internal/service"
+	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+func keysCmd() *cobra.Command {
+	var database string
+
+	cmd := &cobra.Command{
+		Use:   "keys",
+		Short: "Manages the api keys that authenticate requests.",
+		Long: "keys creates, lists and revokes api keys. Only a hash of each key is stored, so a key is only shown when it's created. " +
+			"The database is given as driver:dsn, using the same drivers and DSNs as the database config. Only sqlite and postgres can store api keys.",
+	}
+
+	cmd.PersistentFlags().StringVarP(&database, "database", "d", "", "Database the keys are stored in, as driver:dsn.")
+	cmd.MarkPersistentFlagRequired("database")
+
+	// withKeyService opens the database and runs fn with a key service for it
+	withKeyService := func(fn func(cmd *cobra.Command, args []string, keys service.KeyService) error) func(cmd *cobra.Command, args []string) error {
+		return func(cmd *cobra.Command, args []string) error {
+			// the memory driver would hold the keys until the command exits, so a created key could never be used
+			if driver, _, _ := strings.Cut(database, ":"); driver == repository.DriverMemory {
+				return fmt.Errorf("%w, use sqlite or postgres", repository.ErrApiKeysUnsupported)
+			}
+
+			repo, err := openEndpoint(database)
+			if err != nil {
+				return fmt.Errorf("couldn't open database: %w", err)
+			}
+			defer closeRepository(repo)
+
+			apiKeyRepo, err := repository.NewApiKeyRepository(repo)
+			if err != nil {
+				return fmt.Errorf("%w, use sqlite or postgres", err)
+			}
+
+			workspaceRepo, err := repository.NewWorkspaceRepository(repo)
+			if err != nil {
+				return fmt.Errorf("%w, use sqlite or postgres", err)
+			}
+
+			// keys created and revoked by the cli are audited
+			auditRepo, err := repository.NewAuditRepository(repo)
+			if err != nil {
+				return fmt.Errorf("%w, use sqlite or postgres", err)
+			}
+
+			return fn(cmd, args, service.NewKeyService(&service.KeyConfig{
+				Logger:        logger.NewApiLogger("production"),
+				ApiKeyRepo:    apiKeyRepo,
+				WorkspaceRepo: workspaceRepo,
+				AuditRepo:     auditRepo,
+			}))
+		}
+	}
+
+	var (
+		name, workspace string
+		scopes          []string
+	)
+	createCmd := &cobra.Command{
+		Use:   "create",
+		Short: "Creates an api key, and prints it.",
+		Long: "create creates an api key for a workspace, which can only do what its scopes allow: " +
+			"links:create, links:write, links:delete, analytics:read, or admin, which allows everything and owning the workspace. " +
+			"Requests made with the key act as an editor of the workspace, or its owner with the admin scope.",
+		Example: "url-shortener-api keys create -d sqlite:db/url.db --name dashboard --workspace ws_0123456789abcdef --scope analytics:read",
+		RunE: withKeyService(func(cmd *cobra.Command, args []string, keys service.KeyService) error {
+			keyScopes := make(entity.Scopes, len(scopes))
+			for i, scope := range scopes {
+				keyScopes[i] = entity.Scope(scope)
+			}
+
+			key, secret, err := keys.CreateKey(cliContext(cmd.Context(), ""), name, workspace, keyScopes)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Created api key %s (%s) for workspace %s, with scopes %s. Store it now, it can't be shown again:\n\n",
+				key.ID, key.Name, key.WorkspaceID, key.Scopes)
+			fmt.Fprintln(out, secret)
+			return nil
+		}),
+	}
+	createCmd.Flags().StringVar(&name, "name", "", "Name of the key, ie. who or what it's for.")
+	createCmd.MarkFlagRequired("name")
+	createCmd.Flags().StringVar(&workspace, "workspace", entity.DEFAULT_WORKSPACE, "Id of the workspace the key is for.")
+	createCmd.Flags().StringSliceVar(&scopes, "scope", strings.Fields(entity.DEFAULT_SCOPES.String()), "Scopes of the key, repeated or comma separated.")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "Lists every api key.",
+		RunE: withKeyService(func(cmd *cobra.Command, args []string, keys service.KeyService) error {
+			list, err := keys.ListKeys(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintf(tw, "ID\tNAME\tWORKSPACE\tSCOPES\tCREATED\tREVOKED\n")
+			for _, key := range list {
+				revoked := "-"
+				if key.Revoked() {
+					revoked = key.RevokedAt.Format(time.RFC3339)
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", key.ID, key.Name, key.WorkspaceID, key.Scopes,
+					key.CreatedAt.Format(time.RFC3339), revoked)
+			}
+
+			return tw.Flush()
+		}),
+	}
+
+	revokeCmd := &cobra.Command{
+		Use:   "revoke <id>",
+		Short: "Revokes an api key, so it no longer authenticates requests.",
+		Args:  cobra.ExactArgs(1),
+		RunE: withKeyService(func(cmd *cobra.Command, args []string, keys service.KeyService) error {
+			if err := keys.RevokeKey(cliContext(cmd.Context(), ""), args[0]); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Revoked api key %s\n", args[0])
+			return nil
+		}),
+	}
+
+	cmd.AddCommand(createCmd, listCmd, revokeCmd)
+	return cmd
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/handler"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/Jaytpa01/url-shortener-api/internal/service"
+	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+func privacyCmd() *cobra.Command {
+	var database string
+
+	cmd := &cobra.Command{
+		Use:   "privacy",
+		Short: "Exports or erases everything tied to an api key or user, for data protection requests.",
+		Long: "privacy exports or erases the links an api key or user owns, the visits to them, the audit entries they made or that target them, " +
+			"and the key or user itself with its memberships and quota usage. " +
+			"Each export or erasure is tracked as a privacy job, like those started with POST /privacy/jobs on the admin api, but runs until it finishes. " +
+			"The database is given as driver:dsn, using the same drivers and DSNs as the database config. Only sqlite and postgres can store privacy jobs.",
+	}
+
+	cmd.PersistentFlags().StringVarP(&database, "database", "d", "", "Database the data is stored in, as driver:dsn.")
+	cmd.MarkPersistentFlagRequired("database")
+
+	// withPrivacyService opens the database and runs fn with a privacy service for it
+	withPrivacyService := func(fn func(cmd *cobra.Command, args []string, privacy service.PrivacyService) error) func(cmd *cobra.Command, args []string) error {
+		return func(cmd *cobra.Command, args []string) error {
+			repo, err := openEndpoint(database)
+			if err != nil {
+				return fmt.Errorf("couldn't open database: %w", err)
+			}
+			defer closeRepository(repo)
+
+			privacyRepo, err := repository.NewPrivacyRepository(repo)
+			if err != nil {
+				return fmt.Errorf("%w, use sqlite or postgres", err)
+			}
+
+			auditRepo, err := repository.NewAuditRepository(repo)
+			if err != nil {
+				return fmt.Errorf("%w, use sqlite or postgres", err)
+			}
+
+			// sqlite and postgres store api keys, users and quota usage alongside the privacy jobs
+			apiKeyRepo, _ := repository.NewApiKeyRepository(repo)
+			workspaceRepo, _ := repository.NewWorkspaceRepository(repo)
+			quotaRepo, _ := repository.NewQuotaRepository(repo)
+
+			return fn(cmd, args, service.NewPrivacyService(&service.PrivacyConfig{
+				Logger:      logger.NewApiLogger("production"),
+				UrlRepo:     repo,
+				AuditRepo:   auditRepo,
+				PrivacyRepo: privacyRepo,
+
+				ApiKeyRepo:    apiKeyRepo,
+				WorkspaceRepo: workspaceRepo,
+				QuotaRepo:     quotaRepo,
+			}))
+		}
+	}
+
+	var output string
+	exportCmd := &cobra.Command{
+		Use:   "export <subject>",
+		Short: "Exports everything tied to an api key or user id, as JSON.",
+		Long: "export writes the links the subject owns, with their visits, the audit entries they made or that target them, " +
+			"and the subject's api key (without its hash) or user, memberships and quota usage, in the same shape as GET /privacy/jobs/{id}/export on the admin api.",
+		Example: "url-shortener-api privacy export key_0123456789abcdef -d sqlite:db/url.db -o export.json",
+		Args:    cobra.ExactArgs(1),
+		RunE: withPrivacyService(func(cmd *cobra.Command, args []string, privacy service.PrivacyService) error {
+			job, err := privacy.RunJob(cliContext(cmd.Context(), ""), entity.PrivacyExport, args[0])
+			if err != nil {
+				return err
+			}
+			if job.Status == entity.PrivacyJobFailed {
+				return fmt.Errorf("privacy job %s failed: %s", job.ID, job.Error)
+			}
+
+			out := cmd.OutOrStdout()
+			if output != "" {
+				f, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				out = f
+			}
+
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(handler.SubjectExportResponse(job)); err != nil {
+				return err
+			}
+
+			if output != "" {
+				fmt.Fprintf(cmd.OutOrStdout(), "Exported %d links and %d audit entries of %s to %s (privacy job %s)\n",
+					job.Links, job.AuditEntries, job.Subject, output, job.ID)
+			}
+			return nil
+		}),
+	}
+	exportCmd.Flags().StringVarP(&output, "output", "o", "", "File to export to, instead of stdout.")
+
+	var confirmed bool
+	eraseCmd := &cobra.Command{
+		Use:   "erase <subject>",
+		Short: "Erases everything tied to an api key or user id.",
+		Long: "erase anonymizes the links the subject owns, which keep working without an owner, and replaces the subject " +
+			"with a pseudonym in the audit log, clearing the ip of the entries they made and the names in the entries that target them. " +
+			"The subject's api key or user, memberships and quota usage are deleted, but links, visits and audit entries are kept, so their counts stay the same. " +
+			"Privacy jobs for the subject, this one included, are given the pseudonym, and their exports are cleared. It can't be undone.",
+		Example: "url-shortener-api privacy erase key_0123456789abcdef -d sqlite:db/url.db --yes",
+		Args:    cobra.ExactArgs(1),
+		RunE: withPrivacyService(func(cmd *cobra.Command, args []string, privacy service.PrivacyService) error {
+			if !confirmed {
+				return errors.New("erasing a subject can't be undone, pass --yes to confirm")
+			}
+
+			job, err := privacy.RunJob(cliContext(cmd.Context(), ""), entity.PrivacyErase, args[0])
+			if err != nil {
+				return err
+			}
+			if job.Status == entity.PrivacyJobFailed {
+				return fmt.Errorf("privacy job %s failed: %s", job.ID, job.Error)
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Erased %s from %d links and %d audit entries (privacy job %s)\n",
+				args[0], job.Links, job.AuditEntries, job.ID)
+			return nil
+		}),
+	}
+	eraseCmd.Flags().BoolVar(&confirmed, "yes", false, "Confirm the erasure.")
+
+	statusCmd := &cobra.Command{
+		Use:   "status <job id>",
+		Short: "Shows how far a privacy job has got.",
+		Args:  cobra.ExactArgs(1),
+		RunE: withPrivacyService(func(cmd *cobra.Command, args []string, privacy service.PrivacyService) error {
+			job, err := privacy.FindJob(cliContext(cmd.Context(), ""), args[0])
+			if err != nil {
+				return err
+			}
+
+			// formatTime formats the start and finish times, which aren't set until the job gets that far
+			formatTime := func(t *time.Time) string {
+				if t == nil {
+					return "-"
+				}
+				return t.Format(time.RFC3339)
+			}
+
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintf(tw, "ID\t%s\n", job.ID)
+			fmt.Fprintf(tw, "KIND\t%s\n", job.Kind)
+			fmt.Fprintf(tw, "SUBJECT\t%s\n", job.Subject)
+			fmt.Fprintf(tw, "STATUS\t%s\n", job.Status)
+			if job.Error != "" {
+				fmt.Fprintf(tw, "ERROR\t%s\n", job.Error)
+			}
+			fmt.Fprintf(tw, "REQUESTED BY\t%s\n", job.RequestedBy)
+			fmt.Fprintf(tw, "LINKS\t%d\n", job.Links)
+			fmt.Fprintf(tw, "AUDIT ENTRIES\t%d\n", job.AuditEntries)
+			fmt.Fprintf(tw, "CREATED\t%s\n", job.CreatedAt.Format(time.RFC3339))
+			fmt.Fprintf(tw, "STARTED\t%s\n", formatTime(job.StartedAt))
+			fmt.Fprintf(tw, "FINISHED\t%s\n", formatTime(job.FinishedAt))
+
+			return tw.Flush()
+		}),
+	}
+
+	cmd.AddCommand(exportCmd, eraseCmd, statusCmd)
+	return cmd
+}
@@ -13,6 +13,14 @@ func rootCommand() *cobra.Command {
 	}
 
 	rootCmd.AddCommand(migrateCmd())
+	rootCmd.AddCommand(benchCmd())
+	rootCmd.AddCommand(backupCmd())
+	rootCmd.AddCommand(restoreCmd())
+	rootCmd.AddCommand(copyDataCmd())
+	rootCmd.AddCommand(importCmd())
+	rootCmd.AddCommand(keysCmd())
+	rootCmd.AddCommand(auditCmd())
+	rootCmd.AddCommand(privacyCmd())
 
 	return rootCmd
 }
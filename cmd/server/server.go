@@ -2,17 +2,24 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/Jaytpa01/url-shortener-api/config"
+	"github.com/Jaytpa01/url-shortener-api/internal/auth"
 	"github.com/Jaytpa01/url-shortener-api/internal/handler"
+	"github.com/Jaytpa01/url-shortener-api/internal/migration"
 	"github.com/Jaytpa01/url-shortener-api/internal/repository"
 	"github.com/Jaytpa01/url-shortener-api/internal/service"
 	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
@@ -20,6 +27,9 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
+// DEFAULT_ADMIN_HOST is where the admin api listens without admin.host, which keeps it off the public network.
+const DEFAULT_ADMIN_HOST = "127.0.0.1"
+
 func serveHTTP() {
 
 	configPath := utils.GetConfigFilepathFromFilename("config.local.yaml")
@@ -31,18 +41,147 @@ func serveHTTP() {
 
 	logger := logger.NewApiLogger(config.Server.Environment)
 
+	// make sure the schema matches this binary before anything touches the database
+	if err := migration.EnsureSchema(config.Database, logger); err != nil {
+		logger.Fatalf("database schema isn't ready: %v", err)
+	}
+
 	// create our repo(s)
-	urlRepo, err := repository.NewSQLiteRepository("./db/url.db")
+	urlRepo, err := repository.NewUrlRepository(config.Database)
+	if err != nil {
+		logger.Fatalf("couldn't create url repository: %v", err)
+	}
+
+	tokens, err := newTokenGenerator(config.Token, urlRepo)
+	if err != nil {
+		logger.Fatalf("couldn't create token generator: %v", err)
+	}
+
+	// sequence tokens never clash, they only grow once the keyspace is full
+	collisionThreshold := config.Token.CollisionThreshold
+	if config.Token.Strategy == "sequence" {
+		collisionThreshold = 1
+	}
+
+	// the audit log is stored alongside the urls, if the database supports it
+	auditRepo, err := repository.NewAuditRepository(urlRepo)
 	if err != nil {
-		logger.Fatalf("couldnt connect to sqlite database: %v", err)
+		logger.Warnf("The %s driver can't store the audit log.", config.Database.Driver)
+	}
+
+	// workspaces are stored alongside the urls, if the database supports it.
+	// Without them, every link is in the default workspace
+	var workspaceService service.WorkspaceService
+	workspaceRepo, err := repository.NewWorkspaceRepository(urlRepo)
+	if err == nil {
+		workspaceService = service.NewWorkspaceService(&service.WorkspaceConfig{
+			Logger:        logger,
+			WorkspaceRepo: workspaceRepo,
+			AuditRepo:     auditRepo,
+		})
+	} else {
+		logger.Warnf("The %s driver can't store workspaces.", config.Database.Driver)
+	}
+
+	// short domains are stored alongside the urls, if the database supports it.
+	// Without them, every link is on the default domain
+	var domainService service.DomainService
+	domainRepo, err := repository.NewDomainRepository(urlRepo)
+	if err == nil && workspaceRepo != nil {
+		domainService = service.NewDomainService(&service.DomainConfig{
+			Logger:        logger,
+			DomainRepo:    domainRepo,
+			WorkspaceRepo: workspaceRepo,
+			AuditRepo:     auditRepo,
+			DefaultHost:   config.Server.Host(),
+		})
+	} else {
+		logger.Warnf("The %s driver can't store short domains.", config.Database.Driver)
 	}
 
 	// create our service(s)
 	urlService := service.NewUrlService(&service.Config{
-		Logger:  logger,
-		UrlRepo: urlRepo,
+		Logger:             logger,
+		UrlRepo:            urlRepo,
+		WorkspaceRepo:      workspaceRepo,
+		AuditRepo:          auditRepo,
+		Tokens:             tokens,
+		CollisionThreshold: collisionThreshold,
 	})
 
+	verifier, err := newTokenVerifier(config.Auth.JWT)
+	if err != nil {
+		logger.Fatalf("couldn't create jwt verifier: %v", err)
+	}
+
+	// api keys are stored alongside the urls, if the database supports it
+	var keyService service.KeyService
+	apiKeyRepo, err := repository.NewApiKeyRepository(urlRepo)
+	switch {
+	case err == nil:
+		keyService = service.NewKeyService(&service.KeyConfig{
+			Logger:        logger,
+			ApiKeyRepo:    apiKeyRepo,
+			WorkspaceRepo: workspaceRepo,
+			AuditRepo:     auditRepo,
+		})
+	case config.Auth.RequireApiKey && verifier == nil:
+		logger.Fatalf("auth.require_api_key is set, but the %s driver can't store api keys", config.Database.Driver)
+	default:
+		logger.Warnf("The %s driver can't store api keys.", config.Database.Driver)
+	}
+
+	// quota usage is stored alongside the urls, if the database supports it
+	quotaRepo, _ := repository.NewQuotaRepository(urlRepo)
+	quotaService, err := newQuotaService(config.RateLimit.Quota, quotaRepo, logger)
+	if err != nil {
+		logger.Fatalf("couldn't create quotas: %v", err)
+	}
+
+	var auditService service.AuditService
+	if auditRepo != nil {
+		auditService = service.NewAuditService(&service.AuditConfig{
+			Logger:        logger,
+			AuditRepo:     auditRepo,
+			WorkspaceRepo: workspaceRepo,
+		})
+
+		// config changes are recorded when the server starts with them
+		entry, err := auditService.RecordConfig(context.Background(), config.Settings())
+		switch {
+		case err != nil:
+			logger.Errorf("couldn't record config changes in the audit log: %v", err)
+		case entry != nil:
+			logger.Infof("Recorded %d config changes in the audit log.", len(entry.Changes))
+		}
+	}
+
+	// data protection requests are answered from the links and the audit log, so need a database that stores both
+	var privacyService service.PrivacyService
+	if privacyRepo, err := repository.NewPrivacyRepository(urlRepo); err == nil && auditRepo != nil {
+		privacyService = service.NewPrivacyService(&service.PrivacyConfig{
+			Logger:      logger,
+			UrlRepo:     urlRepo,
+			AuditRepo:   auditRepo,
+			PrivacyRepo: privacyRepo,
+
+			ApiKeyRepo:    apiKeyRepo,
+			WorkspaceRepo: workspaceRepo,
+			QuotaRepo:     quotaRepo,
+		})
+
+		// jobs that were running when the server last stopped are run again
+		resumed, err := privacyService.ResumeJobs(context.Background())
+		switch {
+		case err != nil:
+			logger.Errorf("couldn't resume privacy jobs: %v", err)
+		case resumed > 0:
+			logger.Infof("Resumed %d unfinished privacy jobs.", resumed)
+		}
+	} else {
+		logger.Warnf("The %s driver can't store privacy jobs.", config.Database.Driver)
+	}
+
 	// create our router
 	router := chi.NewRouter()
 
@@ -51,6 +190,18 @@ func serveHTTP() {
 		Router:     router,
 		ApiConfig:  config,
 		UrlService: urlService,
+		KeyService: keyService,
+
+		WorkspaceService: workspaceService,
+		DomainService:    domainService,
+		QuotaService:     quotaService,
+		ChallengeService: newChallengeService(config.ProofOfWork),
+		AuditService:     auditService,
+		PrivacyService:   privacyService,
+	}
+	// a nil *auth.Verifier would make a non-nil TokenVerifier
+	if verifier != nil {
+		cfg.TokenVerifier = verifier
 	}
 	err = handler.NewHandler(cfg)
 	if err != nil {
@@ -62,6 +213,12 @@ func serveHTTP() {
 		Handler: router,
 	}
 
+	// the admin api has its own listener, so it can be kept off the public network
+	adminServer, err := newAdminServer(config, *cfg)
+	if err != nil {
+		logger.Fatalf("couldn't create the admin api: %v", err)
+	}
+
 	// start the server
 	go func() {
 		if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
@@ -70,6 +227,23 @@ func serveHTTP() {
 		logger.Info("Stopped serving new connections.")
 	}()
 
+	if adminServer != nil {
+		go func() {
+			var err error
+			if config.Admin.TLS.CertFile != "" {
+				err = adminServer.ListenAndServeTLS(config.Admin.TLS.CertFile, config.Admin.TLS.KeyFile)
+			} else {
+				err = adminServer.ListenAndServe()
+			}
+
+			if !errors.Is(err, http.ErrServerClosed) {
+				logger.Fatalf("Admin server error: %v", err)
+			}
+			logger.Info("Stopped serving new admin connections.")
+		}()
+		logger.Infof("Serving the admin api on %s.", adminServer.Addr)
+	}
+
 	logger.Info("Server Initialised.")
 
 	// Wait for a termination signal
@@ -83,5 +257,156 @@ func serveHTTP() {
 	if err := httpServer.Shutdown(shutdownCtx); err != nil {
 		logger.Fatalf("HTTP server shutdown error: %v", err)
 	}
+
+	if adminServer != nil {
+		if err := adminServer.Shutdown(shutdownCtx); err != nil {
+			logger.Fatalf("Admin server shutdown error: %v", err)
+		}
+	}
+
+	// some repositories need to flush to disk before we exit
+	if closer, ok := urlRepo.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			logger.Errorf("couldn't close url repository: %v", err)
+		}
+	}
 	logger.Info("Graceful shutdown complete.")
 }
+
+// newAdminServer creates the server for the admin api, using the services in cfg. It returns nil if admin.port isn't set.
+// It listens on admin.host, or on loopback if that isn't set.
+// If admin.tls.client_ca_file is set, clients must present a certificate it signed.
+func newAdminServer(config *config.Config, cfg handler.Config) (*http.Server, error) {
+	admin := config.Admin
+	switch {
+	case admin.Port == 0:
+		return nil, nil
+	case admin.Port == config.Server.Port:
+		return nil, errors.New("admin.port must be different to server.port")
+	case (admin.TLS.CertFile == "") != (admin.TLS.KeyFile == ""):
+		return nil, errors.New("admin.tls.cert_file and admin.tls.key_file must be set together")
+	case admin.TLS.ClientCAFile != "" && admin.TLS.CertFile == "":
+		return nil, errors.New("admin.tls.cert_file and admin.tls.key_file must be set to verify client certificates")
+	}
+
+	cfg.Router = chi.NewRouter()
+	if err := handler.NewAdminHandler(&cfg); err != nil {
+		return nil, err
+	}
+
+	host := admin.Host
+	if host == "" {
+		host = DEFAULT_ADMIN_HOST
+	}
+
+	server := &http.Server{
+		Addr:    net.JoinHostPort(host, strconv.Itoa(admin.Port)),
+		Handler: cfg.Router,
+	}
+
+	if admin.TLS.ClientCAFile != "" {
+		pem, err := os.ReadFile(admin.TLS.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read admin client CA: %w", err)
+		}
+
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("admin client CA (%s) has no PEM certificates", admin.TLS.ClientCAFile)
+		}
+
+		server.TLSConfig = &tls.Config{
+			ClientCAs:  clientCAs,
+			ClientAuth: tls.RequireAndVerifyClientCert,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	return server, nil
+}
+
+// newTokenGenerator creates the token generator for the configured strategy.
+// The sequence strategy needs a repository that can hand out ids.
+func newTokenGenerator(cfg config.TokenConfig, urlRepo repository.UrlRepository) (utils.TokenGenerator, error) {
+	switch cfg.Strategy {
+	case "", "random":
+		return utils.NewRandomTokenGenerator(utils.NewRandomiser()), nil
+	case "sequence":
+		seq, ok := urlRepo.(utils.Sequence)
+		if !ok {
+			return nil, errors.New("the configured repository doesn't support the sequence token strategy")
+		}
+
+		if cfg.Secret == "" {
+			return nil, errors.New("token.secret must be set to use the sequence token strategy")
+		}
+
+		return utils.NewSequenceTokenGenerator(seq, []byte(cfg.Secret)), nil
+	default:
+		return nil, fmt.Errorf("unknown token strategy (%s)", cfg.Strategy)
+	}
+}
+
+// newTokenVerifier creates the JWT verifier, if a key set is configured. It returns nil if JWTs aren't accepted.
+func newTokenVerifier(cfg config.JWTConfig) (*auth.Verifier, error) {
+	var keys *auth.JWKS
+	switch {
+	case cfg.JWKSUrl != "" && cfg.JWKSFile != "":
+		return nil, errors.New("only one of auth.jwt.jwks_url and auth.jwt.jwks_file can be set")
+	case cfg.JWKSUrl != "":
+		keys = auth.NewRemoteJWKS(cfg.JWKSUrl, nil, cfg.CacheTTL)
+	case cfg.JWKSFile != "":
+		keys = auth.NewFileJWKS(cfg.JWKSFile, cfg.CacheTTL)
+	default:
+		return nil, nil
+	}
+
+	return auth.NewVerifier(auth.VerifierConfig{
+		Keys:           keys,
+		Issuer:         cfg.Issuer,
+		Audience:       cfg.Audience,
+		UserClaim:      cfg.UserClaim,
+		WorkspaceClaim: cfg.WorkspaceClaim,
+		Leeway:         cfg.Leeway,
+	})
+}
+
+// newQuotaService creates the quota service, if quotas are configured. It returns nil if urls can be created without limit.
+// Quotas are counted in the database, so it's an error to configure them when quotaRepo is nil, as the driver can't store them.
+func newQuotaService(cfg config.QuotaConfig, quotaRepo repository.QuotaRepository, logger logger.Logger) (service.QuotaService, error) {
+	if cfg.Daily <= 0 && cfg.Monthly <= 0 && len(cfg.Keys) == 0 {
+		return nil, nil
+	}
+
+	if quotaRepo == nil {
+		return nil, repository.ErrQuotasUnsupported
+	}
+
+	overrides := make(map[string]service.Quota, len(cfg.Keys))
+	for id, quota := range cfg.Keys {
+		overrides[id] = service.Quota{Daily: quota.Daily, Monthly: quota.Monthly}
+	}
+
+	return service.NewQuotaService(&service.QuotaConfig{
+		Logger:    logger,
+		QuotaRepo: quotaRepo,
+		Default:   service.Quota{Daily: cfg.Daily, Monthly: cfg.Monthly},
+		Overrides: overrides,
+	}), nil
+}
+
+// newChallengeService creates the challenge service, if anonymous requests need to solve a proof of work.
+// It returns nil if no secret is configured to sign challenges with.
+func newChallengeService(cfg config.ProofOfWorkConfig) service.ChallengeService {
+	if cfg.Secret == "" {
+		return nil
+	}
+
+	return service.NewChallengeService(&service.ChallengeConfig{
+		Secret:        []byte(cfg.Secret),
+		TTL:           cfg.TTL,
+		MinDifficulty: cfg.MinDifficulty,
+		MaxDifficulty: cfg.MaxDifficulty,
+		LoadStep:      cfg.LoadStep,
+	})
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SECRET_SET is the setting of a secret when there's no audit.secret_key to HMAC it with.
+const SECRET_SET = "set"
+
+// Settings flattens the config into its settings, keyed by their path in the config file, such as "rate_limit.groups.api.requests".
+// Settings left at their zero value and fields tagged `audit:"omit"` are omitted, and fields tagged `audit:"secret"` are
+// replaced by their HMAC keyed by audit.secret_key, or SECRET_SET without one, so the settings can be compared between runs
+// without revealing secrets.
+func (c *Config) Settings() map[string]string {
+	settings := map[string]string{}
+	flatten(settings, "", reflect.ValueOf(*c), []byte(c.Audit.SecretKey), false)
+	return settings
+}
+
+// flatten adds the settings in v to settings, prefixing their keys with prefix. Secrets are HMACed with key.
+func flatten(settings map[string]string, prefix string, v reflect.Value, key []byte, secret bool) {
+	if v.IsZero() {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.Tag.Get("audit") == "omit" {
+				continue
+			}
+
+			name := strings.ToLower(field.Name)
+			if tag := field.Tag.Get("mapstructure"); tag != "" {
+				name = tag
+			}
+
+			flatten(settings, join(prefix, name), v.Field(i), key, field.Tag.Get("audit") == "secret")
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			flatten(settings, join(prefix, fmt.Sprint(iter.Key().Interface())), iter.Value(), key, secret)
+		}
+	default:
+		value := fmt.Sprint(v.Interface())
+		switch {
+		case secret && len(key) == 0:
+			value = SECRET_SET
+		case secret:
+			mac := hmac.New(sha256.New, key)
+			mac.Write([]byte(value))
+			value = "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))
+		}
+
+		settings[prefix] = value
+	}
+}
+
+// join joins the keys of a setting with dots.
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+
+	return prefix + "." + key
+}
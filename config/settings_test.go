@@ -0,0 +1,32 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Settings_Secrets(t *testing.T) {
+	config := &Config{
+		Server: ServerConfig{Port: 8080},
+		Admin:  AdminConfig{Token: "s3cret"},
+	}
+
+	// without a key, secrets are only recorded as set
+	settings := config.Settings()
+	assert.Equal(t, map[string]string{"server.port": "8080", "admin.token": SECRET_SET}, settings)
+
+	// with one, they're HMACed, so changing them is recorded, but the key itself never is
+	config.Audit.SecretKey = "audit key"
+	settings = config.Settings()
+	assert.True(t, strings.HasPrefix(settings["admin.token"], "hmac-sha256:"), settings["admin.token"])
+	assert.NotContains(t, settings["admin.token"], "s3cret")
+	assert.NotContains(t, settings, "audit.secret_key")
+
+	config.Admin.Token = "s3cret2"
+	assert.NotEqual(t, settings["admin.token"], config.Settings()["admin.token"])
+
+	config.Admin.Token = "s3cret"
+	assert.Equal(t, settings["admin.token"], config.Settings()["admin.token"])
+}
@@ -0,0 +1,9 @@
+// Package db embeds the SQL migrations, so the binary can migrate a database from any directory.
+package db
+
+import "embed"
+
+// Migrations holds the SQLite migrations in "migrations", and the PostgreSQL migrations in "migrations/postgres".
+//
+//go:embed migrations
+var Migrations embed.FS
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS "audit_log_actor";
+DROP INDEX IF EXISTS "url_owner";
+DROP TABLE IF EXISTS "privacy_job";
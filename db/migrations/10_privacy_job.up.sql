@@ -0,0 +1,22 @@
+-- jobs that export or erase everything tied to an api key or user, for data protection requests
+CREATE TABLE "privacy_job" (
+    id TEXT PRIMARY KEY,
+    kind TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    status TEXT NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    requested_by TEXT NOT NULL DEFAULT '',
+    links INT NOT NULL DEFAULT 0,
+    audit_entries INT NOT NULL DEFAULT 0,
+    export TEXT,
+    created_at TIMESTAMP NOT NULL,
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX "privacy_job_subject" ON "privacy_job" (subject);
+CREATE INDEX "privacy_job_status" ON "privacy_job" (status);
+
+-- a subject's links and audit entries are looked up by their id
+CREATE INDEX "url_owner" ON "url" (owner);
+CREATE INDEX "audit_log_actor" ON "audit_log" (actor);
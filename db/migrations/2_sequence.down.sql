@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "sequence";
\ No newline at end of file
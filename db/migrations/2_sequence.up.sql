@@ -0,0 +1,6 @@
+CREATE TABLE "sequence" (
+    name TEXT PRIMARY KEY,
+    value INTEGER NOT NULL DEFAULT 0
+);
+
+INSERT INTO "sequence" (name, value) VALUES ('url', 0);
\ No newline at end of file
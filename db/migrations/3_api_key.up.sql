@@ -0,0 +1,9 @@
+CREATE TABLE "api_key" (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP
+);
+
+ALTER TABLE "url" ADD COLUMN owner TEXT NOT NULL DEFAULT '';
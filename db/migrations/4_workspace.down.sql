@@ -0,0 +1,8 @@
+ALTER TABLE "api_key" DROP COLUMN workspace_id;
+
+DROP INDEX IF EXISTS url_workspace_id;
+ALTER TABLE "url" DROP COLUMN workspace_id;
+
+DROP TABLE IF EXISTS "membership";
+DROP TABLE IF EXISTS "app_user";
+DROP TABLE IF EXISTS "workspace";
@@ -0,0 +1 @@
+ALTER TABLE "api_key" DROP COLUMN scopes;
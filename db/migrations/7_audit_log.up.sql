@@ -0,0 +1,15 @@
+-- who changed what, when and from where. Entries are only ever appended
+CREATE TABLE "audit_log" (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    action TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    workspace_id TEXT NOT NULL DEFAULT '',
+    target TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT '',
+    request_id TEXT NOT NULL DEFAULT '',
+    changes TEXT NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX "audit_log_target" ON "audit_log" (target);
+CREATE INDEX "audit_log_workspace_id" ON "audit_log" (workspace_id);
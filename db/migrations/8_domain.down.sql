@@ -0,0 +1,19 @@
+-- urls on other domains can't be kept, as their tokens may clash with the default domain's
+CREATE TABLE "url_old" (
+    token TEXT PRIMARY KEY,
+    target_url TEXT NOT NULL,
+    visits INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP,
+    owner TEXT NOT NULL DEFAULT '',
+    workspace_id TEXT NOT NULL DEFAULT 'default'
+);
+
+INSERT INTO "url_old" (token, target_url, visits, created_at, owner, workspace_id)
+    SELECT token, target_url, visits, created_at, owner, workspace_id FROM "url" WHERE domain = '';
+
+DROP TABLE "url";
+ALTER TABLE "url_old" RENAME TO "url";
+
+CREATE INDEX url_workspace_id ON url (workspace_id);
+
+DROP TABLE IF EXISTS "domain";
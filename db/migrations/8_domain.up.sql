@@ -0,0 +1,34 @@
+-- short domains registered by workspaces
+CREATE TABLE "domain" (
+    name TEXT PRIMARY KEY,
+    workspace_id TEXT NOT NULL REFERENCES workspace (id) ON DELETE CASCADE,
+    verification_token TEXT NOT NULL,
+    verified_at TIMESTAMP,
+    default_redirect TEXT NOT NULL DEFAULT '',
+    not_found_url TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX "domain_workspace_id" ON "domain" (workspace_id);
+
+-- the same token can be used on each domain, so urls are keyed by their domain and token.
+-- sqlite can't change a primary key, so the table is rebuilt
+CREATE TABLE "url_new" (
+    domain TEXT NOT NULL DEFAULT '',
+    token TEXT NOT NULL,
+    target_url TEXT NOT NULL,
+    visits INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP,
+    owner TEXT NOT NULL DEFAULT '',
+    workspace_id TEXT NOT NULL DEFAULT 'default',
+    PRIMARY KEY (domain, token)
+);
+
+INSERT INTO "url_new" (token, target_url, visits, created_at, owner, workspace_id)
+    SELECT token, target_url, visits, created_at, owner, workspace_id FROM "url";
+
+DROP TABLE "url";
+ALTER TABLE "url_new" RENAME TO "url";
+
+CREATE INDEX url_workspace_id ON url (workspace_id);
+CREATE INDEX url_token ON url (token);
@@ -0,0 +1,2 @@
+-- disabled urls aren't redirected to, until an admin enables them again
+ALTER TABLE "url" ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT 0;
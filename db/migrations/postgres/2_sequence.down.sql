@@ -0,0 +1 @@
+DROP SEQUENCE IF EXISTS url_sequence;
@@ -0,0 +1 @@
+CREATE SEQUENCE url_sequence;
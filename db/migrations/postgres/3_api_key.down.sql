@@ -0,0 +1,3 @@
+ALTER TABLE url DROP COLUMN owner;
+
+DROP TABLE IF EXISTS api_key;
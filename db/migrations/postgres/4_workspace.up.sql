@@ -0,0 +1,29 @@
+CREATE TABLE workspace (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE app_user (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE membership (
+    workspace_id TEXT NOT NULL REFERENCES workspace (id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES app_user (id) ON DELETE CASCADE,
+    role TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (workspace_id, user_id)
+);
+
+CREATE INDEX membership_user_id ON membership (user_id);
+
+-- existing links and keys belong to the default workspace
+INSERT INTO workspace (id, name, created_at) VALUES ('default', 'Default', NOW());
+
+ALTER TABLE url ADD COLUMN workspace_id TEXT NOT NULL DEFAULT 'default';
+CREATE INDEX url_workspace_id ON url (workspace_id);
+
+ALTER TABLE api_key ADD COLUMN workspace_id TEXT NOT NULL DEFAULT 'default';
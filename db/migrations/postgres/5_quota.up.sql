@@ -0,0 +1,7 @@
+-- how many urls each api key, user or anonymous ip has created in a day or month
+CREATE TABLE quota_usage (
+    subject TEXT NOT NULL,
+    period TEXT NOT NULL,
+    used INTEGER NOT NULL,
+    PRIMARY KEY (subject, period)
+);
@@ -0,0 +1,2 @@
+-- existing keys keep everything they could do before keys had scopes
+ALTER TABLE api_key ADD COLUMN scopes TEXT NOT NULL DEFAULT 'links:create links:write links:delete analytics:read';
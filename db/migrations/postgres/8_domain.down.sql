@@ -0,0 +1,8 @@
+-- urls on other domains can't be kept, as their tokens may clash with the default domain's
+DELETE FROM url WHERE domain <> '';
+DROP INDEX IF EXISTS url_token;
+ALTER TABLE url DROP CONSTRAINT url_pkey;
+ALTER TABLE url DROP COLUMN domain;
+ALTER TABLE url ADD PRIMARY KEY (token);
+
+DROP TABLE IF EXISTS domain;
@@ -0,0 +1,18 @@
+-- short domains registered by workspaces
+CREATE TABLE domain (
+    name TEXT PRIMARY KEY,
+    workspace_id TEXT NOT NULL REFERENCES workspace (id) ON DELETE CASCADE,
+    verification_token TEXT NOT NULL,
+    verified_at TIMESTAMPTZ,
+    default_redirect TEXT NOT NULL DEFAULT '',
+    not_found_url TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX domain_workspace_id ON domain (workspace_id);
+
+-- the same token can be used on each domain, so urls are keyed by their domain and token
+ALTER TABLE url ADD COLUMN domain TEXT NOT NULL DEFAULT '';
+ALTER TABLE url DROP CONSTRAINT url_pkey;
+ALTER TABLE url ADD PRIMARY KEY (domain, token);
+CREATE INDEX url_token ON url (token);
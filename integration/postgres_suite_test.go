@@ -0,0 +1,75 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/Jaytpa01/url-shortener-api/config"
+	"github.com/Jaytpa01/url-shortener-api/internal/migration"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository/repotest"
+	"github.com/Jaytpa01/url-shortener-api/pkg/utils"
+	"github.com/stretchr/testify/suite"
+)
+
+// PostgresRepoSuite runs the repository conformance suite against the database in the POSTGRES_DSN environment variable.
+// Start one locally with "make postgres.start".
+type PostgresRepoSuite struct {
+	repotest.Suite
+	dsn      string
+	postgres repository.UrlRepository
+}
+
+func TestPostgresRepoSuite(t *testing.T) {
+	suite.Run(t, new(PostgresRepoSuite))
+}
+
+func (s *PostgresRepoSuite) SetupSuite() {
+	s.dsn = os.Getenv("POSTGRES_DSN")
+	if s.dsn == "" {
+		s.T().Skip("POSTGRES_DSN isn't set")
+	}
+
+	var err error
+	s.postgres, err = repository.NewPostgresRepository(s.dsn, config.PoolConfig{MaxOpenConns: 4})
+	s.Require().NoError(err)
+
+	// every test starts with freshly migrated tables, so the same repository can be reused
+	s.NewRepository = func(t *testing.T) repository.UrlRepository {
+		return s.postgres
+	}
+}
+
+func (s *PostgresRepoSuite) SetupTest() {
+	m, err := migration.New(s.dsn)
+	s.Require().NoError(err)
+	defer m.Close()
+
+	s.Require().NoError(m.Up())
+
+	s.Suite.SetupTest()
+}
+
+func (s *PostgresRepoSuite) TearDownTest() {
+	m, err := migration.New(s.dsn)
+	s.Require().NoError(err)
+	defer m.Close()
+
+	status, err := m.Status()
+	s.Require().NoError(err)
+	s.Require().NoError(m.Down(len(status.Migrations)))
+}
+
+func (s *PostgresRepoSuite) TestNextSequence() {
+	seq := s.postgres.(utils.Sequence)
+
+	first, err := seq.NextSequence(context.Background())
+	s.NoError(err)
+	second, err := seq.NextSequence(context.Background())
+	s.NoError(err)
+	s.Equal(first+1, second)
+}
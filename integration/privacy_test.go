@@ -0,0 +1,184 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/config"
+	"github.com/Jaytpa01/url-shortener-api/internal/auth"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/migration"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/Jaytpa01/url-shortener-api/internal/service"
+	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPrivacyErase erases a subject from a sqlite database holding the data of two api keys and two users,
+// and checks no row of any table still contains the subject or their name, while the other subjects are untouched.
+func TestPrivacyErase(t *testing.T) {
+	testCases := []struct {
+		subject string
+		name    string
+		kept    []string
+	}{
+		{"key_1", "Jane's key", []string{"key_2", "Bob's key", "user_1", "Jane Doe", "user_2", "Bob Smith"}},
+		{"user_1", "Jane Doe", []string{"key_1", "Jane's key", "key_2", "Bob's key", "user_2", "Bob Smith"}},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.subject, func(t *testing.T) {
+			dsn := filepath.Join(t.TempDir(), "test.db")
+			m, err := migration.New("sqlite3://" + dsn)
+			require.NoError(t, err)
+			require.NoError(t, m.Up())
+			require.NoError(t, m.Close())
+
+			urlRepo, err := repository.NewSQLiteRepository(dsn, config.PoolConfig{MaxOpenConns: 4})
+			require.NoError(t, err)
+			t.Cleanup(func() { urlRepo.(io.Closer).Close() })
+
+			privacy := newPrivacyService(t, urlRepo)
+			ctx := auth.NewContext(context.Background(), &auth.Principal{Name: "admin", Admin: true})
+
+			// an earlier export of the subject is stored with the job
+			export, err := privacy.RunJob(ctx, entity.PrivacyExport, test.subject)
+			require.NoError(t, err)
+			require.Equal(t, entity.PrivacyJobSucceeded, export.Status, export.Error)
+
+			job, err := privacy.RunJob(ctx, entity.PrivacyErase, test.subject)
+			require.NoError(t, err)
+			require.Equal(t, entity.PrivacyJobSucceeded, job.Status, job.Error)
+			assert.Equal(t, 1, job.Links)
+
+			db, err := sql.Open("sqlite3", dsn)
+			require.NoError(t, err)
+			defer db.Close()
+
+			rows := tableRows(t, db)
+			for _, erased := range []string{test.subject, test.name} {
+				for _, row := range rows {
+					assert.NotContains(t, row, erased)
+				}
+			}
+
+			for _, kept := range test.kept {
+				found := false
+				for _, row := range rows {
+					found = found || strings.Contains(row, kept)
+				}
+				assert.True(t, found, "%s was erased too", kept)
+			}
+		})
+	}
+}
+
+// newPrivacyService seeds urlRepo's database with the links, keys, users, memberships, quota usage and audit entries
+// of key_1, key_2, user_1 and user_2, and returns a privacy service for it.
+func newPrivacyService(t *testing.T, urlRepo repository.UrlRepository) service.PrivacyService {
+	ctx := context.Background()
+	createdAt := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	apiKeyRepo, err := repository.NewApiKeyRepository(urlRepo)
+	require.NoError(t, err)
+	workspaceRepo, err := repository.NewWorkspaceRepository(urlRepo)
+	require.NoError(t, err)
+	quotaRepo, err := repository.NewQuotaRepository(urlRepo)
+	require.NoError(t, err)
+	auditRepo, err := repository.NewAuditRepository(urlRepo)
+	require.NoError(t, err)
+	privacyRepo, err := repository.NewPrivacyRepository(urlRepo)
+	require.NoError(t, err)
+
+	for i, key := range []struct{ id, name string }{{"key_1", "Jane's key"}, {"key_2", "Bob's key"}} {
+		require.NoError(t, apiKeyRepo.CreateApiKey(ctx, &entity.ApiKey{ID: key.id, Name: key.name, Hash: fmt.Sprintf("hash_%d", i),
+			WorkspaceID: entity.DEFAULT_WORKSPACE, Scopes: entity.DEFAULT_SCOPES, CreatedAt: createdAt}))
+		require.NoError(t, auditRepo.AppendAuditEntry(ctx, &entity.AuditEntry{Action: entity.AuditKeyCreate, Actor: "cli", WorkspaceID: entity.DEFAULT_WORKSPACE,
+			Target: key.id, Changes: entity.AuditChanges{"name": {After: key.name}, "scopes": {After: entity.DEFAULT_SCOPES.String()}}, CreatedAt: createdAt}))
+	}
+
+	for _, user := range []struct{ id, name string }{{"user_1", "Jane Doe"}, {"user_2", "Bob Smith"}} {
+		require.NoError(t, workspaceRepo.SaveUser(ctx, &entity.User{ID: user.id, Name: user.name, CreatedAt: createdAt}))
+	}
+	require.NoError(t, workspaceRepo.CreateWorkspace(ctx, &entity.Workspace{ID: "ws_1", Name: "Team", CreatedAt: createdAt},
+		&entity.Membership{WorkspaceID: "ws_1", UserID: "user_1", Role: entity.RoleOwner, CreatedAt: createdAt}))
+	require.NoError(t, workspaceRepo.AddMember(ctx, &entity.Membership{WorkspaceID: "ws_1", UserID: "user_2", Role: entity.RoleEditor, CreatedAt: createdAt}))
+	require.NoError(t, auditRepo.AppendAuditEntry(ctx, &entity.AuditEntry{Action: entity.AuditMemberAdd, Actor: "user_1", WorkspaceID: "ws_1",
+		Target: "user_2", IP: "192.0.2.1", Changes: entity.AuditChanges{"role": {After: string(entity.RoleEditor)}}, CreatedAt: createdAt}))
+
+	for i, owner := range []string{"key_1", "key_2", "user_1", "user_2"} {
+		token := fmt.Sprintf("link%02d", i)
+		require.NoError(t, urlRepo.Create(ctx, &entity.Url{Token: token, TargetUrl: "https://example.com/" + token, Owner: owner,
+			WorkspaceID: entity.DEFAULT_WORKSPACE, CreatedAt: createdAt}))
+		require.NoError(t, auditRepo.AppendAuditEntry(ctx, &entity.AuditEntry{Action: entity.AuditLinkCreate, Actor: owner, WorkspaceID: entity.DEFAULT_WORKSPACE,
+			Target: token, IP: "192.0.2.2", Changes: entity.AuditChanges{"target_url": {After: "https://example.com/" + token}}, CreatedAt: createdAt}))
+
+		_, err := quotaRepo.UseQuota(ctx, owner, []repository.QuotaPeriod{{Key: "day:2023-04-01", Limit: 10}})
+		require.NoError(t, err)
+	}
+
+	return service.NewPrivacyService(&service.PrivacyConfig{
+		Logger:        logger.NewApiLogger("development"),
+		UrlRepo:       urlRepo,
+		AuditRepo:     auditRepo,
+		PrivacyRepo:   privacyRepo,
+		ApiKeyRepo:    apiKeyRepo,
+		WorkspaceRepo: workspaceRepo,
+		QuotaRepo:     quotaRepo,
+	})
+}
+
+// tableRows returns every row of every table in the database, each formatted as "table: column=value, ...".
+func tableRows(t *testing.T, db *sql.DB) []string {
+	tables := []string{}
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'`)
+	require.NoError(t, err)
+	for rows.Next() {
+		var table string
+		require.NoError(t, rows.Scan(&table))
+		tables = append(tables, table)
+	}
+	require.NoError(t, rows.Err())
+	rows.Close()
+
+	formatted := []string{}
+	for _, table := range tables {
+		rows, err := db.Query(fmt.Sprintf(`SELECT * FROM "%s"`, table))
+		require.NoError(t, err)
+
+		columns, err := rows.Columns()
+		require.NoError(t, err)
+
+		for rows.Next() {
+			values := make([]interface{}, len(columns))
+			pointers := make([]interface{}, len(columns))
+			for i := range values {
+				pointers[i] = &values[i]
+			}
+			require.NoError(t, rows.Scan(pointers...))
+
+			fields := make([]string, len(columns))
+			for i, column := range columns {
+				if b, ok := values[i].([]byte); ok {
+					values[i] = string(b)
+				}
+				fields[i] = fmt.Sprintf("%s=%v", column, values[i])
+			}
+			formatted = append(formatted, table+": "+strings.Join(fields, ", "))
+		}
+		require.NoError(t, rows.Err())
+		rows.Close()
+	}
+
+	return formatted
+}
@@ -1,44 +1,55 @@
-// NOTE: currently have a warning when build tags present. Don't forget to address this later.
+//go:build integration
+// +build integration
 
 package integration
 
 import (
-	"log"
-
-	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
-	"github.com/jmoiron/sqlx"
-	"github.com/stretchr/testify/assert"
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jaytpa01/url-shortener-api/config"
+	"github.com/Jaytpa01/url-shortener-api/internal/migration"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository/repotest"
+	"github.com/Jaytpa01/url-shortener-api/pkg/utils"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
+// SQLiteRepoSuite runs the repository conformance suite against a freshly migrated sqlite database for every test.
 type SQLiteRepoSuite struct {
-	suite.Suite
-	db *sqlx.DB
+	repotest.Suite
 }
 
-const sqliteDSN = "sqlite3://db/test.db"
-
-func (s *SQLiteRepoSuite) SetupSuite() {
-	var err error
-	s.db, err = sqlx.Connect("sqlite3", "./db/test.db")
-	if err != nil {
-		log.Fatalf("couldn't connect to sqlite database: %v", err)
-	}
+func TestSQLiteRepoSuite(t *testing.T) {
+	suite.Run(t, &SQLiteRepoSuite{
+		Suite: repotest.Suite{NewRepository: newSQLiteRepository},
+	})
 }
 
-func (s *SQLiteRepoSuite) SetupTest() {
-	m, err := migrate.New("file://db/migrations", sqliteDSN)
-	assert.NoError(s.T(), err)
+func newSQLiteRepository(t *testing.T) repository.UrlRepository {
+	dsn := filepath.Join(t.TempDir(), "test.db")
+
+	m, err := migration.New("sqlite3://" + dsn)
+	require.NoError(t, err)
+	require.NoError(t, m.Up())
+	require.NoError(t, m.Close())
 
-	if err = m.Up(); err != nil && err != migrate.ErrNoChange {
-		panic(err)
-	}
+	repo, err := repository.NewSQLiteRepository(dsn, config.PoolConfig{MaxOpenConns: 4})
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.(io.Closer).Close() })
+
+	return repo
 }
 
-func (s *SQLiteRepoSuite) TearDownTest() {
-	m, err := migrate.New("file://db/migrations", sqliteDSN)
-	assert.NoError(s.T(), err)
-	assert.NoError(s.T(), m.Down())
+func (s *SQLiteRepoSuite) TestNextSequence() {
+	seq := s.NewRepository(s.T()).(utils.Sequence)
+
+	first, err := seq.NextSequence(context.Background())
+	s.NoError(err)
+	second, err := seq.NextSequence(context.Background())
+	s.NoError(err)
+	s.Equal(first+1, second)
 }
@@ -0,0 +1,23 @@
+// Package audit carries where a request came from in its context, so the changes it makes can be audited.
+package audit
+
+import "context"
+
+// Source is where a request came from. Requests from the command line have no source.
+type Source struct {
+	IP        string
+	RequestID string
+}
+
+type sourceKey struct{}
+
+// NewContext returns a copy of ctx carrying the source.
+func NewContext(ctx context.Context, source Source) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+// FromContext returns the source in ctx, or an empty source if there's none.
+func FromContext(ctx context.Context) Source {
+	source, _ := ctx.Value(sourceKey{}).(Source)
+	return source
+}
@@ -0,0 +1,83 @@
+// Package auth generates and hashes api keys, and carries the principal a request was authenticated as in its context.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+)
+
+const (
+	KEY_PREFIX    = "usk_" // prefix of every api key, so leaked keys are easy to spot
+	KEY_BYTES     = 32     // random bytes in an api key
+	KEY_ID_BYTES  = 8      // random bytes in an api key's id
+	KEY_ID_PREFIX = "key_"
+)
+
+// Principal is who a request was authenticated as, either an api key, a user with a JWT, or the admin token.
+type Principal struct {
+	KeyID       string // set for api keys
+	UserID      string // set for JWTs
+	WorkspaceID string // the workspace the request acts in, empty for the default workspace
+	Name        string
+	Admin       bool          // set for the admin token, which can act in every workspace
+	Scopes      entity.Scopes // set for api keys, which can only do what their scopes allow
+}
+
+// HasScope reports whether the principal is allowed to do what scope allows. Only api keys are limited by scopes.
+func (p *Principal) HasScope(scope entity.Scope) bool {
+	if p.KeyID == "" {
+		return true
+	}
+
+	return p.Scopes.Has(scope)
+}
+
+// Owner returns the id urls created by the principal are owned by.
+func (p *Principal) Owner() string {
+	if p.KeyID != "" {
+		return p.KeyID
+	}
+
+	return p.UserID
+}
+
+type principalKey struct{}
+
+// NewContext returns a copy of ctx carrying the principal.
+func NewContext(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the principal in ctx, or false if the request is anonymous.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// GenerateKey returns a new api key and its id. Only the key's hash should be stored.
+func GenerateKey() (id, key string, err error) {
+	idBytes := make([]byte, KEY_ID_BYTES)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", "", fmt.Errorf("couldn't generate api key id: %w", err)
+	}
+
+	keyBytes := make([]byte, KEY_BYTES)
+	if _, err := rand.Read(keyBytes); err != nil {
+		return "", "", fmt.Errorf("couldn't generate api key: %w", err)
+	}
+
+	return KEY_ID_PREFIX + hex.EncodeToString(idBytes), KEY_PREFIX + base64.RawURLEncoding.EncodeToString(keyBytes), nil
+}
+
+// HashKey returns the hash of an api key that is stored in its place.
+// Keys are long and random, so a fast hash is enough, and lets keys be looked up by their hash.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GenerateKey(t *testing.T) {
+	id, key, err := GenerateKey()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(id, KEY_ID_PREFIX))
+	assert.True(t, strings.HasPrefix(key, KEY_PREFIX))
+
+	otherId, otherKey, err := GenerateKey()
+	require.NoError(t, err)
+	assert.NotEqual(t, id, otherId)
+	assert.NotEqual(t, key, otherKey)
+
+	assert.Equal(t, HashKey(key), HashKey(key))
+	assert.NotEqual(t, HashKey(key), HashKey(otherKey))
+	assert.NotContains(t, HashKey(key), key)
+}
+
+func Test_Context(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+
+	ctx := NewContext(context.Background(), &Principal{KeyID: "key_1", Name: "ci"})
+	principal, ok := FromContext(ctx)
+	require.True(t, ok)
+	assert.Equal(t, "key_1", principal.KeyID)
+}
+
+func Test_HasScope(t *testing.T) {
+	testCases := []struct {
+		name      string
+		principal *Principal
+		scope     entity.Scope
+		expected  bool
+	}{
+		{"Key With Scope", &Principal{KeyID: "key_1", Scopes: entity.Scopes{entity.ScopeAnalyticsRead}}, entity.ScopeAnalyticsRead, true},
+		{"Key Without Scope", &Principal{KeyID: "key_1", Scopes: entity.Scopes{entity.ScopeAnalyticsRead}}, entity.ScopeLinksCreate, false},
+		{"Admin Key", &Principal{KeyID: "key_1", Scopes: entity.Scopes{entity.ScopeAdmin}}, entity.ScopeLinksDelete, true},
+		{"Key Without Scopes", &Principal{KeyID: "key_1"}, entity.ScopeLinksCreate, false},
+		{"User", &Principal{UserID: "user_1"}, entity.ScopeLinksDelete, true},
+		{"Admin Token", &Principal{Admin: true}, entity.ScopeAdmin, true},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.principal.HasScope(test.scope))
+		})
+	}
+}
@@ -0,0 +1,340 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_JWKS_TTL   = time.Hour        // how long a fetched key set is used before it is fetched again
+	JWKS_MIN_REFRESH   = 10 * time.Second // how often an unknown key id can trigger a fetch
+	MAX_JWKS_SIZE      = 1 << 20
+	JWKS_FETCH_TIMEOUT = 10 * time.Second
+)
+
+var ErrKeyNotFound = errors.New("no key in the key set matches the token")
+
+// JSONWebKey is a public key from a JWKS.
+type JSONWebKey struct {
+	ID  string
+	Alg string // the algorithm the key is for, empty if it can be used with any algorithm for its type
+	Key crypto.PublicKey
+}
+
+// KeySource finds the key a token was signed with.
+type KeySource interface {
+	Key(ctx context.Context, kid string) (*JSONWebKey, error)
+}
+
+// jsonWebKey is a key as it appears in a JWKS document.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseKeySet parses a JWKS document. Keys that aren't RSA or EC signing keys are skipped,
+// as identity providers often publish encryption keys alongside them.
+func ParseKeySet(data []byte) ([]*JSONWebKey, error) {
+	doc := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid jwks: %w", err)
+	}
+
+	keys := []*JSONWebKey{}
+	for _, jwk := range doc.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		var (
+			key crypto.PublicKey
+			err error
+		)
+		switch jwk.Kty {
+		case "RSA":
+			key, err = jwk.rsaKey()
+		case "EC":
+			key, err = jwk.ecKey()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwks key (%s): %w", jwk.Kid, err)
+		}
+
+		keys = append(keys, &JSONWebKey{ID: jwk.Kid, Alg: jwk.Alg, Key: key})
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("jwks has no rsa or ec signing keys")
+	}
+
+	return keys, nil
+}
+
+func (jwk *jsonWebKey) rsaKey() (*rsa.PublicKey, error) {
+	n, err := decodeBigInt(jwk.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+
+	e, err := decodeBigInt(jwk.E)
+	if err != nil || !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid exponent")
+	}
+
+	if n.BitLen() < 2048 {
+		return nil, fmt.Errorf("rsa keys must be at least 2048 bits, got %d", n.BitLen())
+	}
+
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (jwk *jsonWebKey) ecKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch jwk.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve (%s)", jwk.Crv)
+	}
+
+	x, err := decodeBigInt(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("invalid x: %w", err)
+	}
+
+	y, err := decodeBigInt(jwk.Y)
+	if err != nil {
+		return nil, fmt.Errorf("invalid y: %w", err)
+	}
+
+	if !curve.IsOnCurve(x, y) {
+		return nil, errors.New("point isn't on the curve")
+	}
+
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, errors.New("empty value")
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
+
+// JWKS is a cached key set, loaded from a url or a file.
+// The keys are loaded again once they are older than the TTL, or when a token names a key that isn't in the set,
+// so keys rotated by the identity provider are picked up without a restart.
+// Loads happen at most every JWKS_MIN_REFRESH, one at a time, and without holding the lock, so an identity provider
+// that is down doesn't hold up every request. Stale keys are used while they're loaded again.
+type JWKS struct {
+	load func(ctx context.Context) ([]byte, error)
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu         sync.Mutex
+	keys       []*JSONWebKey
+	loaded     time.Time
+	lastLoaded time.Time     // when a load was last attempted, successful or not
+	loadErr    error         // why the last load failed, nil if it succeeded
+	loading    chan struct{} // closed when the load in progress finishes, nil if there isn't one
+}
+
+// NewRemoteJWKS returns a key set fetched from url, such as an identity provider's jwks_uri.
+// A ttl of zero defaults to DEFAULT_JWKS_TTL.
+func NewRemoteJWKS(url string, client *http.Client, ttl time.Duration) *JWKS {
+	if client == nil {
+		client = &http.Client{Timeout: JWKS_FETCH_TIMEOUT}
+	}
+
+	return newJWKS(func(ctx context.Context) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+
+		res, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't fetch jwks: %w", err)
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("couldn't fetch jwks: %s returned %s", url, res.Status)
+		}
+
+		return io.ReadAll(io.LimitReader(res.Body, MAX_JWKS_SIZE))
+	}, ttl)
+}
+
+// NewFileJWKS returns a key set read from the file at path, for deployments that can't reach the identity provider.
+// The file is read again once the ttl has passed, so it can be replaced to rotate keys.
+func NewFileJWKS(path string, ttl time.Duration) *JWKS {
+	return newJWKS(func(ctx context.Context) ([]byte, error) {
+		return os.ReadFile(path)
+	}, ttl)
+}
+
+func newJWKS(load func(ctx context.Context) ([]byte, error), ttl time.Duration) *JWKS {
+	if ttl <= 0 {
+		ttl = DEFAULT_JWKS_TTL
+	}
+
+	return &JWKS{
+		load: load,
+		ttl:  ttl,
+		now:  time.Now,
+	}
+}
+
+// Key returns the key with the id kid. If kid is empty, the set must hold a single key.
+func (j *JWKS) Key(ctx context.Context, kid string) (*JSONWebKey, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := j.now()
+	if j.keys == nil || now.Sub(j.loaded) >= j.ttl {
+		loading := j.refresh(now)
+
+		// if the identity provider is down, keep using the keys we have
+		if j.keys == nil {
+			if loading == nil {
+				return nil, j.loadErr
+			}
+			if err := j.wait(ctx, loading); err != nil {
+				return nil, err
+			}
+			if j.keys == nil {
+				return nil, j.loadErr
+			}
+		}
+	}
+
+	if key := j.find(kid); key != nil {
+		return key, nil
+	}
+
+	// the key may have just been rotated in, but don't let made up key ids hammer the identity provider
+	if loading := j.refresh(now); loading != nil {
+		if err := j.wait(ctx, loading); err != nil {
+			return nil, err
+		}
+		if j.loadErr != nil {
+			return nil, j.loadErr
+		}
+
+		if key := j.find(kid); key != nil {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w (kid %q)", ErrKeyNotFound, kid)
+}
+
+// refresh starts loading the key set, unless it was attempted less than JWKS_MIN_REFRESH ago.
+// It returns a channel that's closed once the load in progress finishes, or nil if there isn't one. j.mu must be held.
+//
+// The load uses a context of its own, so a request giving up doesn't fail it for the others waiting on it.
+func (j *JWKS) refresh(now time.Time) <-chan struct{} {
+	if j.loading != nil || now.Sub(j.lastLoaded) < JWKS_MIN_REFRESH {
+		return j.loading
+	}
+
+	j.lastLoaded = now
+	loading := make(chan struct{})
+	j.loading = loading
+
+	go func() {
+		defer close(loading)
+
+		ctx, cancel := context.WithTimeout(context.Background(), JWKS_FETCH_TIMEOUT)
+		defer cancel()
+
+		var keys []*JSONWebKey
+		data, err := j.load(ctx)
+		if err == nil {
+			keys, err = ParseKeySet(data)
+		}
+
+		j.mu.Lock()
+		defer j.mu.Unlock()
+
+		j.loading = nil
+		j.loadErr = err
+		if err == nil {
+			j.keys = keys
+			j.loaded = now
+		}
+	}()
+
+	return loading
+}
+
+// wait releases j.mu until the load finishes, or ctx is done. j.mu must be held, and is held again when it returns.
+func (j *JWKS) wait(ctx context.Context, loading <-chan struct{}) error {
+	j.mu.Unlock()
+	defer j.mu.Lock()
+
+	select {
+	case <-loading:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// find returns the key with the id kid. j.mu must be held.
+func (j *JWKS) find(kid string) *JSONWebKey {
+	if kid == "" {
+		if len(j.keys) == 1 {
+			return j.keys[0]
+		}
+		return nil
+	}
+
+	for _, key := range j.keys {
+		if key.ID == kid {
+			return key
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,273 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	DEFAULT_USER_CLAIM      = "sub"
+	DEFAULT_WORKSPACE_CLAIM = "workspace_id"
+	DEFAULT_LEEWAY          = time.Minute // allowed clock skew between us and the identity provider
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token has expired")
+)
+
+// TokenVerifier verifies bearer tokens, resolving them into the principal they were issued to.
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (*Principal, error)
+}
+
+// VerifierConfig configures how JWTs are verified.
+type VerifierConfig struct {
+	Keys     KeySource
+	Issuer   string // the iss claim every token must have
+	Audience string // a value the aud claim of every token must contain
+
+	UserClaim      string // claim holding the user id, defaults to DEFAULT_USER_CLAIM
+	WorkspaceClaim string // claim holding the workspace id, defaults to DEFAULT_WORKSPACE_CLAIM. It's optional in tokens.
+
+	Leeway time.Duration // defaults to DEFAULT_LEEWAY
+}
+
+// Verifier verifies JWT access tokens signed with RSA or ECDSA keys from a key set.
+// Symmetric and unsigned tokens are always rejected, whatever their header says.
+type Verifier struct {
+	cfg VerifierConfig
+	now func() time.Time
+}
+
+// NewVerifier returns a Verifier for cfg. The issuer and audience must be set, as without them
+// a token issued for any other service would be accepted here.
+func NewVerifier(cfg VerifierConfig) (*Verifier, error) {
+	if cfg.Keys == nil {
+		return nil, errors.New("jwt verifier needs a key set")
+	}
+
+	if cfg.Issuer == "" || cfg.Audience == "" {
+		return nil, errors.New("jwt verifier needs an issuer and audience to check")
+	}
+
+	if cfg.UserClaim == "" {
+		cfg.UserClaim = DEFAULT_USER_CLAIM
+	}
+
+	if cfg.WorkspaceClaim == "" {
+		cfg.WorkspaceClaim = DEFAULT_WORKSPACE_CLAIM
+	}
+
+	if cfg.Leeway <= 0 {
+		cfg.Leeway = DEFAULT_LEEWAY
+	}
+
+	return &Verifier{
+		cfg: cfg,
+		now: time.Now,
+	}, nil
+}
+
+// IsJWT reports whether token looks like a JWT, rather than an api key.
+func IsJWT(token string) bool {
+	return strings.Count(token, ".") == 2 && !strings.HasPrefix(token, KEY_PREFIX)
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verify checks the token's signature, issuer, audience and lifetime, and maps its claims to a principal.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: not a jwt", ErrInvalidToken)
+	}
+
+	header := jwtHeader{}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: invalid header: %v", ErrInvalidToken, err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid signature encoding", ErrInvalidToken)
+	}
+
+	key, err := v.cfg.Keys.Key(ctx, header.Kid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if key.Alg != "" && key.Alg != header.Alg {
+		return nil, fmt.Errorf("%w: key %s is for %s, not %s", ErrInvalidToken, key.ID, key.Alg, header.Alg)
+	}
+
+	if err := verifySignature(header.Alg, key.Key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	claims := map[string]interface{}{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: invalid claims: %v", ErrInvalidToken, err)
+	}
+
+	if err := v.checkClaims(claims); err != nil {
+		return nil, err
+	}
+
+	user, _ := claims[v.cfg.UserClaim].(string)
+	if user == "" {
+		return nil, fmt.Errorf("%w: missing %s claim", ErrInvalidToken, v.cfg.UserClaim)
+	}
+
+	workspace, _ := claims[v.cfg.WorkspaceClaim].(string)
+	name, _ := claims["name"].(string)
+
+	return &Principal{UserID: user, WorkspaceID: workspace, Name: name}, nil
+}
+
+// checkClaims checks the registered claims of a token.
+func (v *Verifier) checkClaims(claims map[string]interface{}) error {
+	now := v.now()
+
+	exp, ok := numericDate(claims["exp"])
+	if !ok {
+		return fmt.Errorf("%w: missing exp claim", ErrInvalidToken)
+	}
+	if now.After(exp.Add(v.cfg.Leeway)) {
+		return ErrTokenExpired
+	}
+
+	if nbf, ok := numericDate(claims["nbf"]); ok && now.Add(v.cfg.Leeway).Before(nbf) {
+		return fmt.Errorf("%w: token isn't valid yet", ErrInvalidToken)
+	}
+
+	if iss, _ := claims["iss"].(string); iss != v.cfg.Issuer {
+		return fmt.Errorf("%w: issuer %q isn't %q", ErrInvalidToken, iss, v.cfg.Issuer)
+	}
+
+	// aud is either a single string or an array of them
+	audiences := []string{}
+	switch aud := claims["aud"].(type) {
+	case string:
+		audiences = append(audiences, aud)
+	case []interface{}:
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				audiences = append(audiences, s)
+			}
+		}
+	}
+
+	for _, aud := range audiences {
+		if aud == v.cfg.Audience {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: audience %v doesn't include %q", ErrInvalidToken, audiences, v.cfg.Audience)
+}
+
+// numericDate reads a NumericDate claim, which is seconds since the epoch.
+func numericDate(claim interface{}) (time.Time, bool) {
+	n, ok := claim.(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	seconds, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, int64(seconds*float64(time.Second))), true
+}
+
+// decodeSegment decodes a base64url encoded JSON segment of a token into v.
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// jwtAlgorithms are the JWS algorithms tokens can be signed with, and their hashes.
+var jwtAlgorithms = map[string]crypto.Hash{
+	"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+	"PS256": crypto.SHA256, "PS384": crypto.SHA384, "PS512": crypto.SHA512,
+	"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+}
+
+// verifySignature verifies the signature of signed with key, using the JWS algorithm alg.
+func verifySignature(alg string, key crypto.PublicKey, signed, signature []byte) error {
+	hash, ok := jwtAlgorithms[alg]
+	if !ok {
+		return fmt.Errorf("unsupported algorithm (%s)", alg)
+	}
+
+	switch alg[:2] {
+	case "RS", "PS":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%s needs an rsa key", alg)
+		}
+
+		h := hash.New()
+		h.Write(signed)
+		if alg[0] == 'P' {
+			return rsa.VerifyPSS(rsaKey, hash, h.Sum(nil), signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		return rsa.VerifyPKCS1v15(rsaKey, hash, h.Sum(nil), signature)
+	default:
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%s needs an ec key", alg)
+		}
+
+		// the signature is r and s, each padded to the size of the curve
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size || ecKey.Curve.Params().BitSize != curveBits(alg) {
+			return errors.New("invalid ecdsa signature")
+		}
+
+		h := hash.New()
+		h.Write(signed)
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(ecKey, h.Sum(nil), r, s) {
+			return errors.New("invalid ecdsa signature")
+		}
+		return nil
+	}
+}
+
+// curveBits returns the size of the curve an ES algorithm must be used with.
+func curveBits(alg string) int {
+	switch alg {
+	case "ES256":
+		return 256
+	case "ES384":
+		return 384
+	default:
+		return 521
+	}
+}
@@ -0,0 +1,391 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testIssuer   = "https://id.example.com"
+	testAudience = "url-shortener"
+)
+
+var (
+	rsaKeyOnce sync.Once
+	rsaKey     *rsa.PrivateKey
+)
+
+// testRSAKey returns an rsa key shared by the tests, as generating one is slow.
+func testRSAKey(t *testing.T) *rsa.PrivateKey {
+	rsaKeyOnce.Do(func() {
+		var err error
+		rsaKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+	})
+	return rsaKey
+}
+
+func testECKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// jwk returns the public half of key as a JWKS key.
+func jwk(kid string, key crypto.Signer) map[string]string {
+	switch pub := key.Public().(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kty": "RSA", "kid": kid, "use": "sig", "n": b64(pub.N.Bytes()), "e": b64(big.NewInt(int64(pub.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		return map[string]string{"kty": "EC", "kid": kid, "crv": "P-256", "x": b64(pub.X.FillBytes(make([]byte, size))), "y": b64(pub.Y.FillBytes(make([]byte, size)))}
+	}
+	panic("unsupported key")
+}
+
+func jwks(t *testing.T, keys ...map[string]string) []byte {
+	data, err := json.Marshal(map[string]interface{}{"keys": keys})
+	require.NoError(t, err)
+	return data
+}
+
+// sign returns a JWT of claims, signed with key using alg.
+func sign(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	signed := b64(header) + "." + b64(payload)
+	hash := jwtAlgorithms[alg]
+	if hash == 0 {
+		// an unsupported algorithm, the signature doesn't matter
+		return signed + "." + b64([]byte("signature"))
+	}
+
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	var signature []byte
+	switch alg[:2] {
+	case "RS":
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), hash, digest)
+	case "PS":
+		signature, err = rsa.SignPSS(rand.Reader, key.(*rsa.PrivateKey), hash, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case "ES":
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, key.(*ecdsa.PrivateKey), digest)
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	require.NoError(t, err)
+
+	return signed + "." + b64(signature)
+}
+
+func validClaims(now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"iss":          testIssuer,
+		"aud":          testAudience,
+		"sub":          "user_1",
+		"workspace_id": "ws_1",
+		"name":         "Jane",
+		"iat":          now.Unix(),
+		"exp":          now.Add(time.Hour).Unix(),
+	}
+}
+
+// staticKeys is a KeySource over fixed keys
+type staticKeys []*JSONWebKey
+
+func (s staticKeys) Key(ctx context.Context, kid string) (*JSONWebKey, error) {
+	for _, key := range s {
+		if key.ID == kid {
+			return key, nil
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+func Test_Verifier(t *testing.T) {
+	now := time.Now()
+	rsaKey := testRSAKey(t)
+	ecKey := testECKey(t)
+
+	keys, err := ParseKeySet(jwks(t, jwk("rsa", rsaKey), jwk("ec", ecKey)))
+	require.NoError(t, err)
+	keys = append(keys, &JSONWebKey{ID: "rsa-ps", Alg: "PS256", Key: rsaKey.Public()})
+
+	verifier, err := NewVerifier(VerifierConfig{Keys: staticKeys(keys), Issuer: testIssuer, Audience: testAudience})
+	require.NoError(t, err)
+
+	with := func(changes map[string]interface{}) map[string]interface{} {
+		claims := validClaims(now)
+		for k, v := range changes {
+			if v == nil {
+				delete(claims, k)
+			} else {
+				claims[k] = v
+			}
+		}
+		return claims
+	}
+
+	testCases := []struct {
+		name        string
+		token       string
+		expected    *Principal
+		expectedErr error
+	}{
+		{"RS256", sign(t, "RS256", "rsa", rsaKey, validClaims(now)), &Principal{UserID: "user_1", WorkspaceID: "ws_1", Name: "Jane"}, nil},
+		{"PS256", sign(t, "PS256", "rsa-ps", rsaKey, validClaims(now)), &Principal{UserID: "user_1", WorkspaceID: "ws_1", Name: "Jane"}, nil},
+		{"ES256", sign(t, "ES256", "ec", ecKey, validClaims(now)), &Principal{UserID: "user_1", WorkspaceID: "ws_1", Name: "Jane"}, nil},
+		{"Audience List", sign(t, "RS256", "rsa", rsaKey, with(map[string]interface{}{"aud": []string{"other", testAudience}})), &Principal{UserID: "user_1", WorkspaceID: "ws_1", Name: "Jane"}, nil},
+		{"No Workspace", sign(t, "RS256", "rsa", rsaKey, with(map[string]interface{}{"workspace_id": nil, "name": nil})), &Principal{UserID: "user_1"}, nil},
+		{"Within Leeway", sign(t, "RS256", "rsa", rsaKey, with(map[string]interface{}{"exp": now.Add(-30 * time.Second).Unix()})), &Principal{UserID: "user_1", WorkspaceID: "ws_1", Name: "Jane"}, nil},
+		{"Expired", sign(t, "RS256", "rsa", rsaKey, with(map[string]interface{}{"exp": now.Add(-time.Hour).Unix()})), nil, ErrTokenExpired},
+		{"No Expiry", sign(t, "RS256", "rsa", rsaKey, with(map[string]interface{}{"exp": nil})), nil, ErrInvalidToken},
+		{"Not Yet Valid", sign(t, "RS256", "rsa", rsaKey, with(map[string]interface{}{"nbf": now.Add(time.Hour).Unix()})), nil, ErrInvalidToken},
+		{"Wrong Issuer", sign(t, "RS256", "rsa", rsaKey, with(map[string]interface{}{"iss": "https://evil.example.com"})), nil, ErrInvalidToken},
+		{"Wrong Audience", sign(t, "RS256", "rsa", rsaKey, with(map[string]interface{}{"aud": "another-service"})), nil, ErrInvalidToken},
+		{"No Subject", sign(t, "RS256", "rsa", rsaKey, with(map[string]interface{}{"sub": nil})), nil, ErrInvalidToken},
+		{"Unknown Key", sign(t, "RS256", "unknown", rsaKey, validClaims(now)), nil, ErrInvalidToken},
+		{"Key For Another Algorithm", sign(t, "RS256", "rsa-ps", rsaKey, validClaims(now)), nil, ErrInvalidToken},
+		{"Wrong Key Type", sign(t, "ES256", "rsa", ecKey, validClaims(now)), nil, ErrInvalidToken},
+		{"Unsigned", sign(t, "none", "rsa", nil, validClaims(now)), nil, ErrInvalidToken},
+		{"Symmetric", sign(t, "HS256", "rsa", nil, validClaims(now)), nil, ErrInvalidToken},
+		{"Not A JWT", "usk_abc", nil, ErrInvalidToken},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			principal, err := verifier.Verify(context.Background(), test.token)
+			if test.expectedErr != nil {
+				assert.ErrorIs(t, err, test.expectedErr)
+				assert.Nil(t, principal)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, principal)
+		})
+	}
+
+	t.Run("Tampered Claims", func(t *testing.T) {
+		parts := strings.Split(sign(t, "RS256", "rsa", rsaKey, validClaims(now)), ".")
+		claims, err := json.Marshal(with(map[string]interface{}{"sub": "admin"}))
+		require.NoError(t, err)
+
+		_, err = verifier.Verify(context.Background(), parts[0]+"."+b64(claims)+"."+parts[2])
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
+
+func Test_Verifier_CustomClaims(t *testing.T) {
+	ecKey := testECKey(t)
+	keys, err := ParseKeySet(jwks(t, jwk("ec", ecKey)))
+	require.NoError(t, err)
+
+	verifier, err := NewVerifier(VerifierConfig{Keys: staticKeys(keys), Issuer: testIssuer, Audience: testAudience, UserClaim: "email", WorkspaceClaim: "org"})
+	require.NoError(t, err)
+
+	claims := validClaims(time.Now())
+	claims["email"] = "jane@example.com"
+	claims["org"] = "acme"
+
+	principal, err := verifier.Verify(context.Background(), sign(t, "ES256", "ec", ecKey, claims))
+	require.NoError(t, err)
+	assert.Equal(t, "jane@example.com", principal.UserID)
+	assert.Equal(t, "acme", principal.WorkspaceID)
+	assert.Equal(t, "jane@example.com", principal.Owner())
+
+	_, err = NewVerifier(VerifierConfig{Keys: staticKeys(keys), Issuer: testIssuer})
+	assert.Error(t, err)
+}
+
+func Test_ParseKeySet(t *testing.T) {
+	rsaKey := testRSAKey(t)
+
+	// encryption and symmetric keys are skipped
+	enc := jwk("enc", rsaKey)
+	enc["use"] = "enc"
+	keys, err := ParseKeySet(jwks(t, enc, map[string]string{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"}, jwk("sig", rsaKey)))
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, "sig", keys[0].ID)
+
+	_, err = ParseKeySet(jwks(t, enc))
+	assert.Error(t, err)
+
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	_, err = ParseKeySet(jwks(t, jwk("small", small)))
+	assert.Error(t, err)
+
+	offCurve := jwk("ec", testECKey(t))
+	offCurve["y"] = offCurve["x"]
+	_, err = ParseKeySet(jwks(t, offCurve))
+	assert.Error(t, err)
+}
+
+func Test_RemoteJWKS_Rotation(t *testing.T) {
+	oldKey, newKey := testECKey(t), testECKey(t)
+
+	var (
+		mu      sync.Mutex
+		current = jwks(t, jwk("old", oldKey))
+		fetches int32
+		down    bool
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		mu.Lock()
+		defer mu.Unlock()
+		if down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(current)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	keys := NewRemoteJWKS(server.URL, server.Client(), time.Hour)
+	keys.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	key, err := keys.Key(ctx, "old")
+	require.NoError(t, err)
+	assert.Equal(t, "old", key.ID)
+
+	// cached
+	_, err = keys.Key(ctx, "old")
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+
+	// the identity provider rotates its key, a token with the new key id triggers a fetch
+	mu.Lock()
+	current = jwks(t, jwk("new", newKey))
+	mu.Unlock()
+	now = now.Add(JWKS_MIN_REFRESH)
+
+	key, err = keys.Key(ctx, "new")
+	require.NoError(t, err)
+	assert.Equal(t, "new", key.ID)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&fetches))
+
+	// made up key ids don't trigger a fetch every time
+	_, err = keys.Key(ctx, "made-up")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	_, err = keys.Key(ctx, "made-up")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&fetches))
+
+	// if the identity provider is down when the cache expires, the cached keys are kept
+	mu.Lock()
+	down = true
+	mu.Unlock()
+	now = now.Add(time.Hour)
+
+	key, err = keys.Key(ctx, "new")
+	require.NoError(t, err)
+	assert.Equal(t, "new", key.ID)
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&fetches) == 3 }, time.Second, time.Millisecond)
+
+	// and while it's down, requests don't each try to fetch the keys again
+	for i := 0; i < 5; i++ {
+		key, err = keys.Key(ctx, "new")
+		require.NoError(t, err)
+		assert.Equal(t, "new", key.ID)
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&fetches))
+}
+
+func Test_RemoteJWKS_SlowIdentityProvider(t *testing.T) {
+	ecKey := testECKey(t)
+
+	release := make(chan struct{})
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&fetches, 1) > 1 {
+			<-release
+		}
+		w.Write(jwks(t, jwk("ec", ecKey)))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	now := time.Now()
+	var mu sync.Mutex
+	keys := NewRemoteJWKS(server.URL, server.Client(), time.Hour)
+	keys.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+
+	_, err := keys.Key(context.Background(), "ec")
+	require.NoError(t, err)
+
+	// once the cache expires, the cached key is used while a single fetch hangs
+	mu.Lock()
+	now = now.Add(time.Hour)
+	mu.Unlock()
+	for i := 0; i < 5; i++ {
+		key, err := keys.Key(context.Background(), "ec")
+		require.NoError(t, err)
+		assert.Equal(t, "ec", key.ID)
+	}
+
+	// a request looking for a new key gives up when it's cancelled, without failing the fetch
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = keys.Key(ctx, "unknown")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&fetches))
+}
+
+func Test_FileJWKS(t *testing.T) {
+	ecKey := testECKey(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwks(t, jwk("file", ecKey)), 0o600))
+
+	keys := NewFileJWKS(path, time.Minute)
+	verifier, err := NewVerifier(VerifierConfig{Keys: keys, Issuer: testIssuer, Audience: testAudience})
+	require.NoError(t, err)
+
+	principal, err := verifier.Verify(context.Background(), sign(t, "ES256", "file", ecKey, validClaims(time.Now())))
+	require.NoError(t, err)
+	assert.Equal(t, "user_1", principal.UserID)
+
+	// a key set without a kid can still be used when it holds one key
+	principal, err = verifier.Verify(context.Background(), sign(t, "ES256", "", ecKey, validClaims(time.Now())))
+	require.NoError(t, err)
+	assert.Equal(t, "user_1", principal.UserID)
+
+	_, err = NewFileJWKS(filepath.Join(t.TempDir(), "missing.json"), time.Minute).Key(context.Background(), "file")
+	assert.Error(t, err)
+}
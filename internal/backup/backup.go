@@ -0,0 +1,349 @@
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/migration"
+	"github.com/mattn/go-sqlite3"
+)
+
+const (
+	PAGES_PER_STEP = 100                   // pages copied before writers get a chance at the database
+	STEP_PAUSE     = 10 * time.Millisecond // how long writers get between steps
+)
+
+var (
+	ErrChecksumMismatch = errors.New("backup doesn't match the checksum in its manifest")
+	ErrSchemaMismatch   = errors.New("backup schema can't be restored by this binary")
+)
+
+// Manifest describes a backup. It is written next to the backup, at ManifestPath.
+type Manifest struct {
+	File          string    `json:"file"` // name of the backup file, relative to the manifest
+	SHA256        string    `json:"sha256"`
+	Size          int64     `json:"size"`
+	Gzip          bool      `json:"gzip"`
+	SchemaVersion uint      `json:"schema_version"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ManifestPath returns the path of the manifest for the backup at path.
+func ManifestPath(path string) string {
+	return path + ".manifest.json"
+}
+
+// Backup takes a consistent snapshot of the sqlite database at dbPath with SQLite's online backup API,
+// and writes it to dst, optionally gzipped, along with a manifest.
+// The database can keep being written to while it is backed up. The backup and then its manifest are each
+// written to a temporary file and renamed into place, so a failed backup never leaves a truncated file at dst.
+func Backup(ctx context.Context, dbPath, dst string, compress bool) (*Manifest, error) {
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, fmt.Errorf("couldn't find database: %w", err)
+	}
+
+	tmp, err := tempPath(dst)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp)
+
+	if err := copyDatabase(ctx, dbPath, tmp); err != nil {
+		return nil, fmt.Errorf("couldn't back up database: %w", err)
+	}
+
+	status, err := schemaStatus(tmp)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeFile(tmp, dst, compress); err != nil {
+		return nil, err
+	}
+
+	sum, size, err := checksum(dst)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{
+		File:          filepath.Base(dst),
+		SHA256:        sum,
+		Size:          size,
+		Gzip:          compress,
+		SchemaVersion: status.Version,
+		CreatedAt:     time.Now().UTC(),
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeAtomic(ManifestPath(dst), func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't write manifest: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// Restore verifies the backup at src against its manifest, checks its schema can be used by this binary,
+// and copies it over the sqlite database at dbPath with SQLite's online backup API.
+// Nothing is written to dbPath unless every check passes.
+func Restore(ctx context.Context, src, dbPath string) (*Manifest, error) {
+	manifest, err := readManifest(src)
+	if err != nil {
+		return nil, err
+	}
+
+	sum, size, err := checksum(src)
+	if err != nil {
+		return nil, err
+	}
+	if sum != manifest.SHA256 || size != manifest.Size {
+		return nil, ErrChecksumMismatch
+	}
+
+	tmp, err := tempPath(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp)
+
+	if err := readFile(src, tmp, manifest.Gzip); err != nil {
+		return nil, err
+	}
+
+	status, err := schemaStatus(tmp)
+	if err != nil {
+		return nil, err
+	}
+	if status.Dirty {
+		return nil, fmt.Errorf("%w, version %d is dirty", ErrSchemaMismatch, status.Version)
+	}
+	if status.Version > status.Latest() {
+		return nil, fmt.Errorf("%w, backup is at version %d but the latest migration is %d", ErrSchemaMismatch, status.Version, status.Latest())
+	}
+
+	if err := copyDatabase(ctx, tmp, dbPath); err != nil {
+		return nil, fmt.Errorf("couldn't restore database: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// copyDatabase copies the sqlite database at src to dst with the online backup API.
+// A step at a time is copied, so writers to src aren't locked out for the whole copy.
+// If src is written to by another connection, SQLite restarts the copy so the result is consistent.
+func copyDatabase(ctx context.Context, src, dst string) error {
+	srcDB, err := sql.Open("sqlite3", src)
+	if err != nil {
+		return err
+	}
+	defer srcDB.Close()
+
+	dstDB, err := sql.Open("sqlite3", dst)
+	if err != nil {
+		return err
+	}
+	defer dstDB.Close()
+
+	srcConn, err := srcDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	dstConn, err := dstDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer dstConn.Close()
+
+	return dstConn.Raw(func(dstRaw interface{}) error {
+		return srcConn.Raw(func(srcRaw interface{}) error {
+			backup, err := dstRaw.(*sqlite3.SQLiteConn).Backup("main", srcRaw.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+
+			for {
+				done, err := backup.Step(PAGES_PER_STEP)
+				if err != nil {
+					backup.Close()
+					return err
+				}
+				if done {
+					return backup.Finish()
+				}
+
+				select {
+				case <-ctx.Done():
+					backup.Close()
+					return ctx.Err()
+				case <-time.After(STEP_PAUSE):
+				}
+			}
+		})
+	})
+}
+
+// schemaStatus returns the schema version of the sqlite database at path.
+func schemaStatus(path string) (*migration.Status, error) {
+	m, err := migration.New(migration.DatabaseURL("sqlite", path))
+	if err != nil {
+		return nil, err
+	}
+	defer m.Close()
+
+	status, err := m.Status()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read schema version: %w", err)
+	}
+
+	return status, nil
+}
+
+// writeFile copies src to dst, gzipping it if compress is set.
+func writeFile(src, dst string, compress bool) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	err = writeAtomic(dst, func(out io.Writer) error {
+		if !compress {
+			_, err := io.Copy(out, in)
+			return err
+		}
+
+		gz := gzip.NewWriter(out)
+		if _, err := io.Copy(gz, in); err != nil {
+			return err
+		}
+
+		return gz.Close()
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't write backup: %w", err)
+	}
+
+	return nil
+}
+
+// writeAtomic writes path with write, by writing path + ".tmp" in the same directory, syncing it and renaming it
+// into place. If anything fails, the temporary file is removed and whatever was at path is left as it was.
+func writeAtomic(path string, write func(w io.Writer) error) (err error) {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(tmp)
+		}
+	}()
+
+	if err := write(f); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// readFile copies src to dst, gunzipping it if compressed is set.
+func readFile(src, dst string, compressed bool) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var r io.Reader = in
+	if compressed {
+		gz, err := gzip.NewReader(in)
+		if err != nil {
+			return fmt.Errorf("couldn't read gzipped backup: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	_, err = io.Copy(out, r)
+	return err
+}
+
+// readManifest reads the manifest of the backup at path.
+func readManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(ManifestPath(path))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read manifest: %w", err)
+	}
+
+	manifest := &Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// checksum returns the hex encoded SHA-256 and size of the file at path.
+func checksum(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// tempPath returns the path of a new, empty file in the same directory as path.
+func tempPath(path string) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+
+	return f.Name(), f.Close()
+}
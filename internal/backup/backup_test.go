@@ -0,0 +1,149 @@
+package backup
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/config"
+	"github.com/Jaytpa01/url-shortener-api/db"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/migration"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// latestVersion returns the version of the last sqlite migration, which a migrated database is at.
+func latestVersion(t *testing.T) uint {
+	src, err := iofs.New(db.Migrations, "migrations")
+	require.NoError(t, err)
+	defer src.Close()
+
+	version, err := src.First()
+	require.NoError(t, err)
+	for {
+		next, err := src.Next(version)
+		if err != nil {
+			return version
+		}
+		version = next
+	}
+}
+
+// newDatabase creates a migrated sqlite database in dir with a single url.
+func newDatabase(t *testing.T, dir string) string {
+	path := filepath.Join(dir, "url.db")
+
+	m, err := migration.New(migration.DatabaseURL("sqlite", path))
+	require.NoError(t, err)
+	require.NoError(t, m.Up())
+	m.Close()
+
+	repo, err := repository.NewSQLiteRepository(path, config.PoolConfig{})
+	require.NoError(t, err)
+	defer repo.(io.Closer).Close()
+
+	require.NoError(t, repo.Create(context.Background(), &entity.Url{Token: "123456", TargetUrl: "https://example.com", CreatedAt: time.Now()}))
+
+	return path
+}
+
+func Test_BackupAndRestore(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		dir := t.TempDir()
+		db := newDatabase(t, dir)
+		dst := filepath.Join(dir, "backup.db")
+
+		manifest, err := Backup(context.Background(), db, dst, compress)
+		require.NoError(t, err)
+		assert.Equal(t, compress, manifest.Gzip)
+		assert.Equal(t, "backup.db", manifest.File)
+		assert.Equal(t, latestVersion(t), manifest.SchemaVersion)
+		assert.FileExists(t, ManifestPath(dst))
+
+		restored := filepath.Join(dir, "restored.db")
+		_, err = Restore(context.Background(), dst, restored)
+		require.NoError(t, err)
+
+		repo, err := repository.NewSQLiteRepository(restored, config.PoolConfig{})
+		require.NoError(t, err)
+
+		url, err := repo.FindByToken(context.Background(), "123456")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com", url.TargetUrl)
+		repo.(io.Closer).Close()
+	}
+}
+
+func Test_Backup_FailedWriteKeepsPrevious(t *testing.T) {
+	dir := t.TempDir()
+	db := newDatabase(t, dir)
+	dst := filepath.Join(dir, "backup.db")
+
+	previous, err := Backup(context.Background(), db, dst, false)
+	require.NoError(t, err)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3, "only the database, backup and manifest should be left")
+
+	// the next backup can't create its temporary file
+	require.NoError(t, os.Mkdir(dst+".tmp", 0o755))
+	_, err = Backup(context.Background(), db, dst, true)
+	assert.Error(t, err)
+
+	// so the previous backup and manifest are untouched, and can still be restored
+	restored, err := Restore(context.Background(), dst, filepath.Join(dir, "restored.db"))
+	require.NoError(t, err)
+	assert.Equal(t, previous.SHA256, restored.SHA256)
+	assert.False(t, restored.Gzip)
+}
+
+func Test_Restore_ChecksumMismatch(t *testing.T) {
+	dir := t.TempDir()
+	db := newDatabase(t, dir)
+	dst := filepath.Join(dir, "backup.db")
+
+	_, err := Backup(context.Background(), db, dst, false)
+	require.NoError(t, err)
+
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	f.WriteString("corrupt")
+	f.Close()
+
+	restored := filepath.Join(dir, "restored.db")
+	_, err = Restore(context.Background(), dst, restored)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.NoFileExists(t, restored)
+}
+
+func Test_Restore_SchemaNewerThanBinary(t *testing.T) {
+	dir := t.TempDir()
+	db := newDatabase(t, dir)
+
+	m, err := migration.New(migration.DatabaseURL("sqlite", db))
+	require.NoError(t, err)
+	require.NoError(t, m.Force(int(latestVersion(t))+1))
+	m.Close()
+
+	dst := filepath.Join(dir, "backup.db")
+	_, err = Backup(context.Background(), db, dst, true)
+	require.NoError(t, err)
+
+	restored := filepath.Join(dir, "restored.db")
+	_, err = Restore(context.Background(), dst, restored)
+	assert.ErrorIs(t, err, ErrSchemaMismatch)
+	assert.NoFileExists(t, restored)
+}
+
+func Test_Backup_MissingDatabase(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := Backup(context.Background(), filepath.Join(dir, "missing.db"), filepath.Join(dir, "backup.db"), false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,393 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+)
+
+// Operation is a type of request the benchmark sends.
+type Operation string
+
+const (
+	Shorten  Operation = "shorten"  // POST /shorten
+	Redirect Operation = "redirect" // GET /{token}
+	Visits   Operation = "visits"   // GET /{token}/visits
+)
+
+// MAX_RATE is the most requests per second a run can be scheduled at
+const MAX_RATE = int(time.Second)
+
+// TransportError is the error type recorded when a request couldn't be sent or its response couldn't be read.
+const TransportError = "TRANSPORT"
+
+// ErrDenied is returned for requests the server refused with a 401 or 403. They're reported apart from other errors,
+// as they mean the run is missing credentials or a proof of work, rather than the server struggling.
+var ErrDenied = errors.New("request denied")
+
+// Options configures a benchmark run.
+type Options struct {
+	Target      string            // base url of the server, ie. http://localhost:8080
+	Duration    time.Duration     // how long to send requests for
+	Rate        int               // requests per second to send. If 0, workers send requests as fast as they can
+	Concurrency int               // maximum number of requests in flight
+	Mix         map[Operation]int // relative weight of each operation
+	Url         string            // the url shortened by shorten requests
+	Seed        int               // number of links to create before the run, so there is something to redirect to
+	Key         string            // optional, the api key sent as a bearer token with every request
+	Client      *http.Client      // optional, the client used to send requests
+}
+
+// ParseMix parses a mix such as "shorten=1,redirect=8,visits=1".
+func ParseMix(mix string) (map[Operation]int, error) {
+	weights := map[Operation]int{}
+
+	for _, part := range strings.Split(mix, ",") {
+		name, weight, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			return nil, fmt.Errorf("invalid mix entry (%s), expected operation=weight", part)
+		}
+
+		op := Operation(name)
+		switch op {
+		case Shorten, Redirect, Visits:
+		default:
+			return nil, fmt.Errorf("unknown operation (%s)", name)
+		}
+
+		w, err := strconv.Atoi(weight)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("invalid weight (%s) for operation (%s)", weight, name)
+		}
+
+		weights[op] = w
+	}
+
+	return weights, nil
+}
+
+// validate checks the options, and fills in defaults.
+func (o *Options) validate() error {
+	if o.Target == "" {
+		return errors.New("target was empty")
+	}
+	o.Target = strings.TrimRight(o.Target, "/")
+
+	if o.Duration <= 0 {
+		return errors.New("duration must be greater than zero")
+	}
+
+	if o.Concurrency <= 0 {
+		return errors.New("concurrency must be greater than zero")
+	}
+
+	if o.Rate < 0 {
+		return errors.New("rate must not be negative")
+	}
+
+	// requests are scheduled by a ticker, whose interval can't be shorter than a nanosecond
+	if o.Rate > MAX_RATE {
+		return fmt.Errorf("rate must be at most %d requests per second", MAX_RATE)
+	}
+
+	total := 0
+	for _, weight := range o.Mix {
+		total += weight
+	}
+	if total == 0 {
+		return errors.New("mix must have at least one operation with a weight above zero")
+	}
+
+	if (o.Mix[Redirect] > 0 || o.Mix[Visits] > 0) && o.Mix[Shorten] == 0 && o.Seed == 0 {
+		return errors.New("redirect and visits requests need tokens, either seed some links or include shorten requests")
+	}
+
+	if o.Url == "" {
+		o.Url = "https://example.com"
+	}
+
+	if o.Client == nil {
+		o.Client = &http.Client{
+			Timeout: 10 * time.Second,
+			// we're measuring our server, not the target of the redirect
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+			Transport: &http.Transport{
+				MaxIdleConns:        o.Concurrency,
+				MaxIdleConnsPerHost: o.Concurrency,
+			},
+		}
+	}
+
+	return nil
+}
+
+// runner holds the state shared by the workers of a benchmark run.
+type runner struct {
+	opts   *Options
+	report *Report
+
+	mu     sync.RWMutex
+	tokens []string
+}
+
+// Run sends requests to the target until the duration has elapsed or ctx is cancelled,
+// and reports on the results.
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	r := &runner{
+		opts:   &opts,
+		report: newReport(),
+	}
+
+	// seed links so redirect and visits requests have tokens from the start.
+	// These aren't part of the report.
+	for i := 0; i < opts.Seed; i++ {
+		token, _, err := r.shorten(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't seed links: %w", err)
+		}
+		r.addToken(token)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+
+	// when sending at a fixed rate, a ticker hands out work to the workers.
+	// Otherwise workers send their next request as soon as the last one completes.
+	var work chan struct{}
+	if opts.Rate > 0 {
+		work = make(chan struct{}, opts.Concurrency)
+		go r.schedule(ctx, work)
+	}
+
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.work(ctx, work)
+		}()
+	}
+	wg.Wait()
+
+	r.report.Elapsed = time.Now().Sub(start)
+	return r.report, nil
+}
+
+// schedule sends on work at the configured rate until ctx is done.
+// If every worker is busy, the tick is dropped and counted as missed.
+func (r *runner) schedule(ctx context.Context, work chan<- struct{}) {
+	defer close(work)
+
+	ticker := time.NewTicker(time.Second / time.Duration(r.opts.Rate))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			select {
+			case work <- struct{}{}:
+			default:
+				r.report.miss()
+			}
+		}
+	}
+}
+
+// work sends requests until ctx is done, or work is closed.
+func (r *runner) work(ctx context.Context, work <-chan struct{}) {
+	for {
+		if work != nil {
+			if _, ok := <-work; !ok {
+				return
+			}
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		op := r.pick()
+		start := time.Now()
+		errType, err := r.send(ctx, op)
+		latency := time.Now().Sub(start)
+
+		// requests cut off by the end of the run aren't the server's fault
+		if ctx.Err() != nil && err != nil {
+			return
+		}
+
+		r.report.record(op, latency, errType, errors.Is(err, ErrDenied))
+	}
+}
+
+// pick chooses an operation at random according to the mix.
+// Until there are tokens, only shorten requests can be sent.
+func (r *runner) pick() Operation {
+	if !r.hasTokens() {
+		return Shorten
+	}
+
+	total := 0
+	for _, weight := range r.opts.Mix {
+		total += weight
+	}
+
+	n := rand.Intn(total)
+	for _, op := range []Operation{Shorten, Redirect, Visits} {
+		if n < r.opts.Mix[op] {
+			return op
+		}
+		n -= r.opts.Mix[op]
+	}
+
+	return Shorten
+}
+
+// send sends a single request for op, returning the error type if it failed.
+func (r *runner) send(ctx context.Context, op Operation) (string, error) {
+	var errType string
+	var err error
+
+	switch op {
+	case Shorten:
+		var token string
+		token, errType, err = r.shorten(ctx)
+		if err == nil {
+			r.addToken(token)
+		}
+	case Redirect:
+		errType, err = r.get(ctx, "/"+r.randomToken(), http.StatusFound)
+	case Visits:
+		errType, err = r.get(ctx, "/"+r.randomToken()+"/visits", http.StatusOK)
+	}
+
+	return errType, err
+}
+
+// shorten creates a link, returning its token.
+func (r *runner) shorten(ctx context.Context) (string, string, error) {
+	body, err := json.Marshal(&api.CreateUrlRequest{Url: r.opts.Url})
+	if err != nil {
+		return "", TransportError, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.opts.Target+"/shorten", bytes.NewReader(body))
+	if err != nil {
+		return "", TransportError, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := r.do(req)
+	if err != nil {
+		return "", TransportError, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusCreated {
+		errType, err := readError(res)
+		return "", errType, err
+	}
+
+	created := &api.UrlResponse{}
+	if err := json.NewDecoder(res.Body).Decode(created); err != nil {
+		return "", TransportError, err
+	}
+
+	return created.Token, "", nil
+}
+
+// get sends a GET request to path, expecting the given status code.
+func (r *runner) get(ctx context.Context, path string, expectedStatus int) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.opts.Target+path, nil)
+	if err != nil {
+		return TransportError, err
+	}
+
+	res, err := r.do(req)
+	if err != nil {
+		return TransportError, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != expectedStatus {
+		return readError(res)
+	}
+
+	// drain the body so the connection can be reused
+	_, err = io.Copy(io.Discard, res.Body)
+	if err != nil {
+		return TransportError, err
+	}
+
+	return "", nil
+}
+
+// do sends the request with the api key, if there is one.
+func (r *runner) do(req *http.Request) (*http.Response, error) {
+	if r.opts.Key != "" {
+		req.Header.Set("Authorization", "Bearer "+r.opts.Key)
+	}
+
+	return r.opts.Client.Do(req)
+}
+
+// readError reads an api.ApiError from an unsuccessful response, returning its type.
+// If the body isn't an ApiError, the type is the HTTP status code.
+// Denied requests are typed by their error code instead, ie. auth/missing-key, and wrap ErrDenied.
+func readError(res *http.Response) (string, error) {
+	denied := res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden
+
+	apiErr := &api.ApiError{}
+	if err := json.NewDecoder(res.Body).Decode(apiErr); err != nil || apiErr.Type == "" {
+		errType, err := fmt.Sprintf("HTTP_%d", res.StatusCode), fmt.Errorf("unexpected status code %d", res.StatusCode)
+		if denied {
+			err = fmt.Errorf("%w: %v", ErrDenied, err)
+		}
+		return errType, err
+	}
+
+	if denied {
+		return apiErr.Code, fmt.Errorf("%w: %v", ErrDenied, apiErr)
+	}
+
+	return string(apiErr.Type), apiErr
+}
+
+func (r *runner) addToken(token string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens = append(r.tokens, token)
+}
+
+func (r *runner) hasTokens() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.tokens) > 0
+}
+
+func (r *runner) randomToken() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.tokens[rand.Intn(len(r.tokens))]
+}
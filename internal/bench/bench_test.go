@@ -0,0 +1,223 @@
+package bench
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/config"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/handler"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/Jaytpa01/url-shortener-api/internal/service"
+	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// statusCounter counts the responses a server sent by status code.
+type statusCounter struct {
+	mu     sync.Mutex
+	counts map[int]int
+}
+
+func (c *statusCounter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.counts[ww.Status()]++
+	})
+}
+
+func (c *statusCounter) total() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	total := 0
+	for _, count := range c.counts {
+		total += count
+	}
+	return total
+}
+
+func (c *statusCounter) count(status int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts[status]
+}
+
+// newTestServer starts a server with the config, backed by an in memory repository, which counts the responses it sends.
+// It also returns the key service, to create api keys with.
+func newTestServer(t *testing.T, cfg *config.Config) (*httptest.Server, *statusCounter, service.KeyService) {
+	counter := &statusCounter{counts: map[int]int{}}
+
+	urlRepo := repository.NewInMemoryRepo()
+	apiKeyRepo, err := repository.NewApiKeyRepository(urlRepo)
+	assert.NoError(t, err)
+	keys := service.NewKeyService(&service.KeyConfig{
+		Logger:     logger.NewApiLogger("production"),
+		ApiKeyRepo: apiKeyRepo,
+	})
+
+	router := chi.NewRouter()
+	router.Use(counter.middleware)
+	err = handler.NewHandler(&handler.Config{
+		Router:    router,
+		ApiConfig: cfg,
+		UrlService: service.NewUrlService(&service.Config{
+			Logger:  logger.NewApiLogger("production"),
+			UrlRepo: urlRepo,
+		}),
+		KeyService: keys,
+	})
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	return server, counter, keys
+}
+
+func Test_Run(t *testing.T) {
+	server, counter, _ := newTestServer(t, &config.Config{})
+
+	opts := Options{
+		Target:      server.URL,
+		Duration:    200 * time.Millisecond,
+		Concurrency: 2,
+		Mix:         map[Operation]int{Shorten: 1, Redirect: 1, Visits: 1},
+		Seed:        1,
+	}
+	report, err := Run(context.Background(), opts)
+	assert.NoError(t, err)
+
+	// wait for the requests cut off by the end of the run, which the server may still have answered
+	server.Close()
+
+	stats := report.Stats()
+	total := stats[len(stats)-1]
+	assert.Equal(t, Operation("total"), total.Operation)
+	assert.Greater(t, total.Requests, 0)
+	assert.Greater(t, total.Throughput, 0.0)
+	assert.LessOrEqual(t, total.P50, total.P99)
+	assert.LessOrEqual(t, total.P99, total.Max)
+
+	// every response the server sent is reported, apart from the seeded link and up to one cut off request per worker
+	served := total.Requests + opts.Seed
+	assert.GreaterOrEqual(t, counter.total(), served)
+	assert.LessOrEqual(t, counter.total(), served+opts.Concurrency)
+
+	limited := total.Errors[string(api.TooManyRequests)]
+	assert.GreaterOrEqual(t, counter.count(http.StatusTooManyRequests), limited)
+	assert.LessOrEqual(t, counter.count(http.StatusTooManyRequests), limited+opts.Concurrency)
+}
+
+func Test_Run_FixedRate(t *testing.T) {
+	server, _, _ := newTestServer(t, &config.Config{})
+
+	report, err := Run(context.Background(), Options{
+		Target:      server.URL,
+		Duration:    500 * time.Millisecond,
+		Rate:        10,
+		Concurrency: 2,
+		Mix:         map[Operation]int{Shorten: 1},
+	})
+	assert.NoError(t, err)
+
+	stats := report.Stats()
+	total := stats[len(stats)-1]
+	assert.InDelta(t, 5, total.Requests, 2)
+	assert.Empty(t, total.Errors)
+}
+
+func Test_Run_ApiKey(t *testing.T) {
+	server, counter, keys := newTestServer(t, &config.Config{Auth: config.AuthConfig{RequireApiKey: true}})
+
+	_, key, err := keys.CreateKey(context.Background(), "bench", "", entity.Scopes{entity.ScopeLinksCreate})
+	require.NoError(t, err)
+
+	run := func(key string) OperationStats {
+		report, err := Run(context.Background(), Options{
+			Target:      server.URL,
+			Duration:    100 * time.Millisecond,
+			Rate:        20,
+			Concurrency: 1,
+			Mix:         map[Operation]int{Shorten: 1},
+			Key:         key,
+		})
+		require.NoError(t, err)
+
+		stats := report.Stats()
+		return stats[len(stats)-1]
+	}
+
+	// without the key, every request is refused, and counted by its code apart from other errors
+	denied := run("")
+	assert.Greater(t, denied.Requests, 0)
+	assert.Empty(t, denied.Errors)
+	assert.Equal(t, map[string]int{"auth/missing-key": denied.Requests}, denied.Denied)
+
+	allowed := run(key)
+	assert.Greater(t, allowed.Requests, 0)
+	assert.Empty(t, allowed.Errors)
+	assert.Empty(t, allowed.Denied)
+
+	server.Close()
+	assert.GreaterOrEqual(t, counter.count(http.StatusUnauthorized), denied.Requests)
+	assert.GreaterOrEqual(t, counter.count(http.StatusCreated), allowed.Requests)
+}
+
+func Test_Run_InvalidOptions(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts Options
+	}{
+		{"No Target", Options{Duration: time.Second, Concurrency: 1, Mix: map[Operation]int{Shorten: 1}}},
+		{"No Duration", Options{Target: "http://localhost", Concurrency: 1, Mix: map[Operation]int{Shorten: 1}}},
+		{"No Concurrency", Options{Target: "http://localhost", Duration: time.Second, Mix: map[Operation]int{Shorten: 1}}},
+		{"Empty Mix", Options{Target: "http://localhost", Duration: time.Second, Concurrency: 1}},
+		{"Negative Rate", Options{Target: "http://localhost", Duration: time.Second, Rate: -1, Concurrency: 1, Mix: map[Operation]int{Shorten: 1}}},
+		{"Rate Too High", Options{Target: "http://localhost", Duration: time.Second, Rate: MAX_RATE + 1, Concurrency: 1, Mix: map[Operation]int{Shorten: 1}}},
+		{"No Tokens", Options{Target: "http://localhost", Duration: time.Second, Concurrency: 1, Mix: map[Operation]int{Redirect: 1}}},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Run(context.Background(), test.opts)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_ParseMix(t *testing.T) {
+	mix, err := ParseMix("shorten=1, redirect=8,visits=0")
+	assert.NoError(t, err)
+	assert.Equal(t, map[Operation]int{Shorten: 1, Redirect: 8, Visits: 0}, mix)
+
+	for _, invalid := range []string{"shorten", "delete=1", "shorten=-1", "shorten=one"} {
+		_, err := ParseMix(invalid)
+		assert.Errorf(t, err, "expected %s to be invalid", invalid)
+	}
+}
+
+func Test_Percentile(t *testing.T) {
+	latencies := []time.Duration{}
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	assert.Equal(t, time.Duration(0), percentile(nil, 50))
+	assert.Equal(t, 50*time.Millisecond, percentile(latencies, 50))
+	assert.Equal(t, 99*time.Millisecond, percentile(latencies, 99))
+	assert.Equal(t, 100*time.Millisecond, percentile(latencies, 100))
+	assert.Equal(t, time.Millisecond, percentile(latencies[:1], 99))
+}
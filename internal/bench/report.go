@@ -0,0 +1,206 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Report holds the results of a benchmark run.
+type Report struct {
+	Elapsed time.Duration
+
+	mu         sync.Mutex
+	latencies  map[Operation][]time.Duration
+	errors     map[Operation]map[string]int
+	denied     map[Operation]map[string]int
+	missedTick int
+}
+
+func newReport() *Report {
+	return &Report{
+		latencies: map[Operation][]time.Duration{},
+		errors:    map[Operation]map[string]int{},
+		denied:    map[Operation]map[string]int{},
+	}
+}
+
+// OperationStats summarises the requests sent for a single operation.
+type OperationStats struct {
+	Operation  Operation
+	Requests   int
+	Errors     map[string]int // error counts keyed by api.ApiError.Type
+	Denied     map[string]int // counts of requests refused with a 401 or 403, keyed by api.ApiError.Code
+	Throughput float64        // requests per second
+	P50        time.Duration
+	P90        time.Duration
+	P99        time.Duration
+	Max        time.Duration
+}
+
+// record records the latency of a request, and the type of error it returned if any.
+// Denied requests are counted apart from the other errors.
+func (r *Report) record(op Operation, latency time.Duration, errType string, denied bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.latencies[op] = append(r.latencies[op], latency)
+	if errType == "" {
+		return
+	}
+
+	counts := r.errors
+	if denied {
+		counts = r.denied
+	}
+
+	if counts[op] == nil {
+		counts[op] = map[string]int{}
+	}
+	counts[op][errType]++
+}
+
+// miss records that a request couldn't be sent at the configured rate because every worker was busy.
+func (r *Report) miss() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.missedTick++
+}
+
+// Missed returns the number of requests that couldn't be sent at the configured rate.
+func (r *Report) Missed() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.missedTick
+}
+
+// Stats summarises each operation, and all operations combined.
+// The combined stats are last, with an Operation of "total".
+func (r *Report) Stats() []OperationStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := []OperationStats{}
+	all := []time.Duration{}
+	allErrors, allDenied := map[string]int{}, map[string]int{}
+
+	for _, op := range []Operation{Shorten, Redirect, Visits} {
+		latencies, ok := r.latencies[op]
+		if !ok {
+			continue
+		}
+
+		stats = append(stats, r.summarise(op, latencies, r.errors[op], r.denied[op]))
+
+		all = append(all, latencies...)
+		for errType, count := range r.errors[op] {
+			allErrors[errType] += count
+		}
+		for code, count := range r.denied[op] {
+			allDenied[code] += count
+		}
+	}
+
+	return append(stats, r.summarise("total", all, allErrors, allDenied))
+}
+
+func (r *Report) summarise(op Operation, latencies []time.Duration, errors, denied map[string]int) OperationStats {
+	sorted := make([]time.Duration, len(latencies))
+	copy(sorted, latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	stats := OperationStats{
+		Operation: op,
+		Requests:  len(sorted),
+		Errors:    map[string]int{},
+		Denied:    map[string]int{},
+		P50:       percentile(sorted, 50),
+		P90:       percentile(sorted, 90),
+		P99:       percentile(sorted, 99),
+		Max:       percentile(sorted, 100),
+	}
+
+	for errType, count := range errors {
+		stats.Errors[errType] = count
+	}
+	for code, count := range denied {
+		stats.Denied[code] = count
+	}
+
+	if r.Elapsed > 0 {
+		stats.Throughput = float64(len(sorted)) / r.Elapsed.Seconds()
+	}
+
+	return stats
+}
+
+// percentile returns the nearest-rank percentile p of the sorted latencies.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1]
+}
+
+// Print writes a human readable summary of the report to w.
+func (r *Report) Print(w io.Writer) error {
+	stats := r.Stats()
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "OPERATION\tREQUESTS\tERRORS\tDENIED\tREQ/S\tP50\tP90\tP99\tMAX\n")
+	for _, s := range stats {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\n",
+			s.Operation, s.Requests, sum(s.Errors), sum(s.Denied), s.Throughput,
+			s.P50.Round(time.Microsecond), s.P90.Round(time.Microsecond), s.P99.Round(time.Microsecond), s.Max.Round(time.Microsecond))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	total := stats[len(stats)-1]
+	printCounts(w, "Errors by type", total.Errors)
+	printCounts(w, "Denied by code (check the api key, and that proof of work is disabled)", total.Denied)
+
+	if missed := r.Missed(); missed > 0 {
+		fmt.Fprintf(w, "\n%d requests couldn't be sent at the configured rate, try increasing the concurrency.\n", missed)
+	}
+
+	_, err := fmt.Fprintf(w, "\nCompleted %d requests in %s.\n", total.Requests, r.Elapsed.Round(time.Millisecond))
+	return err
+}
+
+// printCounts writes each count under the title, sorted by key. Nothing is written if there are no counts.
+func printCounts(w io.Writer, title string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\n%s:\n", title)
+
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Fprintf(w, "  %s: %d\n", key, counts[key])
+	}
+}
+
+func sum(counts map[string]int) int {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	return total
+}
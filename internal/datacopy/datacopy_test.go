@@ -0,0 +1,135 @@
+package datacopy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/config"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSource creates a memory repository with n urls, with tokens 000000, 000001...
+func newSource(t *testing.T, n int) repository.UrlRepository {
+	repo := repository.NewInMemoryRepo()
+	createdAt := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < n; i++ {
+		require.NoError(t, repo.Create(context.Background(), &entity.Url{
+			Token:     fmt.Sprintf("%06d", i),
+			TargetUrl: "https://example.com",
+			Visits:    i,
+			CreatedAt: createdAt,
+		}))
+	}
+
+	return repo
+}
+
+func newDestination(t *testing.T) repository.UrlRepository {
+	repo, err := repository.NewBoltRepository(filepath.Join(t.TempDir(), "url.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.(io.Closer).Close() })
+
+	return repo
+}
+
+func Test_Copy(t *testing.T) {
+	src := newSource(t, 25)
+	dst := newDestination(t)
+
+	batches := 0
+	result, err := Copy(context.Background(), Options{
+		From:      src,
+		To:        dst,
+		BatchSize: 10,
+		Progress:  func(result *Result) { batches++ },
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 25, result.Copied)
+	assert.Equal(t, "000024", result.LastToken)
+	assert.Equal(t, 25, result.DestinationCount)
+	assert.Equal(t, 3, batches)
+
+	// visits and created at should survive the copy
+	url, err := dst.FindByToken(context.Background(), "000007")
+	assert.NoError(t, err)
+	assert.Equal(t, 7, url.Visits)
+	assert.Equal(t, time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC), url.CreatedAt)
+}
+
+func Test_Copy_Resume(t *testing.T) {
+	src := newSource(t, 10)
+	dst := newDestination(t)
+
+	// pretend a previous copy got as far as 000004, and also copied 000005 before stopping
+	for _, token := range []string{"000000", "000001", "000002", "000003", "000004", "000005"} {
+		url, err := src.FindByToken(context.Background(), token)
+		require.NoError(t, err)
+		require.NoError(t, dst.Create(context.Background(), url))
+	}
+
+	result, err := Copy(context.Background(), Options{From: src, To: dst, After: "000004"})
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.Copied)
+	assert.Equal(t, 1, result.Existing)
+	assert.Equal(t, 10, result.DestinationCount)
+}
+
+func Test_Copy_DryRun(t *testing.T) {
+	src := newSource(t, 10)
+	dst := newDestination(t)
+	require.NoError(t, dst.Create(context.Background(), &entity.Url{Token: "000003"}))
+
+	result, err := Copy(context.Background(), Options{From: src, To: dst, DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, 9, result.Copied)
+	assert.Equal(t, 1, result.Existing)
+
+	count, err := dst.Count(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func Test_Copy_CountMismatch(t *testing.T) {
+	src := newSource(t, 10)
+	dst := newDestination(t)
+	require.NoError(t, dst.Create(context.Background(), &entity.Url{Token: "qwerty"}))
+
+	_, err := Copy(context.Background(), Options{From: src, To: dst})
+	assert.ErrorIs(t, err, ErrCountMismatch)
+}
+
+func Test_ParseEndpoint(t *testing.T) {
+	testCases := []struct {
+		name        string
+		endpoint    string
+		expected    config.DatabaseConfig
+		expectedErr bool
+	}{
+		{"SQLite", "sqlite:db/url.db", config.DatabaseConfig{Driver: "sqlite", DSN: "db/url.db"}, false},
+		{"Postgres", "postgres:postgres://localhost:5432/url", config.DatabaseConfig{Driver: "postgres", DSN: "postgres://localhost:5432/url"}, false},
+		{"Memory Without DSN", "memory:", config.DatabaseConfig{Driver: "memory"}, false},
+		{"No Driver", "db/url.db", config.DatabaseConfig{}, true},
+		{"Empty Driver", ":db/url.db", config.DatabaseConfig{}, true},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			cfg, err := ParseEndpoint(test.endpoint)
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, cfg)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ApiKey authenticates requests to the api. Only a hash of the key is stored,
+// the key itself is shown once when it is created.
+type ApiKey struct {
+	ID        string     `db:"id"`
+	Name      string     `db:"name"`
+	Hash      string     `db:"hash"`
+	CreatedAt time.Time  `db:"created_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+
+	WorkspaceID string `db:"workspace_id"` // requests made with the key act in this workspace
+	Scopes      Scopes `db:"scopes"`       // what requests made with the key can do
+}
+
+// Revoked reports whether the key has been revoked.
+func (k *ApiKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+// Scope is something an api key is allowed to do.
+type Scope string
+
+const (
+	ScopeLinksCreate   Scope = "links:create"   // create links
+	ScopeLinksWrite    Scope = "links:write"    // change the target of links
+	ScopeLinksDelete   Scope = "links:delete"   // delete links
+	ScopeAnalyticsRead Scope = "analytics:read" // list links and view their visits
+	ScopeAdmin         Scope = "admin"          // every other scope, and owning the key's workspace
+)
+
+// ALL_SCOPES are the scopes an api key can have.
+var ALL_SCOPES = Scopes{ScopeLinksCreate, ScopeLinksWrite, ScopeLinksDelete, ScopeAnalyticsRead, ScopeAdmin}
+
+// DEFAULT_SCOPES are the scopes of keys created without any, which is everything keys could do before they had scopes.
+var DEFAULT_SCOPES = Scopes{ScopeLinksCreate, ScopeLinksWrite, ScopeLinksDelete, ScopeAnalyticsRead}
+
+// Valid reports whether the scope is one of ALL_SCOPES.
+func (s Scope) Valid() bool {
+	for _, scope := range ALL_SCOPES {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Scopes are the scopes of an api key. They're stored space separated.
+type Scopes []Scope
+
+// Has reports whether the scopes allow scope. The admin scope allows every scope.
+func (s Scopes) Has(scope Scope) bool {
+	for _, has := range s {
+		if has == scope || has == ScopeAdmin {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String returns the scopes space separated.
+func (s Scopes) String() string {
+	scopes := make([]string, len(s))
+	for i, scope := range s {
+		scopes[i] = string(scope)
+	}
+
+	return strings.Join(scopes, " ")
+}
+
+// Value implements driver.Valuer, storing the scopes space separated.
+func (s Scopes) Value() (driver.Value, error) {
+	return s.String(), nil
+}
+
+// Scan implements sql.Scanner, reading space separated scopes.
+func (s *Scopes) Scan(src interface{}) error {
+	var value string
+	switch src := src.(type) {
+	case string:
+		value = src
+	case []byte:
+		value = string(src)
+	case nil:
+	default:
+		return fmt.Errorf("can't scan %T into scopes", src)
+	}
+
+	*s = nil
+	for _, scope := range strings.Fields(value) {
+		*s = append(*s, Scope(scope))
+	}
+
+	return nil
+}
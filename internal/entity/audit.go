@@ -0,0 +1,128 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// AuditAction is a kind of change recorded in the audit log.
+type AuditAction string
+
+const (
+	AuditLinkCreate      AuditAction = "link.create"
+	AuditLinkRetarget    AuditAction = "link.retarget"
+	AuditLinkDelete      AuditAction = "link.delete"
+	AuditLinkDisable     AuditAction = "link.disable"
+	AuditLinkEnable      AuditAction = "link.enable"
+	AuditKeyCreate       AuditAction = "key.create"
+	AuditKeyRevoke       AuditAction = "key.revoke"
+	AuditWorkspaceCreate AuditAction = "workspace.create"
+	AuditMemberAdd       AuditAction = "member.add"
+	AuditMemberUpdate    AuditAction = "member.update"
+	AuditMemberRemove    AuditAction = "member.remove"
+	AuditDomainAdd       AuditAction = "domain.add"
+	AuditDomainVerify    AuditAction = "domain.verify"
+	AuditDomainUpdate    AuditAction = "domain.update"
+	AuditDomainRemove    AuditAction = "domain.remove"
+	AuditConfigChange    AuditAction = "config.change"
+	AuditSubjectExport   AuditAction = "subject.export"
+	AuditSubjectErase    AuditAction = "subject.erase"
+)
+
+// AUDIT_ACTIONS are the actions recorded in the audit log.
+var AUDIT_ACTIONS = []AuditAction{
+	AuditLinkCreate, AuditLinkRetarget, AuditLinkDelete, AuditLinkDisable, AuditLinkEnable, AuditKeyCreate, AuditKeyRevoke,
+	AuditWorkspaceCreate, AuditMemberAdd, AuditMemberUpdate, AuditMemberRemove,
+	AuditDomainAdd, AuditDomainVerify, AuditDomainUpdate, AuditDomainRemove, AuditConfigChange,
+	AuditSubjectExport, AuditSubjectErase,
+}
+
+// Valid reports whether the action is one of AUDIT_ACTIONS.
+func (a AuditAction) Valid() bool {
+	for _, action := range AUDIT_ACTIONS {
+		if a == action {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AuditEntry records who changed what, when and from where. Entries are only ever appended.
+type AuditEntry struct {
+	ID     int64       `db:"id"`
+	Action AuditAction `db:"action"`
+	// Actor is the api key or user id that made the change, "admin" or "cli" for the admin token
+	// and command line, "server" for config changes, or "anonymous".
+	Actor       string       `db:"actor"`
+	WorkspaceID string       `db:"workspace_id"` // the workspace the change was made in, if any
+	Target      string       `db:"target"`       // the link, key id, workspace id, user id, domain or privacy job that changed
+	IP          string       `db:"ip"`
+	RequestID   string       `db:"request_id"`
+	Changes     AuditChanges `db:"changes"`
+	CreatedAt   time.Time    `db:"created_at"`
+}
+
+// AuditChange is the value of a field before and after a change. Before is empty for creations, and After for deletions.
+type AuditChange struct {
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// AuditChanges are the fields a change touched, by name. They're stored as JSON.
+type AuditChanges map[string]AuditChange
+
+// PERSONAL_AUDIT_FIELDS are the changes that can hold personal data about an entry's target, such as the name of an api key.
+// They're removed from the entries that target an erased subject.
+var PERSONAL_AUDIT_FIELDS = []string{"name"}
+
+// RemovePersonal removes the PERSONAL_AUDIT_FIELDS from the changes, and reports whether there were any.
+func (c AuditChanges) RemovePersonal() bool {
+	removed := false
+	for _, field := range PERSONAL_AUDIT_FIELDS {
+		if _, ok := c[field]; ok {
+			delete(c, field)
+			removed = true
+		}
+	}
+
+	return removed
+}
+
+func (c AuditChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return "{}", nil
+	}
+
+	data, err := json.Marshal(c)
+	return string(data), err
+}
+
+func (c *AuditChanges) Scan(src interface{}) error {
+	var data []byte
+	switch src := src.(type) {
+	case string:
+		data = []byte(src)
+	case []byte:
+		data = src
+	case nil:
+		*c = nil
+		return nil
+	default:
+		return fmt.Errorf("can't scan %T into audit changes", src)
+	}
+
+	changes := AuditChanges{}
+	if err := json.Unmarshal(data, &changes); err != nil {
+		return err
+	}
+
+	*c = nil
+	if len(changes) > 0 {
+		*c = changes
+	}
+
+	return nil
+}
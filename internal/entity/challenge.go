@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"crypto/sha256"
+	"math/bits"
+	"time"
+)
+
+// Challenge is a hashcash-style proof of work that anonymous requests solve before creating links.
+// A solution is a nonce, such that the SHA-256 hash of "<token>:<nonce>" starts with at least Difficulty zero bits.
+type Challenge struct {
+	Token      string // the signed challenge, which is sent back with its solution
+	Difficulty int
+	ExpiresAt  time.Time
+}
+
+// SolvedBy reports whether the nonce solves the challenge.
+func (c *Challenge) SolvedBy(nonce string) bool {
+	sum := sha256.Sum256([]byte(c.Token + ":" + nonce))
+
+	zeros := 0
+	for _, b := range sum {
+		zeros += bits.LeadingZeros8(b)
+		if b != 0 {
+			break
+		}
+	}
+
+	return zeros >= c.Difficulty
+}
@@ -0,0 +1,39 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+)
+
+// DOMAIN_VERIFICATION_PREFIX is the name of the TXT record, under the domain, that proves a workspace controls it.
+const DOMAIN_VERIFICATION_PREFIX = "_url-shortener"
+
+// Domain is a short domain registered by a workspace. Links created on it are served from it,
+// independently of links with the same token on other domains. It isn't served until it's verified.
+type Domain struct {
+	Name              string     `db:"name"` // the lowercase host name, such as go.example.com
+	WorkspaceID       string     `db:"workspace_id"`
+	VerificationToken string     `db:"verification_token"`
+	VerifiedAt        *time.Time `db:"verified_at"`
+	// DefaultRedirect is where requests for the domain's root are redirected to, if set.
+	DefaultRedirect string `db:"default_redirect"`
+	// NotFoundUrl is where requests for tokens that don't exist on the domain are redirected to, if set.
+	// Otherwise they get the usual 404 response.
+	NotFoundUrl string    `db:"not_found_url"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+// Verified reports whether the workspace has proven it controls the domain.
+func (d *Domain) Verified() bool {
+	return d.VerifiedAt != nil
+}
+
+// VerificationRecord returns the name of the TXT record that verifies the domain.
+func (d *Domain) VerificationRecord() string {
+	return DOMAIN_VERIFICATION_PREFIX + "." + d.Name
+}
+
+// VerificationValue returns the value the verification TXT record must have.
+func (d *Domain) VerificationValue() string {
+	return fmt.Sprintf("url-shortener-verification=%s", d.VerificationToken)
+}
@@ -0,0 +1,12 @@
+package entity
+
+// KeyspaceStats describes how full the keyspace of the current token length is.
+type KeyspaceStats struct {
+	TokenLength   int
+	Links         int
+	Capacity      float64
+	Utilisation   float64
+	Attempts      int
+	Collisions    int
+	CollisionRate float64
+}
@@ -0,0 +1,86 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// PrivacyJobKind is what a privacy job does with the data of its subject.
+type PrivacyJobKind string
+
+const (
+	PrivacyExport PrivacyJobKind = "export" // collects the subject's data, for a subject access request
+	PrivacyErase  PrivacyJobKind = "erase"  // anonymizes the subject's data, for an erasure request
+)
+
+// Valid reports whether the kind is PrivacyExport or PrivacyErase.
+func (k PrivacyJobKind) Valid() bool {
+	return k == PrivacyExport || k == PrivacyErase
+}
+
+// PrivacyJobStatus is how far a privacy job has got.
+type PrivacyJobStatus string
+
+const (
+	PrivacyJobPending   PrivacyJobStatus = "pending"
+	PrivacyJobRunning   PrivacyJobStatus = "running"
+	PrivacyJobSucceeded PrivacyJobStatus = "succeeded"
+	PrivacyJobFailed    PrivacyJobStatus = "failed"
+)
+
+// PrivacyJob exports or erases everything tied to a subject, which is an api key or user id:
+// the links they own, the visits to those links, the audit entries they made or that target them,
+// and their api key, user, memberships and quota usage.
+// Jobs run in the background, and their status is polled until they finish.
+type PrivacyJob struct {
+	ID          string           `db:"id"`
+	Kind        PrivacyJobKind   `db:"kind"`
+	Subject     string           `db:"subject"`
+	Status      PrivacyJobStatus `db:"status"`
+	Error       string           `db:"error"`        // why the job failed
+	RequestedBy string           `db:"requested_by"` // who started the job, like AuditEntry.Actor
+
+	Links        int `db:"links"`         // how many links were exported or anonymized
+	AuditEntries int `db:"audit_entries"` // how many audit entries were exported or anonymized
+
+	// Export is the subject's data, once an export succeeds. It's cleared when the subject is erased.
+	Export *SubjectExport `db:"export"`
+
+	CreatedAt  time.Time  `db:"created_at"`
+	StartedAt  *time.Time `db:"started_at"`
+	FinishedAt *time.Time `db:"finished_at"`
+}
+
+// Finished reports whether the job has stopped, whether it succeeded or not.
+func (j *PrivacyJob) Finished() bool {
+	return j.Status == PrivacyJobSucceeded || j.Status == PrivacyJobFailed
+}
+
+// SubjectExport is everything tied to a subject. It's stored as JSON.
+type SubjectExport struct {
+	Links        []Url        `json:"links"`
+	AuditEntries []AuditEntry `json:"audit_entries"`
+
+	ApiKey      *ApiKey      `json:"api_key,omitempty"` // the subject's api key, without its hash
+	User        *User        `json:"user,omitempty"`
+	Memberships []Membership `json:"memberships,omitempty"`
+	QuotaUsage  []QuotaUsage `json:"quota_usage,omitempty"`
+}
+
+func (e SubjectExport) Value() (driver.Value, error) {
+	data, err := json.Marshal(e)
+	return string(data), err
+}
+
+func (e *SubjectExport) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case string:
+		return json.Unmarshal([]byte(src), e)
+	case []byte:
+		return json.Unmarshal(src, e)
+	default:
+		return fmt.Errorf("can't scan %T into a subject export", src)
+	}
+}
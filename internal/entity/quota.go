@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// QuotaUsage is how many urls a subject created in a period, such as "day:2023-04-01".
+type QuotaUsage struct {
+	Period string `db:"period" json:"period"`
+	Used   int    `db:"used" json:"used"`
+}
+
+// QuotaStatus is how much of its quota a subject has left, in the period closest to running out.
+type QuotaStatus struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time // when the period ends, and its uses are forgotten
+}
@@ -7,7 +7,31 @@ const UrlTableName = "url"
 // Url defines the domain model
 type Url struct {
 	Token     string    `db:"token"`
+	Domain    string    `db:"domain"` // the short domain the url is served from, empty for the default domain
 	TargetUrl string    `db:"target_url"`
 	Visits    int       `db:"visits"`
 	CreatedAt time.Time `db:"created_at"`
+	Owner     string    `db:"owner"`    // id of the api key or user that created the url, empty if it was created anonymously
+	Disabled  bool      `db:"disabled"` // disabled urls aren't redirected to, until an admin enables them again
+
+	WorkspaceID string `db:"workspace_id"` // the workspace the url belongs to, see Workspace
+}
+
+// Workspace returns the id of the workspace the url belongs to.
+// Urls stored before workspaces existed belong to the default workspace.
+func (u *Url) Workspace() string {
+	if u.WorkspaceID == "" {
+		return DEFAULT_WORKSPACE
+	}
+
+	return u.WorkspaceID
+}
+
+// Address identifies the url across domains: its token on the default domain, otherwise its domain and token, such as go.example.com/abcdef.
+func (u *Url) Address() string {
+	if u.Domain == "" {
+		return u.Token
+	}
+
+	return u.Domain + "/" + u.Token
 }
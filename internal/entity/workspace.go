@@ -0,0 +1,55 @@
+package entity
+
+import "time"
+
+// DEFAULT_WORKSPACE holds links created anonymously, and links created before workspaces existed.
+const DEFAULT_WORKSPACE = "default"
+
+// Role is what a member can do in a workspace.
+type Role string
+
+const (
+	RoleViewer Role = "viewer" // can view links and their analytics
+	RoleEditor Role = "editor" // can also create, edit and delete links
+	RoleOwner  Role = "owner"  // can also manage the workspace's members
+)
+
+// roleRanks orders the roles, each role can do everything the roles ranked below it can.
+var roleRanks = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+// Valid reports whether r is one of the roles.
+func (r Role) Valid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// Includes reports whether r can do everything other can.
+func (r Role) Includes(other Role) bool {
+	return r.Valid() && roleRanks[r] >= roleRanks[other]
+}
+
+// User is someone who authenticates with a JWT. Their id is the token's user claim.
+type User struct {
+	ID        string    `db:"id"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// Workspace groups links, and the users who can manage them.
+type Workspace struct {
+	ID        string    `db:"id"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// Membership gives a user a role in a workspace.
+type Membership struct {
+	WorkspaceID string    `db:"workspace_id"`
+	UserID      string    `db:"user_id"`
+	Role        Role      `db:"role"`
+	CreatedAt   time.Time `db:"created_at"`
+}
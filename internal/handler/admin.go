@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/auth"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/host"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// RequireAdminToken only lets through requests with the configured admin token as a bearer token,
+// which act as the admin principal.
+func (h *handler) RequireAdminToken(next http.Handler) http.Handler {
+	expected := []byte("Bearer " + h.apiConfig.Admin.Token)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			api.ReturnApiError(w, r, api.NewUnauthorized("api/unauthorized", "A valid admin token is required.",
+				api.WithAction("Send the admin token in the Authorization header, as 'Bearer <token>'.")))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), adminPrincipal())))
+	})
+}
+
+// RequireAdmin only lets through requests to the admin api with a client certificate the listener verified,
+// or the admin token. Either way, they act as the admin principal, named after the certificate's common name.
+func (h *handler) RequireAdmin(next http.Handler) http.Handler {
+	requireToken := h.RequireAdminToken(next)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+			principal := adminPrincipal()
+			if name := r.TLS.VerifiedChains[0][0].Subject.CommonName; name != "" {
+				principal.Name = "admin:" + name
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), principal)))
+			return
+		}
+
+		// an empty token would let through anyone sending "Bearer "
+		if h.apiConfig.Admin.Token == "" {
+			api.ReturnApiError(w, r, api.NewUnauthorized("api/unauthorized", "A client certificate is required.",
+				api.WithAction("Connect with a certificate signed by the admin client CA.")))
+			return
+		}
+
+		requireToken.ServeHTTP(w, r)
+	})
+}
+
+// adminPrincipal is who requests with the admin token are authenticated as.
+func adminPrincipal() *auth.Principal {
+	return &auth.Principal{Name: "admin", Admin: true}
+}
+
+// AdminShortDomain puts the short domain named by the short_domain query parameter into the request's context,
+// so admin routes act on links on that domain, rather than the default domain.
+func (h *handler) AdminShortDomain(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if name := r.URL.Query().Get("short_domain"); name != "" {
+			r = r.WithContext(host.NewContext(r.Context(), &entity.Domain{Name: name}))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AdminListUrls handles listing the urls of every workspace, filtered like ListUrls,
+// and by the workspace_id query parameter.
+func (h *handler) AdminListUrls() http.HandlerFunc {
+	return h.listUrls(func(r *http.Request, filter *repository.ListFilter) {
+		filter.WorkspaceID = r.URL.Query().Get("workspace_id")
+	})
+}
+
+// SetUrlDisabled handles disabling or enabling a link, which stops or resumes redirects to its target
+func (h *handler) SetUrlDisabled(disabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		url, err := h.urlService.SetUrlDisabled(r.Context(), chi.URLParam(r, "token"), disabled)
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, h.urlDetailsResponse(url))
+	}
+}
+
+// GetAdminStats handles returning runtime stats of the server, and how full the keyspace is
+func (h *handler) GetAdminStats() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		mem := &runtime.MemStats{}
+		runtime.ReadMemStats(mem)
+
+		render.JSON(w, r, &api.AdminStatsResponse{
+			StartedAt:     h.started,
+			UptimeSeconds: time.Since(h.started).Seconds(),
+			GoVersion:     runtime.Version(),
+			Goroutines:    runtime.NumGoroutine(),
+			Memory: api.MemoryStatsResponse{
+				AllocBytes:      mem.Alloc,
+				TotalAllocBytes: mem.TotalAlloc,
+				SysBytes:        mem.Sys,
+				HeapObjects:     mem.HeapObjects,
+				GCCycles:        mem.NumGC,
+			},
+			Keyspace: *keyspaceStatsResponse(h.urlService.KeyspaceStats(r.Context())),
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package handler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/config"
+	"github.com/Jaytpa01/url-shortener-api/internal/auth"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/host"
+	"github.com/Jaytpa01/url-shortener-api/internal/mocks"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/Jaytpa01/url-shortener-api/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const adminToken = "s3cret"
+
+func newAdminRouter(t *testing.T, urlService service.UrlService, keyService service.KeyService, token string) *chi.Mux {
+	r := chi.NewRouter()
+	err := NewAdminHandler(&Config{
+		Router: r,
+		ApiConfig: &config.Config{
+			Server: config.ServerConfig{Environment: "test", BaseUrl: "https://sho.rt"},
+			Admin:  config.AdminConfig{Token: token, TLS: config.AdminTLSConfig{ClientCAFile: "ca.pem"}},
+		},
+		UrlService: urlService,
+		KeyService: keyService,
+	})
+	assert.NoError(t, err)
+
+	return r
+}
+
+// withClientCert makes req look like it came over a connection with a verified client certificate.
+func withClientCert(req *http.Request, name string) *http.Request {
+	req.TLS = &tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: name}}}},
+	}
+	return req
+}
+
+// principalNamed matches a context authenticated as the admin principal with the name.
+func principalNamed(name string) interface{} {
+	return mock.MatchedBy(func(ctx context.Context) bool {
+		principal, ok := auth.FromContext(ctx)
+		return ok && principal.Admin && principal.Name == name
+	})
+}
+
+func TestHandler_RequireAdmin(t *testing.T) {
+	stats := &entity.KeyspaceStats{TokenLength: 6, Links: 3}
+
+	testCases := []struct {
+		name          string
+		token         string
+		authorization string
+		clientCert    string
+		expectedActor string
+		expected      int
+	}{
+		{"Admin Token", adminToken, "Bearer " + adminToken, "", "admin", http.StatusOK},
+		{"Client Certificate", adminToken, "", "ops", "admin:ops", http.StatusOK},
+		{"Client Certificate Without Token", "", "", "ops", "admin:ops", http.StatusOK},
+		{"No Credentials", adminToken, "", "", "", http.StatusUnauthorized},
+		{"Wrong Token", adminToken, "Bearer nope", "", "", http.StatusUnauthorized},
+		{"Empty Token", "", "Bearer ", "", "", http.StatusUnauthorized},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockUrlService := mocks.NewMockUrlService()
+			mockUrlService.On("KeyspaceStats", principalNamed(test.expectedActor)).Return(stats)
+
+			req := httptest.NewRequest(http.MethodGet, "/stats", nil)
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+			if test.clientCert != "" {
+				req = withClientCert(req, test.clientCert)
+			}
+			rec := httptest.NewRecorder()
+
+			newAdminRouter(t, mockUrlService, nil, test.token).ServeHTTP(rec, req)
+
+			assert.Equal(t, test.expected, rec.Code)
+			if test.expected != http.StatusOK {
+				mockUrlService.AssertNotCalled(t, "KeyspaceStats", mock.Anything)
+				return
+			}
+
+			res := &api.AdminStatsResponse{}
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(res))
+			assert.Equal(t, 3, res.Keyspace.Links)
+			assert.NotEmpty(t, res.GoVersion)
+			assert.Positive(t, res.Goroutines)
+			assert.False(t, res.StartedAt.IsZero())
+		})
+	}
+}
+
+func TestHandler_NewAdminHandler_NeedsCredentials(t *testing.T) {
+	err := NewAdminHandler(&Config{
+		Router:     chi.NewRouter(),
+		ApiConfig:  &config.Config{Admin: config.AdminConfig{Port: 8081}},
+		UrlService: mocks.NewMockUrlService(),
+	})
+
+	assert.EqualError(t, err, "admin.token or admin.tls.client_ca_file must be set to serve the admin api")
+}
+
+func TestHandler_AdminUrls(t *testing.T) {
+	createdAt := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("List Every Workspace", func(t *testing.T) {
+		mockUrlService := mocks.NewMockUrlService()
+		mockUrlService.On("ListUrls", principalNamed("admin"), repository.ListFilter{WorkspaceID: "ws_1", Search: "example", Sort: repository.SortTokenAsc}, "", 10).
+			Return([]entity.Url{{Token: "abcdef", TargetUrl: "https://example.com", CreatedAt: createdAt, WorkspaceID: "ws_1", Disabled: true}}, "", nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/urls?workspace_id=ws_1&q=example&sort=token_asc&limit=10", nil)
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		rec := httptest.NewRecorder()
+
+		newAdminRouter(t, mockUrlService, nil, adminToken).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"urls":[{"token":"abcdef","short_url":"https://sho.rt/abcdef","target_url":"https://example.com","visits":0,`+
+			`"created_at":"2023-04-01T12:00:00Z","workspace_id":"ws_1","disabled":true}]}`, rec.Body.String())
+	})
+
+	testCases := []struct {
+		name     string
+		path     string
+		disabled bool
+		domain   string
+	}{
+		{"Disable", "/urls/abcdef/disable", true, ""},
+		{"Enable", "/urls/abcdef/enable", false, ""},
+		{"Disable On Short Domain", "/urls/abcdef/disable?short_domain=go.example.com", true, "go.example.com"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			onDomain := mock.MatchedBy(func(ctx context.Context) bool {
+				return host.DomainName(ctx) == test.domain
+			})
+
+			mockUrlService := mocks.NewMockUrlService()
+			mockUrlService.On("SetUrlDisabled", onDomain, "abcdef", test.disabled).
+				Return(&entity.Url{Token: "abcdef", Domain: test.domain, TargetUrl: "https://example.com", CreatedAt: createdAt, Disabled: test.disabled}, nil)
+
+			req := httptest.NewRequest(http.MethodPost, test.path, nil)
+			req.Header.Set("Authorization", "Bearer "+adminToken)
+			rec := httptest.NewRecorder()
+
+			newAdminRouter(t, mockUrlService, nil, adminToken).ServeHTTP(rec, req)
+
+			res := &api.UrlDetailsResponse{}
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(res))
+			assert.Equal(t, test.disabled, res.Disabled)
+			assert.Equal(t, test.domain, res.Domain)
+		})
+	}
+
+	t.Run("Delete", func(t *testing.T) {
+		mockUrlService := mocks.NewMockUrlService()
+		mockUrlService.On("DeleteUrl", principalNamed("admin"), "abcdef").Return(nil)
+
+		req := httptest.NewRequest(http.MethodDelete, "/urls/abcdef", nil)
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		rec := httptest.NewRecorder()
+
+		newAdminRouter(t, mockUrlService, nil, adminToken).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		mockUrlService.AssertExpectations(t)
+	})
+}
+
+func TestHandler_AdminKeys(t *testing.T) {
+	createdAt := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	key := &entity.ApiKey{ID: "key_1", Name: "ci", CreatedAt: createdAt, WorkspaceID: "default", Scopes: entity.Scopes{entity.ScopeLinksCreate}}
+	keyJSON := `"id":"key_1","name":"ci","workspace_id":"default","scopes":["links:create"],"created_at":"2023-04-01T12:00:00Z"`
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+
+		serviceMethod string
+		serviceArgs   []interface{}
+		serviceResp   []interface{}
+
+		expectedResponseStatus int
+		expectedResponseBody   string
+	}{
+		{
+			name:                   "List Keys",
+			method:                 http.MethodGet,
+			path:                   "/keys",
+			serviceMethod:          "ListKeys",
+			serviceResp:            []interface{}{[]entity.ApiKey{*key}, nil},
+			expectedResponseStatus: http.StatusOK,
+			expectedResponseBody:   `{"keys":[{` + keyJSON + `}]}`,
+		},
+		{
+			name:                   "Create Key",
+			method:                 http.MethodPost,
+			path:                   "/keys",
+			body:                   `{"name":"ci","scopes":["links:create"]}`,
+			serviceMethod:          "CreateKey",
+			serviceArgs:            []interface{}{"ci", "", entity.Scopes{entity.ScopeLinksCreate}},
+			serviceResp:            []interface{}{key, "usk_secret", nil},
+			expectedResponseStatus: http.StatusCreated,
+			expectedResponseBody:   `{` + keyJSON + `,"key":"usk_secret"}`,
+		},
+		{
+			name:                   "Revoke Key",
+			method:                 http.MethodDelete,
+			path:                   "/keys/key_1",
+			serviceMethod:          "RevokeKey",
+			serviceArgs:            []interface{}{"key_1"},
+			serviceResp:            []interface{}{nil},
+			expectedResponseStatus: http.StatusNoContent,
+		},
+		{
+			name:                   "Revoke Unknown Key",
+			method:                 http.MethodDelete,
+			path:                   "/keys/key_2",
+			serviceMethod:          "RevokeKey",
+			serviceArgs:            []interface{}{"key_2"},
+			serviceResp:            []interface{}{api.NewNotFound("key/not-found", "There is no api key with the id key_2.")},
+			expectedResponseStatus: http.StatusNotFound,
+			expectedResponseBody:   `{"type":"NOT_FOUND","code":"key/not-found","message":"There is no api key with the id key_2."}`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockKeyService := mocks.NewMockKeyService()
+			mockKeyService.On(test.serviceMethod, append([]interface{}{principalNamed("admin")}, test.serviceArgs...)...).Return(test.serviceResp...)
+
+			req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			req.Header.Set("Authorization", "Bearer "+adminToken)
+			if test.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			rec := httptest.NewRecorder()
+
+			newAdminRouter(t, mocks.NewMockUrlService(), mockKeyService, adminToken).ServeHTTP(rec, req)
+
+			assert.Equal(t, test.expectedResponseStatus, rec.Code)
+			if test.expectedResponseBody != "" {
+				assert.JSONEq(t, test.expectedResponseBody, rec.Body.String())
+			}
+			mockKeyService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/audit"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// REQUEST_ID_HEADER carries the id of a request, which is recorded with the changes it makes in the audit log.
+// Requests without one are given one by middleware.RequestID, which reads the same header, and it's sent back in the response.
+const REQUEST_ID_HEADER = "X-Request-Id"
+
+// AuditSource puts the IP and id of the request into its context, for the audit log.
+// It must come after middleware.RequestID.
+func (h *handler) AuditSource(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+
+		source := audit.Source{IP: ip, RequestID: middleware.GetReqID(r.Context())}
+		w.Header().Set(REQUEST_ID_HEADER, source.RequestID)
+
+		next.ServeHTTP(w, r.WithContext(audit.NewContext(r.Context(), source)))
+	})
+}
+
+// GetAuditLog returns a page of the audit log, filtered by the action, actor, workspace_id, target, since and until query parameters.
+func (h *handler) GetAuditLog() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		filter := repository.AuditFilter{
+			Action:      entity.AuditAction(query.Get("action")),
+			Actor:       query.Get("actor"),
+			WorkspaceID: query.Get("workspace_id"),
+			Target:      query.Get("target"),
+		}
+
+		for param, value := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+			if query.Get(param) == "" {
+				continue
+			}
+
+			t, err := time.Parse(time.RFC3339, query.Get(param))
+			if err != nil {
+				api.ReturnApiError(w, r, api.NewBadRequest("audit/invalid-filter", fmt.Sprintf("The provided %s (%s) isn't an RFC 3339 time.", param, query.Get(param))))
+				return
+			}
+			*value = t
+		}
+
+		limit := 0
+		if query.Get("limit") != "" {
+			var err error
+			limit, err = strconv.Atoi(query.Get("limit"))
+			if err != nil || limit <= 0 {
+				api.ReturnApiError(w, r, api.NewBadRequest("audit/invalid-limit", fmt.Sprintf("The provided limit (%s) must be a positive number.", query.Get("limit"))))
+				return
+			}
+		}
+
+		entries, next, err := h.auditService.ListAuditEntries(r.Context(), filter, query.Get("cursor"), limit)
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		res := &api.ListAuditEntriesResponse{
+			Entries:    make([]api.AuditEntryResponse, len(entries)),
+			NextCursor: next,
+		}
+		for i, entry := range entries {
+			res.Entries[i] = AuditEntryResponse(entry)
+		}
+
+		render.JSON(w, r, res)
+	}
+}
+
+// AuditEntryResponse converts an audit entry into its api response, which is also how the cli exports it.
+func AuditEntryResponse(entry entity.AuditEntry) api.AuditEntryResponse {
+	res := api.AuditEntryResponse{
+		ID:          entry.ID,
+		Action:      string(entry.Action),
+		Actor:       entry.Actor,
+		WorkspaceID: entry.WorkspaceID,
+		Target:      entry.Target,
+		IP:          entry.IP,
+		RequestID:   entry.RequestID,
+		CreatedAt:   entry.CreatedAt,
+	}
+
+	if len(entry.Changes) > 0 {
+		res.Changes = make(map[string]api.AuditChangeResponse, len(entry.Changes))
+		for field, change := range entry.Changes {
+			res.Changes[field] = api.AuditChangeResponse{Before: change.Before, After: change.After}
+		}
+	}
+
+	return res
+}
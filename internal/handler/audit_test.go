@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/audit"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/mocks"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandler_GetAuditLog(t *testing.T) {
+	createdAt := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	entry := entity.AuditEntry{
+		ID: 7, Action: entity.AuditLinkRetarget, Actor: "user_1", WorkspaceID: "ws_1", Target: "abcdef", IP: "192.0.2.1", RequestID: "req-1",
+		Changes: entity.AuditChanges{"target_url": {Before: "https://example.com", After: "https://example.org"}}, CreatedAt: createdAt,
+	}
+
+	testCases := []struct {
+		name  string
+		query string
+
+		serviceArgs []interface{}
+		serviceResp []interface{}
+
+		expectedResponseStatus int
+		expectedResponseBody   string
+	}{
+		{
+			name:                   "Everything",
+			serviceArgs:            []interface{}{repository.AuditFilter{}, "", 0},
+			serviceResp:            []interface{}{[]entity.AuditEntry{}, "", nil},
+			expectedResponseStatus: http.StatusOK,
+			expectedResponseBody:   `{"entries":[]}`,
+		},
+		{
+			name:  "Filtered",
+			query: "?action=link.retarget&actor=user_1&workspace_id=ws_1&target=abcdef&since=2023-04-01T00:00:00Z&until=2023-04-02T00:00:00Z&cursor=Mw&limit=1",
+			serviceArgs: []interface{}{repository.AuditFilter{
+				Action: entity.AuditLinkRetarget, Actor: "user_1", WorkspaceID: "ws_1", Target: "abcdef",
+				Since: createdAt.Add(-12 * time.Hour), Until: createdAt.Add(12 * time.Hour),
+			}, "Mw", 1},
+			serviceResp:            []interface{}{[]entity.AuditEntry{entry}, "Nw", nil},
+			expectedResponseStatus: http.StatusOK,
+			expectedResponseBody: `{"entries":[{"id":7,"action":"link.retarget","actor":"user_1","workspace_id":"ws_1","target":"abcdef","ip":"192.0.2.1",` +
+				`"request_id":"req-1","changes":{"target_url":{"before":"https://example.com","after":"https://example.org"}},"created_at":"2023-04-01T12:00:00Z"}],` +
+				`"next_cursor":"Nw"}`,
+		},
+		{
+			name:                   "Service Error",
+			serviceArgs:            []interface{}{repository.AuditFilter{}, "", 0},
+			serviceResp:            []interface{}{nil, "", api.NewForbidden("workspace/forbidden", "You need the owner role to view the audit log of workspace (default).")},
+			expectedResponseStatus: http.StatusForbidden,
+			expectedResponseBody:   `{"type":"FORBIDDEN","code":"workspace/forbidden","message":"You need the owner role to view the audit log of workspace (default)."}`,
+		},
+		{
+			name:                   "Invalid Since",
+			query:                  "?since=yesterday",
+			expectedResponseStatus: http.StatusBadRequest,
+			expectedResponseBody:   `{"type":"BAD_REQUEST","code":"audit/invalid-filter","message":"The provided since (yesterday) isn't an RFC 3339 time."}`,
+		},
+		{
+			name:                   "Invalid Limit",
+			query:                  "?limit=-1",
+			expectedResponseStatus: http.StatusBadRequest,
+			expectedResponseBody:   `{"type":"BAD_REQUEST","code":"audit/invalid-limit","message":"The provided limit (-1) must be a positive number."}`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			// setup
+			req := httptest.NewRequest(http.MethodGet, "/audit"+test.query, nil)
+			rec := httptest.NewRecorder()
+
+			mockAuditService := mocks.NewMockAuditService()
+			if test.serviceArgs != nil {
+				args := append([]interface{}{mock.Anything}, test.serviceArgs...)
+				mockAuditService.On("ListAuditEntries", args...).Return(test.serviceResp...)
+			}
+
+			r := chi.NewRouter()
+			NewHandler(&Config{
+				Router:       r,
+				UrlService:   mocks.NewMockUrlService(),
+				AuditService: mockAuditService,
+				ApiConfig:    apiConfig,
+			})
+			r.ServeHTTP(rec, req)
+
+			// Assertions
+			assert.Equal(t, test.expectedResponseStatus, rec.Code)
+			assert.JSONEq(t, test.expectedResponseBody, rec.Body.String())
+			mockAuditService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_AuditSource(t *testing.T) {
+	testCases := []struct {
+		name      string
+		requestID string
+	}{
+		{"Request ID Sent", "req-1"},
+		{"Request ID Generated", ""},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			// setup
+			req := httptest.NewRequest(http.MethodDelete, "/abcdef", nil)
+			if test.requestID != "" {
+				req.Header.Set(REQUEST_ID_HEADER, test.requestID)
+			}
+			rec := httptest.NewRecorder()
+
+			var source audit.Source
+			mockUrlService := mocks.NewMockUrlService()
+			mockUrlService.On("DeleteUrl", mock.Anything, "abcdef").Run(func(args mock.Arguments) {
+				source = audit.FromContext(args.Get(0).(context.Context))
+			}).Return(nil)
+
+			r := chi.NewRouter()
+			NewHandler(&Config{
+				Router:     r,
+				UrlService: mockUrlService,
+				ApiConfig:  apiConfig,
+			})
+			r.ServeHTTP(rec, req)
+
+			// Assertions
+			assert.Equal(t, http.StatusNoContent, rec.Code)
+			assert.Equal(t, "192.0.2.1", source.IP)
+			assert.NotEmpty(t, source.RequestID)
+			assert.Equal(t, source.RequestID, rec.Header().Get(REQUEST_ID_HEADER))
+			if test.requestID != "" {
+				assert.Equal(t, test.requestID, source.RequestID)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/auth"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+)
+
+// WORKSPACE_HEADER lets users pick the workspace a request acts in. Api keys always act in their own workspace.
+const WORKSPACE_HEADER = "X-Workspace-ID"
+
+// Authenticate resolves the api key or JWT sent as a bearer token into a principal in the request context.
+// Requests without a token carry on anonymously, unless auth.require_api_key is set. The admin token isn't accepted here,
+// only by the admin api and RequireAdminToken, so it can't be used across the public api.
+func (h *handler) Authenticate(next http.Handler) http.Handler {
+	required := h.apiConfig != nil && h.apiConfig.Auth.RequireApiKey
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			if required {
+				api.ReturnApiError(w, r, api.NewUnauthorized("auth/missing-key", "An api key is required.",
+					api.WithAction("Send your api key in the Authorization header, as 'Bearer <key>'.")))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := strings.TrimPrefix(header, "Bearer ")
+		if key == header || key == "" {
+			api.ReturnApiError(w, r, api.NewUnauthorized("auth/invalid-header", "The Authorization header is invalid.",
+				api.WithAction("Send your api key in the Authorization header, as 'Bearer <key>'.")))
+			return
+		}
+
+		principal, err := h.authenticate(r, key)
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		if workspaceID := r.Header.Get(WORKSPACE_HEADER); workspaceID != "" && principal.UserID != "" {
+			principal.WorkspaceID = workspaceID
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), principal)))
+	})
+}
+
+// RequireScope rejects requests made with an api key that lacks the scope. Users and
+// anonymous requests pass through, since the url service authorizes them against the workspace they act in.
+func (h *handler) RequireScope(scope entity.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if principal, ok := auth.FromContext(r.Context()); ok && !principal.HasScope(scope) {
+				api.ReturnApiError(w, r, api.NewForbidden("auth/missing-scope", fmt.Sprintf("This api key needs the %s scope to do this.", scope),
+					api.WithAction(fmt.Sprintf("Create a key with the %s scope.", scope))))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// authenticate resolves a bearer token, which is either a JWT or an api key.
+func (h *handler) authenticate(r *http.Request, token string) (*auth.Principal, error) {
+	if h.verifier != nil && auth.IsJWT(token) {
+		principal, err := h.verifier.Verify(r.Context(), token)
+		if err != nil {
+			if errors.Is(err, auth.ErrTokenExpired) {
+				return nil, api.NewUnauthorized("auth/token-expired", "The provided token has expired.",
+					api.WithAction("Sign in again for a new token."), api.WithDebug(err.Error()))
+			}
+
+			return nil, api.NewUnauthorized("auth/invalid-token", "The provided token is invalid.", api.WithDebug(err.Error()))
+		}
+
+		return principal, nil
+	}
+
+	if h.keyService == nil {
+		return nil, api.NewUnauthorized("auth/keys-unsupported", "Api keys aren't supported by this server.")
+	}
+
+	return h.keyService.Authenticate(r.Context(), token)
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/auth"
+	"github.com/go-chi/render"
+)
+
+// The headers anonymous requests send a solved proof of work challenge in
+const (
+	CHALLENGE_HEADER          = "X-Challenge"
+	CHALLENGE_SOLUTION_HEADER = "X-Challenge-Solution"
+)
+
+// GetChallenge handles issuing a proof of work challenge, which anonymous requests solve before creating links
+func (h *handler) GetChallenge() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		challenge, err := h.challengeService.IssueChallenge(r.Context())
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		// every challenge is different, so mustn't be cached
+		w.Header().Set("Cache-Control", "no-store")
+		render.JSON(w, r, &api.ChallengeResponse{
+			Challenge:  challenge.Token,
+			Algorithm:  "sha256",
+			Difficulty: challenge.Difficulty,
+			ExpiresAt:  challenge.ExpiresAt,
+		})
+	}
+}
+
+// ProofOfWork only lets through anonymous requests that solved a challenge from GET /challenge, sent in the
+// X-Challenge and X-Challenge-Solution headers. Authenticated requests don't need to solve one.
+// It should come after Authenticate.
+func (h *handler) ProofOfWork(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := auth.FromContext(r.Context()); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		challenge, nonce := r.Header.Get(CHALLENGE_HEADER), r.Header.Get(CHALLENGE_SOLUTION_HEADER)
+		if challenge == "" || nonce == "" {
+			api.ReturnApiError(w, r, api.NewForbidden("challenge/required", "Anonymous requests need to solve a proof of work challenge to create links.",
+				api.WithAction("Solve a challenge from GET /challenge, and send it in the X-Challenge header, with its solution in the X-Challenge-Solution header. Or send an api key.")))
+			return
+		}
+
+		if err := h.challengeService.VerifySolution(r.Context(), challenge, nonce); err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
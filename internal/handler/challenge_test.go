@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/config"
+	"github.com/Jaytpa01/url-shortener-api/internal/auth"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/mocks"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandler_GetChallenge(t *testing.T) {
+	expiresAt := time.Date(2023, 4, 1, 12, 5, 0, 0, time.UTC)
+
+	mockChallengeService := mocks.NewMockChallengeService()
+	mockChallengeService.On("IssueChallenge", mock.Anything).Return(&entity.Challenge{Token: "abc.16.1680350700.sig", Difficulty: 16, ExpiresAt: expiresAt}, nil)
+
+	r := chi.NewRouter()
+	NewHandler(&Config{
+		Router:           r,
+		UrlService:       mocks.NewMockUrlService(),
+		ChallengeService: mockChallengeService,
+		ApiConfig:        &config.Config{Server: config.ServerConfig{Environment: "test"}},
+	})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/challenge", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+
+	res := &api.ChallengeResponse{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(res))
+	assert.Equal(t, &api.ChallengeResponse{Challenge: "abc.16.1680350700.sig", Algorithm: "sha256", Difficulty: 16, ExpiresAt: expiresAt}, res)
+}
+
+func TestHandler_ProofOfWork(t *testing.T) {
+	unsolved := api.NewForbidden("challenge/unsolved", "The provided solution doesn't solve the challenge, whose difficulty is 16.")
+
+	testCases := []struct {
+		name      string
+		key       string
+		challenge string
+		nonce     string
+
+		verifyErr error // what VerifySolution returns, if it's called
+
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "Solved", challenge: "abc", nonce: "42", expectedStatus: http.StatusCreated},
+		{name: "Unsolved", challenge: "abc", nonce: "41", verifyErr: unsolved, expectedStatus: http.StatusForbidden, expectedCode: "challenge/unsolved"},
+		{name: "No Challenge", expectedStatus: http.StatusForbidden, expectedCode: "challenge/required"},
+		{name: "No Solution", challenge: "abc", expectedStatus: http.StatusForbidden, expectedCode: "challenge/required"},
+		{name: "Api Key", key: "usk_team", expectedStatus: http.StatusCreated},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockUrlService := mocks.NewMockUrlService()
+			mockUrlService.On("ShortenUrl", mock.Anything, exampleUrl).Return(&entity.Url{Token: "123456", TargetUrl: exampleUrl}, nil)
+
+			mockKeyService := mocks.NewMockKeyService()
+			mockKeyService.On("Authenticate", mock.Anything, "usk_team").Return(&auth.Principal{KeyID: "key_team", Scopes: entity.DEFAULT_SCOPES}, nil)
+
+			mockChallengeService := mocks.NewMockChallengeService()
+			mockChallengeService.On("VerifySolution", mock.Anything, test.challenge, test.nonce).Return(test.verifyErr)
+
+			r := chi.NewRouter()
+			NewHandler(&Config{
+				Router:           r,
+				UrlService:       mockUrlService,
+				KeyService:       mockKeyService,
+				ChallengeService: mockChallengeService,
+				ApiConfig:        &config.Config{Server: config.ServerConfig{Environment: "test"}},
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(fmt.Sprintf(`{"url":"%s"}`, exampleUrl)))
+			req.Header.Set(contentTypeHeader, contentTypeJSON)
+			if test.key != "" {
+				req.Header.Set("Authorization", "Bearer "+test.key)
+			}
+			if test.challenge != "" {
+				req.Header.Set(CHALLENGE_HEADER, test.challenge)
+			}
+			if test.nonce != "" {
+				req.Header.Set(CHALLENGE_SOLUTION_HEADER, test.nonce)
+			}
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.expectedStatus, rec.Code)
+			if test.expectedStatus != http.StatusCreated {
+				res := &api.ApiError{}
+				assert.NoError(t, json.NewDecoder(rec.Body).Decode(res))
+				assert.Equal(t, test.expectedCode, res.Code)
+				mockUrlService.AssertNotCalled(t, "ShortenUrl", mock.Anything, mock.Anything)
+			}
+
+			if test.challenge == "" || test.nonce == "" {
+				mockChallengeService.AssertNotCalled(t, "VerifySolution", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/host"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// ShortDomain puts the short domain named by the request's Host header into its context, so its links are found
+// and created on that domain. Requests to the default domain, unknown hosts and unverified domains use the default domain.
+func (h *handler) ShortDomain(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.Host
+		if hostname, _, err := net.SplitHostPort(r.Host); err == nil {
+			name = hostname
+		}
+
+		domain, err := h.domainService.ResolveDomain(r.Context(), name)
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		if domain != nil {
+			r = r.WithContext(host.NewContext(r.Context(), domain))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Root handles requests for "/", which short domains with a default redirect redirect to it.
+// Otherwise it reports the api is up.
+func (h *handler) Root() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if domain := host.FromContext(r.Context()); domain != nil && domain.DefaultRedirect != "" {
+			http.Redirect(w, r, domain.DefaultRedirect, http.StatusFound)
+			return
+		}
+
+		render.JSON(w, r, map[string]string{
+			"status": "ok",
+		})
+	}
+}
+
+// ListDomains handles listing the short domains of a workspace
+func (h *handler) ListDomains() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		domains, err := h.domainService.ListDomains(r.Context(), chi.URLParam(r, "workspace"))
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		res := &api.ListDomainsResponse{Domains: make([]api.DomainResponse, len(domains))}
+		for i := range domains {
+			res.Domains[i] = *domainResponse(&domains[i])
+		}
+
+		render.JSON(w, r, res)
+	}
+}
+
+// AddDomain handles adding a short domain to a workspace. The response includes the TXT record that verifies it.
+func (h *handler) AddDomain() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &api.DomainRequest{}
+
+		err := h.decoder.DecodeJSON(w, r, req)
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		domain, err := h.domainService.AddDomain(r.Context(), chi.URLParam(r, "workspace"), &entity.Domain{
+			Name:            req.Name,
+			DefaultRedirect: req.DefaultRedirect,
+			NotFoundUrl:     req.NotFoundUrl,
+		})
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, domainResponse(domain))
+	}
+}
+
+// VerifyDomain handles checking the TXT record of a short domain, so it can be served
+func (h *handler) VerifyDomain() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		domain, err := h.domainService.VerifyDomain(r.Context(), chi.URLParam(r, "workspace"), chi.URLParam(r, "domain"))
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, domainResponse(domain))
+	}
+}
+
+// UpdateDomain handles changing the default redirect and not found url of a short domain
+func (h *handler) UpdateDomain() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &api.DomainRequest{}
+
+		err := h.decoder.DecodeJSON(w, r, req)
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		domain, err := h.domainService.UpdateDomain(r.Context(), chi.URLParam(r, "workspace"), &entity.Domain{
+			Name:            chi.URLParam(r, "domain"),
+			DefaultRedirect: req.DefaultRedirect,
+			NotFoundUrl:     req.NotFoundUrl,
+		})
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, domainResponse(domain))
+	}
+}
+
+// RemoveDomain handles removing a short domain from a workspace
+func (h *handler) RemoveDomain() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h.domainService.RemoveDomain(r.Context(), chi.URLParam(r, "workspace"), chi.URLParam(r, "domain")); err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// domainResponse converts a domain into its api response.
+func domainResponse(domain *entity.Domain) *api.DomainResponse {
+	res := &api.DomainResponse{
+		Name:            domain.Name,
+		WorkspaceID:     domain.WorkspaceID,
+		Verified:        domain.Verified(),
+		VerifiedAt:      domain.VerifiedAt,
+		DefaultRedirect: domain.DefaultRedirect,
+		NotFoundUrl:     domain.NotFoundUrl,
+		CreatedAt:       domain.CreatedAt,
+	}
+
+	if !domain.Verified() {
+		res.Verification = &api.VerificationRecord{Type: "TXT", Name: domain.VerificationRecord(), Value: domain.VerificationValue()}
+	}
+
+	return res
+}
@@ -0,0 +1,293 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/config"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/host"
+	"github.com/Jaytpa01/url-shortener-api/internal/mocks"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandler_Domains(t *testing.T) {
+	createdAt := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	verifiedAt := createdAt.Add(time.Hour)
+	unverified := &entity.Domain{Name: "go.example.com", WorkspaceID: "ws_1", VerificationToken: "abc", NotFoundUrl: "https://example.com/404", CreatedAt: createdAt}
+	unverifiedJSON := `{"name":"go.example.com","workspace_id":"ws_1","verified":false,` +
+		`"verification":{"type":"TXT","name":"_url-shortener.go.example.com","value":"url-shortener-verification=abc"},` +
+		`"not_found_url":"https://example.com/404","created_at":"2023-04-01T12:00:00Z"}`
+	verified := &entity.Domain{Name: "go.example.com", WorkspaceID: "ws_1", VerificationToken: "abc", VerifiedAt: &verifiedAt, DefaultRedirect: "https://example.com", CreatedAt: createdAt}
+	verifiedJSON := `{"name":"go.example.com","workspace_id":"ws_1","verified":true,"verified_at":"2023-04-01T13:00:00Z",` +
+		`"default_redirect":"https://example.com","created_at":"2023-04-01T12:00:00Z"}`
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+
+		serviceMethod string
+		serviceArgs   []interface{}
+		serviceResp   []interface{}
+
+		expectedResponseStatus int
+		expectedResponseBody   string
+	}{
+		{
+			name:                   "List Domains",
+			method:                 http.MethodGet,
+			path:                   "/workspaces/ws_1/domains",
+			serviceMethod:          "ListDomains",
+			serviceArgs:            []interface{}{"ws_1"},
+			serviceResp:            []interface{}{[]entity.Domain{*unverified, *verified}, nil},
+			expectedResponseStatus: http.StatusOK,
+			expectedResponseBody:   `{"domains":[` + unverifiedJSON + `,` + verifiedJSON + `]}`,
+		},
+		{
+			name:                   "Add Domain",
+			method:                 http.MethodPost,
+			path:                   "/workspaces/ws_1/domains",
+			body:                   `{"name":"go.example.com","not_found_url":"https://example.com/404"}`,
+			serviceMethod:          "AddDomain",
+			serviceArgs:            []interface{}{"ws_1", &entity.Domain{Name: "go.example.com", NotFoundUrl: "https://example.com/404"}},
+			serviceResp:            []interface{}{unverified, nil},
+			expectedResponseStatus: http.StatusCreated,
+			expectedResponseBody:   unverifiedJSON,
+		},
+		{
+			name:          "Verify Domain",
+			method:        http.MethodPost,
+			path:          "/workspaces/ws_1/domains/go.example.com/verify",
+			serviceMethod: "VerifyDomain",
+			serviceArgs:   []interface{}{"ws_1", "go.example.com"},
+			serviceResp:   []interface{}{verified, nil},
+
+			expectedResponseStatus: http.StatusOK,
+			expectedResponseBody:   verifiedJSON,
+		},
+		{
+			name:          "Verify Missing Record",
+			method:        http.MethodPost,
+			path:          "/workspaces/ws_1/domains/go.example.com/verify",
+			serviceMethod: "VerifyDomain",
+			serviceArgs:   []interface{}{"ws_1", "go.example.com"},
+			serviceResp: []interface{}{nil, api.NewConflict("domain/unverified", "Couldn't find the verification record of go.example.com.",
+				api.WithAction("Add a TXT record named _url-shortener.go.example.com with the value url-shortener-verification=abc, then try again once it has propagated."))},
+			expectedResponseStatus: http.StatusConflict,
+			expectedResponseBody: `{"type":"CONFLICT","code":"domain/unverified","message":"Couldn't find the verification record of go.example.com.",` +
+				`"action":"Add a TXT record named _url-shortener.go.example.com with the value url-shortener-verification=abc, then try again once it has propagated."}`,
+		},
+		{
+			name:                   "Update Domain",
+			method:                 http.MethodPatch,
+			path:                   "/workspaces/ws_1/domains/go.example.com",
+			body:                   `{"default_redirect":"https://example.com"}`,
+			serviceMethod:          "UpdateDomain",
+			serviceArgs:            []interface{}{"ws_1", &entity.Domain{Name: "go.example.com", DefaultRedirect: "https://example.com"}},
+			serviceResp:            []interface{}{verified, nil},
+			expectedResponseStatus: http.StatusOK,
+			expectedResponseBody:   verifiedJSON,
+		},
+		{
+			name:                   "Remove Domain",
+			method:                 http.MethodDelete,
+			path:                   "/workspaces/ws_1/domains/go.example.com",
+			serviceMethod:          "RemoveDomain",
+			serviceArgs:            []interface{}{"ws_1", "go.example.com"},
+			serviceResp:            []interface{}{nil},
+			expectedResponseStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			// setup
+			req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			req.Header.Set(contentTypeHeader, contentTypeJSON)
+			rec := httptest.NewRecorder()
+
+			mockDomainService := mocks.NewMockDomainService()
+			mockDomainService.On("ResolveDomain", mock.Anything, "example.com").Return(nil, nil)
+			mockDomainService.On(test.serviceMethod, append([]interface{}{mock.Anything}, test.serviceArgs...)...).Return(test.serviceResp...)
+
+			r := chi.NewRouter()
+			NewHandler(&Config{
+				Router:           r,
+				ApiConfig:        apiConfig,
+				UrlService:       mocks.NewMockUrlService(),
+				WorkspaceService: mocks.NewMockWorkspaceService(),
+				DomainService:    mockDomainService,
+			})
+			r.ServeHTTP(rec, req)
+
+			// Assertions
+			assert.Equal(t, test.expectedResponseStatus, rec.Code)
+			assert.Equal(t, test.expectedResponseBody, strings.Trim(rec.Body.String(), "\n"))
+			mockDomainService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_ShortDomain(t *testing.T) {
+	verifiedAt := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	domain := &entity.Domain{Name: "go.example.com", WorkspaceID: "ws_1", VerifiedAt: &verifiedAt, DefaultRedirect: "https://example.com", NotFoundUrl: "https://example.com/404"}
+	bare := &entity.Domain{Name: "go.example.com", WorkspaceID: "ws_1", VerifiedAt: &verifiedAt}
+	notFound := api.NewNotFound("url/not-found", "Couldn't find URL with token (abcdef).")
+
+	// onDomain matches contexts carrying the short domain
+	onDomain := mock.MatchedBy(func(ctx context.Context) bool {
+		return host.DomainName(ctx) == "go.example.com"
+	})
+	onDefault := mock.MatchedBy(func(ctx context.Context) bool {
+		return host.FromContext(ctx) == nil
+	})
+
+	testCases := []struct {
+		name    string
+		host    string
+		path    string
+		resolve []interface{} // what ResolveDomain returns for go.example.com
+
+		serviceMethod string
+		serviceArgs   []interface{}
+		serviceResp   []interface{}
+
+		expectedResponseStatus int
+		expectedLocation       string
+		expectedResponseBody   string
+	}{
+		{
+			name:                   "Default Redirect",
+			host:                   "go.example.com",
+			path:                   "/",
+			resolve:                []interface{}{domain, nil},
+			expectedResponseStatus: http.StatusFound,
+			expectedLocation:       "https://example.com",
+		},
+		{
+			name:                   "No Default Redirect",
+			host:                   "go.example.com:8080",
+			path:                   "/",
+			resolve:                []interface{}{bare, nil},
+			expectedResponseStatus: http.StatusOK,
+			expectedResponseBody:   `{"status":"ok"}`,
+		},
+		{
+			name:                   "Redirect To Target",
+			host:                   "go.example.com",
+			path:                   "/abcdef",
+			resolve:                []interface{}{domain, nil},
+			serviceMethod:          "FindUrlByToken",
+			serviceArgs:            []interface{}{onDomain, "abcdef"},
+			serviceResp:            []interface{}{&entity.Url{Domain: "go.example.com", Token: "abcdef", TargetUrl: "https://example.org"}, nil},
+			expectedResponseStatus: http.StatusFound,
+			expectedLocation:       "https://example.org",
+		},
+		{
+			name:                   "Not Found Redirect",
+			host:                   "go.example.com",
+			path:                   "/abcdef",
+			resolve:                []interface{}{domain, nil},
+			serviceMethod:          "FindUrlByToken",
+			serviceArgs:            []interface{}{onDomain, "abcdef"},
+			serviceResp:            []interface{}{nil, notFound},
+			expectedResponseStatus: http.StatusFound,
+			expectedLocation:       "https://example.com/404",
+		},
+		{
+			name:                   "No Not Found Redirect",
+			host:                   "go.example.com",
+			path:                   "/abcdef",
+			resolve:                []interface{}{bare, nil},
+			serviceMethod:          "FindUrlByToken",
+			serviceArgs:            []interface{}{onDomain, "abcdef"},
+			serviceResp:            []interface{}{nil, notFound},
+			expectedResponseStatus: http.StatusNotFound,
+			expectedResponseBody:   `{"type":"NOT_FOUND","code":"url/not-found","message":"Couldn't find URL with token (abcdef)."}`,
+		},
+		{
+			name:                   "Unknown Host",
+			host:                   "go.example.com",
+			path:                   "/abcdef",
+			resolve:                []interface{}{nil, nil},
+			serviceMethod:          "FindUrlByToken",
+			serviceArgs:            []interface{}{onDefault, "abcdef"},
+			serviceResp:            []interface{}{nil, notFound},
+			expectedResponseStatus: http.StatusNotFound,
+			expectedResponseBody:   `{"type":"NOT_FOUND","code":"url/not-found","message":"Couldn't find URL with token (abcdef)."}`,
+		},
+		{
+			name:                   "Resolve Error",
+			host:                   "go.example.com",
+			path:                   "/abcdef",
+			resolve:                []interface{}{nil, api.NewInternal("domain/couldnt-resolve")},
+			expectedResponseStatus: http.StatusInternalServerError,
+			expectedResponseBody:   `{"type":"INTERNAL","code":"domain/couldnt-resolve","message":"An internal server error occured."}`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			// setup
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			req.Host = test.host
+			rec := httptest.NewRecorder()
+
+			mockDomainService := mocks.NewMockDomainService()
+			mockDomainService.On("ResolveDomain", mock.Anything, "go.example.com").Return(test.resolve...)
+
+			mockUrlService := mocks.NewMockUrlService()
+			if test.serviceMethod != "" {
+				mockUrlService.On(test.serviceMethod, test.serviceArgs...).Return(test.serviceResp...)
+			}
+			mockUrlService.On("IncrementUrlVisits", mock.Anything, mock.Anything).Return(nil)
+
+			r := chi.NewRouter()
+			NewHandler(&Config{
+				Router:        r,
+				ApiConfig:     apiConfig,
+				UrlService:    mockUrlService,
+				DomainService: mockDomainService,
+			})
+			r.ServeHTTP(rec, req)
+
+			// Assertions
+			assert.Equal(t, test.expectedResponseStatus, rec.Code)
+			assert.Equal(t, test.expectedLocation, rec.Header().Get("Location"))
+			if test.expectedResponseBody != "" {
+				assert.Equal(t, test.expectedResponseBody, strings.Trim(rec.Body.String(), "\n"))
+			}
+			mockDomainService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_ShortUrl(t *testing.T) {
+	testCases := []struct {
+		name     string
+		baseUrl  string
+		url      *entity.Url
+		expected string
+	}{
+		{"Default Domain", "https://sho.rt/", &entity.Url{Token: "abcdef"}, "https://sho.rt/abcdef"},
+		{"Default Domain Without Base Url", "", &entity.Url{Token: "abcdef"}, ""},
+		{"Short Domain", "http://localhost:8080", &entity.Url{Domain: "go.example.com", Token: "abcdef"}, "http://go.example.com/abcdef"},
+		{"Short Domain Without Base Url", "", &entity.Url{Domain: "go.example.com", Token: "abcdef"}, "https://go.example.com/abcdef"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			h := &handler{apiConfig: &config.Config{Server: config.ServerConfig{BaseUrl: test.baseUrl}}}
+			assert.Equal(t, test.expected, h.shortUrl(test.url))
+		})
+	}
+}
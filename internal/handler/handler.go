@@ -2,18 +2,16 @@ package handler
 
 import (
 	"errors"
-	"net/http"
 	"time"
 
-	"github.com/Jaytpa01/url-shortener-api/api"
 	"github.com/Jaytpa01/url-shortener-api/config"
+	"github.com/Jaytpa01/url-shortener-api/internal/auth"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
 	"github.com/Jaytpa01/url-shortener-api/internal/service"
 	"github.com/Jaytpa01/url-shortener-api/pkg/utils"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
-	"github.com/go-chi/httprate"
-	"github.com/go-chi/render"
 )
 
 // Config is used to setup the router and services, which
@@ -23,6 +21,16 @@ type Config struct {
 	Decoder    utils.JSONDecoder
 	ApiConfig  *config.Config
 	UrlService service.UrlService
+	KeyService service.KeyService // nil if the database can't store api keys
+
+	WorkspaceService service.WorkspaceService // nil if the database can't store workspaces
+	DomainService    service.DomainService    // nil if the database can't store short domains
+	QuotaService     service.QuotaService     // nil if quotas aren't configured
+	ChallengeService service.ChallengeService // nil if anonymous requests don't need to solve a proof of work
+	AuditService     service.AuditService     // nil if the database can't store the audit log
+	PrivacyService   service.PrivacyService   // nil if the database can't store privacy jobs, only used by the admin api
+
+	TokenVerifier auth.TokenVerifier // nil if JWT authentication isn't configured
 }
 
 // validate is used to validate the handler config
@@ -43,6 +51,10 @@ func (c *Config) validate() error {
 		return errors.New("UrlService was nil")
 	}
 
+	if c.ApiConfig.Auth.RequireApiKey && c.KeyService == nil && c.TokenVerifier == nil {
+		return errors.New("auth.require_api_key is set, but KeyService and TokenVerifier were nil")
+	}
+
 	return nil
 }
 
@@ -52,6 +64,17 @@ type handler struct {
 	decoder    utils.JSONDecoder
 	apiConfig  *config.Config
 	urlService service.UrlService
+	keyService service.KeyService
+	verifier   auth.TokenVerifier
+
+	workspaceService service.WorkspaceService
+	domainService    service.DomainService
+	quotaService     service.QuotaService
+	challengeService service.ChallengeService
+	auditService     service.AuditService
+	privacyService   service.PrivacyService
+
+	started time.Time // when the handler was created, for the admin api's uptime
 }
 
 // NewHandler initialises the handler with the injected services, and sets up the http routes.
@@ -69,57 +92,195 @@ func NewHandler(cfg *Config) error {
 	}
 
 	// create a handler
-	h := newHandler(cfg.Router, decoder, cfg.ApiConfig, cfg.UrlService)
+	h := newHandler(cfg.Router, decoder, cfg.ApiConfig, cfg.UrlService, cfg.KeyService)
+	h.verifier = cfg.TokenVerifier
+	h.workspaceService = cfg.WorkspaceService
+	h.domainService = cfg.DomainService
+	h.quotaService = cfg.QuotaService
+	h.challengeService = cfg.ChallengeService
+	h.auditService = cfg.AuditService
 
 	// get a reference to the router and
 	// put it in a variable easier to work with
 	r := h.router
 
+	r.Use(middleware.RequestID)
 	r.Use(middleware.Logger)
-
-	r.Use(httprate.Limit(10, time.Second, httprate.WithLimitHandler(func(w http.ResponseWriter, r *http.Request) {
-		limitErr := api.NewTooManyRequests()
-		render.Status(r, http.StatusTooManyRequests)
-		render.JSON(w, r, limitErr)
-	})))
+	r.Use(h.AuditSource)
+	if h.domainService != nil {
+		r.Use(h.ShortDomain)
+	}
 
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins: []string{"*"},
-		AllowedMethods: []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders: []string{"Accept", "Content-Type", "X-CSRF-Token"},
+		AllowedMethods: []string{"GET", "POST", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", WORKSPACE_HEADER, REQUEST_ID_HEADER, CHALLENGE_HEADER, CHALLENGE_SOLUTION_HEADER},
+		ExposedHeaders: []string{REQUEST_ID_HEADER},
 		MaxAge:         300, // Maximum value not ignored by any of major browsers
 	}))
 
-	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-		render.JSON(w, r, map[string]string{
-			"status": "ok",
-		})
+	// every ip is limited before it authenticates, then each group of routes has its own rate limits,
+	// counted per api key, user or anonymous ip
+	r.Use(h.RateLimitIP)
+	apiLimit := h.RateLimit(RATE_LIMIT_API)
+
+	r.Group(func(r chi.Router) {
+		r.Use(h.RateLimit(RATE_LIMIT_PUBLIC))
+		r.Get("/", h.Root())
+		r.Get("/metrics/keyspace", h.GetKeyspaceStats())
+		if h.challengeService != nil {
+			r.Get("/challenge", h.GetChallenge())
+		}
+		r.Get("/{token}", h.RedirectToTargetUrl())
 	})
-	r.Get("/{token}", h.RedirectToTargetUrl())
-	r.Get("/{token}/visits", h.GetUrlVisits())
 
+	// the url service authorizes each request against the workspace it acts in, and api keys also need the route's scope
 	r.Group(func(r chi.Router) {
-		r.Use(middleware.AllowContentType("application/json"))
-		r.Post("/shorten", h.ShortenUrl())
-		r.Post("/lengthen", h.LengthenUrl())
+		r.Use(h.Authenticate)
+
+		r.Group(func(r chi.Router) {
+			r.Use(h.RateLimit(RATE_LIMIT_CREATE))
+			r.Use(h.RequireScope(entity.ScopeLinksCreate))
+			r.Use(middleware.AllowContentType("application/json"))
+			// anonymous requests solve a challenge before they count against quotas
+			if h.challengeService != nil {
+				r.Use(h.ProofOfWork)
+			}
+			if h.quotaService != nil {
+				r.Use(h.CreationQuota)
+			}
+			r.Post("/shorten", h.ShortenUrl())
+			r.Post("/lengthen", h.LengthenUrl())
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(apiLimit)
+			r.With(h.RequireScope(entity.ScopeAnalyticsRead)).Get("/urls", h.ListUrls())
+			r.With(h.RequireScope(entity.ScopeAnalyticsRead)).Get("/{token}/visits", h.GetUrlVisits())
+			r.With(h.RequireScope(entity.ScopeLinksDelete)).Delete("/{token}", h.DeleteUrl())
+			r.With(h.RequireScope(entity.ScopeLinksWrite), middleware.AllowContentType("application/json")).Patch("/{token}", h.UpdateUrl())
+
+			if h.auditService != nil {
+				r.With(h.RequireScope(entity.ScopeAdmin)).Get("/audit", h.GetAuditLog())
+			}
+
+			if h.workspaceService != nil {
+				r.Route("/workspaces", func(r chi.Router) {
+					r.Use(h.RequireScope(entity.ScopeAdmin))
+					r.Get("/", h.ListWorkspaces())
+					r.With(middleware.AllowContentType("application/json")).Post("/", h.CreateWorkspace())
+					r.Get("/{workspace}/members", h.ListMembers())
+					r.Delete("/{workspace}/members/{user}", h.RemoveMember())
+
+					r.Group(func(r chi.Router) {
+						r.Use(middleware.AllowContentType("application/json"))
+						r.Post("/{workspace}/members", h.AddMember())
+						r.Patch("/{workspace}/members/{user}", h.UpdateMember())
+					})
+
+					if h.domainService != nil {
+						r.Get("/{workspace}/domains", h.ListDomains())
+						r.Post("/{workspace}/domains/{domain}/verify", h.VerifyDomain())
+						r.Delete("/{workspace}/domains/{domain}", h.RemoveDomain())
+
+						r.Group(func(r chi.Router) {
+							r.Use(middleware.AllowContentType("application/json"))
+							r.Post("/{workspace}/domains", h.AddDomain())
+							r.Patch("/{workspace}/domains/{domain}", h.UpdateDomain())
+						})
+					}
+				})
+			}
+		})
 	})
 
-	if h.apiConfig != nil {
-		switch h.apiConfig.Server.Environment {
-		case "dev", "development", "local":
-			r.Get("/all", h.GetAllUrls())
-		}
+	// importing is protected by the admin token, and disabled without one
+	if h.apiConfig != nil && h.apiConfig.Admin.Token != "" {
+		r.Group(func(r chi.Router) {
+			r.Use(h.RequireAdminToken)
+			r.Use(apiLimit)
+			r.Post("/import", h.ImportUrls())
+		})
+	}
+
+	return nil
+}
+
+// NewAdminHandler initialises a handler for the admin api, which is served on its own listener, and sets up its routes
+// on cfg.Router. Every route needs the admin token, or a client certificate verified by the listener.
+// It lists, searches, disables and deletes the links of every workspace, manages api keys, and reports runtime stats.
+func NewAdminHandler(cfg *Config) error {
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+
+	if cfg.ApiConfig.Admin.Token == "" && cfg.ApiConfig.Admin.TLS.ClientCAFile == "" {
+		return errors.New("admin.token or admin.tls.client_ca_file must be set to serve the admin api")
+	}
+
+	decoder := cfg.Decoder
+	if decoder == nil {
+		decoder = utils.NewJSONDecoder()
+	}
+
+	h := newHandler(cfg.Router, decoder, cfg.ApiConfig, cfg.UrlService, cfg.KeyService)
+	h.auditService = cfg.AuditService
+	h.privacyService = cfg.PrivacyService
+	h.started = time.Now().UTC()
+
+	r := h.router
+
+	r.Use(middleware.RequestID)
+	r.Use(middleware.Logger)
+	r.Use(h.AuditSource)
+	r.Use(h.RateLimitIP)
+	r.Use(h.RequireAdmin)
+
+	r.Get("/stats", h.GetAdminStats())
+
+	r.Route("/urls", func(r chi.Router) {
+		r.Get("/", h.AdminListUrls())
+
+		// links on short domains are named by the short_domain query parameter
+		r.Group(func(r chi.Router) {
+			r.Use(h.AdminShortDomain)
+			r.Delete("/{token}", h.DeleteUrl())
+			r.Post("/{token}/disable", h.SetUrlDisabled(true))
+			r.Post("/{token}/enable", h.SetUrlDisabled(false))
+		})
+	})
+
+	if h.keyService != nil {
+		r.Route("/keys", func(r chi.Router) {
+			r.Get("/", h.ListKeys())
+			r.With(middleware.AllowContentType("application/json")).Post("/", h.CreateKey())
+			r.Delete("/{id}", h.RevokeKey())
+		})
+	}
+
+	if h.auditService != nil {
+		r.Get("/audit", h.GetAuditLog())
+	}
+
+	// exports and erasures of an api key or user's data run in the background, and are polled until they finish
+	if h.privacyService != nil {
+		r.Route("/privacy/jobs", func(r chi.Router) {
+			r.With(middleware.AllowContentType("application/json")).Post("/", h.StartPrivacyJob())
+			r.Get("/{id}", h.GetPrivacyJob())
+			r.Get("/{id}/export", h.GetPrivacyExport())
+		})
 	}
 
 	return nil
 }
 
 // new handler is a package scoped facotry function for creating a handler
-func newHandler(router *chi.Mux, decoder utils.JSONDecoder, apiConfig *config.Config, urlService service.UrlService) *handler {
+func newHandler(router *chi.Mux, decoder utils.JSONDecoder, apiConfig *config.Config, urlService service.UrlService, keyService service.KeyService) *handler {
 	return &handler{
 		router:     router,
 		decoder:    decoder,
 		apiConfig:  apiConfig,
 		urlService: urlService,
+		keyService: keyService,
 	}
 }
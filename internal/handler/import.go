@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/importer"
+	"github.com/go-chi/render"
+)
+
+// MAX_IMPORT_SIZE is the largest import body we accept, in bytes
+const MAX_IMPORT_SIZE = 32 << 20
+
+// ImportUrls handles importing urls from a CSV, JSON or bookmark HTML body.
+// The format and conflict policy are given by the "format" and "conflict" query parameters.
+func (h *handler) ImportUrls() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, err := importer.ParseFormat(r.URL.Query().Get("format"))
+		if err != nil {
+			api.ReturnApiError(w, r, api.NewBadRequest("import/invalid-format", err.Error()))
+			return
+		}
+
+		conflict, err := importer.ParseConflict(r.URL.Query().Get("conflict"))
+		if err != nil {
+			api.ReturnApiError(w, r, api.NewBadRequest("import/invalid-conflict", err.Error()))
+			return
+		}
+
+		records, err := importer.Parse(http.MaxBytesReader(w, r.Body, MAX_IMPORT_SIZE), format)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				api.ReturnApiError(w, r, api.NewRequestPayloadTooLarge("import/too-large",
+					fmt.Sprintf("Imports must be smaller than %d MB.", MAX_IMPORT_SIZE>>20), api.WithAction("Split the import into smaller files.")))
+				return
+			}
+
+			api.ReturnApiError(w, r, api.NewBadRequest("import/invalid", fmt.Sprintf("Couldn't read the import: %v.", err)))
+			return
+		}
+
+		report, err := h.urlService.ImportUrls(r.Context(), records, conflict)
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		res := &api.ImportResponse{
+			Created:     report.Created,
+			Overwritten: report.Overwritten,
+			Renamed:     report.Renamed,
+			Skipped:     report.Skipped,
+			Failed:      report.Failed,
+			Rows:        make([]api.ImportRowResponse, len(report.Rows)),
+		}
+		for i, row := range report.Rows {
+			res.Rows[i] = api.ImportRowResponse{
+				Row:           row.Row,
+				Token:         row.Token,
+				OriginalToken: row.OriginalToken,
+				Status:        string(row.Status),
+				Error:         row.Error,
+			}
+		}
+
+		render.JSON(w, r, res)
+	}
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/config"
+	"github.com/Jaytpa01/url-shortener-api/internal/importer"
+	"github.com/Jaytpa01/url-shortener-api/internal/mocks"
+	"github.com/Jaytpa01/url-shortener-api/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const importToken = "s3cret"
+
+func newImportRouter(urlService service.UrlService, token string) *chi.Mux {
+	r := chi.NewRouter()
+	NewHandler(&Config{
+		Router: r,
+		ApiConfig: &config.Config{
+			Server: config.ServerConfig{Environment: "test"},
+			Admin:  config.AdminConfig{Token: token},
+		},
+		UrlService: urlService,
+	})
+
+	return r
+}
+
+func TestHandler_ImportUrls(t *testing.T) {
+	body := "keyword,url,clicks\nabc123,https://example.com,4\nabc124,nope,1\n"
+	req := httptest.NewRequest(http.MethodPost, "/import?format=csv&conflict=rename", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+importToken)
+	rec := httptest.NewRecorder()
+
+	report := &importer.Report{}
+	report.Add(importer.RowResult{Row: 1, Token: "xyz789", OriginalToken: "abc123", Status: importer.StatusRenamed})
+	report.Add(importer.RowResult{Row: 2, Token: "abc124", Status: importer.StatusFailed, Error: "invalid target url (nope)"})
+
+	mockUrlService := mocks.NewMockUrlService()
+	mockUrlService.On("ImportUrls", mock.Anything, mock.MatchedBy(func(records []importer.Record) bool {
+		return len(records) == 2 && records[0].Token == "abc123" && records[0].Visits == 4 && records[1].Err != nil
+	}), importer.ConflictRename).Return(report, nil)
+
+	newImportRouter(mockUrlService, importToken).ServeHTTP(rec, req)
+
+	res := &api.ImportResponse{}
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(res))
+	assert.Equal(t, &api.ImportResponse{
+		Renamed: 1,
+		Failed:  1,
+		Rows: []api.ImportRowResponse{
+			{Row: 1, Token: "xyz789", OriginalToken: "abc123", Status: "renamed"},
+			{Row: 2, Token: "abc124", Status: "failed", Error: "invalid target url (nope)"},
+		},
+	}, res)
+}
+
+func TestHandler_ImportUrls_Errors(t *testing.T) {
+	testCases := []struct {
+		name           string
+		path           string
+		authorization  string
+		body           string
+		expectedStatus int
+	}{
+		{"No Token", "/import", "", "token,url\n", http.StatusUnauthorized},
+		{"Wrong Token", "/import", "Bearer nope", "token,url\n", http.StatusUnauthorized},
+		{"Unknown Format", "/import?format=xml", "Bearer " + importToken, "token,url\n", http.StatusBadRequest},
+		{"Unknown Conflict", "/import?conflict=merge", "Bearer " + importToken, "token,url\n", http.StatusBadRequest},
+		{"Unreadable", "/import?format=json", "Bearer " + importToken, "{", http.StatusBadRequest},
+		{"Too Large", "/import", "Bearer " + importToken, "token,url\n" + strings.Repeat("a", MAX_IMPORT_SIZE), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, test.path, strings.NewReader(test.body))
+			req.Header.Set("Authorization", test.authorization)
+			rec := httptest.NewRecorder()
+
+			mockUrlService := mocks.NewMockUrlService()
+			newImportRouter(mockUrlService, importToken).ServeHTTP(rec, req)
+
+			assert.Equal(t, test.expectedStatus, rec.Code)
+			mockUrlService.AssertNotCalled(t, "ImportUrls", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestHandler_ImportUrls_DisabledWithoutToken(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/import", strings.NewReader("token,url\n"))
+	rec := httptest.NewRecorder()
+
+	newImportRouter(mocks.NewMockUrlService(), "").ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
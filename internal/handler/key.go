@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// ListKeys handles listing every api key, including revoked ones
+func (h *handler) ListKeys() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		keys, err := h.keyService.ListKeys(r.Context())
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		res := &api.ListKeysResponse{Keys: make([]api.KeyResponse, len(keys))}
+		for i := range keys {
+			res.Keys[i] = *keyResponse(&keys[i])
+		}
+
+		render.JSON(w, r, res)
+	}
+}
+
+// CreateKey handles creating an api key. The response is the only time the key is returned.
+func (h *handler) CreateKey() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &api.CreateKeyRequest{}
+
+		err := h.decoder.DecodeJSON(w, r, req)
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		scopes := make(entity.Scopes, len(req.Scopes))
+		for i, scope := range req.Scopes {
+			scopes[i] = entity.Scope(scope)
+		}
+
+		key, secret, err := h.keyService.CreateKey(r.Context(), req.Name, req.WorkspaceID, scopes)
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, &api.CreateKeyResponse{KeyResponse: *keyResponse(key), Key: secret})
+	}
+}
+
+// RevokeKey handles revoking an api key, so it no longer authenticates requests
+func (h *handler) RevokeKey() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h.keyService.RevokeKey(r.Context(), chi.URLParam(r, "id")); err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// keyResponse converts an api key into its api response.
+func keyResponse(key *entity.ApiKey) *api.KeyResponse {
+	scopes := make([]string, len(key.Scopes))
+	for i, scope := range key.Scopes {
+		scopes[i] = string(scope)
+	}
+
+	return &api.KeyResponse{
+		ID:          key.ID,
+		Name:        key.Name,
+		WorkspaceID: key.WorkspaceID,
+		Scopes:      scopes,
+		CreatedAt:   key.CreatedAt,
+		RevokedAt:   key.RevokedAt,
+	}
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// StartPrivacyJob handles starting an export or erasure of an api key or user's data.
+// The job runs in the background, so the response is its id and where to poll its status.
+func (h *handler) StartPrivacyJob() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &api.StartPrivacyJobRequest{}
+
+		err := h.decoder.DecodeJSON(w, r, req)
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		job, err := h.privacyService.StartJob(r.Context(), entity.PrivacyJobKind(req.Kind), req.Subject)
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Location", "/privacy/jobs/"+job.ID)
+		render.Status(r, http.StatusAccepted)
+		render.JSON(w, r, privacyJobResponse(job))
+	}
+}
+
+// GetPrivacyJob handles polling the status of a privacy job
+func (h *handler) GetPrivacyJob() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, err := h.privacyService.FindJob(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, privacyJobResponse(job))
+	}
+}
+
+// GetPrivacyExport handles downloading the data an export job collected, once it has succeeded
+func (h *handler) GetPrivacyExport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, err := h.privacyService.FindJob(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		switch {
+		case job.Kind != entity.PrivacyExport:
+			err = api.NewNotFound("privacy/no-export", fmt.Sprintf("The privacy job (%s) is an erasure, so has no export.", job.ID))
+		case job.Status == entity.PrivacyJobFailed:
+			err = api.NewConflict("privacy/job-failed", fmt.Sprintf("The privacy job (%s) failed, so has no export.", job.ID),
+				api.WithAction("Start a new export with POST /privacy/jobs."))
+		case !job.Finished():
+			err = api.NewConflict("privacy/not-ready", fmt.Sprintf("The privacy job (%s) hasn't finished yet.", job.ID),
+				api.WithAction(fmt.Sprintf("Poll GET /privacy/jobs/%s until its status is succeeded.", job.ID)))
+		case job.Export == nil:
+			err = api.NewGone("privacy/export-cleared", fmt.Sprintf("The export of privacy job (%s) was cleared when its subject was erased.", job.ID))
+		}
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, job.ID))
+		render.JSON(w, r, SubjectExportResponse(job))
+	}
+}
+
+// privacyJobResponse converts a privacy job into its api response.
+func privacyJobResponse(job *entity.PrivacyJob) *api.PrivacyJobResponse {
+	res := &api.PrivacyJobResponse{
+		ID:           job.ID,
+		Kind:         string(job.Kind),
+		Subject:      job.Subject,
+		Status:       string(job.Status),
+		Error:        job.Error,
+		RequestedBy:  job.RequestedBy,
+		Links:        job.Links,
+		AuditEntries: job.AuditEntries,
+		CreatedAt:    job.CreatedAt,
+		StartedAt:    job.StartedAt,
+		FinishedAt:   job.FinishedAt,
+	}
+
+	if job.Export != nil {
+		res.ExportUrl = "/privacy/jobs/" + job.ID + "/export"
+	}
+
+	return res
+}
+
+// SubjectExportResponse converts the data a succeeded export job collected into its api response.
+// It's exported so the cli writes exports in the same shape.
+func SubjectExportResponse(job *entity.PrivacyJob) api.SubjectExportResponse {
+	res := api.SubjectExportResponse{
+		Subject:      job.Subject,
+		JobID:        job.ID,
+		Links:        []api.ExportedLinkResponse{},
+		AuditEntries: []api.AuditEntryResponse{},
+		Memberships:  []api.MembershipResponse{},
+		QuotaUsage:   []api.QuotaUsageResponse{},
+	}
+
+	if job.FinishedAt != nil {
+		res.ExportedAt = *job.FinishedAt
+	}
+
+	if job.Export == nil {
+		return res
+	}
+
+	for _, url := range job.Export.Links {
+		res.Links = append(res.Links, api.ExportedLinkResponse{
+			Token:       url.Token,
+			Domain:      url.Domain,
+			TargetUrl:   url.TargetUrl,
+			Visits:      url.Visits,
+			CreatedAt:   url.CreatedAt,
+			WorkspaceID: url.Workspace(),
+			Disabled:    url.Disabled,
+		})
+	}
+
+	for _, entry := range job.Export.AuditEntries {
+		res.AuditEntries = append(res.AuditEntries, AuditEntryResponse(entry))
+	}
+
+	if job.Export.ApiKey != nil {
+		res.ApiKey = keyResponse(job.Export.ApiKey)
+	}
+
+	if user := job.Export.User; user != nil {
+		res.User = &api.ExportedUserResponse{ID: user.ID, Name: user.Name, CreatedAt: user.CreatedAt}
+	}
+
+	for i := range job.Export.Memberships {
+		res.Memberships = append(res.Memberships, *membershipResponse(&job.Export.Memberships[i]))
+	}
+
+	for _, usage := range job.Export.QuotaUsage {
+		res.QuotaUsage = append(res.QuotaUsage, api.QuotaUsageResponse{Period: usage.Period, Used: usage.Used})
+	}
+
+	return res
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/config"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/mocks"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_PrivacyJobs(t *testing.T) {
+	createdAt := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	finishedAt := createdAt.Add(time.Minute)
+
+	pending := &entity.PrivacyJob{ID: "job_1", Kind: entity.PrivacyExport, Subject: "key_1", Status: entity.PrivacyJobPending, RequestedBy: "admin", CreatedAt: createdAt}
+	exported := &entity.PrivacyJob{ID: "job_1", Kind: entity.PrivacyExport, Subject: "key_1", Status: entity.PrivacyJobSucceeded, RequestedBy: "admin",
+		Links: 1, AuditEntries: 1, CreatedAt: createdAt, StartedAt: &createdAt, FinishedAt: &finishedAt,
+		Export: &entity.SubjectExport{
+			Links:        []entity.Url{{Token: "abcdef", TargetUrl: "https://example.com", Visits: 3, CreatedAt: createdAt, Owner: "key_1"}},
+			AuditEntries: []entity.AuditEntry{{ID: 1, Action: entity.AuditLinkCreate, Actor: "key_1", Target: "abcdef", CreatedAt: createdAt}},
+			ApiKey:       &entity.ApiKey{ID: "key_1", Name: "ci", WorkspaceID: "default", Scopes: entity.Scopes{entity.ScopeLinksWrite}, CreatedAt: createdAt},
+			QuotaUsage:   []entity.QuotaUsage{{Period: "day:2023-04-01", Used: 1}},
+		}}
+	cleared := &entity.PrivacyJob{ID: "job_1", Kind: entity.PrivacyExport, Subject: "key_1", Status: entity.PrivacyJobSucceeded, CreatedAt: createdAt, FinishedAt: &finishedAt}
+	failed := &entity.PrivacyJob{ID: "job_1", Kind: entity.PrivacyExport, Subject: "key_1", Status: entity.PrivacyJobFailed, Error: "disk full", CreatedAt: createdAt}
+	erasure := &entity.PrivacyJob{ID: "job_1", Kind: entity.PrivacyErase, Subject: "key_1", Status: entity.PrivacyJobSucceeded, CreatedAt: createdAt}
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+
+		serviceMethod string
+		serviceArgs   []interface{}
+		serviceResp   []interface{}
+
+		expectedResponseStatus int
+		expectedLocation       string
+		expectedResponseBody   string
+	}{
+		{
+			name:                   "Start Job",
+			method:                 http.MethodPost,
+			path:                   "/privacy/jobs",
+			body:                   `{"kind":"export","subject":"key_1"}`,
+			serviceMethod:          "StartJob",
+			serviceArgs:            []interface{}{entity.PrivacyExport, "key_1"},
+			serviceResp:            []interface{}{pending, nil},
+			expectedResponseStatus: http.StatusAccepted,
+			expectedLocation:       "/privacy/jobs/job_1",
+			expectedResponseBody: `{"id":"job_1","kind":"export","subject":"key_1","status":"pending","requested_by":"admin","links":0,"audit_entries":0,` +
+				`"created_at":"2023-04-01T12:00:00Z"}`,
+		},
+		{
+			name:                   "Start Invalid Job",
+			method:                 http.MethodPost,
+			path:                   "/privacy/jobs",
+			body:                   `{"kind":"delete","subject":"key_1"}`,
+			serviceMethod:          "StartJob",
+			serviceArgs:            []interface{}{entity.PrivacyJobKind("delete"), "key_1"},
+			serviceResp:            []interface{}{nil, api.NewBadRequest("privacy/invalid-kind", "Unknown kind of privacy job (delete).")},
+			expectedResponseStatus: http.StatusBadRequest,
+			expectedResponseBody:   `{"type":"BAD_REQUEST","code":"privacy/invalid-kind","message":"Unknown kind of privacy job (delete)."}`,
+		},
+		{
+			name:                   "Poll Job",
+			method:                 http.MethodGet,
+			path:                   "/privacy/jobs/job_1",
+			serviceMethod:          "FindJob",
+			serviceArgs:            []interface{}{"job_1"},
+			serviceResp:            []interface{}{exported, nil},
+			expectedResponseStatus: http.StatusOK,
+			expectedResponseBody: `{"id":"job_1","kind":"export","subject":"key_1","status":"succeeded","requested_by":"admin","links":1,"audit_entries":1,` +
+				`"export_url":"/privacy/jobs/job_1/export","created_at":"2023-04-01T12:00:00Z","started_at":"2023-04-01T12:00:00Z","finished_at":"2023-04-01T12:01:00Z"}`,
+		},
+		{
+			name:                   "Download Export",
+			method:                 http.MethodGet,
+			path:                   "/privacy/jobs/job_1/export",
+			serviceMethod:          "FindJob",
+			serviceArgs:            []interface{}{"job_1"},
+			serviceResp:            []interface{}{exported, nil},
+			expectedResponseStatus: http.StatusOK,
+			expectedResponseBody: `{"subject":"key_1","job_id":"job_1","exported_at":"2023-04-01T12:01:00Z",` +
+				`"links":[{"token":"abcdef","target_url":"https://example.com","visits":3,"created_at":"2023-04-01T12:00:00Z","workspace_id":"default"}],` +
+				`"audit_entries":[{"id":1,"action":"link.create","actor":"key_1","target":"abcdef","created_at":"2023-04-01T12:00:00Z"}],` +
+				`"api_key":{"id":"key_1","name":"ci","workspace_id":"default","scopes":["links:write"],"created_at":"2023-04-01T12:00:00Z"},` +
+				`"memberships":[],"quota_usage":[{"period":"day:2023-04-01","used":1}]}`,
+		},
+		{
+			name:                   "Export Not Ready",
+			method:                 http.MethodGet,
+			path:                   "/privacy/jobs/job_1/export",
+			serviceMethod:          "FindJob",
+			serviceArgs:            []interface{}{"job_1"},
+			serviceResp:            []interface{}{pending, nil},
+			expectedResponseStatus: http.StatusConflict,
+			expectedResponseBody: `{"type":"CONFLICT","code":"privacy/not-ready","message":"The privacy job (job_1) hasn't finished yet.",` +
+				`"action":"Poll GET /privacy/jobs/job_1 until its status is succeeded."}`,
+		},
+		{
+			name:                   "Export Failed",
+			method:                 http.MethodGet,
+			path:                   "/privacy/jobs/job_1/export",
+			serviceMethod:          "FindJob",
+			serviceArgs:            []interface{}{"job_1"},
+			serviceResp:            []interface{}{failed, nil},
+			expectedResponseStatus: http.StatusConflict,
+			expectedResponseBody: `{"type":"CONFLICT","code":"privacy/job-failed","message":"The privacy job (job_1) failed, so has no export.",` +
+				`"action":"Start a new export with POST /privacy/jobs."}`,
+		},
+		{
+			name:                   "Export Cleared",
+			method:                 http.MethodGet,
+			path:                   "/privacy/jobs/job_1/export",
+			serviceMethod:          "FindJob",
+			serviceArgs:            []interface{}{"job_1"},
+			serviceResp:            []interface{}{cleared, nil},
+			expectedResponseStatus: http.StatusGone,
+			expectedResponseBody:   `{"type":"GONE","code":"privacy/export-cleared","message":"The export of privacy job (job_1) was cleared when its subject was erased."}`,
+		},
+		{
+			name:                   "Erasure Has No Export",
+			method:                 http.MethodGet,
+			path:                   "/privacy/jobs/job_1/export",
+			serviceMethod:          "FindJob",
+			serviceArgs:            []interface{}{"job_1"},
+			serviceResp:            []interface{}{erasure, nil},
+			expectedResponseStatus: http.StatusNotFound,
+			expectedResponseBody:   `{"type":"NOT_FOUND","code":"privacy/no-export","message":"The privacy job (job_1) is an erasure, so has no export."}`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockPrivacyService := mocks.NewMockPrivacyService()
+			mockPrivacyService.On(test.serviceMethod, append([]interface{}{principalNamed("admin")}, test.serviceArgs...)...).Return(test.serviceResp...)
+
+			r := chi.NewRouter()
+			require.NoError(t, NewAdminHandler(&Config{
+				Router: r,
+				ApiConfig: &config.Config{
+					Server: config.ServerConfig{Environment: "test"},
+					Admin:  config.AdminConfig{Token: adminToken},
+				},
+				UrlService:     mocks.NewMockUrlService(),
+				PrivacyService: mockPrivacyService,
+			}))
+
+			req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			req.Header.Set("Authorization", "Bearer "+adminToken)
+			if test.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.expectedResponseStatus, rec.Code)
+			assert.Equal(t, test.expectedLocation, rec.Header().Get("Location"))
+			assert.JSONEq(t, test.expectedResponseBody, rec.Body.String())
+			mockPrivacyService.AssertExpectations(t)
+		})
+	}
+}
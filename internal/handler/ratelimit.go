@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/config"
+	"github.com/Jaytpa01/url-shortener-api/internal/auth"
+	"github.com/go-chi/httprate"
+)
+
+// The groups of routes rate limits are configured for, in config.RateLimitConfig
+const (
+	RATE_LIMIT_PUBLIC = "public" // the index, keyspace metrics and redirects
+	RATE_LIMIT_CREATE = "create" // creating urls
+	RATE_LIMIT_API    = "api"    // everything else
+	RATE_LIMIT_IP     = "ip"     // every request from an ip, counted before it's authenticated
+
+	DEFAULT_RATE_LIMIT        = 10  // requests per window, for groups without limits of their own
+	DEFAULT_IP_RATE_LIMIT     = 100 // requests per window from each ip, high enough for api keys with limits of their own
+	DEFAULT_RATE_LIMIT_WINDOW = time.Second
+)
+
+// RateLimitIP limits every request from each ip, whether or not it authenticates. It comes before Authenticate
+// and RequireAdminToken, so guessing tokens, and the key lookups and JWT verification they cause, are limited too.
+func (h *handler) RateLimitIP(next http.Handler) http.Handler {
+	var limit config.LimitConfig
+	if h.apiConfig != nil {
+		limit = h.apiConfig.RateLimit.Groups[RATE_LIMIT_IP]
+	}
+
+	requests := limit.Requests
+	if requests <= 0 {
+		requests = DEFAULT_IP_RATE_LIMIT
+	}
+
+	return newLimiter(requests, limit.Window, httprate.KeyByIP)(next)
+}
+
+// RateLimit limits the requests to a group of routes. Every api key and user, and every anonymous ip, is counted separately,
+// so it should come after Authenticate. Api keys and users with limits of their own in the config get their own limiter.
+// Responses carry X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers, and Retry-After once limited.
+func (h *handler) RateLimit(group string) func(next http.Handler) http.Handler {
+	var cfg config.RateLimitConfig
+	if h.apiConfig != nil {
+		cfg = h.apiConfig.RateLimit
+	}
+
+	limit := cfg.Groups[group]
+	authenticated := newLimiter(limit.Requests, limit.Window, bySubject)
+	anonymous := authenticated
+	if limit.Anonymous > 0 {
+		anonymous = newLimiter(limit.Anonymous, limit.Window, bySubject)
+	}
+
+	keys := make(map[string]func(http.Handler) http.Handler, len(cfg.Keys))
+	for id, limit := range cfg.Keys {
+		keys[id] = newLimiter(limit.Requests, limit.Window, bySubject)
+	}
+
+	return func(next http.Handler) http.Handler {
+		authenticatedNext := authenticated(next)
+		anonymousNext := anonymous(next)
+		keysNext := make(map[string]http.Handler, len(keys))
+		for id, limiter := range keys {
+			keysNext[id] = limiter(next)
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := auth.FromContext(r.Context()); !ok {
+				anonymousNext.ServeHTTP(w, r)
+				return
+			}
+
+			if limited, ok := keysNext[requestSubject(r)]; ok {
+				limited.ServeHTTP(w, r)
+				return
+			}
+
+			authenticatedNext.ServeHTTP(w, r)
+		})
+	}
+}
+
+// bySubject keys a limiter by requestSubject.
+func bySubject(r *http.Request) (string, error) {
+	return requestSubject(r), nil
+}
+
+// newLimiter returns a limiter allowing requests per window to each key, with the defaults for zero values.
+func newLimiter(requests int, window time.Duration, key httprate.KeyFunc) func(http.Handler) http.Handler {
+	if requests <= 0 {
+		requests = DEFAULT_RATE_LIMIT
+	}
+	if window <= 0 {
+		window = DEFAULT_RATE_LIMIT_WINDOW
+	}
+
+	return httprate.Limit(requests, window,
+		httprate.WithKeyFuncs(key),
+		httprate.WithLimitHandler(func(w http.ResponseWriter, r *http.Request) {
+			api.ReturnApiError(w, r, api.NewTooManyRequests("api/too-many-requests", "You are sending too many requests to the server.",
+				api.WithAction(fmt.Sprintf("C'mon buddy, please slow down. You are limited to %d requests every %s.", requests, window))))
+		}),
+	)
+}
+
+// CreationQuota counts the urls created by each api key, user and anonymous ip against their daily and monthly quotas.
+// Every request counts, even if the url turns out to be invalid.
+// Responses carry X-Quota-Limit, X-Quota-Remaining and X-Quota-Reset headers, and Retry-After once the quota runs out.
+func (h *handler) CreationQuota(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, err := h.quotaService.UseCreateQuota(r.Context(), requestSubject(r))
+		if status != nil {
+			w.Header().Set("X-Quota-Limit", strconv.Itoa(status.Limit))
+			w.Header().Set("X-Quota-Remaining", strconv.Itoa(status.Remaining))
+			w.Header().Set("X-Quota-Reset", strconv.FormatInt(status.Reset.Unix(), 10))
+		}
+
+		if err != nil {
+			if status != nil {
+				w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(status.Reset).Seconds())+1))
+			}
+
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestSubject returns who rate limits and quotas count a request against: the admin, an api key or user by their id,
+// or the ip of an anonymous request.
+func requestSubject(r *http.Request) string {
+	principal, ok := auth.FromContext(r.Context())
+	switch {
+	case !ok:
+		ip, _ := httprate.KeyByIP(r)
+		return "ip:" + ip
+	case principal.Admin:
+		return "admin"
+	default:
+		return principal.Owner()
+	}
+}
@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/config"
+	"github.com/Jaytpa01/url-shortener-api/internal/auth"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/mocks"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandler_RateLimit_PerSubject(t *testing.T) {
+	rateLimit := config.RateLimitConfig{
+		Groups: map[string]config.LimitConfig{
+			RATE_LIMIT_CREATE: {Requests: 3, Anonymous: 1, Window: time.Minute},
+		},
+		Keys: map[string]config.LimitConfig{
+			"key_ci": {Requests: 5, Window: time.Minute},
+		},
+	}
+
+	testCases := []struct {
+		name       string
+		key        string // the api key sent, or "" for anonymous requests
+		remoteAddr string
+
+		expectedAllowed int
+	}{
+		{"Anonymous", "", "192.0.2.1:1234", 1},
+		{"Api Key", "usk_team", "192.0.2.1:1234", 3},
+		{"Api Key With Its Own Limit", "usk_ci", "192.0.2.1:1234", 5},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockUrlService := mocks.NewMockUrlService()
+			mockUrlService.On("ShortenUrl", mock.Anything, exampleUrl).Return(&entity.Url{Token: "123456", TargetUrl: exampleUrl}, nil)
+
+			mockKeyService := mocks.NewMockKeyService()
+			mockKeyService.On("Authenticate", mock.Anything, "usk_team").Return(&auth.Principal{KeyID: "key_team", Scopes: entity.DEFAULT_SCOPES}, nil)
+			mockKeyService.On("Authenticate", mock.Anything, "usk_ci").Return(&auth.Principal{KeyID: "key_ci", Scopes: entity.DEFAULT_SCOPES}, nil)
+
+			r := chi.NewRouter()
+			NewHandler(&Config{
+				Router:     r,
+				UrlService: mockUrlService,
+				KeyService: mockKeyService,
+				ApiConfig: &config.Config{
+					Server:    config.ServerConfig{Environment: "test"},
+					RateLimit: rateLimit,
+				},
+			})
+
+			shorten := func(key, remoteAddr string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(fmt.Sprintf(`{"url":"%s"}`, exampleUrl)))
+				req.Header.Set(contentTypeHeader, contentTypeJSON)
+				req.RemoteAddr = remoteAddr
+				if key != "" {
+					req.Header.Set("Authorization", "Bearer "+key)
+				}
+				rec := httptest.NewRecorder()
+				r.ServeHTTP(rec, req)
+				return rec
+			}
+
+			for i := 0; i < test.expectedAllowed; i++ {
+				rec := shorten(test.key, test.remoteAddr)
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				assert.Equal(t, fmt.Sprint(test.expectedAllowed), rec.Header().Get("X-RateLimit-Limit"))
+				assert.Equal(t, fmt.Sprint(test.expectedAllowed-i), rec.Header().Get("X-RateLimit-Remaining"))
+			}
+
+			rec := shorten(test.key, test.remoteAddr)
+			assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+			assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+			apiErr := &api.ApiError{}
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(apiErr))
+			assert.Equal(t, "api/too-many-requests", apiErr.Code)
+
+			// anonymous requests from another ip, and the other keys, aren't limited by this one
+			assert.Equal(t, http.StatusCreated, shorten("", "198.51.100.1:1234").Code)
+			if test.key != "usk_ci" {
+				assert.Equal(t, http.StatusCreated, shorten("usk_ci", test.remoteAddr).Code)
+			}
+
+			// nor are the other groups
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = test.remoteAddr
+			rec = httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusOK, rec.Code)
+		})
+	}
+}
+
+func TestHandler_CreationQuota(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	testCases := []struct {
+		name          string
+		authorization string
+		subject       string
+		quotaStatus   *entity.QuotaStatus
+		quotaErr      error
+
+		expectedResponseStatus int
+		expectedHeaders        map[string]string
+	}{
+		{
+			name:                   "Within Quota",
+			subject:                "ip:192.0.2.1",
+			quotaStatus:            &entity.QuotaStatus{Limit: 10, Remaining: 4, Reset: reset},
+			expectedResponseStatus: http.StatusCreated,
+			expectedHeaders:        map[string]string{"X-Quota-Limit": "10", "X-Quota-Remaining": "4", "X-Quota-Reset": fmt.Sprint(reset.Unix())},
+		},
+		{
+			name:                   "No Quota",
+			authorization:          "Bearer usk_ci",
+			subject:                "key_ci",
+			expectedResponseStatus: http.StatusCreated,
+			expectedHeaders:        map[string]string{"X-Quota-Limit": ""},
+		},
+		{
+			name:                   "Quota Exceeded",
+			subject:                "ip:192.0.2.1",
+			quotaStatus:            &entity.QuotaStatus{Limit: 10, Remaining: 0, Reset: reset},
+			quotaErr:               api.NewTooManyRequests("quota/exceeded", "You have created the 10 urls your daily quota allows."),
+			expectedResponseStatus: http.StatusTooManyRequests,
+			expectedHeaders:        map[string]string{"X-Quota-Remaining": "0", "Retry-After": "3600"},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(fmt.Sprintf(`{"url":"%s"}`, exampleUrl)))
+			req.Header.Set(contentTypeHeader, contentTypeJSON)
+			req.RemoteAddr = "192.0.2.1:1234"
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+			rec := httptest.NewRecorder()
+
+			mockUrlService := mocks.NewMockUrlService()
+			mockUrlService.On("ShortenUrl", mock.Anything, exampleUrl).Return(&entity.Url{Token: "123456", TargetUrl: exampleUrl}, nil)
+
+			mockKeyService := mocks.NewMockKeyService()
+			mockKeyService.On("Authenticate", mock.Anything, "usk_ci").Return(&auth.Principal{KeyID: "key_ci", Scopes: entity.DEFAULT_SCOPES}, nil)
+
+			mockQuotaService := mocks.NewMockQuotaService()
+			mockQuotaService.On("UseCreateQuota", mock.Anything, test.subject).Return(test.quotaStatus, test.quotaErr)
+
+			r := chi.NewRouter()
+			NewHandler(&Config{
+				Router:       r,
+				ApiConfig:    apiConfig,
+				UrlService:   mockUrlService,
+				KeyService:   mockKeyService,
+				QuotaService: mockQuotaService,
+			})
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.expectedResponseStatus, rec.Code)
+			for header, value := range test.expectedHeaders {
+				if header == "Retry-After" {
+					// the time until the reset has passed a little
+					retryAfter, err := strconv.Atoi(rec.Header().Get(header))
+					assert.NoError(t, err)
+					assert.InDelta(t, 3600, retryAfter, 2)
+					continue
+				}
+				assert.Equal(t, value, rec.Header().Get(header), header)
+			}
+			mockQuotaService.AssertExpectations(t)
+			if test.quotaErr != nil {
+				mockUrlService.AssertNotCalled(t, "ShortenUrl", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestHandler_RateLimitIP_BeforeAuthenticate(t *testing.T) {
+	mockKeyService := mocks.NewMockKeyService()
+	mockKeyService.On("Authenticate", mock.Anything, "usk_guess").Return(nil, api.NewUnauthorized("auth/invalid-key", "The api key is invalid."))
+
+	r := chi.NewRouter()
+	NewHandler(&Config{
+		Router:     r,
+		UrlService: mocks.NewMockUrlService(),
+		KeyService: mockKeyService,
+		ApiConfig: &config.Config{
+			Server: config.ServerConfig{Environment: "test"},
+			Admin:  config.AdminConfig{Token: "s3cret"},
+			RateLimit: config.RateLimitConfig{
+				Groups: map[string]config.LimitConfig{RATE_LIMIT_IP: {Requests: 2, Window: time.Minute}},
+			},
+		},
+	})
+
+	send := func(path, authorization string) int {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(fmt.Sprintf(`{"url":"%s"}`, exampleUrl)))
+		req.Header.Set(contentTypeHeader, contentTypeJSON)
+		req.Header.Set("Authorization", authorization)
+		req.RemoteAddr = "192.0.2.1:1234"
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// rejected keys and admin token guesses are counted against the ip, and stop being checked once it's limited
+	assert.Equal(t, http.StatusUnauthorized, send("/shorten", "Bearer usk_guess"))
+	assert.Equal(t, http.StatusUnauthorized, send("/import", "Bearer guess"))
+	assert.Equal(t, http.StatusTooManyRequests, send("/shorten", "Bearer usk_guess"))
+	assert.Equal(t, http.StatusTooManyRequests, send("/import", "Bearer guess"))
+	mockKeyService.AssertNumberOfCalls(t, "Authenticate", 1)
+}
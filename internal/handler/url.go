@@ -1,22 +1,37 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/host"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
 	"github.com/Jaytpa01/url-shortener-api/pkg/utils"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 )
 
 // RedirectToTargetUrl handles redirecting the user
-// to the target link from the generated link on our server
+// to the target link from the generated link on our server.
+// Short domains with a not found url redirect there when the token doesn't exist on them.
 func (h *handler) RedirectToTargetUrl() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		token := chi.URLParam(r, "token")
 
 		url, err := h.urlService.FindUrlByToken(r.Context(), token)
 		if err != nil {
+			domain := host.FromContext(r.Context())
+			if domain != nil && domain.NotFoundUrl != "" && api.EnsureApiError(err).Type == api.NotFound {
+				http.Redirect(w, r, domain.NotFoundUrl, http.StatusFound)
+				return
+			}
+
 			api.ReturnApiError(w, r, err)
 			return
 		}
@@ -27,7 +42,8 @@ func (h *handler) RedirectToTargetUrl() http.HandlerFunc {
 			return
 		}
 
-		http.Redirect(w, r, url.TargetUrl, http.StatusMovedPermanently)
+		// links can be retargeted and disabled, so the redirect mustn't be cached permanently
+		http.Redirect(w, r, url.TargetUrl, http.StatusFound)
 	}
 }
 
@@ -36,7 +52,7 @@ func (h *handler) GetUrlVisits() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		token := chi.URLParam(r, "token")
 
-		url, err := h.urlService.FindUrlByToken(r.Context(), token)
+		url, err := h.urlService.GetUrlAnalytics(r.Context(), token)
 		if err != nil {
 			api.ReturnApiError(w, r, err)
 			return
@@ -70,11 +86,7 @@ func (h *handler) ShortenUrl() http.HandlerFunc {
 		}
 
 		// convert the data model to an api response model
-		apiResponse := &api.UrlResponse{
-			Token:     createdUrl.Token,
-			TargetUrl: createdUrl.TargetUrl,
-			QRCode:    utils.GenerateQRCodeLink(createdUrl.TargetUrl),
-		}
+		apiResponse := h.urlResponse(createdUrl)
 
 		// return the successfully created url with HTTP Status Created
 		render.Status(r, http.StatusCreated)
@@ -100,27 +112,167 @@ func (h *handler) LengthenUrl() http.HandlerFunc {
 		}
 
 		// convert the data model to an api response model
-		apiResponse := &api.UrlResponse{
-			Token:     createdUrl.Token,
-			TargetUrl: createdUrl.TargetUrl,
-			QRCode:    utils.GenerateQRCodeLink(createdUrl.TargetUrl),
-		}
+		apiResponse := h.urlResponse(createdUrl)
 
 		render.Status(r, http.StatusCreated)
 		render.JSON(w, r, apiResponse)
 	}
 }
 
-// GetAllUrls is a handler only available in a development environment. It
-// gets all urls in the repo and returns them
-func (h *handler) GetAllUrls() http.HandlerFunc {
+// UpdateUrl handles changing the target of a generated link
+func (h *handler) UpdateUrl() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &api.UpdateUrlRequest{}
+
+		err := h.decoder.DecodeJSON(w, r, req)
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		url, err := h.urlService.UpdateUrl(r.Context(), chi.URLParam(r, "token"), req.Url)
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, h.urlResponse(url))
+	}
+}
+
+// DeleteUrl handles deleting a generated link
+func (h *handler) DeleteUrl() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h.urlService.DeleteUrl(r.Context(), chi.URLParam(r, "token")); err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetKeyspaceStats handles returning how full the keyspace of the current token length is.
+func (h *handler) GetKeyspaceStats() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, keyspaceStatsResponse(h.urlService.KeyspaceStats(r.Context())))
+	}
+}
+
+// keyspaceStatsResponse converts keyspace stats into their api response.
+func keyspaceStatsResponse(stats *entity.KeyspaceStats) *api.KeyspaceStatsResponse {
+	return &api.KeyspaceStatsResponse{
+		TokenLength:   stats.TokenLength,
+		Links:         stats.Links,
+		Capacity:      stats.Capacity,
+		Utilisation:   stats.Utilisation,
+		Attempts:      stats.Attempts,
+		Collisions:    stats.Collisions,
+		CollisionRate: stats.CollisionRate,
+	}
+}
+
+// ListUrls handles returning a page of urls. The urls can be filtered with the created_after, created_before,
+// domain and q query parameters, and ordered with sort. Pages are fetched with the cursor and limit query parameters.
+func (h *handler) ListUrls() http.HandlerFunc {
+	return h.listUrls(func(r *http.Request, filter *repository.ListFilter) {})
+}
+
+// listUrls handles returning a page of urls, after narrowing down the filter from the query parameters.
+func (h *handler) listUrls(narrow func(r *http.Request, filter *repository.ListFilter)) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		urls, err := h.urlService.GetAllUrls(r.Context())
+		query := r.URL.Query()
+
+		filter := repository.ListFilter{
+			Domain: query.Get("domain"),
+			Search: query.Get("q"),
+			Sort:   repository.SortOrder(query.Get("sort")),
+		}
+		narrow(r, &filter)
+
+		for param, value := range map[string]*time.Time{"created_after": &filter.CreatedAfter, "created_before": &filter.CreatedBefore} {
+			if query.Get(param) == "" {
+				continue
+			}
+
+			t, err := time.Parse(time.RFC3339, query.Get(param))
+			if err != nil {
+				api.ReturnApiError(w, r, api.NewBadRequest("url/invalid-filter", fmt.Sprintf("The provided %s (%s) isn't an RFC 3339 time.", param, query.Get(param))))
+				return
+			}
+			*value = t
+		}
+
+		limit := 0
+		if query.Get("limit") != "" {
+			var err error
+			limit, err = strconv.Atoi(query.Get("limit"))
+			if err != nil || limit <= 0 {
+				api.ReturnApiError(w, r, api.NewBadRequest("url/invalid-limit", fmt.Sprintf("The provided limit (%s) must be a positive number.", query.Get("limit"))))
+				return
+			}
+		}
+
+		urls, next, err := h.urlService.ListUrls(r.Context(), filter, query.Get("cursor"), limit)
 		if err != nil {
 			api.ReturnApiError(w, r, err)
 			return
 		}
 
-		render.JSON(w, r, urls)
+		res := &api.ListUrlsResponse{
+			Urls:       make([]api.UrlDetailsResponse, len(urls)),
+			NextCursor: next,
+		}
+		for i := range urls {
+			res.Urls[i] = *h.urlDetailsResponse(&urls[i])
+		}
+
+		render.JSON(w, r, res)
+	}
+}
+
+// urlDetailsResponse converts a url into its detailed api response.
+func (h *handler) urlDetailsResponse(url *entity.Url) *api.UrlDetailsResponse {
+	return &api.UrlDetailsResponse{
+		Token:     url.Token,
+		Domain:    url.Domain,
+		ShortUrl:  h.shortUrl(url),
+		TargetUrl: url.TargetUrl,
+		Visits:    url.Visits,
+		CreatedAt: url.CreatedAt,
+		Owner:     url.Owner,
+		Disabled:  url.Disabled,
+
+		WorkspaceID: url.Workspace(),
+	}
+}
+
+// urlResponse converts a url into its api response.
+func (h *handler) urlResponse(url *entity.Url) *api.UrlResponse {
+	return &api.UrlResponse{
+		Token:     url.Token,
+		TargetUrl: url.TargetUrl,
+		QRCode:    utils.GenerateQRCodeLink(url.TargetUrl),
+		Domain:    url.Domain,
+		ShortUrl:  h.shortUrl(url),
 	}
 }
+
+// shortUrl returns the url that redirects to url's target. Urls on the default domain are under server.base_url,
+// so have none if it isn't set, and urls on short domains use its scheme, or https if it isn't set.
+func (h *handler) shortUrl(url *entity.Url) string {
+	base := strings.TrimSuffix(h.apiConfig.Server.BaseUrl, "/")
+	if url.Domain == "" {
+		if base == "" {
+			return ""
+		}
+		return base + "/" + url.Token
+	}
+
+	scheme := "https"
+	if parsed, err := neturl.Parse(base); err == nil && parsed.Scheme != "" {
+		scheme = parsed.Scheme
+	}
+
+	return scheme + "://" + url.Domain + "/" + url.Token
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/Jaytpa01/url-shortener-api/config"
 	"github.com/Jaytpa01/url-shortener-api/internal/entity"
 	"github.com/Jaytpa01/url-shortener-api/internal/mocks"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
 	"github.com/Jaytpa01/url-shortener-api/pkg/utils"
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
@@ -62,7 +63,7 @@ func TestHandler_Url_RedirectToTargetUrl(t *testing.T) {
 	redirectLocation, _ := result.Location()
 
 	// Assertions
-	assert.Equal(t, http.StatusMovedPermanently, result.StatusCode)
+	assert.Equal(t, http.StatusFound, result.StatusCode)
 	assert.Equal(t, exampleUrl, redirectLocation.String())
 }
 
@@ -193,7 +194,7 @@ func TestHandler_Url_GetUrlVisits(t *testing.T) {
 	}
 
 	mockUrlService := mocks.NewMockUrlService()
-	mockUrlService.On("FindUrlByToken", mock.Anything, token).Return(mockResponse, nil)
+	mockUrlService.On("GetUrlAnalytics", mock.Anything, token).Return(mockResponse, nil)
 
 	r := chi.NewRouter()
 	NewHandler(&Config{
@@ -220,7 +221,7 @@ func TestHandler_Url_GetUrlVisits_UrlDoesntExist(t *testing.T) {
 	mockResponse := api.NewNotFound("url-not-found", mockMsg)
 
 	mockUrlService := mocks.NewMockUrlService()
-	mockUrlService.On("FindUrlByToken", mock.Anything, token).Return(nil, mockResponse)
+	mockUrlService.On("GetUrlAnalytics", mock.Anything, token).Return(nil, mockResponse)
 
 	r := chi.NewRouter()
 	NewHandler(&Config{
@@ -541,10 +542,15 @@ func TestHandler_Url_LengthenUrl(t *testing.T) {
 	}
 }
 
-func TestHandler_Url_GetAllUrls(t *testing.T) {
+func TestHandler_Url_ListUrls(t *testing.T) {
+	createdAt := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+
 	testCases := []struct {
 		name string
+		path string
 
+		filter      repository.ListFilter
+		limit       int
 		serviceResp []entity.Url
 		serviceErr  error
 
@@ -553,18 +559,35 @@ func TestHandler_Url_GetAllUrls(t *testing.T) {
 	}{
 		{
 			name:                   "Success",
-			serviceResp:            []entity.Url{},
+			path:                   "/urls?domain=example.com&sort=created_asc&created_after=2023-01-01T00:00:00Z&cursor=abc&limit=1",
+			filter:                 repository.ListFilter{Domain: "example.com", Sort: repository.SortCreatedAsc, CreatedAfter: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+			limit:                  1,
+			serviceResp:            []entity.Url{{Token: "123456", TargetUrl: exampleUrl, Visits: 2, CreatedAt: createdAt}},
 			expectedResponseStatus: http.StatusOK,
-			expectedResponseBody:   "[]",
+			expectedResponseBody:   `{"urls":[{"token":"123456","target_url":"https://example.com","visits":2,"created_at":"2023-04-01T12:00:00Z","workspace_id":"default"}],"next_cursor":"next"}`,
+		},
+		{
+			name:                   "Invalid Time",
+			path:                   "/urls?created_before=yesterday",
+			expectedResponseStatus: http.StatusBadRequest,
+			expectedResponseBody:   `{"type":"BAD_REQUEST","code":"url/invalid-filter","message":"The provided created_before (yesterday) isn't an RFC 3339 time."}`,
+		},
+		{
+			name:                   "Invalid Limit",
+			path:                   "/urls?limit=-1",
+			expectedResponseStatus: http.StatusBadRequest,
+			expectedResponseBody:   `{"type":"BAD_REQUEST","code":"url/invalid-limit","message":"The provided limit (-1) must be a positive number."}`,
 		},
 		{
 			name:                   "Known Service Error",
+			path:                   "/urls?cursor=abc",
 			serviceErr:             api.NewBadRequest("bad", "bad message"),
 			expectedResponseStatus: http.StatusBadRequest,
 			expectedResponseBody:   "{\"type\":\"BAD_REQUEST\",\"code\":\"bad\",\"message\":\"bad message\"}",
 		},
 		{
 			name:                   "Unknown Service Error",
+			path:                   "/urls?cursor=abc",
 			serviceErr:             errors.New("whoops"),
 			expectedResponseStatus: http.StatusInternalServerError,
 			expectedResponseBody:   "{\"type\":\"INTERNAL\",\"code\":\"unknown\",\"message\":\"An internal server error occured.\"}",
@@ -572,25 +595,158 @@ func TestHandler_Url_GetAllUrls(t *testing.T) {
 	}
 
 	for _, test := range testCases {
-		// setup
-		req := httptest.NewRequest(http.MethodGet, "/all", nil)
-		rec := httptest.NewRecorder()
-
-		mockUrlService := mocks.NewMockUrlService()
-		mockUrlService.On("GetAllUrls", mock.Anything).Return(test.serviceResp, test.serviceErr)
-
-		r := chi.NewRouter()
-		handlerConfig := &Config{Router: r, UrlService: mockUrlService, ApiConfig: &config.Config{Server: config.ServerConfig{Environment: "dev"}}}
-		NewHandler(handlerConfig)
-		r.ServeHTTP(rec, req)
-		result := rec.Result()
-
-		// Assertions
-		assert.Equal(t, test.expectedResponseStatus, result.StatusCode)
-		assert.Equal(t, test.expectedResponseBody, strings.Trim(rec.Body.String(), "\n"))
+		t.Run(test.name, func(t *testing.T) {
+			// setup
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			rec := httptest.NewRecorder()
+
+			mockUrlService := mocks.NewMockUrlService()
+			mockUrlService.On("ListUrls", mock.Anything, test.filter, "abc", test.limit).Return(test.serviceResp, "next", test.serviceErr)
+
+			r := chi.NewRouter()
+			handlerConfig := &Config{Router: r, UrlService: mockUrlService, ApiConfig: &config.Config{Server: config.ServerConfig{Environment: "dev"}}}
+			NewHandler(handlerConfig)
+			r.ServeHTTP(rec, req)
+			result := rec.Result()
+
+			// Assertions
+			assert.Equal(t, test.expectedResponseStatus, result.StatusCode)
+			assert.Equal(t, test.expectedResponseBody, strings.Trim(rec.Body.String(), "\n"))
+		})
+	}
+}
+
+func TestHandler_Url_ListUrls_AdminToken(t *testing.T) {
+	mockUrlService := mocks.NewMockUrlService()
+	mockKeyService := mocks.NewMockKeyService()
+	mockKeyService.On("Authenticate", mock.Anything, "s3cret").Return(nil, api.NewUnauthorized("auth/invalid-key", "The api key is invalid."))
+
+	r := chi.NewRouter()
+	NewHandler(&Config{
+		Router:     r,
+		UrlService: mockUrlService,
+		KeyService: mockKeyService,
+		ApiConfig: &config.Config{
+			Server: config.ServerConfig{Environment: "production"},
+			Admin:  config.AdminConfig{Token: "s3cret"},
+		},
+	})
+
+	// the admin token only works on the admin api and POST /import, so it's just an unknown key here
+	req := httptest.NewRequest(http.MethodGet, "/urls", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	mockUrlService.AssertNotCalled(t, "ListUrls", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockKeyService.AssertExpectations(t)
+}
+
+func TestHandler_Url_UpdateUrl(t *testing.T) {
+	testCases := []struct {
+		name        string
+		body        string
+		serviceResp *entity.Url
+		serviceErr  error
+
+		expectedResponseStatus int
+		expectedResponseBody   string
+	}{
+		{
+			name:                   "Success",
+			body:                   `{"url":"https://example.org"}`,
+			serviceResp:            &entity.Url{Token: "abcdef", TargetUrl: "https://example.org"},
+			expectedResponseStatus: http.StatusOK,
+			expectedResponseBody:   fmt.Sprintf(`{"token":"abcdef","target_url":"https://example.org","qr_code":"%s"}`, utils.GenerateQRCodeLink("https://example.org")),
+		},
+		{
+			name:                   "Forbidden",
+			body:                   `{"url":"https://example.org"}`,
+			serviceErr:             api.NewForbidden("workspace/forbidden", "You need the editor role to edit links in workspace (ws_1)."),
+			expectedResponseStatus: http.StatusForbidden,
+			expectedResponseBody:   `{"type":"FORBIDDEN","code":"workspace/forbidden","message":"You need the editor role to edit links in workspace (ws_1)."}`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/abcdef", strings.NewReader(test.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			mockUrlService := mocks.NewMockUrlService()
+			mockUrlService.On("UpdateUrl", mock.Anything, "abcdef", "https://example.org").Return(test.serviceResp, test.serviceErr)
+
+			r := chi.NewRouter()
+			NewHandler(&Config{Router: r, ApiConfig: apiConfig, UrlService: mockUrlService})
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.expectedResponseStatus, rec.Code)
+			assert.Equal(t, test.expectedResponseBody, strings.Trim(rec.Body.String(), "\n"))
+		})
+	}
+}
+
+func TestHandler_Url_DeleteUrl(t *testing.T) {
+	testCases := []struct {
+		name       string
+		serviceErr error
+
+		expectedResponseStatus int
+	}{
+		{"Success", nil, http.StatusNoContent},
+		{"Not Found", api.NewNotFound("url/not-found", "Couldn't find URL with token (abcdef)."), http.StatusNotFound},
+		{"Anonymous", api.NewUnauthorized("auth/required", "You need to authenticate to delete links in workspace (default)."), http.StatusUnauthorized},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/abcdef", nil)
+			rec := httptest.NewRecorder()
+
+			mockUrlService := mocks.NewMockUrlService()
+			mockUrlService.On("DeleteUrl", mock.Anything, "abcdef").Return(test.serviceErr)
+
+			r := chi.NewRouter()
+			NewHandler(&Config{Router: r, ApiConfig: apiConfig, UrlService: mockUrlService})
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.expectedResponseStatus, rec.Code)
+		})
 	}
 }
 
+func TestHandler_Url_GetKeyspaceStats(t *testing.T) {
+	// setup
+	req := httptest.NewRequest(http.MethodGet, "/metrics/keyspace", nil)
+	rec := httptest.NewRecorder()
+
+	mockUrlService := mocks.NewMockUrlService()
+	mockUrlService.On("KeyspaceStats", mock.Anything).Return(&entity.KeyspaceStats{
+		TokenLength:   6,
+		Links:         62,
+		Capacity:      62,
+		Utilisation:   1,
+		Attempts:      4,
+		Collisions:    1,
+		CollisionRate: 0.25,
+	})
+
+	r := chi.NewRouter()
+	NewHandler(&Config{
+		Router:     r,
+		ApiConfig:  apiConfig,
+		UrlService: mockUrlService,
+	})
+
+	r.ServeHTTP(rec, req)
+	result := rec.Result()
+
+	// Assertions
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+	assert.Equal(t, "{\"token_length\":6,\"links\":62,\"capacity\":62,\"utilisation\":1,\"attempts\":4,\"collisions\":1,\"collision_rate\":0.25}", strings.Trim(rec.Body.String(), "\n"))
+}
+
 func TestHandler_NewHandler(t *testing.T) {
 	testCases := []struct {
 		name          string
@@ -669,7 +825,7 @@ func TestHandler_RateLimit(t *testing.T) {
 				if rec.Result().StatusCode == http.StatusOK {
 					expectedResponseBody = "{\"status\":\"ok\"}"
 				} else if rec.Result().StatusCode == http.StatusTooManyRequests {
-					expectedResponseBody = "{\"type\":\"TOO_MANY_REQUESTS\",\"code\":\"api/too-many-requests\",\"message\":\"You are sending too many requests to the server.\",\"action\":\"C'mon buddy, please slow down. You are limited to 1 request/second.\"}"
+					expectedResponseBody = "{\"type\":\"TOO_MANY_REQUESTS\",\"code\":\"api/too-many-requests\",\"message\":\"You are sending too many requests to the server.\",\"action\":\"C'mon buddy, please slow down. You are limited to 10 requests every 1s.\"}"
 				}
 
 				assert.Equal(t, expectedResponseBody, strings.Trim(rec.Body.String(), "\n"))
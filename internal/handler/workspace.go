@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// CreateWorkspace handles creating a workspace, owned by the user making the request
+func (h *handler) CreateWorkspace() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &api.CreateWorkspaceRequest{}
+
+		err := h.decoder.DecodeJSON(w, r, req)
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		workspace, err := h.workspaceService.CreateWorkspace(r.Context(), req.Name)
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, &api.WorkspaceResponse{
+			ID:        workspace.ID,
+			Name:      workspace.Name,
+			CreatedAt: workspace.CreatedAt,
+		})
+	}
+}
+
+// ListWorkspaces handles listing the workspaces the user making the request is a member of
+func (h *handler) ListWorkspaces() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		memberships, err := h.workspaceService.ListWorkspaces(r.Context())
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, membershipsResponse(memberships))
+	}
+}
+
+// ListMembers handles listing the members of a workspace
+func (h *handler) ListMembers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		members, err := h.workspaceService.ListMembers(r.Context(), chi.URLParam(r, "workspace"))
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, membershipsResponse(members))
+	}
+}
+
+// AddMember handles giving a user a role in a workspace
+func (h *handler) AddMember() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &api.AddMemberRequest{}
+
+		err := h.decoder.DecodeJSON(w, r, req)
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		membership, err := h.workspaceService.AddMember(r.Context(), chi.URLParam(r, "workspace"), req.UserID, entity.Role(req.Role))
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, membershipResponse(membership))
+	}
+}
+
+// UpdateMember handles changing the role of a member of a workspace
+func (h *handler) UpdateMember() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &api.UpdateMemberRequest{}
+
+		err := h.decoder.DecodeJSON(w, r, req)
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		membership, err := h.workspaceService.UpdateMember(r.Context(), chi.URLParam(r, "workspace"), chi.URLParam(r, "user"), entity.Role(req.Role))
+		if err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, membershipResponse(membership))
+	}
+}
+
+// RemoveMember handles removing a member from a workspace
+func (h *handler) RemoveMember() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h.workspaceService.RemoveMember(r.Context(), chi.URLParam(r, "workspace"), chi.URLParam(r, "user")); err != nil {
+			api.ReturnApiError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// membershipResponse converts a membership to its api response model
+func membershipResponse(membership *entity.Membership) *api.MembershipResponse {
+	return &api.MembershipResponse{
+		WorkspaceID: membership.WorkspaceID,
+		UserID:      membership.UserID,
+		Role:        string(membership.Role),
+		CreatedAt:   membership.CreatedAt,
+	}
+}
+
+// membershipsResponse converts a list of memberships to its api response model
+func membershipsResponse(memberships []entity.Membership) *api.ListMembershipsResponse {
+	res := &api.ListMembershipsResponse{Memberships: make([]api.MembershipResponse, len(memberships))}
+	for i := range memberships {
+		res.Memberships[i] = *membershipResponse(&memberships[i])
+	}
+
+	return res
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/mocks"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandler_Workspaces(t *testing.T) {
+	createdAt := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	membership := &entity.Membership{WorkspaceID: "ws_1", UserID: "user_2", Role: entity.RoleEditor, CreatedAt: createdAt}
+	membershipJSON := `{"workspace_id":"ws_1","user_id":"user_2","role":"editor","created_at":"2023-04-01T12:00:00Z"}`
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+
+		serviceMethod string
+		serviceArgs   []interface{}
+		serviceResp   []interface{}
+
+		expectedResponseStatus int
+		expectedResponseBody   string
+	}{
+		{
+			name:                   "Create Workspace",
+			method:                 http.MethodPost,
+			path:                   "/workspaces",
+			body:                   `{"name":"Team"}`,
+			serviceMethod:          "CreateWorkspace",
+			serviceArgs:            []interface{}{"Team"},
+			serviceResp:            []interface{}{&entity.Workspace{ID: "ws_1", Name: "Team", CreatedAt: createdAt}, nil},
+			expectedResponseStatus: http.StatusCreated,
+			expectedResponseBody:   `{"id":"ws_1","name":"Team","created_at":"2023-04-01T12:00:00Z"}`,
+		},
+		{
+			name:                   "List Workspaces",
+			method:                 http.MethodGet,
+			path:                   "/workspaces",
+			serviceMethod:          "ListWorkspaces",
+			serviceResp:            []interface{}{[]entity.Membership{*membership}, nil},
+			expectedResponseStatus: http.StatusOK,
+			expectedResponseBody:   `{"memberships":[` + membershipJSON + `]}`,
+		},
+		{
+			name:                   "List Members",
+			method:                 http.MethodGet,
+			path:                   "/workspaces/ws_1/members",
+			serviceMethod:          "ListMembers",
+			serviceArgs:            []interface{}{"ws_1"},
+			serviceResp:            []interface{}{[]entity.Membership{}, nil},
+			expectedResponseStatus: http.StatusOK,
+			expectedResponseBody:   `{"memberships":[]}`,
+		},
+		{
+			name:                   "Add Member",
+			method:                 http.MethodPost,
+			path:                   "/workspaces/ws_1/members",
+			body:                   `{"user_id":"user_2","role":"editor"}`,
+			serviceMethod:          "AddMember",
+			serviceArgs:            []interface{}{"ws_1", "user_2", entity.RoleEditor},
+			serviceResp:            []interface{}{membership, nil},
+			expectedResponseStatus: http.StatusCreated,
+			expectedResponseBody:   membershipJSON,
+		},
+		{
+			name:                   "Update Member",
+			method:                 http.MethodPatch,
+			path:                   "/workspaces/ws_1/members/user_2",
+			body:                   `{"role":"editor"}`,
+			serviceMethod:          "UpdateMember",
+			serviceArgs:            []interface{}{"ws_1", "user_2", entity.RoleEditor},
+			serviceResp:            []interface{}{membership, nil},
+			expectedResponseStatus: http.StatusOK,
+			expectedResponseBody:   membershipJSON,
+		},
+		{
+			name:          "Update Last Owner",
+			method:        http.MethodPatch,
+			path:          "/workspaces/ws_1/members/user_1",
+			body:          `{"role":"viewer"}`,
+			serviceMethod: "UpdateMember",
+			serviceArgs:   []interface{}{"ws_1", "user_1", entity.RoleViewer},
+			serviceResp: []interface{}{nil, api.NewConflict("workspace/last-owner", "Workspace (ws_1) must keep at least one owner.",
+				api.WithAction("Make another member an owner first."))},
+			expectedResponseStatus: http.StatusConflict,
+			expectedResponseBody:   `{"type":"CONFLICT","code":"workspace/last-owner","message":"Workspace (ws_1) must keep at least one owner.","action":"Make another member an owner first."}`,
+		},
+		{
+			name:                   "Remove Member",
+			method:                 http.MethodDelete,
+			path:                   "/workspaces/ws_1/members/user_2",
+			serviceMethod:          "RemoveMember",
+			serviceArgs:            []interface{}{"ws_1", "user_2"},
+			serviceResp:            []interface{}{nil},
+			expectedResponseStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			// setup
+			req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			req.Header.Set(contentTypeHeader, contentTypeJSON)
+			rec := httptest.NewRecorder()
+
+			mockWorkspaceService := mocks.NewMockWorkspaceService()
+			mockWorkspaceService.On(test.serviceMethod, append([]interface{}{mock.Anything}, test.serviceArgs...)...).Return(test.serviceResp...)
+
+			r := chi.NewRouter()
+			NewHandler(&Config{
+				Router:           r,
+				ApiConfig:        apiConfig,
+				UrlService:       mocks.NewMockUrlService(),
+				WorkspaceService: mockWorkspaceService,
+			})
+			r.ServeHTTP(rec, req)
+
+			// Assertions
+			assert.Equal(t, test.expectedResponseStatus, rec.Code)
+			assert.Equal(t, test.expectedResponseBody, strings.Trim(rec.Body.String(), "\n"))
+			mockWorkspaceService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_Workspaces_Unsupported(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/workspaces/ws_1/members", nil)
+	rec := httptest.NewRecorder()
+
+	r := chi.NewRouter()
+	NewHandler(&Config{Router: r, ApiConfig: apiConfig, UrlService: mocks.NewMockUrlService()})
+	r.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,30 @@
+// Package host carries the short domain a request was made to in its context, so links are found and created on it.
+package host
+
+import (
+	"context"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+)
+
+type domainKey struct{}
+
+// NewContext returns a copy of ctx carrying the short domain.
+func NewContext(ctx context.Context, domain *entity.Domain) context.Context {
+	return context.WithValue(ctx, domainKey{}, domain)
+}
+
+// FromContext returns the short domain in ctx, or nil if the request was made to the default domain.
+func FromContext(ctx context.Context) *entity.Domain {
+	domain, _ := ctx.Value(domainKey{}).(*entity.Domain)
+	return domain
+}
+
+// DomainName returns the name of the short domain in ctx, or "" for the default domain.
+func DomainName(ctx context.Context) string {
+	if domain := FromContext(ctx); domain != nil {
+		return domain.Name
+	}
+
+	return ""
+}
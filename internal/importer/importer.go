@@ -0,0 +1,416 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/pkg/validation"
+)
+
+// Format is the format of an import.
+type Format string
+
+// Exports from YOURLS, Bitly and Kutt are CSV or JSON with their own field names,
+// so they're imported as CSV or JSON, and recognised by their fields.
+const (
+	FormatAuto      Format = "auto" // work out the format from the content
+	FormatCSV       Format = "csv"
+	FormatJSON      Format = "json"
+	FormatBookmarks Format = "bookmarks" // Netscape bookmark HTML, as exported by browsers
+)
+
+// Conflict is what happens to an imported url whose token already exists.
+type Conflict string
+
+const (
+	ConflictSkip      Conflict = "skip"      // keep the existing url
+	ConflictOverwrite Conflict = "overwrite" // replace the existing url with the imported one
+	ConflictRename    Conflict = "rename"    // import the url with a newly generated token
+)
+
+// Status is what happened to a single imported row.
+type Status string
+
+const (
+	StatusCreated     Status = "created"
+	StatusOverwritten Status = "overwritten"
+	StatusRenamed     Status = "renamed"
+	StatusSkipped     Status = "skipped"
+	StatusFailed      Status = "failed"
+)
+
+// MAX_TOKEN_LENGTH is the longest token that can be imported
+const MAX_TOKEN_LENGTH = 128
+
+var (
+	ErrUnknownFormat   = errors.New("unknown import format")
+	ErrUnknownConflict = errors.New("unknown conflict policy")
+)
+
+// the field names used for each part of a url by the formats we import, in order of preference
+var (
+	tokenFields     = []string{"token", "keyword", "address", "slug", "shorturl", "short_url", "bitlink", "link"}
+	targetFields    = []string{"target_url", "url", "long_url", "target", "href"}
+	visitsFields    = []string{"visits", "clicks", "visit_count", "click_count", "total_clicks"}
+	createdAtFields = []string{"created_at", "created", "timestamp", "date", "add_date"}
+)
+
+// the time formats created dates are parsed with, as well as unix timestamps
+var timeFormats = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05-0700", // Bitly
+	"2006-01-02 15:04:05",      // YOURLS
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+var validToken = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// RESERVED_TOKENS are the static routes of the api, which an imported token would shadow or be shadowed by
+var RESERVED_TOKENS = []string{"urls", "import", "audit", "challenge", "workspaces", "shorten", "lengthen", "metrics"}
+
+// Record is a single url read from an import.
+// If the row couldn't be parsed, Err is set and the other fields may be empty.
+type Record struct {
+	Row       int // 1 based position of the url in the import, not counting any header
+	Token     string
+	TargetUrl string
+	Visits    int
+	CreatedAt time.Time
+	Err       error
+}
+
+// RowResult is the outcome of importing a single row.
+type RowResult struct {
+	Row           int
+	Token         string // the token the url was imported with
+	OriginalToken string // the token in the import, if the url was renamed
+	Status        Status
+	Error         string
+}
+
+// Report is the outcome of an import.
+type Report struct {
+	Rows        []RowResult
+	Created     int
+	Overwritten int
+	Renamed     int
+	Skipped     int
+	Failed      int
+}
+
+// Add adds the outcome of a row to the report.
+func (r *Report) Add(result RowResult) {
+	r.Rows = append(r.Rows, result)
+
+	switch result.Status {
+	case StatusCreated:
+		r.Created++
+	case StatusOverwritten:
+		r.Overwritten++
+	case StatusRenamed:
+		r.Renamed++
+	case StatusSkipped:
+		r.Skipped++
+	case StatusFailed:
+		r.Failed++
+	}
+}
+
+// ParseFormat validates a format, defaulting to FormatAuto.
+func ParseFormat(format string) (Format, error) {
+	switch f := Format(strings.ToLower(format)); f {
+	case "":
+		return FormatAuto, nil
+	case FormatAuto, FormatCSV, FormatJSON, FormatBookmarks:
+		return f, nil
+	default:
+		return "", fmt.Errorf("%w (%s), expected one of %s, %s, %s or %s", ErrUnknownFormat, format, FormatAuto, FormatCSV, FormatJSON, FormatBookmarks)
+	}
+}
+
+// ParseConflict validates a conflict policy, defaulting to ConflictSkip.
+func ParseConflict(conflict string) (Conflict, error) {
+	switch c := Conflict(strings.ToLower(conflict)); c {
+	case "":
+		return ConflictSkip, nil
+	case ConflictSkip, ConflictOverwrite, ConflictRename:
+		return c, nil
+	default:
+		return "", fmt.Errorf("%w (%s), expected one of %s, %s or %s", ErrUnknownConflict, conflict, ConflictSkip, ConflictOverwrite, ConflictRename)
+	}
+}
+
+// Parse reads every url from r. Rows that can't be parsed are returned with Err set,
+// so one bad row doesn't stop the rest being imported. An error is only returned if the import as a whole can't be read.
+func Parse(r io.Reader, format Format) ([]Record, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// exports saved on windows often start with a byte order mark
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	if format == FormatAuto || format == "" {
+		format = detectFormat(data)
+	}
+
+	var rows []map[string]string
+	switch format {
+	case FormatCSV:
+		rows, err = parseCSV(data)
+	case FormatJSON:
+		rows, err = parseJSON(data)
+	case FormatBookmarks:
+		rows = parseBookmarks(data)
+	default:
+		err = fmt.Errorf("%w (%s)", ErrUnknownFormat, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]Record, len(rows))
+	for i, row := range rows {
+		records[i] = newRecord(i+1, row)
+	}
+
+	return records, nil
+}
+
+// detectFormat guesses the format of an import from its first non whitespace character.
+func detectFormat(data []byte) Format {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("[")), bytes.HasPrefix(trimmed, []byte("{")):
+		return FormatJSON
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return FormatBookmarks
+	default:
+		return FormatCSV
+	}
+}
+
+// parseCSV reads a CSV with a header row into rows keyed by the lowercased header.
+func parseCSV(data []byte) ([]map[string]string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read csv header: %w", err)
+	}
+	for i := range header {
+		header[i] = normaliseField(header[i])
+	}
+
+	rows := []map[string]string{}
+	for {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read csv: %w", err)
+		}
+
+		row := map[string]string{}
+		for i, field := range fields {
+			if i < len(header) {
+				row[header[i]] = strings.TrimSpace(field)
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+// parseJSON reads a JSON array of urls. The array can also be nested under a "links", "data", "urls" or "items" key,
+// which can be an object of urls rather than an array, as YOURLS exports them.
+func parseJSON(data []byte) ([]map[string]string, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("couldn't read json: %w", err)
+	}
+
+	if object, ok := doc.(map[string]interface{}); ok {
+		doc = nil
+		for _, key := range []string{"links", "data", "urls", "items"} {
+			if nested, ok := object[key]; ok {
+				doc = nested
+				break
+			}
+		}
+	}
+
+	var items []interface{}
+	switch v := doc.(type) {
+	case []interface{}:
+		items = v
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			items = append(items, v[key])
+		}
+	default:
+		return nil, errors.New("couldn't find a list of urls in json")
+	}
+
+	rows := make([]map[string]string, len(items))
+	for i, item := range items {
+		row := map[string]string{}
+		object, _ := item.(map[string]interface{})
+		for key, value := range object {
+			switch v := value.(type) {
+			case string:
+				row[normaliseField(key)] = strings.TrimSpace(v)
+			case float64:
+				row[normaliseField(key)] = strconv.FormatFloat(v, 'f', -1, 64)
+			}
+		}
+		rows[i] = row
+	}
+
+	return rows, nil
+}
+
+var (
+	bookmarkLink      = regexp.MustCompile(`(?is)<a\s([^>]*)>`)
+	bookmarkAttribute = regexp.MustCompile(`(?is)([a-z_-]+)\s*=\s*"([^"]*)"`)
+)
+
+// parseBookmarks reads every link from Netscape bookmark HTML. Bookmarks have no token, so one is generated on import.
+func parseBookmarks(data []byte) []map[string]string {
+	rows := []map[string]string{}
+	for _, link := range bookmarkLink.FindAllSubmatch(data, -1) {
+		row := map[string]string{}
+		for _, attribute := range bookmarkAttribute.FindAllSubmatch(link[1], -1) {
+			row[normaliseField(string(attribute[1]))] = html.UnescapeString(string(attribute[2]))
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// newRecord builds a record from a row's fields.
+func newRecord(row int, fields map[string]string) Record {
+	record := Record{
+		Row:       row,
+		Token:     parseToken(firstField(fields, tokenFields)),
+		TargetUrl: firstField(fields, targetFields),
+	}
+
+	if record.TargetUrl == "" {
+		record.Err = errors.New("missing target url")
+		return record
+	}
+
+	if !validation.IsValidUrl(record.TargetUrl) {
+		record.Err = fmt.Errorf("invalid target url (%s)", record.TargetUrl)
+		return record
+	}
+
+	if record.Token != "" && (len(record.Token) > MAX_TOKEN_LENGTH || !validToken.MatchString(record.Token)) {
+		record.Err = fmt.Errorf("invalid token (%s), tokens can only contain letters, numbers, - and _", record.Token)
+		return record
+	}
+
+	if isReserved(record.Token) {
+		record.Err = fmt.Errorf("reserved token (%s), it's used by the api", record.Token)
+		return record
+	}
+
+	if visits := firstField(fields, visitsFields); visits != "" {
+		v, err := strconv.Atoi(visits)
+		if err != nil || v < 0 {
+			record.Err = fmt.Errorf("invalid visits (%s)", visits)
+			return record
+		}
+		record.Visits = v
+	}
+
+	if createdAt := firstField(fields, createdAtFields); createdAt != "" {
+		t, err := parseTime(createdAt)
+		if err != nil {
+			record.Err = err
+			return record
+		}
+		record.CreatedAt = t
+	}
+
+	return record
+}
+
+// isReserved reports whether the token is one of RESERVED_TOKENS. Routes are matched case sensitively.
+func isReserved(token string) bool {
+	for _, reserved := range RESERVED_TOKENS {
+		if token == reserved {
+			return true
+		}
+	}
+	return false
+}
+
+// parseToken returns the token from a token field. Some exports only have the full short url, ie. "https://bit.ly/abc123",
+// or a host and path, ie. "bit.ly/abc123", in which case the token is the last part of the path.
+func parseToken(token string) string {
+	if !strings.Contains(token, "/") {
+		return token
+	}
+
+	if u, err := url.Parse(token); err == nil && u.Host != "" {
+		token = u.Path
+	}
+
+	token = strings.TrimRight(token, "/")
+	return token[strings.LastIndex(token, "/")+1:]
+}
+
+// parseTime parses a created date in any of timeFormats, or as a unix timestamp.
+func parseTime(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+
+	for _, format := range timeFormats {
+		if t, err := time.Parse(format, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid created date (%s)", value)
+}
+
+// firstField returns the value of the first of names that is set in fields.
+func firstField(fields map[string]string, names []string) string {
+	for _, name := range names {
+		if value := fields[name]; value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// normaliseField lowercases a field name and replaces spaces with underscores, so "Long URL" matches "long_url".
+func normaliseField(field string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(field)), " ", "_")
+}
@@ -0,0 +1,124 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Parse(t *testing.T) {
+	created := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		format   Format
+		input    string
+		expected []Record
+	}{
+		{
+			"CSV",
+			FormatCSV,
+			"token,target_url,visits,created_at\nabc123,https://example.com,4,2021-03-04T05:06:07Z\n",
+			[]Record{{Row: 1, Token: "abc123", TargetUrl: "https://example.com", Visits: 4, CreatedAt: created}},
+		},
+		{
+			"YOURLS CSV",
+			FormatAuto,
+			"\xef\xbb\xbfkeyword,url,title,timestamp,ip,clicks\nabc123,https://example.com,Example,2021-03-04 05:06:07,127.0.0.1,4\n",
+			[]Record{{Row: 1, Token: "abc123", TargetUrl: "https://example.com", Visits: 4, CreatedAt: created}},
+		},
+		{
+			"Bitly CSV",
+			FormatAuto,
+			"Title,Bitlink,Long URL,Created,Clicks\nExample,https://bit.ly/abc123,https://example.com,2021-03-04T05:06:07+0000,4\n",
+			[]Record{{Row: 1, Token: "abc123", TargetUrl: "https://example.com", Visits: 4, CreatedAt: created}},
+		},
+		{
+			"Bitly JSON",
+			FormatAuto,
+			`{"links": [{"id": "bit.ly/abc123", "link": "https://bit.ly/abc123", "long_url": "https://example.com", "created_at": "2021-03-04T05:06:07+0000"}]}`,
+			[]Record{{Row: 1, Token: "abc123", TargetUrl: "https://example.com", CreatedAt: created}},
+		},
+		{
+			"Kutt JSON",
+			FormatJSON,
+			`{"data": [{"id": "8d3b6e4c-0000-0000-0000-000000000000", "address": "abc123", "target": "https://example.com", "visit_count": 4, "created_at": "2021-03-04T05:06:07.000Z"}]}`,
+			[]Record{{Row: 1, Token: "abc123", TargetUrl: "https://example.com", Visits: 4, CreatedAt: created}},
+		},
+		{
+			"YOURLS JSON",
+			FormatJSON,
+			`{"links": {"link_1": {"shorturl": "https://sho.rt/abc123", "url": "https://example.com", "timestamp": "2021-03-04 05:06:07", "clicks": "4"}}}`,
+			[]Record{{Row: 1, Token: "abc123", TargetUrl: "https://example.com", Visits: 4, CreatedAt: created}},
+		},
+		{
+			"Bookmarks",
+			FormatAuto,
+			`<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<DL><p>
+	<DT><A HREF="https://example.com?a=1&amp;b=2" ADD_DATE="1614834367">Example</A>
+</DL><p>`,
+			[]Record{{Row: 1, TargetUrl: "https://example.com?a=1&b=2", CreatedAt: created}},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			records, err := Parse(strings.NewReader(test.input), test.format)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, records)
+		})
+	}
+}
+
+func Test_Parse_InvalidRows(t *testing.T) {
+	input := "token,url,visits,created_at\n" +
+		"abc123,https://example.com,1,\n" +
+		"abc124,,1,\n" +
+		"abc125,not a url,1,\n" +
+		"abc 126,https://example.com,1,\n" +
+		"abc127,https://example.com,lots,\n" +
+		"abc128,https://example.com,1,yesterday\n" +
+		"workspaces,https://example.com,1,\n" +
+		"https://sho.rt/import,https://example.com,1,\n"
+
+	records, err := Parse(strings.NewReader(input), FormatCSV)
+	require.NoError(t, err)
+	require.Len(t, records, 8)
+
+	assert.NoError(t, records[0].Err)
+	for i, record := range records[1:] {
+		assert.Errorf(t, record.Err, "row %d should be invalid", i+2)
+		assert.Equal(t, i+2, record.Row)
+	}
+}
+
+func Test_Parse_Unreadable(t *testing.T) {
+	_, err := Parse(strings.NewReader(`{"links": [`), FormatAuto)
+	assert.Error(t, err)
+
+	_, err = Parse(strings.NewReader(`{"count": 1}`), FormatJSON)
+	assert.Error(t, err)
+
+	_, err = Parse(strings.NewReader(""), FormatCSV)
+	assert.Error(t, err)
+}
+
+func Test_ParseConflict(t *testing.T) {
+	conflict, err := ParseConflict("")
+	assert.NoError(t, err)
+	assert.Equal(t, ConflictSkip, conflict)
+
+	conflict, err = ParseConflict("Rename")
+	assert.NoError(t, err)
+	assert.Equal(t, ConflictRename, conflict)
+
+	_, err = ParseConflict("merge")
+	assert.ErrorIs(t, err, ErrUnknownConflict)
+
+	_, err = ParseFormat("xml")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
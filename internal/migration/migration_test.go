@@ -0,0 +1,95 @@
+package migration
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMigrator(t *testing.T) *Migrator {
+	m, err := New("sqlite3://" + filepath.Join(t.TempDir(), "url.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { m.Close() })
+
+	return m
+}
+
+func Test_Migrator_UpAndDown(t *testing.T) {
+	m := newTestMigrator(t)
+
+	status, err := m.Status()
+	require.NoError(t, err)
+	assert.Equal(t, uint(0), status.Version)
+	assert.Len(t, status.Pending(), len(status.Migrations))
+
+	require.NoError(t, m.Up())
+	version, dirty, err := m.Version()
+	assert.NoError(t, err)
+	assert.False(t, dirty)
+	assert.Equal(t, status.Migrations[len(status.Migrations)-1].Version, version)
+
+	// running up again is a no-op
+	assert.NoError(t, m.Up())
+
+	require.NoError(t, m.Down(1))
+	status, err = m.Status()
+	require.NoError(t, err)
+	assert.Equal(t, version-1, status.Version)
+	assert.Len(t, status.Pending(), 1)
+
+	// rolling back everything should leave no tables behind, so we can migrate up again
+	require.NoError(t, m.Down(int(status.Version)))
+	assert.NoError(t, m.Up())
+}
+
+func Test_Migrator_DownTooMany(t *testing.T) {
+	m := newTestMigrator(t)
+	require.NoError(t, m.Up())
+
+	status, err := m.Status()
+	require.NoError(t, err)
+
+	assert.Error(t, m.Down(len(status.Migrations)+1))
+	assert.Error(t, m.Down(0))
+
+	// nothing should have been rolled back
+	version, _, err := m.Version()
+	assert.NoError(t, err)
+	assert.Equal(t, status.Version, version)
+}
+
+func Test_Migrator_Force(t *testing.T) {
+	m := newTestMigrator(t)
+
+	require.NoError(t, m.Force(1))
+	status, err := m.Status()
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), status.Version)
+	assert.True(t, status.Migrations[0].Applied)
+
+	require.NoError(t, m.Force(-1))
+	version, _, err := m.Version()
+	assert.NoError(t, err)
+	assert.Equal(t, uint(0), version)
+
+	assert.Error(t, m.Force(-2))
+}
+
+func Test_Status_Print(t *testing.T) {
+	status := &Status{
+		Version: 1,
+		Migrations: []Migration{
+			{Version: 1, Name: "url", Applied: true},
+			{Version: 2, Name: "sequence"},
+		},
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, status.Print(&out))
+	assert.Contains(t, out.String(), "Current version: 1")
+	assert.Contains(t, out.String(), "Pending migrations: 1")
+	assert.Contains(t, out.String(), "2        sequence  pending")
+}
@@ -0,0 +1,123 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Jaytpa01/url-shortener-api/config"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
+)
+
+// The startup modes that can be set in config.DatabaseConfig
+const (
+	ModeCheck = "check" // refuse to start while migrations are pending
+	ModeAuto  = "auto"  // apply pending migrations on startup
+	ModeOff   = "off"   // don't look at the schema
+)
+
+var (
+	ErrPendingMigrations = errors.New("database has pending migrations")
+	ErrSchemaTooNew      = errors.New("database schema is newer than this binary")
+	ErrDirtySchema       = errors.New("database schema is dirty")
+)
+
+// DatabaseURL returns the DSN the migrator expects for a repository driver and DSN.
+// It returns "" for drivers without a schema, or if dsn is empty.
+func DatabaseURL(driver, dsn string) string {
+	if dsn == "" {
+		return ""
+	}
+
+	switch driver {
+	case "", repository.DriverSQLite:
+		return "sqlite3://" + dsn
+	case repository.DriverPostgres:
+		return dsn
+	default:
+		return ""
+	}
+}
+
+// EnsureSchema compares the schema of the configured database with the embedded migrations,
+// and depending on cfg.Migrate, either applies the pending migrations or returns ErrPendingMigrations.
+// A database that is newer than the binary, or that is dirty, is always an error.
+//
+// Only one process checks and migrates a database at a time. SQLite is locked with a file next to the database,
+// and PostgreSQL with the advisory lock the migrator takes.
+func EnsureSchema(cfg config.DatabaseConfig, logger logger.Logger) error {
+	cfg = repository.WithDefaults(cfg)
+	mode := cfg.Migrate
+	if mode == "" {
+		mode = ModeCheck
+	}
+
+	switch mode {
+	case ModeOff:
+		return nil
+	case ModeCheck, ModeAuto:
+	default:
+		return fmt.Errorf("unknown migrate mode (%s), expected one of %s, %s or %s", mode, ModeCheck, ModeAuto, ModeOff)
+	}
+
+	url := DatabaseURL(cfg.Driver, cfg.DSN)
+	if url == "" {
+		return nil
+	}
+
+	if cfg.Driver == "" || cfg.Driver == repository.DriverSQLite {
+		unlock, err := lockFile(sqliteLockPath(cfg.DSN))
+		if err != nil {
+			return fmt.Errorf("couldn't lock database for migration: %w", err)
+		}
+		defer unlock()
+	}
+
+	m, err := New(url)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	status, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	if status.Dirty {
+		return fmt.Errorf("%w at version %d, fix the database and run 'migrate force'", ErrDirtySchema, status.Version)
+	}
+
+	if status.Version > status.Latest() {
+		return fmt.Errorf("%w, database is at version %d but the latest migration is %d", ErrSchemaTooNew, status.Version, status.Latest())
+	}
+
+	pending := status.Pending()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	if mode == ModeCheck {
+		return fmt.Errorf("%w, database is at version %d and %d migrations are pending. Run 'migrate up', or set database.migrate to %s",
+			ErrPendingMigrations, status.Version, len(pending), ModeAuto)
+	}
+
+	logger.Infof("migrating database from version %d to %d", status.Version, status.Latest())
+	return m.Up()
+}
+
+// sqliteLockPath returns the path of the lock file for the sqlite database at dsn.
+func sqliteLockPath(dsn string) string {
+	path, _, _ := strings.Cut(strings.TrimPrefix(dsn, "file:"), "?")
+	return path + ".lock"
+}
+
+// Latest returns the version of the newest migration, or 0 if there are none.
+func (s *Status) Latest() uint {
+	if len(s.Migrations) == 0 {
+		return 0
+	}
+
+	return s.Migrations[len(s.Migrations)-1].Version
+}
@@ -0,0 +1,80 @@
+package migration
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/Jaytpa01/url-shortener-api/config"
+	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_EnsureSchema(t *testing.T) {
+	testCases := []struct {
+		name        string
+		mode        string
+		setup       func(m *Migrator) error
+		expectedErr error
+	}{
+		{"Check Up To Date", ModeCheck, func(m *Migrator) error { return m.Up() }, nil},
+		{"Check Pending", ModeCheck, func(m *Migrator) error { return nil }, ErrPendingMigrations},
+		{"Default Mode Is Check", "", func(m *Migrator) error { return nil }, ErrPendingMigrations},
+		{"Auto Pending", ModeAuto, func(m *Migrator) error { return nil }, nil},
+		{"Auto Newer Than Binary", ModeAuto, func(m *Migrator) error { return m.Force(99) }, ErrSchemaTooNew},
+		{"Check Newer Than Binary", ModeCheck, func(m *Migrator) error { return m.Force(99) }, ErrSchemaTooNew},
+		{"Off", ModeOff, func(m *Migrator) error { return m.Force(99) }, nil},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			dsn := filepath.Join(t.TempDir(), "url.db")
+
+			m, err := New(DatabaseURL("sqlite", dsn))
+			require.NoError(t, err)
+			require.NoError(t, test.setup(m))
+			m.Close()
+
+			err = EnsureSchema(config.DatabaseConfig{Driver: "sqlite", DSN: dsn, Migrate: test.mode}, logger.NewApiLogger("development"))
+			assert.ErrorIs(t, err, test.expectedErr)
+		})
+	}
+}
+
+func Test_EnsureSchema_AutoMigrates(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "url.db")
+	cfg := config.DatabaseConfig{Driver: "sqlite", DSN: dsn, Migrate: ModeAuto}
+
+	// several instances starting at once should all succeed, with only one of them migrating
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = EnsureSchema(cfg, logger.NewApiLogger("development"))
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+
+	cfg.Migrate = ModeCheck
+	assert.NoError(t, EnsureSchema(cfg, logger.NewApiLogger("development")))
+}
+
+func Test_EnsureSchema_UnknownMode(t *testing.T) {
+	err := EnsureSchema(config.DatabaseConfig{Driver: "sqlite", DSN: "url.db", Migrate: "sometimes"}, logger.NewApiLogger("development"))
+	assert.EqualError(t, err, "unknown migrate mode (sometimes), expected one of check, auto or off")
+}
+
+func Test_EnsureSchema_NoSchema(t *testing.T) {
+	// memory and bolt have no schema, so there is nothing to check
+	for _, driver := range []string{"memory", "bolt"} {
+		err := EnsureSchema(config.DatabaseConfig{Driver: driver, DSN: t.TempDir()}, logger.NewApiLogger("development"))
+		assert.NoError(t, err)
+	}
+}
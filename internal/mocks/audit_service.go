@@ -0,0 +1,44 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockAuditService is a mock implementation of our service.AuditService
+type mockAuditService struct {
+	mock.Mock
+}
+
+// NewMockAuditService returns a mock implementation of our AuditService for testing purposes.
+// It is built using testify.Mock
+func NewMockAuditService() *mockAuditService {
+	return new(mockAuditService)
+}
+
+// ListAuditEntries is a mock implementation of AuditService.ListAuditEntries
+func (m *mockAuditService) ListAuditEntries(ctx context.Context, filter repository.AuditFilter, cursor string, limit int) ([]entity.AuditEntry, string, error) {
+	ret := m.Called(ctx, filter, cursor, limit)
+
+	var r0 []entity.AuditEntry
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]entity.AuditEntry)
+	}
+
+	return r0, ret.String(1), ret.Error(2)
+}
+
+// RecordConfig is a mock implementation of AuditService.RecordConfig
+func (m *mockAuditService) RecordConfig(ctx context.Context, settings map[string]string) (*entity.AuditEntry, error) {
+	ret := m.Called(ctx, settings)
+
+	var r0 *entity.AuditEntry
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*entity.AuditEntry)
+	}
+
+	return r0, ret.Error(1)
+}
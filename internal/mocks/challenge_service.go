@@ -0,0 +1,38 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockChallengeService is a mock implementation of our service.ChallengeService
+type mockChallengeService struct {
+	mock.Mock
+}
+
+// NewMockChallengeService returns a mock implementation of our ChallengeService for testing purposes.
+// It is built using testify.Mock
+func NewMockChallengeService() *mockChallengeService {
+	return new(mockChallengeService)
+}
+
+// IssueChallenge is a mock implementation of ChallengeService.IssueChallenge
+func (m *mockChallengeService) IssueChallenge(ctx context.Context) (*entity.Challenge, error) {
+	ret := m.Called(ctx)
+
+	var r0 *entity.Challenge
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*entity.Challenge)
+	}
+
+	return r0, ret.Error(1)
+}
+
+// VerifySolution is a mock implementation of ChallengeService.VerifySolution
+func (m *mockChallengeService) VerifySolution(ctx context.Context, challenge, nonce string) error {
+	ret := m.Called(ctx, challenge, nonce)
+
+	return ret.Error(0)
+}
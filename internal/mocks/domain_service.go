@@ -0,0 +1,71 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockDomainService is a mock implementation of our service.DomainService
+type mockDomainService struct {
+	mock.Mock
+}
+
+// NewMockDomainService returns a mock implementation of our DomainService for testing purposes.
+// It is built using testify.Mock
+func NewMockDomainService() *mockDomainService {
+	return new(mockDomainService)
+}
+
+// domain returns the domain a mocked call returned, or nil
+func domain(ret mock.Arguments) *entity.Domain {
+	var r0 *entity.Domain
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*entity.Domain)
+	}
+
+	return r0
+}
+
+// ListDomains is a mock implementation of DomainService.ListDomains
+func (m *mockDomainService) ListDomains(ctx context.Context, workspaceID string) ([]entity.Domain, error) {
+	ret := m.Called(ctx, workspaceID)
+
+	var r0 []entity.Domain
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]entity.Domain)
+	}
+
+	return r0, ret.Error(1)
+}
+
+// AddDomain is a mock implementation of DomainService.AddDomain
+func (m *mockDomainService) AddDomain(ctx context.Context, workspaceID string, d *entity.Domain) (*entity.Domain, error) {
+	ret := m.Called(ctx, workspaceID, d)
+	return domain(ret), ret.Error(1)
+}
+
+// VerifyDomain is a mock implementation of DomainService.VerifyDomain
+func (m *mockDomainService) VerifyDomain(ctx context.Context, workspaceID, name string) (*entity.Domain, error) {
+	ret := m.Called(ctx, workspaceID, name)
+	return domain(ret), ret.Error(1)
+}
+
+// UpdateDomain is a mock implementation of DomainService.UpdateDomain
+func (m *mockDomainService) UpdateDomain(ctx context.Context, workspaceID string, settings *entity.Domain) (*entity.Domain, error) {
+	ret := m.Called(ctx, workspaceID, settings)
+	return domain(ret), ret.Error(1)
+}
+
+// RemoveDomain is a mock implementation of DomainService.RemoveDomain
+func (m *mockDomainService) RemoveDomain(ctx context.Context, workspaceID, name string) error {
+	ret := m.Called(ctx, workspaceID, name)
+	return ret.Error(0)
+}
+
+// ResolveDomain is a mock implementation of DomainService.ResolveDomain
+func (m *mockDomainService) ResolveDomain(ctx context.Context, host string) (*entity.Domain, error) {
+	ret := m.Called(ctx, host)
+	return domain(ret), ret.Error(1)
+}
@@ -0,0 +1,63 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/auth"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockKeyService is a mock implementation of our service.KeyService
+type mockKeyService struct {
+	mock.Mock
+}
+
+// NewMockKeyService returns a mock implementation of our KeyService for testing purposes.
+// It is built using testify.Mock
+func NewMockKeyService() *mockKeyService {
+	return new(mockKeyService)
+}
+
+// CreateKey is a mock implementation of KeyService.CreateKey
+func (m *mockKeyService) CreateKey(ctx context.Context, name, workspaceID string, scopes entity.Scopes) (*entity.ApiKey, string, error) {
+	ret := m.Called(ctx, name, workspaceID, scopes)
+
+	var r0 *entity.ApiKey
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*entity.ApiKey)
+	}
+
+	return r0, ret.String(1), ret.Error(2)
+}
+
+// ListKeys is a mock implementation of KeyService.ListKeys
+func (m *mockKeyService) ListKeys(ctx context.Context) ([]entity.ApiKey, error) {
+	ret := m.Called(ctx)
+
+	var r0 []entity.ApiKey
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]entity.ApiKey)
+	}
+
+	return r0, ret.Error(1)
+}
+
+// RevokeKey is a mock implementation of KeyService.RevokeKey
+func (m *mockKeyService) RevokeKey(ctx context.Context, id string) error {
+	ret := m.Called(ctx, id)
+
+	return ret.Error(0)
+}
+
+// Authenticate is a mock implementation of KeyService.Authenticate
+func (m *mockKeyService) Authenticate(ctx context.Context, key string) (*auth.Principal, error) {
+	ret := m.Called(ctx, key)
+
+	var r0 *auth.Principal
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*auth.Principal)
+	}
+
+	return r0, ret.Error(1)
+}
@@ -0,0 +1,62 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockPrivacyService is a mock implementation of our service.PrivacyService
+type mockPrivacyService struct {
+	mock.Mock
+}
+
+// NewMockPrivacyService returns a mock implementation of our PrivacyService for testing purposes.
+// It is built using testify.Mock
+func NewMockPrivacyService() *mockPrivacyService {
+	return new(mockPrivacyService)
+}
+
+// StartJob is a mock implementation of PrivacyService.StartJob
+func (m *mockPrivacyService) StartJob(ctx context.Context, kind entity.PrivacyJobKind, subject string) (*entity.PrivacyJob, error) {
+	ret := m.Called(ctx, kind, subject)
+
+	var r0 *entity.PrivacyJob
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*entity.PrivacyJob)
+	}
+
+	return r0, ret.Error(1)
+}
+
+// RunJob is a mock implementation of PrivacyService.RunJob
+func (m *mockPrivacyService) RunJob(ctx context.Context, kind entity.PrivacyJobKind, subject string) (*entity.PrivacyJob, error) {
+	ret := m.Called(ctx, kind, subject)
+
+	var r0 *entity.PrivacyJob
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*entity.PrivacyJob)
+	}
+
+	return r0, ret.Error(1)
+}
+
+// FindJob is a mock implementation of PrivacyService.FindJob
+func (m *mockPrivacyService) FindJob(ctx context.Context, id string) (*entity.PrivacyJob, error) {
+	ret := m.Called(ctx, id)
+
+	var r0 *entity.PrivacyJob
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*entity.PrivacyJob)
+	}
+
+	return r0, ret.Error(1)
+}
+
+// ResumeJobs is a mock implementation of PrivacyService.ResumeJobs
+func (m *mockPrivacyService) ResumeJobs(ctx context.Context) (int, error) {
+	ret := m.Called(ctx)
+
+	return ret.Int(0), ret.Error(1)
+}
@@ -0,0 +1,31 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockQuotaService is a mock implementation of our service.QuotaService
+type mockQuotaService struct {
+	mock.Mock
+}
+
+// NewMockQuotaService returns a mock implementation of our QuotaService for testing purposes.
+// It is built using testify.Mock
+func NewMockQuotaService() *mockQuotaService {
+	return new(mockQuotaService)
+}
+
+// UseCreateQuota is a mock implementation of QuotaService.UseCreateQuota
+func (m *mockQuotaService) UseCreateQuota(ctx context.Context, subject string) (*entity.QuotaStatus, error) {
+	ret := m.Called(ctx, subject)
+
+	var r0 *entity.QuotaStatus
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*entity.QuotaStatus)
+	}
+
+	return r0, ret.Error(1)
+}
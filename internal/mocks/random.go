@@ -7,11 +7,11 @@ type mockRandomiser struct {
 	mock.Mock
 }
 
-func NewMockRandomiser() mockRandomiser {
-	return mockRandomiser{}
+func NewMockRandomiser() *mockRandomiser {
+	return &mockRandomiser{}
 }
 
-func (m mockRandomiser) GenerateRandomString(n int) string {
+func (m *mockRandomiser) GenerateRandomString(n int) string {
 	ret := m.Called(n)
 	return ret.String(0)
 }
@@ -0,0 +1,31 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/auth"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockTokenVerifier is a mock implementation of our auth.TokenVerifier
+type mockTokenVerifier struct {
+	mock.Mock
+}
+
+// NewMockTokenVerifier returns a mock implementation of our TokenVerifier for testing purposes.
+// It is built using testify.Mock
+func NewMockTokenVerifier() *mockTokenVerifier {
+	return new(mockTokenVerifier)
+}
+
+// Verify is a mock implementation of TokenVerifier.Verify
+func (m *mockTokenVerifier) Verify(ctx context.Context, token string) (*auth.Principal, error) {
+	ret := m.Called(ctx, token)
+
+	var r0 *auth.Principal
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*auth.Principal)
+	}
+
+	return r0, ret.Error(1)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -42,14 +43,60 @@ func (m *mockUrlRepository) Update(ctx context.Context, url *entity.Url) error {
 	return ret.Error(0)
 }
 
-// GetAllUrls is a mock implementation of repository.GetAllUrls
-func (m *mockUrlRepository) GetAllUrls(ctx context.Context) ([]entity.Url, error) {
-	ret := m.Called(ctx)
+// Delete is a mock implementation of repository.Delete
+func (m *mockUrlRepository) Delete(ctx context.Context, token string) error {
+	ret := m.Called(ctx, token)
+	return ret.Error(0)
+}
+
+// ListUrls is a mock implementation of repository.ListUrls
+func (m *mockUrlRepository) ListUrls(ctx context.Context, filter repository.ListFilter, cursor string, limit int) ([]entity.Url, string, error) {
+	ret := m.Called(ctx, filter, cursor, limit)
 
 	var r0 []entity.Url
 	if ret.Get(0) != nil {
 		r0 = ret.Get(0).([]entity.Url)
 	}
 
-	return r0, ret.Error(1)
+	return r0, ret.String(1), ret.Error(2)
+}
+
+// Count is a mock implementation of repository.Count
+func (m *mockUrlRepository) Count(ctx context.Context) (int, error) {
+	ret := m.Called(ctx)
+	return ret.Int(0), ret.Error(1)
+}
+
+// mockVisitIncrementingUrlRepository is a mock UrlRepository that also implements repository.VisitIncrementer
+type mockVisitIncrementingUrlRepository struct {
+	mockUrlRepository
+}
+
+// NewMockVisitIncrementingUrlRepository returns a mock UrlRepository that can increment visits itself.
+// It is built using testify.Mock
+func NewMockVisitIncrementingUrlRepository() *mockVisitIncrementingUrlRepository {
+	return new(mockVisitIncrementingUrlRepository)
+}
+
+// IncrementVisits is a mock implementation of repository.IncrementVisits
+func (m *mockVisitIncrementingUrlRepository) IncrementVisits(ctx context.Context, token string) (int, error) {
+	ret := m.Called(ctx, token)
+	return ret.Int(0), ret.Error(1)
+}
+
+// mockDomainVisitIncrementingUrlRepository is a mock UrlRepository that also implements repository.DomainVisitIncrementer
+type mockDomainVisitIncrementingUrlRepository struct {
+	mockUrlRepository
+}
+
+// NewMockDomainVisitIncrementingUrlRepository returns a mock UrlRepository that can increment visits on any domain itself.
+// It is built using testify.Mock
+func NewMockDomainVisitIncrementingUrlRepository() *mockDomainVisitIncrementingUrlRepository {
+	return new(mockDomainVisitIncrementingUrlRepository)
+}
+
+// IncrementDomainVisits is a mock implementation of repository.IncrementDomainVisits
+func (m *mockDomainVisitIncrementingUrlRepository) IncrementDomainVisits(ctx context.Context, domain, token string) (int, error) {
+	ret := m.Called(ctx, domain, token)
+	return ret.Int(0), ret.Error(1)
 }
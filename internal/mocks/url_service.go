@@ -4,6 +4,8 @@ import (
 	"context"
 
 	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/importer"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -54,6 +56,49 @@ func (m *mockUrlService) FindUrlByToken(ctx context.Context, token string) (*ent
 	return r0, ret.Error(1)
 }
 
+// GetUrlAnalytics is a mock implementation of UrlService.GetUrlAnalytics
+func (m *mockUrlService) GetUrlAnalytics(ctx context.Context, token string) (*entity.Url, error) {
+	ret := m.Called(ctx, token)
+
+	var r0 *entity.Url
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*entity.Url)
+	}
+
+	return r0, ret.Error(1)
+}
+
+// UpdateUrl is a mock implementation of UrlService.UpdateUrl
+func (m *mockUrlService) UpdateUrl(ctx context.Context, token, targetUrl string) (*entity.Url, error) {
+	ret := m.Called(ctx, token, targetUrl)
+
+	var r0 *entity.Url
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*entity.Url)
+	}
+
+	return r0, ret.Error(1)
+}
+
+// DeleteUrl is a mock implementation of UrlService.DeleteUrl
+func (m *mockUrlService) DeleteUrl(ctx context.Context, token string) error {
+	ret := m.Called(ctx, token)
+
+	return ret.Error(0)
+}
+
+// SetUrlDisabled is a mock implementation of UrlService.SetUrlDisabled
+func (m *mockUrlService) SetUrlDisabled(ctx context.Context, token string, disabled bool) (*entity.Url, error) {
+	ret := m.Called(ctx, token, disabled)
+
+	var r0 *entity.Url
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*entity.Url)
+	}
+
+	return r0, ret.Error(1)
+}
+
 // IncrementUrlVisits is a mock implementation of UrlService.IncrementUrlVisits
 func (m *mockUrlService) IncrementUrlVisits(ctx context.Context, url *entity.Url) error {
 	ret := m.Called(ctx, url)
@@ -61,13 +106,38 @@ func (m *mockUrlService) IncrementUrlVisits(ctx context.Context, url *entity.Url
 	return ret.Error(0)
 }
 
-func (m *mockUrlService) GetAllUrls(ctx context.Context) ([]entity.Url, error) {
-	ret := m.Called(ctx)
+// ListUrls is a mock implementation of UrlService.ListUrls
+func (m *mockUrlService) ListUrls(ctx context.Context, filter repository.ListFilter, cursor string, limit int) ([]entity.Url, string, error) {
+	ret := m.Called(ctx, filter, cursor, limit)
 
 	var r0 []entity.Url
 	if ret.Get(0) != nil {
 		r0 = ret.Get(0).([]entity.Url)
 	}
 
+	return r0, ret.String(1), ret.Error(2)
+}
+
+// KeyspaceStats is a mock implementation of UrlService.KeyspaceStats
+func (m *mockUrlService) KeyspaceStats(ctx context.Context) *entity.KeyspaceStats {
+	ret := m.Called(ctx)
+
+	var r0 *entity.KeyspaceStats
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*entity.KeyspaceStats)
+	}
+
+	return r0
+}
+
+// ImportUrls is a mock implementation of UrlService.ImportUrls
+func (m *mockUrlService) ImportUrls(ctx context.Context, records []importer.Record, conflict importer.Conflict) (*importer.Report, error) {
+	ret := m.Called(ctx, records, conflict)
+
+	var r0 *importer.Report
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*importer.Report)
+	}
+
 	return r0, ret.Error(1)
 }
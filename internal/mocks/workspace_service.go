@@ -0,0 +1,86 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockWorkspaceService is a mock implementation of our service.WorkspaceService
+type mockWorkspaceService struct {
+	mock.Mock
+}
+
+// NewMockWorkspaceService returns a mock implementation of our WorkspaceService for testing purposes.
+// It is built using testify.Mock
+func NewMockWorkspaceService() *mockWorkspaceService {
+	return new(mockWorkspaceService)
+}
+
+// CreateWorkspace is a mock implementation of WorkspaceService.CreateWorkspace
+func (m *mockWorkspaceService) CreateWorkspace(ctx context.Context, name string) (*entity.Workspace, error) {
+	ret := m.Called(ctx, name)
+
+	var r0 *entity.Workspace
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*entity.Workspace)
+	}
+
+	return r0, ret.Error(1)
+}
+
+// ListWorkspaces is a mock implementation of WorkspaceService.ListWorkspaces
+func (m *mockWorkspaceService) ListWorkspaces(ctx context.Context) ([]entity.Membership, error) {
+	ret := m.Called(ctx)
+
+	var r0 []entity.Membership
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]entity.Membership)
+	}
+
+	return r0, ret.Error(1)
+}
+
+// ListMembers is a mock implementation of WorkspaceService.ListMembers
+func (m *mockWorkspaceService) ListMembers(ctx context.Context, workspaceID string) ([]entity.Membership, error) {
+	ret := m.Called(ctx, workspaceID)
+
+	var r0 []entity.Membership
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]entity.Membership)
+	}
+
+	return r0, ret.Error(1)
+}
+
+// AddMember is a mock implementation of WorkspaceService.AddMember
+func (m *mockWorkspaceService) AddMember(ctx context.Context, workspaceID, userID string, role entity.Role) (*entity.Membership, error) {
+	ret := m.Called(ctx, workspaceID, userID, role)
+
+	var r0 *entity.Membership
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*entity.Membership)
+	}
+
+	return r0, ret.Error(1)
+}
+
+// UpdateMember is a mock implementation of WorkspaceService.UpdateMember
+func (m *mockWorkspaceService) UpdateMember(ctx context.Context, workspaceID, userID string, role entity.Role) (*entity.Membership, error) {
+	ret := m.Called(ctx, workspaceID, userID, role)
+
+	var r0 *entity.Membership
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*entity.Membership)
+	}
+
+	return r0, ret.Error(1)
+}
+
+// RemoveMember is a mock implementation of WorkspaceService.RemoveMember
+func (m *mockWorkspaceService) RemoveMember(ctx context.Context, workspaceID, userID string) error {
+	ret := m.Called(ctx, workspaceID, userID)
+
+	return ret.Error(0)
+}
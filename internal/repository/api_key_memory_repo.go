@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+)
+
+// memoryApiKeyRepo keeps api keys in memory, for use with the memory url repository.
+// Keys are lost on restart.
+type memoryApiKeyRepo struct {
+	keys map[string]*entity.ApiKey // id -> key
+	mu   sync.RWMutex
+}
+
+func NewInMemoryApiKeyRepo() ApiKeyRepository {
+	return &memoryApiKeyRepo{
+		keys: make(map[string]*entity.ApiKey),
+	}
+}
+
+// CreateApiKey is an in memory implementation of ApiKeyRepository.CreateApiKey
+func (r *memoryApiKeyRepo) CreateApiKey(ctx context.Context, key *entity.ApiKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.keys {
+		if existing.ID == key.ID || existing.Hash == key.Hash {
+			return ErrApiKeyExists
+		}
+	}
+
+	stored := *key
+	r.keys[key.ID] = &stored
+	return nil
+}
+
+// FindApiKeyByHash is an in memory implementation of ApiKeyRepository.FindApiKeyByHash
+func (r *memoryApiKeyRepo) FindApiKeyByHash(ctx context.Context, hash string) (*entity.ApiKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.Hash == hash {
+			found := *key
+			return &found, nil
+		}
+	}
+
+	return nil, ErrApiKeyNotFound
+}
+
+// FindApiKey is an in memory implementation of ApiKeyRepository.FindApiKey
+func (r *memoryApiKeyRepo) FindApiKey(ctx context.Context, id string) (*entity.ApiKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, ErrApiKeyNotFound
+	}
+
+	found := *key
+	return &found, nil
+}
+
+// ListApiKeys is an in memory implementation of ApiKeyRepository.ListApiKeys
+func (r *memoryApiKeyRepo) ListApiKeys(ctx context.Context) ([]entity.ApiKey, error) {
+	r.mu.RLock()
+	keys := make([]entity.ApiKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, *key)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+
+	return keys, nil
+}
+
+// RevokeApiKey is an in memory implementation of ApiKeyRepository.RevokeApiKey
+func (r *memoryApiKeyRepo) RevokeApiKey(ctx context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return ErrApiKeyNotFound
+	}
+
+	if key.RevokedAt == nil {
+		key.RevokedAt = &at
+	}
+
+	return nil
+}
+
+// DeleteApiKey is an in memory implementation of ApiKeyRepository.DeleteApiKey
+func (r *memoryApiKeyRepo) DeleteApiKey(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.keys, id)
+	return nil
+}
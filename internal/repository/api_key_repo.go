@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// NewApiKeyRepository returns the ApiKeyRepository that stores keys alongside the urls in urlRepo.
+// The sqlite and postgres repositories keep keys in the api_key table, and the memory repository keeps them in memory.
+// The other repositories return ErrApiKeysUnsupported.
+func NewApiKeyRepository(urlRepo UrlRepository) (ApiKeyRepository, error) {
+	switch repo := urlRepo.(type) {
+	case *sqliteRepository:
+		return &sqlApiKeyRepo{db: repo.db}, nil
+	case *postgresRepository:
+		return &sqlApiKeyRepo{db: repo.db}, nil
+	case *memoryRepo:
+		return NewInMemoryApiKeyRepo(), nil
+	default:
+		return nil, ErrApiKeysUnsupported
+	}
+}
+
+// sqlApiKeyRepo stores api keys in the api_key table of the sqlite or postgres database.
+type sqlApiKeyRepo struct {
+	db *sqlx.DB
+}
+
+func (s *sqlApiKeyRepo) CreateApiKey(ctx context.Context, key *entity.ApiKey) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(`INSERT INTO api_key (id, name, hash, created_at, revoked_at, workspace_id, scopes) VALUES (?, ?, ?, ?, ?, ?, ?)`),
+		key.ID, key.Name, key.Hash, key.CreatedAt, key.RevokedAt, key.WorkspaceID, key.Scopes)
+	if isUniqueViolation(err) {
+		return ErrApiKeyExists
+	}
+
+	return err
+}
+
+func (s *sqlApiKeyRepo) FindApiKeyByHash(ctx context.Context, hash string) (*entity.ApiKey, error) {
+	key := &entity.ApiKey{}
+
+	err := s.db.GetContext(ctx, key, s.db.Rebind(`SELECT id, name, hash, created_at, revoked_at, workspace_id, scopes FROM api_key WHERE hash = ?`), hash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrApiKeyNotFound
+		}
+
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (s *sqlApiKeyRepo) FindApiKey(ctx context.Context, id string) (*entity.ApiKey, error) {
+	key := &entity.ApiKey{}
+
+	err := s.db.GetContext(ctx, key, s.db.Rebind(`SELECT id, name, hash, created_at, revoked_at, workspace_id, scopes FROM api_key WHERE id = ?`), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrApiKeyNotFound
+		}
+
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (s *sqlApiKeyRepo) ListApiKeys(ctx context.Context) ([]entity.ApiKey, error) {
+	keys := []entity.ApiKey{}
+
+	if err := s.db.SelectContext(ctx, &keys, `SELECT id, name, hash, created_at, revoked_at, workspace_id, scopes FROM api_key ORDER BY created_at, id`); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (s *sqlApiKeyRepo) RevokeApiKey(ctx context.Context, id string, at time.Time) error {
+	result, err := s.db.ExecContext(ctx, s.db.Rebind(`UPDATE api_key SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?`), at, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrApiKeyNotFound
+	}
+
+	return nil
+}
+
+func (s *sqlApiKeyRepo) DeleteApiKey(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(`DELETE FROM api_key WHERE id = ?`), id)
+	return err
+}
+
+// isUniqueViolation reports whether err is a sqlite or postgres unique constraint violation.
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey || sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+
+	return false
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ApiKeyRepository(t *testing.T) {
+	testCases := []struct {
+		name string
+		repo func(t *testing.T) ApiKeyRepository
+	}{
+		{"Memory", func(t *testing.T) ApiKeyRepository { return NewInMemoryApiKeyRepo() }},
+		{"SQLite", func(t *testing.T) ApiKeyRepository {
+			repo, err := NewApiKeyRepository(newSQLiteRepo(t))
+			require.NoError(t, err)
+			return repo
+		}},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			repo := test.repo(t)
+			ctx := context.Background()
+			createdAt := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+
+			require.NoError(t, repo.CreateApiKey(ctx, &entity.ApiKey{ID: "key2", Name: "second", Hash: "hash2", CreatedAt: createdAt.Add(time.Hour), WorkspaceID: "ws_1",
+				Scopes: entity.Scopes{entity.ScopeAnalyticsRead}}))
+			require.NoError(t, repo.CreateApiKey(ctx, &entity.ApiKey{ID: "key1", Name: "first", Hash: "hash1", CreatedAt: createdAt}))
+			assert.ErrorIs(t, repo.CreateApiKey(ctx, &entity.ApiKey{ID: "key3", Name: "clash", Hash: "hash1", CreatedAt: createdAt}), ErrApiKeyExists)
+
+			key, err := repo.FindApiKeyByHash(ctx, "hash1")
+			require.NoError(t, err)
+			assert.Equal(t, "key1", key.ID)
+			assert.Equal(t, "first", key.Name)
+			assert.False(t, key.Revoked())
+
+			_, err = repo.FindApiKeyByHash(ctx, "unknown")
+			assert.ErrorIs(t, err, ErrApiKeyNotFound)
+
+			// revoking again keeps the original time
+			require.NoError(t, repo.RevokeApiKey(ctx, "key1", createdAt.Add(2*time.Hour)))
+			require.NoError(t, repo.RevokeApiKey(ctx, "key1", createdAt.Add(3*time.Hour)))
+			assert.ErrorIs(t, repo.RevokeApiKey(ctx, "unknown", createdAt), ErrApiKeyNotFound)
+
+			key, err = repo.FindApiKeyByHash(ctx, "hash1")
+			require.NoError(t, err)
+			require.True(t, key.Revoked())
+			assert.True(t, createdAt.Add(2*time.Hour).Equal(*key.RevokedAt))
+
+			keys, err := repo.ListApiKeys(ctx)
+			require.NoError(t, err)
+			require.Len(t, keys, 2)
+			assert.Equal(t, "key1", keys[0].ID)
+			assert.Equal(t, "key2", keys[1].ID)
+			assert.False(t, keys[1].Revoked())
+			assert.Equal(t, "ws_1", keys[1].WorkspaceID)
+			assert.Equal(t, entity.Scopes{entity.ScopeAnalyticsRead}, keys[1].Scopes)
+			assert.Empty(t, keys[0].Scopes)
+
+			key, err = repo.FindApiKey(ctx, "key2")
+			require.NoError(t, err)
+			assert.Equal(t, "hash2", key.Hash)
+
+			// deleting a key is done once, then there's nothing to delete
+			require.NoError(t, repo.DeleteApiKey(ctx, "key2"))
+			require.NoError(t, repo.DeleteApiKey(ctx, "key2"))
+			_, err = repo.FindApiKey(ctx, "key2")
+			assert.ErrorIs(t, err, ErrApiKeyNotFound)
+		})
+	}
+}
+
+func Test_NewApiKeyRepository_Unsupported(t *testing.T) {
+	_, err := NewApiKeyRepository(newRedisRepo(t))
+	assert.ErrorIs(t, err, ErrApiKeysUnsupported)
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+)
+
+// memoryAuditRepo keeps the audit log in memory, for use with the memory url repository.
+// The log is lost on restart, unless it belongs to a durable memory repository.
+type memoryAuditRepo struct {
+	entries []entity.AuditEntry
+	mu      sync.RWMutex
+}
+
+func NewInMemoryAuditRepo() AuditRepository {
+	return &memoryAuditRepo{}
+}
+
+// AppendAuditEntry is an in memory implementation of AuditRepository.AppendAuditEntry
+func (r *memoryAuditRepo) AppendAuditEntry(ctx context.Context, entry *entity.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry.ID = int64(len(r.entries) + 1)
+	r.entries = append(r.entries, *entry)
+	return nil
+}
+
+// ListAuditEntries is an in memory implementation of AuditRepository.ListAuditEntries
+func (r *memoryAuditRepo) ListAuditEntries(ctx context.Context, filter AuditFilter, cursor string, limit int) ([]entity.AuditEntry, string, error) {
+	after, err := decodeAuditCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// ids are positions in the log, so the page starts right after the cursor
+	matched := []entity.AuditEntry{}
+	for i := int(after); i < len(r.entries) && len(matched) <= limit; i++ {
+		if filter.matches(&r.entries[i]) {
+			matched = append(matched, r.entries[i])
+		}
+	}
+
+	return auditPage(matched, limit)
+}
+
+// AnonymizeAuditEntries is an in memory implementation of AuditRepository.AnonymizeAuditEntries
+func (r *memoryAuditRepo) AnonymizeAuditEntries(ctx context.Context, subject, pseudonym string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed := 0
+	for i := range r.entries {
+		entry := &r.entries[i]
+		if entry.Actor != subject && entry.Target != subject {
+			continue
+		}
+
+		if entry.Actor == subject {
+			entry.Actor, entry.IP = pseudonym, ""
+		}
+		if entry.Target == subject {
+			entry.Target = pseudonym
+
+			// the changes may be shared with entries that were listed before
+			changes := make(entity.AuditChanges, len(entry.Changes))
+			for field, change := range entry.Changes {
+				changes[field] = change
+			}
+			if changes.RemovePersonal() {
+				entry.Changes = changes
+			}
+		}
+		changed++
+	}
+
+	return changed, nil
+}
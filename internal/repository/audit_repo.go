@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/jmoiron/sqlx"
+)
+
+// AuditFilter narrows down the entries returned by ListAuditEntries.
+// Zero values don't filter.
+type AuditFilter struct {
+	Action      entity.AuditAction
+	Actor       string
+	WorkspaceID string
+	Target      string
+	Since       time.Time // only entries created at or after this time
+	Until       time.Time // only entries created before this time
+}
+
+// matches reports whether entry passes the filter.
+func (f *AuditFilter) matches(entry *entity.AuditEntry) bool {
+	switch {
+	case f.Action != "" && entry.Action != f.Action,
+		f.Actor != "" && entry.Actor != f.Actor,
+		f.WorkspaceID != "" && entry.WorkspaceID != f.WorkspaceID,
+		f.Target != "" && entry.Target != f.Target,
+		!f.Since.IsZero() && entry.CreatedAt.Before(f.Since),
+		!f.Until.IsZero() && !entry.CreatedAt.Before(f.Until):
+		return false
+	}
+
+	return true
+}
+
+// encodeAuditCursor returns an opaque cursor for the position of the entry with the id.
+func encodeAuditCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+// decodeAuditCursor decodes a cursor made by encodeAuditCursor into the id of the last entry of the previous page.
+// An empty cursor is the start of the log, and returns 0.
+func decodeAuditCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+
+	id, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidCursor
+	}
+
+	return id, nil
+}
+
+// auditPage trims entries to limit, returning a cursor for the next page if there were more than limit entries.
+func auditPage(entries []entity.AuditEntry, limit int) ([]entity.AuditEntry, string, error) {
+	if len(entries) <= limit {
+		return entries, "", nil
+	}
+
+	entries = entries[:limit]
+	return entries, encodeAuditCursor(entries[limit-1].ID), nil
+}
+
+// NewAuditRepository returns the AuditRepository that stores the audit log alongside the urls in urlRepo.
+// The sqlite and postgres repositories keep it in the audit_log table, the memory repository keeps it in memory,
+// and the durable memory repository also logs it to its directory. The other repositories return ErrAuditUnsupported.
+func NewAuditRepository(urlRepo UrlRepository) (AuditRepository, error) {
+	switch repo := urlRepo.(type) {
+	case *sqliteRepository:
+		return &sqlAuditRepo{db: repo.db}, nil
+	case *postgresRepository:
+		return &sqlAuditRepo{db: repo.db}, nil
+	case *memoryRepo:
+		return NewInMemoryAuditRepo(), nil
+	case *durableMemoryRepo:
+		return &durableAuditRepo{memoryAuditRepo: repo.audit, repo: repo}, nil
+	default:
+		return nil, ErrAuditUnsupported
+	}
+}
+
+// sqlAuditRepo stores the audit log in the audit_log table of the sqlite or postgres database.
+type sqlAuditRepo struct {
+	db *sqlx.DB
+}
+
+func (s *sqlAuditRepo) AppendAuditEntry(ctx context.Context, entry *entity.AuditEntry) error {
+	return s.db.QueryRowxContext(ctx, s.db.Rebind(`INSERT INTO audit_log (action, actor, workspace_id, target, ip, request_id, changes, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`),
+		entry.Action, entry.Actor, entry.WorkspaceID, entry.Target, entry.IP, entry.RequestID, entry.Changes, entry.CreatedAt).Scan(&entry.ID)
+}
+
+func (s *sqlAuditRepo) ListAuditEntries(ctx context.Context, filter AuditFilter, cursor string, limit int) ([]entity.AuditEntry, string, error) {
+	after, err := decodeAuditCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	where := []string{"id > ?"}
+	args := []interface{}{after}
+
+	for column, value := range map[string]string{
+		"action":       string(filter.Action),
+		"actor":        filter.Actor,
+		"workspace_id": filter.WorkspaceID,
+		"target":       filter.Target,
+	} {
+		if value != "" {
+			where = append(where, column+" = ?")
+			args = append(args, value)
+		}
+	}
+
+	if !filter.Since.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, filter.Since.UTC())
+	}
+
+	if !filter.Until.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, filter.Until.UTC())
+	}
+
+	// one more than limit, so we know if there's another page
+	args = append(args, limit+1)
+	query := `SELECT id, action, actor, workspace_id, target, ip, request_id, changes, created_at FROM audit_log
+		WHERE ` + strings.Join(where, " AND ") + ` ORDER BY id LIMIT ?`
+
+	entries := []entity.AuditEntry{}
+	if err := s.db.SelectContext(ctx, &entries, s.db.Rebind(query), args...); err != nil {
+		return nil, "", err
+	}
+
+	return auditPage(entries, limit)
+}
+
+func (s *sqlAuditRepo) AnonymizeAuditEntries(ctx context.Context, subject, pseudonym string) (int, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	// Defer a rollback in case anything fails.
+	defer tx.Rollback()
+
+	// the changes are JSON, which sqlite and postgres query differently, so personal fields are removed here
+	targeted := []entity.AuditEntry{}
+	if err := tx.SelectContext(ctx, &targeted, tx.Rebind(`SELECT id, changes FROM audit_log WHERE target = ?`), subject); err != nil {
+		return 0, err
+	}
+
+	for _, entry := range targeted {
+		if !entry.Changes.RemovePersonal() {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, tx.Rebind(`UPDATE audit_log SET changes = ? WHERE id = ?`), entry.Changes, entry.ID); err != nil {
+			return 0, err
+		}
+	}
+
+	// every expression reads the entry as it was before the update
+	result, err := tx.ExecContext(ctx, tx.Rebind(`UPDATE audit_log SET
+		actor = CASE WHEN actor = ? THEN ? ELSE actor END,
+		ip = CASE WHEN actor = ? THEN '' ELSE ip END,
+		target = CASE WHEN target = ? THEN ? ELSE target END
+		WHERE actor = ? OR target = ?`),
+		subject, pseudonym, subject, subject, pseudonym, subject, subject)
+	if err != nil {
+		return 0, err
+	}
+
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(changed), tx.Commit()
+}
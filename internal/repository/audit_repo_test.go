@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_AuditRepository(t *testing.T) {
+	testCases := []struct {
+		name string
+		repo func(t *testing.T) AuditRepository
+	}{
+		{"Memory", func(t *testing.T) AuditRepository { return NewInMemoryAuditRepo() }},
+		{"Durable Memory", func(t *testing.T) AuditRepository {
+			urlRepo := newDurableRepo(t, t.TempDir())
+			t.Cleanup(func() { urlRepo.Close() })
+			repo, err := NewAuditRepository(urlRepo)
+			require.NoError(t, err)
+			return repo
+		}},
+		{"SQLite", func(t *testing.T) AuditRepository {
+			repo, err := NewAuditRepository(newSQLiteRepo(t))
+			require.NoError(t, err)
+			return repo
+		}},
+	}
+
+	start := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			repo := test.repo(t)
+			ctx := context.Background()
+
+			entries := []entity.AuditEntry{
+				{Action: entity.AuditLinkCreate, Actor: "key_1", WorkspaceID: "default", Target: "abcdef", IP: "192.0.2.1", RequestID: "req-1",
+					Changes: entity.AuditChanges{"target_url": {After: "https://example.com"}}, CreatedAt: start},
+				{Action: entity.AuditLinkRetarget, Actor: "user_1", WorkspaceID: "default", Target: "abcdef", IP: "192.0.2.2", RequestID: "req-2",
+					Changes: entity.AuditChanges{"target_url": {Before: "https://example.com", After: "https://example.org"}}, CreatedAt: start.Add(time.Minute)},
+				{Action: entity.AuditKeyCreate, Actor: "cli", WorkspaceID: "ws_1", Target: "key_2",
+					Changes: entity.AuditChanges{"name": {After: "Jane's key"}, "scopes": {After: "links:write"}}, CreatedAt: start.Add(2 * time.Minute)},
+				{Action: entity.AuditLinkDelete, Actor: "key_1", WorkspaceID: "default", Target: "abcdef",
+					Changes: entity.AuditChanges{"target_url": {Before: "https://example.org"}}, CreatedAt: start.Add(3 * time.Minute)},
+			}
+			for i := range entries {
+				require.NoError(t, repo.AppendAuditEntry(ctx, &entries[i]))
+				assert.NotZero(t, entries[i].ID)
+			}
+
+			filters := []struct {
+				name     string
+				filter   AuditFilter
+				expected []entity.AuditEntry
+			}{
+				{"Everything", AuditFilter{}, entries},
+				{"Target", AuditFilter{Target: "abcdef"}, []entity.AuditEntry{entries[0], entries[1], entries[3]}},
+				{"Action", AuditFilter{Action: entity.AuditLinkRetarget}, []entity.AuditEntry{entries[1]}},
+				{"Actor And Workspace", AuditFilter{Actor: "key_1", WorkspaceID: "default"}, []entity.AuditEntry{entries[0], entries[3]}},
+				{"Time Range", AuditFilter{Since: start.Add(time.Minute), Until: start.Add(3 * time.Minute)}, []entity.AuditEntry{entries[1], entries[2]}},
+				{"No Match", AuditFilter{Actor: "key_unknown"}, []entity.AuditEntry{}},
+			}
+
+			for _, f := range filters {
+				t.Run(f.name, func(t *testing.T) {
+					list, next, err := repo.ListAuditEntries(ctx, f.filter, "", 10)
+					require.NoError(t, err)
+					assert.Empty(t, next)
+					assertAuditEntries(t, f.expected, list)
+				})
+			}
+
+			// pages follow on from each other, oldest first
+			page, next, err := repo.ListAuditEntries(ctx, AuditFilter{Target: "abcdef"}, "", 2)
+			require.NoError(t, err)
+			assertAuditEntries(t, []entity.AuditEntry{entries[0], entries[1]}, page)
+			require.NotEmpty(t, next)
+
+			page, next, err = repo.ListAuditEntries(ctx, AuditFilter{Target: "abcdef"}, next, 2)
+			require.NoError(t, err)
+			assertAuditEntries(t, []entity.AuditEntry{entries[3]}, page)
+			assert.Empty(t, next)
+
+			_, _, err = repo.ListAuditEntries(ctx, AuditFilter{}, "not a cursor", 2)
+			assert.ErrorIs(t, err, ErrInvalidCursor)
+
+			// key_1 made two entries, and cli made one about key_2, whose name is removed
+			changed, err := repo.AnonymizeAuditEntries(ctx, "key_1", "erased_1")
+			require.NoError(t, err)
+			assert.Equal(t, 2, changed)
+
+			changed, err = repo.AnonymizeAuditEntries(ctx, "key_2", "erased_2")
+			require.NoError(t, err)
+			assert.Equal(t, 1, changed)
+
+			entries[0].Actor, entries[0].IP = "erased_1", ""
+			entries[3].Actor = "erased_1"
+			entries[2].Target = "erased_2"
+			entries[2].Changes = entity.AuditChanges{"scopes": {After: "links:write"}}
+			list, _, err := repo.ListAuditEntries(ctx, AuditFilter{}, "", 10)
+			require.NoError(t, err)
+			assertAuditEntries(t, entries, list)
+		})
+	}
+}
+
+// assertAuditEntries compares entries, ignoring the time zone the database returns times in.
+func assertAuditEntries(t *testing.T, expected, actual []entity.AuditEntry) {
+	t.Helper()
+
+	require.Len(t, actual, len(expected))
+	for i := range expected {
+		assert.True(t, expected[i].CreatedAt.Equal(actual[i].CreatedAt))
+		actual[i].CreatedAt = expected[i].CreatedAt
+		assert.Equal(t, expected[i], actual[i])
+	}
+}
+
+func Test_NewAuditRepository_Unsupported(t *testing.T) {
+	_, err := NewAuditRepository(newRedisRepo(t))
+	assert.ErrorIs(t, err, ErrAuditUnsupported)
+}
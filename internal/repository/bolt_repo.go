@@ -0,0 +1,453 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	boltUrlBucket     = []byte("urls")            // token -> url
+	boltTargetBucket  = []byte("urls_by_target")  // target url + separator + token -> nothing
+	boltCreatedBucket = []byte("urls_by_created") // creation time + separator + token -> nothing
+)
+
+// boltTargetSeparator separates the target url and token in the target and creation time indexes.
+// It can't appear in a valid url, so it can't be mistaken for part of one.
+const boltTargetSeparator = 0x00
+
+// boltRepository is the struct used for a bbolt implementation of our UrlRepository.
+// Unlike SQLite, bbolt is pure Go and doesn't need CGO.
+type boltRepository struct {
+	db *bolt.DB
+}
+
+// NewBoltRepository opens (or creates) the bbolt file at path, and creates the buckets it needs.
+// Files created before the creation time index existed have it built from their urls.
+// The repository should be closed on shutdown to release the file lock.
+func NewBoltRepository(path string) (UrlRepository, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open bolt database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		indexed := tx.Bucket(boltCreatedBucket) != nil
+
+		for _, bucket := range [][]byte{boltUrlBucket, boltTargetBucket, boltCreatedBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+
+		if indexed {
+			return nil
+		}
+
+		index := tx.Bucket(boltCreatedBucket)
+		return tx.Bucket(boltUrlBucket).ForEach(func(_, v []byte) error {
+			url := &entity.Url{}
+			if err := json.Unmarshal(v, url); err != nil {
+				return err
+			}
+			return index.Put(boltCreatedKey(url.CreatedAt, url.Token), nil)
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("couldn't create bolt buckets: %w", err)
+	}
+
+	return &boltRepository{
+		db: db,
+	}, nil
+}
+
+func (b *boltRepository) FindByToken(ctx context.Context, token string) (*entity.Url, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	url := &entity.Url{}
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(boltUrlBucket).Get([]byte(token))
+		if data == nil {
+			return ErrUrlNotFound
+		}
+
+		return json.Unmarshal(data, url)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return url, nil
+}
+
+// FindByTargetUrl uses the target index to find every url that redirects to target.
+func (b *boltRepository) FindByTargetUrl(ctx context.Context, target string) ([]entity.Url, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	urls := []entity.Url{}
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		prefix := append([]byte(target), boltTargetSeparator)
+		index := tx.Bucket(boltTargetBucket).Cursor()
+		bucket := tx.Bucket(boltUrlBucket)
+
+		for k, _ := index.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = index.Next() {
+			url := entity.Url{}
+			if err := json.Unmarshal(bucket.Get(k[len(prefix):]), &url); err != nil {
+				return err
+			}
+			urls = append(urls, url)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return urls, nil
+}
+
+func (b *boltRepository) Create(ctx context.Context, url *entity.Url) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(url)
+	if err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltUrlBucket)
+		if bucket.Get([]byte(url.Token)) != nil {
+			return ErrTokenAlreadyExists
+		}
+
+		if err := bucket.Put([]byte(url.Token), data); err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(boltCreatedBucket).Put(boltCreatedKey(url.CreatedAt, url.Token), nil); err != nil {
+			return err
+		}
+
+		return tx.Bucket(boltTargetBucket).Put(boltTargetKey(url), nil)
+	})
+}
+
+// Update replaces the url, keeping its original creation time, owner and workspace.
+func (b *boltRepository) Update(ctx context.Context, url *entity.Url) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltUrlBucket)
+		existing := bucket.Get([]byte(url.Token))
+		if existing == nil {
+			return ErrUrlNotFound
+		}
+
+		// if the target has changed, the old index entry needs replacing
+		old := &entity.Url{}
+		if err := json.Unmarshal(existing, old); err != nil {
+			return err
+		}
+
+		index := tx.Bucket(boltTargetBucket)
+		if old.TargetUrl != url.TargetUrl {
+			if err := index.Delete(boltTargetKey(old)); err != nil {
+				return err
+			}
+			if err := index.Put(boltTargetKey(url), nil); err != nil {
+				return err
+			}
+		}
+
+		updated := *url
+		updated.CreatedAt = old.CreatedAt
+		updated.Owner = old.Owner
+		updated.WorkspaceID = old.WorkspaceID
+
+		data, err := json.Marshal(&updated)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte(url.Token), data)
+	})
+}
+
+// IncrementVisits increments the visits of the url in a single transaction, so it can't undo a concurrent update.
+func (b *boltRepository) IncrementVisits(ctx context.Context, token string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	url := &entity.Url{}
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltUrlBucket)
+		existing := bucket.Get([]byte(token))
+		if existing == nil {
+			return ErrUrlNotFound
+		}
+
+		if err := json.Unmarshal(existing, url); err != nil {
+			return err
+		}
+		url.Visits++
+
+		data, err := json.Marshal(url)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte(token), data)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return url.Visits, nil
+}
+
+// Delete removes the url and its entries in the target and creation time indexes.
+func (b *boltRepository) Delete(ctx context.Context, token string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltUrlBucket)
+		existing := bucket.Get([]byte(token))
+		if existing == nil {
+			return ErrUrlNotFound
+		}
+
+		old := &entity.Url{}
+		if err := json.Unmarshal(existing, old); err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(boltTargetBucket).Delete(boltTargetKey(old)); err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(boltCreatedBucket).Delete(boltCreatedKey(old.CreatedAt, old.Token)); err != nil {
+			return err
+		}
+
+		return bucket.Delete([]byte(token))
+	})
+}
+
+// ListUrls walks the url bucket in token order, or the creation time index, from the cursor.
+// It stops once it has one url more than limit, so a page only decodes the urls up to the end of it and those filtered out.
+func (b *boltRepository) ListUrls(ctx context.Context, filter ListFilter, cursor string, limit int) ([]entity.Url, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if err := filter.normalise(); err != nil {
+		return nil, "", err
+	}
+
+	after, err := decodeCursor(cursor, filter.Sort)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// one more than limit, so we know if there's another page
+	batch := limit + 1
+
+	matched := []entity.Url{}
+
+	err = b.db.View(func(tx *bolt.Tx) error {
+		first, next := boltListWalk(tx, filter, after)
+		for data := first(); data != nil && len(matched) < batch; data = next() {
+			url := entity.Url{}
+			if err := json.Unmarshal(data, &url); err != nil {
+				return err
+			}
+
+			if filter.matches(&url) {
+				matched = append(matched, url)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return page(matched, filter.Sort, limit)
+}
+
+func (b *boltRepository) Count(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var count int
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		count = tx.Bucket(boltUrlBucket).Stats().KeyN
+		return nil
+	})
+
+	return count, err
+}
+
+// NextSequence increments and returns the url bucket's sequence, which the sequence token strategy encodes into tokens.
+func (b *boltRepository) NextSequence(ctx context.Context) (uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var value uint64
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		value, err = tx.Bucket(boltUrlBucket).NextSequence()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("couldn't increment url sequence: %w", err)
+	}
+
+	return value, nil
+}
+
+// Close closes the bolt database, releasing its file lock.
+func (b *boltRepository) Close() error {
+	return b.db.Close()
+}
+
+// boltTargetKey returns the key of url in the target index.
+func boltTargetKey(url *entity.Url) []byte {
+	key := make([]byte, 0, len(url.TargetUrl)+1+len(url.Token))
+	key = append(key, url.TargetUrl...)
+	key = append(key, boltTargetSeparator)
+	return append(key, url.Token...)
+}
+
+// boltCreatedKey returns the key of the url created at createdAt with token in the creation time index.
+func boltCreatedKey(createdAt time.Time, token string) []byte {
+	key := make([]byte, 0, len(createdKeyLayout)+1+len(token))
+	key = append(key, createdKey(createdAt)...)
+	key = append(key, boltTargetSeparator)
+	return append(key, token...)
+}
+
+// boltListWalk returns functions that step through the urls after the cursor in the filter's sort order,
+// returning each url's data, or nil once there are none left.
+// Token order walks the url bucket itself, and creation time order walks the index between the filter's times.
+func boltListWalk(tx *bolt.Tx, filter ListFilter, after *listCursor) (first, next func() []byte) {
+	bucket := tx.Bucket(boltUrlBucket)
+
+	if filter.Sort == SortTokenAsc {
+		c := bucket.Cursor()
+		first = func() []byte {
+			if after == nil {
+				_, v := c.First()
+				return v
+			}
+
+			k, v := c.Seek([]byte(after.Token))
+			if k != nil && string(k) == after.Token {
+				_, v = c.Next()
+			}
+			return v
+		}
+		next = func() []byte {
+			_, v := c.Next()
+			return v
+		}
+		return first, next
+	}
+
+	// creation times without a token sort before every key at that time, so they make exclusive bounds
+	var lower, upper []byte
+	if !filter.CreatedAfter.IsZero() {
+		lower = []byte(createdKey(filter.CreatedAfter))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		upper = []byte(createdKey(filter.CreatedBefore))
+	}
+
+	index := tx.Bucket(boltCreatedBucket).Cursor()
+	get := func(k []byte) []byte {
+		if k == nil || (lower != nil && bytes.Compare(k, lower) < 0) || (upper != nil && bytes.Compare(k, upper) >= 0) {
+			return nil
+		}
+		return bucket.Get(k[len(createdKeyLayout)+1:])
+	}
+
+	if filter.Sort == SortCreatedAsc {
+		start := lower
+		if after != nil {
+			if key := boltCreatedKey(after.CreatedAt, after.Token); start == nil || bytes.Compare(key, start) > 0 {
+				start = key
+			}
+		}
+
+		first = func() []byte {
+			if start == nil {
+				k, _ := index.First()
+				return get(k)
+			}
+
+			k, _ := index.Seek(start)
+			if bytes.Equal(k, start) {
+				k, _ = index.Next()
+			}
+			return get(k)
+		}
+		next = func() []byte {
+			k, _ := index.Next()
+			return get(k)
+		}
+		return first, next
+	}
+
+	end := upper
+	if after != nil {
+		if key := boltCreatedKey(after.CreatedAt, after.Token); end == nil || bytes.Compare(key, end) < 0 {
+			end = key
+		}
+	}
+
+	first = func() []byte {
+		if end == nil {
+			k, _ := index.Last()
+			return get(k)
+		}
+
+		// the last key before end
+		if k, _ := index.Seek(end); k == nil {
+			k, _ = index.Last()
+			return get(k)
+		}
+		k, _ := index.Prev()
+		return get(k)
+	}
+	next = func() []byte {
+		k, _ := index.Prev()
+		return get(k)
+	}
+	return first, next
+}
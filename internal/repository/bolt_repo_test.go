@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func newBoltRepo(t *testing.T) *boltRepository {
+	repo, err := NewBoltRepository(filepath.Join(t.TempDir(), "url.db"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { repo.(*boltRepository).Close() })
+
+	return repo.(*boltRepository)
+}
+
+func Test_BoltRepository(t *testing.T) {
+	repo := newBoltRepo(t)
+	ctx := context.Background()
+	createdAt := time.Now().UTC().Truncate(time.Second)
+
+	assert.NoError(t, repo.Create(ctx, &entity.Url{Token: "123456", TargetUrl: "https://example.com", CreatedAt: createdAt}))
+	assert.NoError(t, repo.Create(ctx, &entity.Url{Token: "987654", TargetUrl: "https://example.com/path", CreatedAt: createdAt}))
+	assert.ErrorIs(t, repo.Create(ctx, &entity.Url{Token: "123456"}), ErrTokenAlreadyExists)
+
+	url, err := repo.FindByToken(ctx, "123456")
+	assert.NoError(t, err)
+	assert.Equal(t, &entity.Url{Token: "123456", TargetUrl: "https://example.com", CreatedAt: createdAt}, url)
+
+	_, err = repo.FindByToken(ctx, "qwerty")
+	assert.ErrorIs(t, err, ErrUrlNotFound)
+
+	url.Visits = 42
+	assert.NoError(t, repo.Update(ctx, url))
+	assert.ErrorIs(t, repo.Update(ctx, &entity.Url{Token: "qwerty"}), ErrUrlNotFound)
+
+	url, err = repo.FindByToken(ctx, "123456")
+	assert.NoError(t, err)
+	assert.Equal(t, 42, url.Visits)
+
+	urls, _, err := repo.ListUrls(ctx, ListFilter{}, "", 1000)
+	assert.NoError(t, err)
+	assert.Len(t, urls, 2)
+
+	count, err := repo.Count(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	first, err := repo.NextSequence(ctx)
+	assert.NoError(t, err)
+	second, err := repo.NextSequence(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, first+1, second)
+}
+
+func Test_BoltRepository_TargetIndex(t *testing.T) {
+	repo := newBoltRepo(t)
+	ctx := context.Background()
+
+	assert.NoError(t, repo.Create(ctx, &entity.Url{Token: "123456", TargetUrl: "https://example.com"}))
+	assert.NoError(t, repo.Create(ctx, &entity.Url{Token: "987654", TargetUrl: "https://example.com"}))
+	// shares a prefix with the other target, but shouldn't be found with it
+	assert.NoError(t, repo.Create(ctx, &entity.Url{Token: "abcdef", TargetUrl: "https://example.com/path"}))
+
+	urls, err := repo.FindByTargetUrl(ctx, "https://example.com")
+	assert.NoError(t, err)
+	assert.Len(t, urls, 2)
+
+	// retargeting should move the url in the index
+	assert.NoError(t, repo.Update(ctx, &entity.Url{Token: "123456", TargetUrl: "https://google.com"}))
+
+	urls, err = repo.FindByTargetUrl(ctx, "https://example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []entity.Url{{Token: "987654", TargetUrl: "https://example.com"}}, urls)
+
+	urls, err = repo.FindByTargetUrl(ctx, "https://google.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []entity.Url{{Token: "123456", TargetUrl: "https://google.com"}}, urls)
+}
+
+func Test_BoltRepository_ListUrls(t *testing.T) {
+	testListUrls(t, newBoltRepo(t))
+}
+
+func Test_BoltRepository_ListUrlsOnlyReadsThePage(t *testing.T) {
+	repo := newBoltRepo(t)
+	ctx := context.Background()
+
+	for _, token := range []string{"aaa", "bbb", "ccc"} {
+		require.NoError(t, repo.Create(ctx, &entity.Url{Token: token, TargetUrl: "https://example.com", CreatedAt: listEpoch}))
+	}
+
+	// a url after the page that can't be decoded, so the page fails if it's read
+	require.NoError(t, repo.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltUrlBucket).Put([]byte("zzz"), []byte("{"))
+	}))
+
+	urls, next, err := repo.ListUrls(ctx, ListFilter{Sort: SortTokenAsc}, "", 2)
+	assert.NoError(t, err)
+	assert.Len(t, urls, 2)
+	assert.NotEmpty(t, next)
+
+	_, _, err = repo.ListUrls(ctx, ListFilter{Sort: SortTokenAsc}, next, 2)
+	assert.Error(t, err)
+}
+
+func Test_BoltRepository_IndexesExistingUrls(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "url.db")
+	ctx := context.Background()
+
+	repo, err := NewBoltRepository(path)
+	require.NoError(t, err)
+	for _, url := range listFixture(4) {
+		require.NoError(t, repo.Create(ctx, &url))
+	}
+
+	// a file from before the creation time index existed
+	require.NoError(t, repo.(*boltRepository).db.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket(boltCreatedBucket)
+	}))
+	require.NoError(t, repo.(*boltRepository).Close())
+
+	repo, err = NewBoltRepository(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.(*boltRepository).Close() })
+
+	urls, _, err := repo.ListUrls(ctx, ListFilter{}, "", 10)
+	assert.NoError(t, err)
+	assert.Len(t, urls, 4)
+	assert.Equal(t, "003", urls[0].Token)
+
+	// deleting a url should remove it from the index
+	require.NoError(t, repo.Delete(ctx, "003"))
+	urls, _, err = repo.ListUrls(ctx, ListFilter{Sort: SortCreatedAsc}, "", 10)
+	assert.NoError(t, err)
+	assert.Len(t, urls, 3)
+}
@@ -0,0 +1,52 @@
+package repository_test
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository/repotest"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// The sql repositories need a migrated database, so they run the conformance suite in the integration package.
+
+func Test_MemoryRepository_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.UrlRepository {
+		return repository.NewInMemoryRepo()
+	})
+}
+
+func Test_DurableMemoryRepository_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.UrlRepository {
+		repo, err := repository.NewDurableInMemoryRepo(repository.DurableConfig{Dir: t.TempDir(), Sync: repository.SyncNever})
+		require.NoError(t, err)
+		t.Cleanup(func() { repo.(io.Closer).Close() })
+
+		return repo
+	})
+}
+
+func Test_BoltRepository_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.UrlRepository {
+		repo, err := repository.NewBoltRepository(filepath.Join(t.TempDir(), "url.bolt"))
+		require.NoError(t, err)
+		t.Cleanup(func() { repo.(io.Closer).Close() })
+
+		return repo
+	})
+}
+
+func Test_RedisRepository_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.UrlRepository {
+		server := miniredis.RunT(t)
+
+		repo, err := repository.NewRedisRepository("redis://" + server.Addr())
+		require.NoError(t, err)
+		t.Cleanup(func() { repo.(io.Closer).Close() })
+
+		return repo
+	})
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+)
+
+// memoryDomainRepo keeps domains in memory, for use with the memory url repository.
+// Domains are lost on restart, unless they belong to a durable memory repository.
+type memoryDomainRepo struct {
+	domains map[string]*entity.Domain
+	mu      sync.RWMutex
+}
+
+func NewInMemoryDomainRepo() DomainRepository {
+	return &memoryDomainRepo{
+		domains: make(map[string]*entity.Domain),
+	}
+}
+
+// CreateDomain is an in memory implementation of DomainRepository.CreateDomain
+func (r *memoryDomainRepo) CreateDomain(ctx context.Context, domain *entity.Domain) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.domains[domain.Name]; exists {
+		return ErrDomainExists
+	}
+
+	stored := *domain
+	r.domains[domain.Name] = &stored
+	return nil
+}
+
+// FindDomain is an in memory implementation of DomainRepository.FindDomain
+func (r *memoryDomainRepo) FindDomain(ctx context.Context, name string) (*entity.Domain, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	domain, ok := r.domains[name]
+	if !ok {
+		return nil, ErrDomainNotFound
+	}
+
+	found := *domain
+	return &found, nil
+}
+
+// ListDomains is an in memory implementation of DomainRepository.ListDomains
+func (r *memoryDomainRepo) ListDomains(ctx context.Context, workspaceID string) ([]entity.Domain, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	domains := []entity.Domain{}
+	for _, domain := range r.domains {
+		if domain.WorkspaceID == workspaceID {
+			domains = append(domains, *domain)
+		}
+	}
+
+	sort.Slice(domains, func(i, j int) bool {
+		return domains[i].Name < domains[j].Name
+	})
+
+	return domains, nil
+}
+
+// UpdateDomain is an in memory implementation of DomainRepository.UpdateDomain
+func (r *memoryDomainRepo) UpdateDomain(ctx context.Context, domain *entity.Domain) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.domains[domain.Name]
+	if !ok || existing.WorkspaceID != domain.WorkspaceID {
+		return ErrDomainNotFound
+	}
+
+	existing.VerifiedAt = domain.VerifiedAt
+	existing.DefaultRedirect = domain.DefaultRedirect
+	existing.NotFoundUrl = domain.NotFoundUrl
+	return nil
+}
+
+// DeleteDomain is an in memory implementation of DomainRepository.DeleteDomain
+func (r *memoryDomainRepo) DeleteDomain(ctx context.Context, workspaceID, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if domain, ok := r.domains[name]; !ok || domain.WorkspaceID != workspaceID {
+		return ErrDomainNotFound
+	}
+
+	delete(r.domains, name)
+	return nil
+}
+
+// DeleteUnverifiedDomain is an in memory implementation of DomainRepository.DeleteUnverifiedDomain
+func (r *memoryDomainRepo) DeleteUnverifiedDomain(ctx context.Context, name string, createdBefore time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	domain, ok := r.domains[name]
+	if !ok || domain.Verified() || !domain.CreatedAt.Before(createdBefore) {
+		return false, nil
+	}
+
+	delete(r.domains, name)
+	return true, nil
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/jmoiron/sqlx"
+)
+
+// NewDomainRepository returns the DomainRepository that stores short domains alongside the urls in urlRepo.
+// The sqlite and postgres repositories keep them in the domain table, the memory repository keeps them in memory,
+// and the durable memory repository also logs them to its directory.
+// The other repositories can't store the same token on more than one domain, so return ErrDomainsUnsupported.
+func NewDomainRepository(urlRepo UrlRepository) (DomainRepository, error) {
+	switch repo := urlRepo.(type) {
+	case *sqliteRepository:
+		return &sqlDomainRepo{db: repo.db}, nil
+	case *postgresRepository:
+		return &sqlDomainRepo{db: repo.db}, nil
+	case *memoryRepo:
+		return NewInMemoryDomainRepo(), nil
+	case *durableMemoryRepo:
+		return &durableDomainRepo{memoryDomainRepo: repo.domains, repo: repo}, nil
+	default:
+		return nil, ErrDomainsUnsupported
+	}
+}
+
+// sqlDomainRepo stores domains in the sqlite or postgres database.
+type sqlDomainRepo struct {
+	db *sqlx.DB
+}
+
+const domainColumns = `name, workspace_id, verification_token, verified_at, default_redirect, not_found_url, created_at`
+
+func (s *sqlDomainRepo) CreateDomain(ctx context.Context, domain *entity.Domain) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(`INSERT INTO domain (`+domainColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`),
+		domain.Name, domain.WorkspaceID, domain.VerificationToken, domain.VerifiedAt, domain.DefaultRedirect, domain.NotFoundUrl, domain.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDomainExists
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *sqlDomainRepo) FindDomain(ctx context.Context, name string) (*entity.Domain, error) {
+	domain := &entity.Domain{}
+
+	err := s.db.GetContext(ctx, domain, s.db.Rebind(`SELECT `+domainColumns+` FROM domain WHERE name = ?`), name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDomainNotFound
+		}
+
+		return nil, err
+	}
+
+	return domain, nil
+}
+
+func (s *sqlDomainRepo) ListDomains(ctx context.Context, workspaceID string) ([]entity.Domain, error) {
+	domains := []entity.Domain{}
+
+	err := s.db.SelectContext(ctx, &domains, s.db.Rebind(`SELECT `+domainColumns+` FROM domain WHERE workspace_id = ? ORDER BY name`), workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return domains, nil
+}
+
+func (s *sqlDomainRepo) UpdateDomain(ctx context.Context, domain *entity.Domain) error {
+	result, err := s.db.ExecContext(ctx, s.db.Rebind(`UPDATE domain SET verified_at = ?, default_redirect = ?, not_found_url = ? WHERE name = ? AND workspace_id = ?`),
+		domain.VerifiedAt, domain.DefaultRedirect, domain.NotFoundUrl, domain.Name, domain.WorkspaceID)
+	if err != nil {
+		return err
+	}
+
+	return expectDomainRow(result)
+}
+
+func (s *sqlDomainRepo) DeleteDomain(ctx context.Context, workspaceID, name string) error {
+	result, err := s.db.ExecContext(ctx, s.db.Rebind(`DELETE FROM domain WHERE name = ? AND workspace_id = ?`), name, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	return expectDomainRow(result)
+}
+
+func (s *sqlDomainRepo) DeleteUnverifiedDomain(ctx context.Context, name string, createdBefore time.Time) (bool, error) {
+	result, err := s.db.ExecContext(ctx, s.db.Rebind(`DELETE FROM domain WHERE name = ? AND verified_at IS NULL AND created_at < ?`), name, createdBefore)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// expectDomainRow returns ErrDomainNotFound if the statement didn't change a domain.
+func expectDomainRow(result sql.Result) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrDomainNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DomainRepository(t *testing.T) {
+	testCases := []struct {
+		name string
+		repo func(t *testing.T) DomainRepository
+	}{
+		{"Memory", func(t *testing.T) DomainRepository { return NewInMemoryDomainRepo() }},
+		{"Durable Memory", func(t *testing.T) DomainRepository {
+			urlRepo := newDurableRepo(t, t.TempDir())
+			t.Cleanup(func() { urlRepo.Close() })
+			repo, err := NewDomainRepository(urlRepo)
+			require.NoError(t, err)
+			return repo
+		}},
+		{"SQLite", func(t *testing.T) DomainRepository {
+			repo, err := NewDomainRepository(newSQLiteRepo(t))
+			require.NoError(t, err)
+			return repo
+		}},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			repo := test.repo(t)
+			ctx := context.Background()
+			createdAt := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+
+			domain := &entity.Domain{Name: "go.example.com", WorkspaceID: entity.DEFAULT_WORKSPACE, VerificationToken: "abc", CreatedAt: createdAt}
+			require.NoError(t, repo.CreateDomain(ctx, domain))
+			assert.ErrorIs(t, repo.CreateDomain(ctx, domain), ErrDomainExists)
+			require.NoError(t, repo.CreateDomain(ctx, &entity.Domain{Name: "a.example.com", WorkspaceID: entity.DEFAULT_WORKSPACE, VerificationToken: "def", CreatedAt: createdAt}))
+
+			found, err := repo.FindDomain(ctx, "go.example.com")
+			require.NoError(t, err)
+			assert.Equal(t, "abc", found.VerificationToken)
+			assert.False(t, found.Verified())
+
+			_, err = repo.FindDomain(ctx, "other.example.com")
+			assert.ErrorIs(t, err, ErrDomainNotFound)
+
+			verifiedAt := createdAt.Add(time.Hour)
+			found.VerifiedAt = &verifiedAt
+			found.DefaultRedirect = "https://example.com"
+			found.NotFoundUrl = "https://example.com/404"
+			require.NoError(t, repo.UpdateDomain(ctx, found))
+			assert.ErrorIs(t, repo.UpdateDomain(ctx, &entity.Domain{Name: "other.example.com"}), ErrDomainNotFound)
+			assert.ErrorIs(t, repo.UpdateDomain(ctx, &entity.Domain{Name: "go.example.com", WorkspaceID: "ws_1"}), ErrDomainNotFound)
+
+			domains, err := repo.ListDomains(ctx, entity.DEFAULT_WORKSPACE)
+			require.NoError(t, err)
+			require.Len(t, domains, 2)
+			assert.Equal(t, "a.example.com", domains[0].Name)
+			assert.Equal(t, "go.example.com", domains[1].Name)
+			assert.True(t, domains[1].VerifiedAt.Equal(verifiedAt))
+			assert.Equal(t, "https://example.com", domains[1].DefaultRedirect)
+			assert.Equal(t, "https://example.com/404", domains[1].NotFoundUrl)
+
+			domains, err = repo.ListDomains(ctx, "ws_1")
+			require.NoError(t, err)
+			assert.Empty(t, domains)
+
+			// only unverified domains added before the given time are deleted to make way for another workspace
+			deleted, err := repo.DeleteUnverifiedDomain(ctx, "go.example.com", createdAt.Add(time.Hour))
+			require.NoError(t, err)
+			assert.False(t, deleted)
+			deleted, err = repo.DeleteUnverifiedDomain(ctx, "a.example.com", createdAt)
+			require.NoError(t, err)
+			assert.False(t, deleted)
+			deleted, err = repo.DeleteUnverifiedDomain(ctx, "a.example.com", createdAt.Add(time.Hour))
+			require.NoError(t, err)
+			assert.True(t, deleted)
+			_, err = repo.FindDomain(ctx, "a.example.com")
+			assert.ErrorIs(t, err, ErrDomainNotFound)
+
+			assert.ErrorIs(t, repo.DeleteDomain(ctx, "ws_1", "go.example.com"), ErrDomainNotFound)
+			require.NoError(t, repo.DeleteDomain(ctx, entity.DEFAULT_WORKSPACE, "go.example.com"))
+			assert.ErrorIs(t, repo.DeleteDomain(ctx, entity.DEFAULT_WORKSPACE, "go.example.com"), ErrDomainNotFound)
+		})
+	}
+}
@@ -1,8 +1,47 @@
 package repository
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrUrlNotFound        = errors.New("url not found")
 	ErrTokenAlreadyExists = errors.New("token already exists")
+	ErrApiKeyNotFound     = errors.New("api key not found")
+	ErrApiKeyExists       = errors.New("api key already exists")
+	ErrApiKeysUnsupported = errors.New("api keys aren't supported by this database driver")
+
+	ErrWorkspaceNotFound     = errors.New("workspace not found")
+	ErrWorkspaceExists       = errors.New("workspace already exists")
+	ErrMembershipNotFound    = errors.New("membership not found")
+	ErrUserNotFound          = errors.New("user not found")
+	ErrMembershipExists      = errors.New("user is already a member of the workspace")
+	ErrLastOwner             = errors.New("a workspace must keep at least one owner")
+	ErrWorkspacesUnsupported = errors.New("workspaces aren't supported by this database driver")
+
+	ErrQuotaExceeded     = errors.New("quota exceeded")
+	ErrQuotasUnsupported = errors.New("quotas aren't supported by this database driver")
+
+	ErrAuditUnsupported = errors.New("the audit log isn't supported by this database driver")
+
+	ErrDomainNotFound     = errors.New("domain not found")
+	ErrDomainExists       = errors.New("domain already exists")
+	ErrDomainsUnsupported = errors.New("short domains aren't supported by this database driver")
+
+	ErrPrivacyJobNotFound = errors.New("privacy job not found")
+	ErrPrivacyUnsupported = errors.New("data protection requests aren't supported by this database driver")
 )
+
+// QuotaExceededError is returned by QuotaRepository.UseQuota when a period is already at its limit.
+type QuotaExceededError struct {
+	Period QuotaPeriod
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%v (%d uses in %s)", ErrQuotaExceeded, e.Period.Limit, e.Period.Key)
+}
+
+func (e *QuotaExceededError) Unwrap() error {
+	return ErrQuotaExceeded
+}
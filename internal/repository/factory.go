@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Jaytpa01/url-shortener-api/config"
+	"github.com/jmoiron/sqlx"
+)
+
+// The drivers that can be selected in config.DatabaseConfig
+const (
+	DriverSQLite   = "sqlite"
+	DriverMemory   = "memory"
+	DriverBolt     = "bolt"
+	DriverPostgres = "postgres"
+	DriverRedis    = "redis"
+)
+
+// DefaultSQLiteDSN is where the sqlite driver stores urls when database.dsn isn't set
+const DefaultSQLiteDSN = "./db/url.db"
+
+// ErrUnknownDriver is returned when the configured driver isn't one of the drivers above
+var ErrUnknownDriver = errors.New("unknown database driver")
+
+// WithDefaults returns cfg with the default driver, sqlite, if it has none, and the default sqlite DSN if that driver has no DSN.
+func WithDefaults(cfg config.DatabaseConfig) config.DatabaseConfig {
+	if cfg.Driver == "" {
+		cfg.Driver = DriverSQLite
+	}
+	if cfg.Driver == DriverSQLite && cfg.DSN == "" {
+		cfg.DSN = DefaultSQLiteDSN
+	}
+
+	return cfg
+}
+
+// NewUrlRepository builds the UrlRepository for the configured driver.
+// If the repository implements io.Closer, it should be closed on shutdown.
+func NewUrlRepository(cfg config.DatabaseConfig) (UrlRepository, error) {
+	cfg = WithDefaults(cfg)
+	driver := cfg.Driver
+
+	// only the memory driver can run without somewhere to store urls
+	if cfg.DSN == "" && driver != DriverMemory {
+		return nil, fmt.Errorf("database.dsn must be set to use the %s driver", driver)
+	}
+
+	switch driver {
+	case DriverSQLite:
+		return NewSQLiteRepository(cfg.DSN, cfg.Pool)
+	case DriverMemory:
+		if cfg.DSN == "" {
+			return NewInMemoryRepo(), nil
+		}
+
+		return NewDurableInMemoryRepo(DurableConfig{
+			Dir:              cfg.DSN,
+			Sync:             SyncPolicy(cfg.Memory.Sync),
+			SyncInterval:     cfg.Memory.SyncInterval,
+			SnapshotInterval: cfg.Memory.SnapshotInterval,
+		})
+	case DriverBolt:
+		return NewBoltRepository(cfg.DSN)
+	case DriverPostgres:
+		return NewPostgresRepository(cfg.DSN, cfg.Pool)
+	case DriverRedis:
+		return NewRedisRepository(cfg.DSN)
+	default:
+		return nil, fmt.Errorf("%w (%s), expected one of %s, %s, %s, %s or %s", ErrUnknownDriver, driver, DriverSQLite, DriverMemory, DriverBolt, DriverPostgres, DriverRedis)
+	}
+}
+
+// applyPoolConfig applies the pool configuration to db
+func applyPoolConfig(db *sqlx.DB, pool config.PoolConfig) {
+	if pool.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(pool.MaxOpenConns)
+	}
+	if pool.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(pool.MaxIdleConns)
+	}
+	if pool.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	}
+	if pool.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+	}
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jaytpa01/url-shortener-api/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewUrlRepository(t *testing.T) {
+	dir := t.TempDir()
+
+	testCases := []struct {
+		name        string
+		cfg         config.DatabaseConfig
+		expectedErr bool
+	}{
+		{"Memory", config.DatabaseConfig{Driver: DriverMemory}, false},
+		{"Durable Memory", config.DatabaseConfig{Driver: DriverMemory, DSN: filepath.Join(dir, "wal")}, false},
+		{"Bolt", config.DatabaseConfig{Driver: DriverBolt, DSN: filepath.Join(dir, "url.bolt")}, false},
+		{"SQLite", config.DatabaseConfig{Driver: DriverSQLite, DSN: filepath.Join(dir, "url.db")}, false},
+		{"Default Driver Is SQLite", config.DatabaseConfig{DSN: filepath.Join(dir, "default.db")}, false},
+		{"Missing DSN", config.DatabaseConfig{Driver: DriverBolt}, true},
+		{"Missing Postgres DSN", config.DatabaseConfig{Driver: DriverPostgres}, true},
+		{"Unknown Driver", config.DatabaseConfig{Driver: "mongodb", DSN: "mongodb://localhost"}, true},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			repo, err := NewUrlRepository(test.cfg)
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.NotNil(t, repo)
+			if closer, ok := repo.(io.Closer); ok {
+				assert.NoError(t, closer.Close())
+			}
+		})
+	}
+}
+
+func Test_WithDefaults(t *testing.T) {
+	assert.Equal(t, config.DatabaseConfig{Driver: DriverSQLite, DSN: DefaultSQLiteDSN}, WithDefaults(config.DatabaseConfig{}))
+	assert.Equal(t, config.DatabaseConfig{Driver: DriverSQLite, DSN: DefaultSQLiteDSN}, WithDefaults(config.DatabaseConfig{Driver: DriverSQLite}))
+	assert.Equal(t, config.DatabaseConfig{Driver: DriverSQLite, DSN: "url.db"}, WithDefaults(config.DatabaseConfig{DSN: "url.db"}))
+	assert.Equal(t, config.DatabaseConfig{Driver: DriverMemory}, WithDefaults(config.DatabaseConfig{Driver: DriverMemory}))
+}
+
+func Test_NewUrlRepository_UnknownDriver(t *testing.T) {
+	_, err := NewUrlRepository(config.DatabaseConfig{Driver: "mongodb", DSN: "mongodb://localhost"})
+	assert.ErrorIs(t, err, ErrUnknownDriver)
+	assert.EqualError(t, err, "unknown database driver (mongodb), expected one of sqlite, memory, bolt, postgres or redis")
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/Jaytpa01/url-shortener-api/internal/entity"
 )
@@ -12,5 +13,121 @@ type UrlRepository interface {
 	FindByToken(ctx context.Context, token string) (*entity.Url, error)
 	Create(ctx context.Context, url *entity.Url) error
 	Update(ctx context.Context, url *entity.Url) error
-	GetAllUrls(ctx context.Context) ([]entity.Url, error)
+	Delete(ctx context.Context, token string) error
+	// ListUrls returns a page of at most limit urls matching the filter, starting after cursor.
+	// An empty cursor starts at the beginning. The returned cursor fetches the next page, and is empty on the last page.
+	ListUrls(ctx context.Context, filter ListFilter, cursor string, limit int) ([]entity.Url, string, error)
+	Count(ctx context.Context) (int, error)
+}
+
+// VisitIncrementer is implemented by repositories that can increment the visits of a url atomically.
+// If the repository implements it, the service uses it rather than updating the whole url, so concurrent visits aren't lost.
+type VisitIncrementer interface {
+	IncrementVisits(ctx context.Context, token string) (int, error)
+}
+
+// DomainVisitIncrementer is implemented by repositories that can increment the visits of a url on any short domain atomically.
+type DomainVisitIncrementer interface {
+	IncrementDomainVisits(ctx context.Context, domain, token string) (int, error)
+}
+
+// DomainUrlRepository is implemented by repositories that can store urls on short domains other than the default one.
+// The same token can be used on each domain. Create and Update use the url's domain, while the token based methods
+// of UrlRepository act on the default domain.
+type DomainUrlRepository interface {
+	FindByDomainToken(ctx context.Context, domain, token string) (*entity.Url, error)
+	DeleteFromDomain(ctx context.Context, domain, token string) error
+}
+
+// DomainRepository stores the short domains registered by workspaces. Each domain belongs to one workspace at a time.
+type DomainRepository interface {
+	CreateDomain(ctx context.Context, domain *entity.Domain) error
+	FindDomain(ctx context.Context, name string) (*entity.Domain, error)
+	// ListDomains returns the domains of a workspace, ordered by name.
+	ListDomains(ctx context.Context, workspaceID string) ([]entity.Domain, error)
+	// UpdateDomain saves the domain's verification time, default redirect and not found url.
+	// It returns ErrDomainNotFound unless the domain belongs to domain.WorkspaceID.
+	UpdateDomain(ctx context.Context, domain *entity.Domain) error
+	// DeleteDomain deletes the domain. It returns ErrDomainNotFound unless the domain belongs to the workspace.
+	DeleteDomain(ctx context.Context, workspaceID, name string) error
+	// DeleteUnverifiedDomain deletes the domain if it was never verified and was added before createdBefore,
+	// so another workspace can add it. It reports whether it deleted the domain.
+	DeleteUnverifiedDomain(ctx context.Context, name string, createdBefore time.Time) (bool, error)
+}
+
+// ApiKeyRepository stores api keys. Keys are found by the hash of the key, as the key itself is never stored.
+type ApiKeyRepository interface {
+	CreateApiKey(ctx context.Context, key *entity.ApiKey) error
+	FindApiKeyByHash(ctx context.Context, hash string) (*entity.ApiKey, error)
+	FindApiKey(ctx context.Context, id string) (*entity.ApiKey, error)
+	ListApiKeys(ctx context.Context) ([]entity.ApiKey, error)
+	// RevokeApiKey marks the key as revoked at the given time. Revoking a revoked key keeps the original time.
+	RevokeApiKey(ctx context.Context, id string, at time.Time) error
+	// DeleteApiKey deletes the key, for an erasure request. Deleting a key that doesn't exist isn't an error.
+	DeleteApiKey(ctx context.Context, id string) error
+}
+
+// WorkspaceRepository stores workspaces, their members and the members' roles.
+// Members are users, which are created when they're first added to a workspace.
+type WorkspaceRepository interface {
+	// CreateWorkspace creates the workspace with owner as its first member.
+	CreateWorkspace(ctx context.Context, workspace *entity.Workspace, owner *entity.Membership) error
+	FindWorkspace(ctx context.Context, id string) (*entity.Workspace, error)
+	// SaveUser creates the user, or updates their name if they already exist and a name is given.
+	SaveUser(ctx context.Context, user *entity.User) error
+	FindUser(ctx context.Context, id string) (*entity.User, error)
+	// DeleteUser deletes the user and their memberships, for an erasure request, even if that leaves a workspace without an owner.
+	// Deleting a user that doesn't exist isn't an error.
+	DeleteUser(ctx context.Context, id string) error
+
+	FindMembership(ctx context.Context, workspaceID, userID string) (*entity.Membership, error)
+	// ListMemberships returns the memberships of a user, ie. the workspaces they can use.
+	ListMemberships(ctx context.Context, userID string) ([]entity.Membership, error)
+	// ListMembers returns the memberships of a workspace.
+	ListMembers(ctx context.Context, workspaceID string) ([]entity.Membership, error)
+	AddMember(ctx context.Context, membership *entity.Membership) error
+	// UpdateMemberRole changes a member's role. It returns ErrLastOwner rather than demote the workspace's only owner.
+	UpdateMemberRole(ctx context.Context, workspaceID, userID string, role entity.Role) error
+	// RemoveMember removes a member. It returns ErrLastOwner rather than remove the workspace's only owner.
+	RemoveMember(ctx context.Context, workspaceID, userID string) error
+}
+
+// QuotaRepository counts the urls each subject, an api key, user or anonymous ip, has created in a period such as a day.
+type QuotaRepository interface {
+	// UseQuota counts one use by the subject in each of the periods, and returns how many uses each period has had.
+	// If any period is already at its limit, nothing is counted and a *QuotaExceededError is returned.
+	UseQuota(ctx context.Context, subject string, periods []QuotaPeriod) ([]int, error)
+	// ListQuotaUsage returns the uses counted for the subject, ordered by period.
+	ListQuotaUsage(ctx context.Context, subject string) ([]entity.QuotaUsage, error)
+	// DeleteQuotaUsage forgets the uses counted for the subject, for an erasure request.
+	DeleteQuotaUsage(ctx context.Context, subject string) error
+}
+
+// AuditRepository stores the audit log. It's append-only, so entries can't be removed once appended,
+// and are only changed to anonymize the subject of an erasure request.
+type AuditRepository interface {
+	// AppendAuditEntry appends the entry, setting its id.
+	AppendAuditEntry(ctx context.Context, entry *entity.AuditEntry) error
+	// ListAuditEntries returns a page of at most limit entries matching the filter, oldest first, starting after cursor.
+	// An empty cursor starts at the beginning. The returned cursor fetches the next page, and is empty on the last page.
+	ListAuditEntries(ctx context.Context, filter AuditFilter, cursor string, limit int) ([]entity.AuditEntry, string, error)
+	// AnonymizeAuditEntries replaces subject with pseudonym wherever it's the actor or target of an entry,
+	// clears the ip of the entries it made, and removes the entity.PERSONAL_AUDIT_FIELDS of the entries that target it.
+	// It returns how many entries it changed.
+	AnonymizeAuditEntries(ctx context.Context, subject, pseudonym string) (int, error)
+}
+
+// PrivacyRepository stores the jobs that answer data protection requests, and anonymizes the links of their subjects.
+type PrivacyRepository interface {
+	CreatePrivacyJob(ctx context.Context, job *entity.PrivacyJob) error
+	FindPrivacyJob(ctx context.Context, id string) (*entity.PrivacyJob, error)
+	// UpdatePrivacyJob saves the job's status, error, counts, export and times.
+	UpdatePrivacyJob(ctx context.Context, job *entity.PrivacyJob) error
+	// ListUnfinishedPrivacyJobs returns the jobs that are pending or running, oldest first.
+	ListUnfinishedPrivacyJobs(ctx context.Context) ([]entity.PrivacyJob, error)
+	// AnonymizePrivacyJobs replaces subject with pseudonym in the jobs for the subject, and removes their exported data.
+	AnonymizePrivacyJobs(ctx context.Context, subject, pseudonym string) error
+	// AnonymizeOwner makes the links owned by owner anonymous, as if they were created without authenticating.
+	// It returns how many links it changed.
+	AnonymizeOwner(ctx context.Context, owner string) (int, error)
 }
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+)
+
+// SortOrder is the order ListUrls returns urls in.
+type SortOrder string
+
+const (
+	SortCreatedDesc SortOrder = "created_desc" // newest first, the default
+	SortCreatedAsc  SortOrder = "created_asc"  // oldest first
+	SortTokenAsc    SortOrder = "token_asc"    // by token, which is stable while urls are being created
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("unknown sort order")
+)
+
+// ListFilter narrows down and orders the urls returned by ListUrls.
+// Zero values don't filter.
+type ListFilter struct {
+	CreatedAfter  time.Time // only urls created at or after this time
+	CreatedBefore time.Time // only urls created before this time
+	Domain        string    // only urls whose target host is this domain, or a subdomain of it
+	WorkspaceID   string    // only urls in this workspace
+	Owner         string    // only urls owned by this api key or user id
+	Search        string    // only urls whose token or target url contains this, ignoring case
+	Sort          SortOrder
+}
+
+// listCursor is the position of the last url of a page. The next page starts after it.
+type listCursor struct {
+	Sort      SortOrder `json:"s"`
+	CreatedAt time.Time `json:"c,omitempty"`
+	Token     string    `json:"t"`
+	Domain    string    `json:"d,omitempty"`
+}
+
+// TokenCursor returns a cursor for ListUrls sorted by SortTokenAsc, that starts after token.
+func TokenCursor(token string) string {
+	return encodeCursor(SortTokenAsc, &entity.Url{Token: token})
+}
+
+// encodeCursor returns an opaque cursor for the position of url.
+func encodeCursor(sort SortOrder, url *entity.Url) string {
+	c := listCursor{Sort: sort, Token: url.Token, Domain: url.Domain}
+	if sort != SortTokenAsc {
+		c.CreatedAt = url.CreatedAt.UTC()
+	}
+
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor decodes a cursor made by encodeCursor. An empty cursor is the start of the list, and returns nil.
+// A cursor made for a different sort order is invalid.
+func decodeCursor(cursor string, sort SortOrder) (*listCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	c := &listCursor{}
+	if err := json.Unmarshal(data, c); err != nil || c.Sort != sort {
+		return nil, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// normalise fills in the default sort order, and checks the filter is valid.
+func (f *ListFilter) normalise() error {
+	if f.Sort == "" {
+		f.Sort = SortCreatedDesc
+	}
+
+	switch f.Sort {
+	case SortCreatedDesc, SortCreatedAsc, SortTokenAsc:
+	default:
+		return fmt.Errorf("%w (%s), expected one of %s, %s or %s", ErrInvalidSort, f.Sort, SortCreatedDesc, SortCreatedAsc, SortTokenAsc)
+	}
+
+	f.Domain = strings.ToLower(strings.TrimSpace(f.Domain))
+	f.Search = strings.ToLower(strings.TrimSpace(f.Search))
+	return nil
+}
+
+// matches reports whether url passes the filter.
+func (f *ListFilter) matches(u *entity.Url) bool {
+	if !f.CreatedAfter.IsZero() && u.CreatedAt.Before(f.CreatedAfter) {
+		return false
+	}
+
+	if !f.CreatedBefore.IsZero() && !u.CreatedAt.Before(f.CreatedBefore) {
+		return false
+	}
+
+	if f.WorkspaceID != "" && u.Workspace() != f.WorkspaceID {
+		return false
+	}
+
+	if f.Owner != "" && u.Owner != f.Owner {
+		return false
+	}
+
+	if f.Search != "" && !strings.Contains(strings.ToLower(u.Token), f.Search) && !strings.Contains(strings.ToLower(u.TargetUrl), f.Search) {
+		return false
+	}
+
+	if f.Domain != "" {
+		target, err := url.Parse(u.TargetUrl)
+		if err != nil {
+			return false
+		}
+
+		host := strings.ToLower(target.Hostname())
+		if host != f.Domain && !strings.HasSuffix(host, "."+f.Domain) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sortsBefore reports whether a comes before b in the sort order.
+// Urls created at the same time are ordered by token, and urls with the same token by domain, so the order is always total.
+func sortsBefore(order SortOrder, a, b *entity.Url) bool {
+	switch order {
+	case SortCreatedAsc:
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+	case SortCreatedDesc:
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		if a.Token != b.Token {
+			return a.Token > b.Token
+		}
+		return a.Domain > b.Domain
+	}
+
+	if a.Token != b.Token {
+		return a.Token < b.Token
+	}
+	return a.Domain < b.Domain
+}
+
+// before reports whether the cursor comes before url, ie. url is on a later page.
+func (c *listCursor) before(u *entity.Url) bool {
+	return sortsBefore(c.Sort, &entity.Url{Token: c.Token, Domain: c.Domain, CreatedAt: c.CreatedAt}, u)
+}
+
+// listSlice returns a page of urls from every url in a repository, for repositories that can't filter or sort themselves.
+func listSlice(urls []entity.Url, filter ListFilter, cursor string, limit int) ([]entity.Url, string, error) {
+	if err := filter.normalise(); err != nil {
+		return nil, "", err
+	}
+
+	after, err := decodeCursor(cursor, filter.Sort)
+	if err != nil {
+		return nil, "", err
+	}
+
+	matched := []entity.Url{}
+	for i := range urls {
+		if filter.matches(&urls[i]) && (after == nil || after.before(&urls[i])) {
+			matched = append(matched, urls[i])
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return sortsBefore(filter.Sort, &matched[i], &matched[j])
+	})
+
+	return page(matched, filter.Sort, limit)
+}
+
+// createdKeyLayout formats creation times at a fixed width in UTC, so they sort as strings in time order.
+const createdKeyLayout = "2006-01-02T15:04:05.000000000Z"
+
+// createdKey returns t as a key that sorts in time order, for indexes ordered by creation time.
+func createdKey(t time.Time) string {
+	return t.UTC().Format(createdKeyLayout)
+}
+
+// page trims urls to limit, returning a cursor for the next page if there were more than limit urls.
+// Repositories fetch one url more than limit, so they know whether there's another page.
+func page(urls []entity.Url, sort SortOrder, limit int) ([]entity.Url, string, error) {
+	if len(urls) <= limit {
+		return urls, "", nil
+	}
+
+	urls = urls[:limit]
+	return urls, encodeCursor(sort, &urls[limit-1]), nil
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/jmoiron/sqlx"
+)
+
+// listSQL returns a page of urls from the url table, for the sqlite and postgres repositories.
+// The domain filter is narrowed down with LIKE, then checked exactly, so it may take more than one query to fill a page.
+func listSQL(ctx context.Context, db *sqlx.DB, filter ListFilter, cursor string, limit int) ([]entity.Url, string, error) {
+	if err := filter.normalise(); err != nil {
+		return nil, "", err
+	}
+
+	after, err := decodeCursor(cursor, filter.Sort)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// one more than limit, so we know if there's another page
+	batch := limit + 1
+
+	matched := []entity.Url{}
+	for {
+		query, args := listQuery(filter, after, batch)
+
+		urls := []entity.Url{}
+		if err := db.SelectContext(ctx, &urls, db.Rebind(query), args...); err != nil {
+			return nil, "", err
+		}
+
+		for i := range urls {
+			if filter.matches(&urls[i]) && len(matched) < batch {
+				matched = append(matched, urls[i])
+			}
+		}
+
+		if len(urls) < batch || len(matched) == batch {
+			return page(matched, filter.Sort, limit)
+		}
+
+		last := urls[len(urls)-1]
+		after = &listCursor{Sort: filter.Sort, CreatedAt: last.CreatedAt, Token: last.Token, Domain: last.Domain}
+	}
+}
+
+// deleteSQL deletes the url with the token on the domain from the url table, for the sqlite and postgres repositories.
+func deleteSQL(ctx context.Context, db *sqlx.DB, domain, token string) error {
+	result, err := db.ExecContext(ctx, db.Rebind(`DELETE FROM url WHERE domain = ? AND token = ?`), domain, token)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUrlNotFound
+	}
+
+	return nil
+}
+
+// incrementVisitsSQL increments the visits of the url in a single statement, so it can't undo a concurrent update.
+func incrementVisitsSQL(ctx context.Context, db *sqlx.DB, domain, token string) (int, error) {
+	var visits int
+
+	err := db.GetContext(ctx, &visits, db.Rebind(`UPDATE url SET visits = visits + 1 WHERE domain = ? AND token = ? RETURNING visits`), domain, token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrUrlNotFound
+		}
+
+		return 0, err
+	}
+
+	return visits, nil
+}
+
+// listQuery builds the query for a batch of urls after the cursor.
+func listQuery(filter ListFilter, after *listCursor, limit int) (string, []interface{}) {
+	where := []string{}
+	args := []interface{}{}
+
+	if !filter.CreatedAfter.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, filter.CreatedAfter.UTC())
+	}
+
+	if !filter.CreatedBefore.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, filter.CreatedBefore.UTC())
+	}
+
+	if filter.WorkspaceID != "" {
+		where = append(where, "workspace_id = ?")
+		args = append(args, filter.WorkspaceID)
+	}
+
+	if filter.Owner != "" {
+		where = append(where, "owner = ?")
+		args = append(args, filter.Owner)
+	}
+
+	if filter.Domain != "" {
+		where = append(where, `LOWER(target_url) LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(filter.Domain)+"%")
+	}
+
+	if filter.Search != "" {
+		where = append(where, `(LOWER(token) LIKE ? ESCAPE '\' OR LOWER(target_url) LIKE ? ESCAPE '\')`)
+		pattern := "%" + escapeLike(filter.Search) + "%"
+		args = append(args, pattern, pattern)
+	}
+
+	var order string
+	switch filter.Sort {
+	case SortCreatedAsc:
+		order = "created_at ASC, token ASC, domain ASC"
+		if after != nil {
+			where = append(where, "(created_at > ? OR (created_at = ? AND (token > ? OR (token = ? AND domain > ?))))")
+			args = append(args, after.CreatedAt, after.CreatedAt, after.Token, after.Token, after.Domain)
+		}
+	case SortCreatedDesc:
+		order = "created_at DESC, token DESC, domain DESC"
+		if after != nil {
+			where = append(where, "(created_at < ? OR (created_at = ? AND (token < ? OR (token = ? AND domain < ?))))")
+			args = append(args, after.CreatedAt, after.CreatedAt, after.Token, after.Token, after.Domain)
+		}
+	case SortTokenAsc:
+		order = "token ASC, domain ASC"
+		if after != nil {
+			where = append(where, "(token > ? OR (token = ? AND domain > ?))")
+			args = append(args, after.Token, after.Token, after.Domain)
+		}
+	}
+
+	query := "SELECT * FROM url"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY " + order + " LIMIT ?"
+	args = append(args, limit)
+
+	return query, args
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
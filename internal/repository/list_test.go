@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var listEpoch = time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+
+// listFixture returns urls with tokens 000..n-1, where every pair of urls shares a creation time,
+// so ties are broken by token.
+func listFixture(n int) []entity.Url {
+	urls := []entity.Url{}
+	for i := 0; i < n; i++ {
+		urls = append(urls, entity.Url{
+			Token:     fmt.Sprintf("%03d", i),
+			TargetUrl: fmt.Sprintf("https://site%d.example.com/page", i%3),
+			CreatedAt: listEpoch.Add(time.Duration(i/2) * time.Hour),
+		})
+	}
+	return urls
+}
+
+// listAll pages through list with the given page size, returning every token in order.
+func listAll(t *testing.T, list func(cursor string) ([]entity.Url, string, error), limit int) []string {
+	tokens := []string{}
+	cursor := ""
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 100, "too many pages")
+
+		urls, next, err := list(cursor)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(urls), limit)
+
+		for _, url := range urls {
+			tokens = append(tokens, url.Token)
+		}
+
+		if next == "" {
+			return tokens
+		}
+		cursor = next
+	}
+}
+
+func Test_ListSlice_Sorts(t *testing.T) {
+	urls := listFixture(7)
+
+	testCases := []struct {
+		sort     SortOrder
+		expected []string
+	}{
+		{"", []string{"006", "005", "004", "003", "002", "001", "000"}},
+		{SortCreatedDesc, []string{"006", "005", "004", "003", "002", "001", "000"}},
+		{SortCreatedAsc, []string{"000", "001", "002", "003", "004", "005", "006"}},
+		{SortTokenAsc, []string{"000", "001", "002", "003", "004", "005", "006"}},
+	}
+
+	for _, test := range testCases {
+		for _, limit := range []int{1, 2, 3, 7, 10} {
+			t.Run(fmt.Sprintf("%s/%d", test.sort, limit), func(t *testing.T) {
+				tokens := listAll(t, func(cursor string) ([]entity.Url, string, error) {
+					return listSlice(urls, ListFilter{Sort: test.sort}, cursor, limit)
+				}, limit)
+				assert.Equal(t, test.expected, tokens)
+			})
+		}
+	}
+}
+
+func Test_ListSlice_Filters(t *testing.T) {
+	urls := append(listFixture(6),
+		entity.Url{Token: "aaa", TargetUrl: "https://notexample.com", CreatedAt: listEpoch},
+		entity.Url{Token: "bbb", TargetUrl: "https://EXAMPLE.com/", CreatedAt: listEpoch},
+	)
+
+	testCases := []struct {
+		name     string
+		filter   ListFilter
+		expected []string
+	}{
+		{"Domain", ListFilter{Domain: "example.com", Sort: SortTokenAsc}, []string{"000", "001", "002", "003", "004", "005", "bbb"}},
+		{"Subdomain", ListFilter{Domain: "Site1.example.com", Sort: SortTokenAsc}, []string{"001", "004"}},
+		{"Created After", ListFilter{CreatedAfter: listEpoch.Add(time.Hour), Sort: SortTokenAsc}, []string{"002", "003", "004", "005"}},
+		{"Created Before", ListFilter{CreatedBefore: listEpoch.Add(time.Hour), Sort: SortTokenAsc}, []string{"000", "001", "aaa", "bbb"}},
+		{"Created Range", ListFilter{CreatedAfter: listEpoch.Add(time.Hour), CreatedBefore: listEpoch.Add(2 * time.Hour), Sort: SortTokenAsc}, []string{"002", "003"}},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			tokens := listAll(t, func(cursor string) ([]entity.Url, string, error) {
+				return listSlice(urls, test.filter, cursor, 2)
+			}, 2)
+			assert.Equal(t, test.expected, tokens)
+		})
+	}
+}
+
+func Test_ListSlice_InvalidCursorAndSort(t *testing.T) {
+	urls := listFixture(3)
+
+	_, _, err := listSlice(urls, ListFilter{}, "not a cursor!", 10)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	// a cursor is only valid for the sort order it was made for
+	_, next, err := listSlice(urls, ListFilter{Sort: SortCreatedAsc}, "", 1)
+	require.NoError(t, err)
+	_, _, err = listSlice(urls, ListFilter{Sort: SortTokenAsc}, next, 1)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	_, _, err = listSlice(urls, ListFilter{Sort: "visits"}, "", 10)
+	assert.ErrorIs(t, err, ErrInvalidSort)
+}
+
+func Test_ListSlice_TokenCursor(t *testing.T) {
+	urls, _, err := listSlice(listFixture(5), ListFilter{Sort: SortTokenAsc}, TokenCursor("002"), 10)
+	require.NoError(t, err)
+	require.Len(t, urls, 2)
+	assert.Equal(t, "003", urls[0].Token)
+	assert.Equal(t, "004", urls[1].Token)
+}
+
+func Test_ListSQL(t *testing.T) {
+	testListUrls(t, newSQLiteRepo(t))
+}
+
+// testListUrls checks that paging through the repository's urls agrees with the in memory implementation.
+func testListUrls(t *testing.T, repo UrlRepository) {
+	ctx := context.Background()
+	urls := listFixture(9)
+	for i := range urls {
+		require.NoError(t, repo.Create(ctx, &urls[i]))
+	}
+
+	testCases := []struct {
+		name   string
+		filter ListFilter
+	}{
+		{"Created Desc", ListFilter{}},
+		{"Created Asc", ListFilter{Sort: SortCreatedAsc}},
+		{"Token Asc", ListFilter{Sort: SortTokenAsc}},
+		{"Domain", ListFilter{Domain: "site2.example.com"}},
+		{"Created Range", ListFilter{CreatedAfter: listEpoch.Add(time.Hour), CreatedBefore: listEpoch.Add(3 * time.Hour), Sort: SortCreatedAsc}},
+		{"Created Range Desc", ListFilter{CreatedAfter: listEpoch.Add(time.Hour), CreatedBefore: listEpoch.Add(3 * time.Hour)}},
+	}
+
+	for _, test := range testCases {
+		for _, limit := range []int{1, 2, 4, 20} {
+			t.Run(fmt.Sprintf("%s/%d", test.name, limit), func(t *testing.T) {
+				expected := listAll(t, func(cursor string) ([]entity.Url, string, error) {
+					return listSlice(urls, test.filter, cursor, limit)
+				}, limit)
+
+				tokens := listAll(t, func(cursor string) ([]entity.Url, string, error) {
+					return repo.ListUrls(ctx, test.filter, cursor, limit)
+				}, limit)
+
+				assert.Equal(t, expected, tokens)
+			})
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+)
+
+// the operations on domains, workspaces and the audit log of a durable in memory repository
+const (
+	opDomainCreate    = "domain_create"
+	opDomainUpdate    = "domain_update"
+	opDomainDelete    = "domain_delete"
+	opDomainExpire    = "domain_delete_unverified"
+	opWorkspace       = "workspace" // a workspace in a snapshot, which may have lost its owners
+	opWorkspaceCreate = "workspace_create"
+	opUserSave        = "user_save"
+	opUserDelete      = "user_delete"
+	opMemberAdd       = "member_add"
+	opMemberUpdate    = "member_update"
+	opMemberRemove    = "member_remove"
+	opAuditAppend     = "audit_append"
+	opAuditAnonymize  = "audit_anonymize"
+)
+
+// durableDomainRepo is the DomainRepository of a durable in memory repository.
+// Domains are read from memory, and changed through the repository's log.
+type durableDomainRepo struct {
+	*memoryDomainRepo
+	repo *durableMemoryRepo
+}
+
+// CreateDomain is a durable in memory implementation of DomainRepository.CreateDomain
+func (r *durableDomainRepo) CreateDomain(ctx context.Context, domain *entity.Domain) error {
+	return r.repo.commit(ctx, &walRecord{Op: opDomainCreate, Domain: domain})
+}
+
+// UpdateDomain is a durable in memory implementation of DomainRepository.UpdateDomain
+func (r *durableDomainRepo) UpdateDomain(ctx context.Context, domain *entity.Domain) error {
+	return r.repo.commit(ctx, &walRecord{Op: opDomainUpdate, Domain: domain})
+}
+
+// DeleteDomain is a durable in memory implementation of DomainRepository.DeleteDomain
+func (r *durableDomainRepo) DeleteDomain(ctx context.Context, workspaceID, name string) error {
+	return r.repo.commit(ctx, &walRecord{Op: opDomainDelete, Domain: &entity.Domain{Name: name, WorkspaceID: workspaceID}})
+}
+
+// DeleteUnverifiedDomain is a durable in memory implementation of DomainRepository.DeleteUnverifiedDomain.
+// The record's domain is created at createdBefore.
+func (r *durableDomainRepo) DeleteUnverifiedDomain(ctx context.Context, name string, createdBefore time.Time) (bool, error) {
+	record := &walRecord{Op: opDomainExpire, Domain: &entity.Domain{Name: name, CreatedAt: createdBefore}}
+	if err := r.repo.commit(ctx, record); err != nil {
+		return false, err
+	}
+
+	return record.changed > 0, nil
+}
+
+// durableWorkspaceRepo is the WorkspaceRepository of a durable in memory repository.
+// Workspaces, users and memberships are read from memory, and changed through the repository's log.
+type durableWorkspaceRepo struct {
+	*memoryWorkspaceRepo
+	repo *durableMemoryRepo
+}
+
+// CreateWorkspace is a durable in memory implementation of WorkspaceRepository.CreateWorkspace
+func (r *durableWorkspaceRepo) CreateWorkspace(ctx context.Context, workspace *entity.Workspace, owner *entity.Membership) error {
+	return r.repo.commit(ctx, &walRecord{Op: opWorkspaceCreate, Workspace: workspace, Membership: owner})
+}
+
+// SaveUser is a durable in memory implementation of WorkspaceRepository.SaveUser
+func (r *durableWorkspaceRepo) SaveUser(ctx context.Context, user *entity.User) error {
+	return r.repo.commit(ctx, &walRecord{Op: opUserSave, User: user})
+}
+
+// DeleteUser is a durable in memory implementation of WorkspaceRepository.DeleteUser
+func (r *durableWorkspaceRepo) DeleteUser(ctx context.Context, id string) error {
+	return r.repo.commit(ctx, &walRecord{Op: opUserDelete, User: &entity.User{ID: id}})
+}
+
+// AddMember is a durable in memory implementation of WorkspaceRepository.AddMember
+func (r *durableWorkspaceRepo) AddMember(ctx context.Context, membership *entity.Membership) error {
+	return r.repo.commit(ctx, &walRecord{Op: opMemberAdd, Membership: membership})
+}
+
+// UpdateMemberRole is a durable in memory implementation of WorkspaceRepository.UpdateMemberRole
+func (r *durableWorkspaceRepo) UpdateMemberRole(ctx context.Context, workspaceID, userID string, role entity.Role) error {
+	return r.repo.commit(ctx, &walRecord{Op: opMemberUpdate, Membership: &entity.Membership{WorkspaceID: workspaceID, UserID: userID, Role: role}})
+}
+
+// RemoveMember is a durable in memory implementation of WorkspaceRepository.RemoveMember
+func (r *durableWorkspaceRepo) RemoveMember(ctx context.Context, workspaceID, userID string) error {
+	return r.repo.commit(ctx, &walRecord{Op: opMemberRemove, Membership: &entity.Membership{WorkspaceID: workspaceID, UserID: userID}})
+}
+
+// durableAuditRepo is the AuditRepository of a durable in memory repository.
+// The audit log is read from memory, and appended to and anonymized through the repository's log.
+type durableAuditRepo struct {
+	*memoryAuditRepo
+	repo *durableMemoryRepo
+}
+
+// AppendAuditEntry is a durable in memory implementation of AuditRepository.AppendAuditEntry.
+// Entry ids are positions in the log, so replaying the entries in order gives them the same ids.
+func (r *durableAuditRepo) AppendAuditEntry(ctx context.Context, entry *entity.AuditEntry) error {
+	return r.repo.commit(ctx, &walRecord{Op: opAuditAppend, Audit: entry})
+}
+
+// AnonymizeAuditEntries is a durable in memory implementation of AuditRepository.AnonymizeAuditEntries
+func (r *durableAuditRepo) AnonymizeAuditEntries(ctx context.Context, subject, pseudonym string) (int, error) {
+	record := &walRecord{Op: opAuditAnonymize, Subject: subject, Pseudonym: pseudonym}
+	if err := r.repo.commit(ctx, record); err != nil {
+		return 0, err
+	}
+
+	return record.changed, nil
+}
+
+// commit checks the context, then logs and applies the record, returning the error of its operation.
+// A failed operation is still logged, and fails the same way when it's replayed.
+func (r *durableMemoryRepo) commit(ctx context.Context, record *walRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.write(record)
+}
+
+// applyState applies a record to the domains, workspaces or audit log. It returns the error of the record's operation,
+// such as ErrDomainExists, or ErrCorruptLog if the record is missing what its operation needs.
+func (r *durableMemoryRepo) applyState(record *walRecord) error {
+	ctx := context.Background()
+
+	var missing bool
+	switch record.Op {
+	case opDomainCreate, opDomainUpdate, opDomainDelete, opDomainExpire:
+		missing = record.Domain == nil
+	case opWorkspace:
+		missing = record.Workspace == nil
+	case opWorkspaceCreate:
+		missing = record.Workspace == nil || record.Membership == nil
+	case opUserSave, opUserDelete:
+		missing = record.User == nil
+	case opMemberAdd, opMemberUpdate, opMemberRemove:
+		missing = record.Membership == nil
+	case opAuditAppend:
+		missing = record.Audit == nil
+	case opAuditAnonymize:
+	default:
+		return fmt.Errorf("%w: unknown operation (%s)", ErrCorruptLog, record.Op)
+	}
+	if missing {
+		return fmt.Errorf("%w: incomplete %s record", ErrCorruptLog, record.Op)
+	}
+
+	switch record.Op {
+	case opDomainCreate:
+		return r.domains.CreateDomain(ctx, record.Domain)
+	case opDomainUpdate:
+		return r.domains.UpdateDomain(ctx, record.Domain)
+	case opDomainDelete:
+		return r.domains.DeleteDomain(ctx, record.Domain.WorkspaceID, record.Domain.Name)
+	case opDomainExpire:
+		deleted, err := r.domains.DeleteUnverifiedDomain(ctx, record.Domain.Name, record.Domain.CreatedAt)
+		if deleted {
+			record.changed = 1
+		}
+		return err
+	case opWorkspace:
+		r.workspaces.mu.Lock()
+		defer r.workspaces.mu.Unlock()
+		stored := *record.Workspace
+		r.workspaces.workspaces[stored.ID] = &stored
+		return nil
+	case opWorkspaceCreate:
+		return r.workspaces.CreateWorkspace(ctx, record.Workspace, record.Membership)
+	case opUserSave:
+		return r.workspaces.SaveUser(ctx, record.User)
+	case opUserDelete:
+		return r.workspaces.DeleteUser(ctx, record.User.ID)
+	case opMemberAdd:
+		return r.workspaces.AddMember(ctx, record.Membership)
+	case opMemberUpdate:
+		return r.workspaces.UpdateMemberRole(ctx, record.Membership.WorkspaceID, record.Membership.UserID, record.Membership.Role)
+	case opMemberRemove:
+		return r.workspaces.RemoveMember(ctx, record.Membership.WorkspaceID, record.Membership.UserID)
+	case opAuditAppend:
+		return r.audit.AppendAuditEntry(ctx, record.Audit)
+	default: // opAuditAnonymize
+		changed, err := r.audit.AnonymizeAuditEntries(ctx, record.Subject, record.Pseudonym)
+		record.changed = changed
+		return err
+	}
+}
+
+// stateRecords returns the records that recreate the domains, workspaces and audit log in a snapshot.
+// Users are saved before their memberships, and audit entries are appended in order, so they keep their ids.
+func (r *durableMemoryRepo) stateRecords() []walRecord {
+	records := []walRecord{}
+
+	r.workspaces.mu.RLock()
+	for _, workspace := range r.workspaces.workspaces {
+		records = append(records, walRecord{Op: opWorkspace, Workspace: copyOf(workspace)})
+	}
+	for _, user := range r.workspaces.users {
+		records = append(records, walRecord{Op: opUserSave, User: copyOf(user)})
+	}
+	for _, members := range r.workspaces.memberships {
+		for _, membership := range members {
+			records = append(records, walRecord{Op: opMemberAdd, Membership: copyOf(membership)})
+		}
+	}
+	r.workspaces.mu.RUnlock()
+
+	r.domains.mu.RLock()
+	for _, domain := range r.domains.domains {
+		records = append(records, walRecord{Op: opDomainCreate, Domain: copyOf(domain)})
+	}
+	r.domains.mu.RUnlock()
+
+	r.audit.mu.RLock()
+	for i := range r.audit.entries {
+		records = append(records, walRecord{Op: opAuditAppend, Audit: copyOf(&r.audit.entries[i])})
+	}
+	r.audit.mu.RUnlock()
+
+	return records
+}
+
+// copyOf returns a pointer to a copy of v.
+func copyOf[T any](v *T) *T {
+	c := *v
+	return &c
+}
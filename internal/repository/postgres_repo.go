@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Jaytpa01/url-shortener-api/config"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+// postgresRepository is the struct used for a PostgreSQL implementation of our UrlRepository
+type postgresRepository struct {
+	db *sqlx.DB
+}
+
+// NewPostgresRepository attempts to connect to the PostgreSQL database at dsn.
+// If successful, creates and returns &postgresRepository
+func NewPostgresRepository(dsn string, pool config.PoolConfig) (UrlRepository, error) {
+	db, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't connect to postgres database: %w", err)
+	}
+
+	applyPoolConfig(db, pool)
+
+	repo := &postgresRepository{
+		db: db,
+	}
+
+	return repo, nil
+}
+
+func (p *postgresRepository) FindByToken(ctx context.Context, token string) (*entity.Url, error) {
+	return p.FindByDomainToken(ctx, "", token)
+}
+
+func (p *postgresRepository) FindByDomainToken(ctx context.Context, domain, token string) (*entity.Url, error) {
+	url := &entity.Url{}
+
+	err := p.db.GetContext(ctx, url, "SELECT domain, token, target_url, visits, created_at, owner, workspace_id, disabled FROM url WHERE domain = $1 AND token = $2", domain, token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUrlNotFound
+		}
+
+		return nil, err
+	}
+
+	return url, nil
+}
+
+func (p *postgresRepository) Create(ctx context.Context, url *entity.Url) error {
+	// if the token is already taken nothing is inserted, rather than the insert failing
+	result, err := p.db.ExecContext(ctx,
+		`INSERT INTO url (domain, token, target_url, visits, created_at, owner, workspace_id, disabled) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT (domain, token) DO NOTHING`,
+		url.Domain, url.Token, url.TargetUrl, url.Visits, url.CreatedAt, url.Owner, url.Workspace(), url.Disabled,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrTokenAlreadyExists
+	}
+
+	return nil
+}
+
+func (p *postgresRepository) Update(ctx context.Context, url *entity.Url) error {
+	result, err := p.db.ExecContext(ctx, `UPDATE url SET target_url = $1, visits = $2, disabled = $3 WHERE domain = $4 AND token = $5`,
+		url.TargetUrl, url.Visits, url.Disabled, url.Domain, url.Token)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUrlNotFound
+	}
+
+	return nil
+}
+
+func (p *postgresRepository) Delete(ctx context.Context, token string) error {
+	return deleteSQL(ctx, p.db, "", token)
+}
+
+func (p *postgresRepository) DeleteFromDomain(ctx context.Context, domain, token string) error {
+	return deleteSQL(ctx, p.db, domain, token)
+}
+
+// IncrementVisits atomically increments the visits of the url with the token on the default domain.
+func (p *postgresRepository) IncrementVisits(ctx context.Context, token string) (int, error) {
+	return incrementVisitsSQL(ctx, p.db, "", token)
+}
+
+// IncrementDomainVisits atomically increments the visits of the url with the token on the short domain.
+func (p *postgresRepository) IncrementDomainVisits(ctx context.Context, domain, token string) (int, error) {
+	return incrementVisitsSQL(ctx, p.db, domain, token)
+}
+
+func (p *postgresRepository) ListUrls(ctx context.Context, filter ListFilter, cursor string, limit int) ([]entity.Url, string, error) {
+	return listSQL(ctx, p.db, filter, cursor, limit)
+}
+
+func (p *postgresRepository) Count(ctx context.Context) (int, error) {
+	var count int
+
+	if err := p.db.GetContext(ctx, &count, "SELECT COUNT(*) FROM url"); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// NextSequence returns the next value of the url sequence, which the sequence token strategy encodes into tokens.
+func (p *postgresRepository) NextSequence(ctx context.Context) (uint64, error) {
+	var value uint64
+
+	if err := p.db.GetContext(ctx, &value, "SELECT nextval('url_sequence')"); err != nil {
+		return 0, fmt.Errorf("couldn't increment url sequence: %w", err)
+	}
+
+	return value, nil
+}
+
+// Close closes every connection in the pool.
+func (p *postgresRepository) Close() error {
+	return p.db.Close()
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+)
+
+// memoryPrivacyRepo keeps privacy jobs in memory, for use with the memory url repository, whose links it anonymizes.
+// Jobs are lost on restart.
+type memoryPrivacyRepo struct {
+	urls *memoryRepo
+	jobs map[string]*entity.PrivacyJob
+	mu   sync.RWMutex
+}
+
+func NewInMemoryPrivacyRepo(urls *memoryRepo) PrivacyRepository {
+	return &memoryPrivacyRepo{
+		urls: urls,
+		jobs: make(map[string]*entity.PrivacyJob),
+	}
+}
+
+// CreatePrivacyJob is an in memory implementation of PrivacyRepository.CreatePrivacyJob
+func (r *memoryPrivacyRepo) CreatePrivacyJob(ctx context.Context, job *entity.PrivacyJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *job
+	r.jobs[job.ID] = &stored
+	return nil
+}
+
+// FindPrivacyJob is an in memory implementation of PrivacyRepository.FindPrivacyJob
+func (r *memoryPrivacyRepo) FindPrivacyJob(ctx context.Context, id string) (*entity.PrivacyJob, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, ErrPrivacyJobNotFound
+	}
+
+	found := *job
+	return &found, nil
+}
+
+// UpdatePrivacyJob is an in memory implementation of PrivacyRepository.UpdatePrivacyJob
+func (r *memoryPrivacyRepo) UpdatePrivacyJob(ctx context.Context, job *entity.PrivacyJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.jobs[job.ID]
+	if !ok {
+		return ErrPrivacyJobNotFound
+	}
+
+	stored.Status, stored.Error = job.Status, job.Error
+	stored.Links, stored.AuditEntries, stored.Export = job.Links, job.AuditEntries, job.Export
+	stored.StartedAt, stored.FinishedAt = job.StartedAt, job.FinishedAt
+	return nil
+}
+
+// ListUnfinishedPrivacyJobs is an in memory implementation of PrivacyRepository.ListUnfinishedPrivacyJobs
+func (r *memoryPrivacyRepo) ListUnfinishedPrivacyJobs(ctx context.Context) ([]entity.PrivacyJob, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	jobs := []entity.PrivacyJob{}
+	for _, job := range r.jobs {
+		if !job.Finished() {
+			jobs = append(jobs, *job)
+		}
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+			return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+	return jobs, nil
+}
+
+// AnonymizePrivacyJobs is an in memory implementation of PrivacyRepository.AnonymizePrivacyJobs
+func (r *memoryPrivacyRepo) AnonymizePrivacyJobs(ctx context.Context, subject, pseudonym string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, job := range r.jobs {
+		if job.Subject == subject {
+			job.Subject, job.Export = pseudonym, nil
+		}
+	}
+
+	return nil
+}
+
+// AnonymizeOwner is an in memory implementation of PrivacyRepository.AnonymizeOwner
+func (r *memoryPrivacyRepo) AnonymizeOwner(ctx context.Context, owner string) (int, error) {
+	r.urls.mu.Lock()
+	defer r.urls.mu.Unlock()
+
+	changed := 0
+	for _, url := range r.urls.urls {
+		if url.Owner == owner {
+			url.Owner = ""
+			changed++
+		}
+	}
+
+	return changed, nil
+}
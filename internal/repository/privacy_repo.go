@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/jmoiron/sqlx"
+)
+
+// NewPrivacyRepository returns the PrivacyRepository that stores privacy jobs alongside the urls in urlRepo.
+// The sqlite and postgres repositories keep them in the privacy_job table, and the memory repository keeps them in memory.
+// The other repositories can't store the audit log, so can't answer data protection requests, and return ErrPrivacyUnsupported.
+func NewPrivacyRepository(urlRepo UrlRepository) (PrivacyRepository, error) {
+	switch repo := urlRepo.(type) {
+	case *sqliteRepository:
+		return &sqlPrivacyRepo{db: repo.db}, nil
+	case *postgresRepository:
+		return &sqlPrivacyRepo{db: repo.db}, nil
+	case *memoryRepo:
+		return NewInMemoryPrivacyRepo(repo), nil
+	default:
+		return nil, ErrPrivacyUnsupported
+	}
+}
+
+// sqlPrivacyRepo stores privacy jobs in the sqlite or postgres database.
+type sqlPrivacyRepo struct {
+	db *sqlx.DB
+}
+
+const privacyJobColumns = `id, kind, subject, status, error, requested_by, links, audit_entries, export, created_at, started_at, finished_at`
+
+func (s *sqlPrivacyRepo) CreatePrivacyJob(ctx context.Context, job *entity.PrivacyJob) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(`INSERT INTO privacy_job (`+privacyJobColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		job.ID, job.Kind, job.Subject, job.Status, job.Error, job.RequestedBy, job.Links, job.AuditEntries, job.Export, job.CreatedAt, job.StartedAt, job.FinishedAt)
+	return err
+}
+
+func (s *sqlPrivacyRepo) FindPrivacyJob(ctx context.Context, id string) (*entity.PrivacyJob, error) {
+	job := &entity.PrivacyJob{}
+
+	err := s.db.GetContext(ctx, job, s.db.Rebind(`SELECT `+privacyJobColumns+` FROM privacy_job WHERE id = ?`), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPrivacyJobNotFound
+		}
+
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (s *sqlPrivacyRepo) UpdatePrivacyJob(ctx context.Context, job *entity.PrivacyJob) error {
+	result, err := s.db.ExecContext(ctx, s.db.Rebind(`UPDATE privacy_job SET status = ?, error = ?, links = ?, audit_entries = ?, export = ?,
+		started_at = ?, finished_at = ? WHERE id = ?`),
+		job.Status, job.Error, job.Links, job.AuditEntries, job.Export, job.StartedAt, job.FinishedAt, job.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrPrivacyJobNotFound
+	}
+
+	return nil
+}
+
+func (s *sqlPrivacyRepo) ListUnfinishedPrivacyJobs(ctx context.Context) ([]entity.PrivacyJob, error) {
+	jobs := []entity.PrivacyJob{}
+
+	err := s.db.SelectContext(ctx, &jobs, s.db.Rebind(`SELECT `+privacyJobColumns+` FROM privacy_job WHERE status IN (?, ?) ORDER BY created_at, id`),
+		entity.PrivacyJobPending, entity.PrivacyJobRunning)
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func (s *sqlPrivacyRepo) AnonymizePrivacyJobs(ctx context.Context, subject, pseudonym string) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(`UPDATE privacy_job SET subject = ?, export = NULL WHERE subject = ?`), pseudonym, subject)
+	return err
+}
+
+func (s *sqlPrivacyRepo) AnonymizeOwner(ctx context.Context, owner string) (int, error) {
+	result, err := s.db.ExecContext(ctx, s.db.Rebind(`UPDATE url SET owner = '' WHERE owner = ?`), owner)
+	if err != nil {
+		return 0, err
+	}
+
+	changed, err := result.RowsAffected()
+	return int(changed), err
+}
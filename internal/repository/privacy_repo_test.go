@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PrivacyRepository(t *testing.T) {
+	testCases := []struct {
+		name string
+		repo func(t *testing.T) UrlRepository
+	}{
+		{"Memory", func(t *testing.T) UrlRepository { return NewInMemoryRepo() }},
+		{"SQLite", func(t *testing.T) UrlRepository { return newSQLiteRepo(t) }},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			urlRepo := test.repo(t)
+			repo, err := NewPrivacyRepository(urlRepo)
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			createdAt := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+
+			export := &entity.PrivacyJob{ID: "job_1", Kind: entity.PrivacyExport, Subject: "key_1", Status: entity.PrivacyJobPending, RequestedBy: "admin", CreatedAt: createdAt}
+			erase := &entity.PrivacyJob{ID: "job_2", Kind: entity.PrivacyErase, Subject: "key_1", Status: entity.PrivacyJobPending, RequestedBy: "admin", CreatedAt: createdAt.Add(time.Minute)}
+			require.NoError(t, repo.CreatePrivacyJob(ctx, export))
+			require.NoError(t, repo.CreatePrivacyJob(ctx, erase))
+
+			_, err = repo.FindPrivacyJob(ctx, "job_3")
+			assert.ErrorIs(t, err, ErrPrivacyJobNotFound)
+
+			// the export finishes, with the subject's data
+			finishedAt := createdAt.Add(time.Hour)
+			export.Status, export.Links, export.AuditEntries = entity.PrivacyJobSucceeded, 1, 2
+			export.StartedAt, export.FinishedAt = &createdAt, &finishedAt
+			export.Export = &entity.SubjectExport{
+				Links:        []entity.Url{{Token: "abcdef", TargetUrl: "https://example.com", Owner: "key_1"}},
+				AuditEntries: []entity.AuditEntry{{ID: 1, Action: entity.AuditLinkCreate, Actor: "key_1", Target: "abcdef"}},
+			}
+			require.NoError(t, repo.UpdatePrivacyJob(ctx, export))
+			assert.ErrorIs(t, repo.UpdatePrivacyJob(ctx, &entity.PrivacyJob{ID: "job_3"}), ErrPrivacyJobNotFound)
+
+			found, err := repo.FindPrivacyJob(ctx, "job_1")
+			require.NoError(t, err)
+			assert.Equal(t, entity.PrivacyJobSucceeded, found.Status)
+			assert.Equal(t, 2, found.AuditEntries)
+			assert.True(t, found.FinishedAt.Equal(finishedAt))
+			require.NotNil(t, found.Export)
+			assert.Equal(t, export.Export.Links[0].Token, found.Export.Links[0].Token)
+			assert.Equal(t, entity.AuditLinkCreate, found.Export.AuditEntries[0].Action)
+
+			unfinished, err := repo.ListUnfinishedPrivacyJobs(ctx)
+			require.NoError(t, err)
+			require.Len(t, unfinished, 1)
+			assert.Equal(t, "job_2", unfinished[0].ID)
+
+			// erasing the subject pseudonymizes its jobs and clears their exports
+			require.NoError(t, repo.AnonymizePrivacyJobs(ctx, "key_1", "erased_1"))
+			found, err = repo.FindPrivacyJob(ctx, "job_1")
+			require.NoError(t, err)
+			assert.Nil(t, found.Export)
+			assert.Equal(t, "erased_1", found.Subject)
+			assert.Equal(t, 1, found.Links)
+
+			// and anonymizes its links
+			require.NoError(t, urlRepo.Create(ctx, &entity.Url{Token: "abcdef", TargetUrl: "https://example.com", Owner: "key_1", CreatedAt: createdAt}))
+			require.NoError(t, urlRepo.Create(ctx, &entity.Url{Token: "ghijkl", TargetUrl: "https://example.com", Owner: "key_2", CreatedAt: createdAt}))
+
+			changed, err := repo.AnonymizeOwner(ctx, "key_1")
+			require.NoError(t, err)
+			assert.Equal(t, 1, changed)
+
+			url, err := urlRepo.FindByToken(ctx, "abcdef")
+			require.NoError(t, err)
+			assert.Empty(t, url.Owner)
+
+			url, err = urlRepo.FindByToken(ctx, "ghijkl")
+			require.NoError(t, err)
+			assert.Equal(t, "key_2", url.Owner)
+		})
+	}
+}
+
+func Test_NewPrivacyRepository_Unsupported(t *testing.T) {
+	_, err := NewPrivacyRepository(newRedisRepo(t))
+	assert.ErrorIs(t, err, ErrPrivacyUnsupported)
+}
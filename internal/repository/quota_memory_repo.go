@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+)
+
+// memoryQuotaRepo counts uses in memory, for use with the memory url repository.
+// Counts are lost on restart.
+type memoryQuotaRepo struct {
+	used map[string]map[string]int // subject -> period -> uses
+	mu   sync.Mutex
+}
+
+func NewInMemoryQuotaRepo() QuotaRepository {
+	return &memoryQuotaRepo{
+		used: make(map[string]map[string]int),
+	}
+}
+
+// UseQuota is an in memory implementation of QuotaRepository.UseQuota
+func (r *memoryQuotaRepo) UseQuota(ctx context.Context, subject string, periods []QuotaPeriod) ([]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts, ok := r.used[subject]
+	if !ok {
+		counts = make(map[string]int)
+		r.used[subject] = counts
+	}
+
+	// check every period before counting, so nothing is counted if one is at its limit
+	for _, period := range periods {
+		if counts[period.Key] >= period.Limit {
+			return nil, &QuotaExceededError{Period: period}
+		}
+	}
+
+	used := make([]int, len(periods))
+	for i, period := range periods {
+		counts[period.Key]++
+		used[i] = counts[period.Key]
+	}
+
+	return used, nil
+}
+
+// ListQuotaUsage is an in memory implementation of QuotaRepository.ListQuotaUsage
+func (r *memoryQuotaRepo) ListQuotaUsage(ctx context.Context, subject string) ([]entity.QuotaUsage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	usage := []entity.QuotaUsage{}
+	for period, used := range r.used[subject] {
+		usage = append(usage, entity.QuotaUsage{Period: period, Used: used})
+	}
+
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Period < usage[j].Period })
+	return usage, nil
+}
+
+// DeleteQuotaUsage is an in memory implementation of QuotaRepository.DeleteQuotaUsage
+func (r *memoryQuotaRepo) DeleteQuotaUsage(ctx context.Context, subject string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.used, subject)
+	return nil
+}
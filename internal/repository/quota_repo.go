@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/jmoiron/sqlx"
+)
+
+// QuotaPeriod is a period uses are counted in, such as "day:2023-04-01", and how many uses it allows.
+type QuotaPeriod struct {
+	Key   string
+	Limit int
+}
+
+// NewQuotaRepository returns the QuotaRepository that counts uses alongside the urls in urlRepo.
+// The sqlite and postgres repositories keep counts in the quota_usage table, and the memory repository keeps them in memory.
+// The other repositories return ErrQuotasUnsupported.
+func NewQuotaRepository(urlRepo UrlRepository) (QuotaRepository, error) {
+	switch repo := urlRepo.(type) {
+	case *sqliteRepository:
+		return &sqlQuotaRepo{db: repo.db}, nil
+	case *postgresRepository:
+		return &sqlQuotaRepo{db: repo.db}, nil
+	case *memoryRepo:
+		return NewInMemoryQuotaRepo(), nil
+	default:
+		return nil, ErrQuotasUnsupported
+	}
+}
+
+// sqlQuotaRepo counts uses in the sqlite or postgres database.
+type sqlQuotaRepo struct {
+	db *sqlx.DB
+}
+
+func (s *sqlQuotaRepo) UseQuota(ctx context.Context, subject string, periods []QuotaPeriod) ([]int, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Defer a rollback in case anything fails, or a period is at its limit.
+	defer tx.Rollback()
+
+	// the update only happens while the period is under its limit, so concurrent uses can't go over it
+	query := tx.Rebind(`INSERT INTO quota_usage (subject, period, used) VALUES (?, ?, 1)
+		ON CONFLICT (subject, period) DO UPDATE SET used = quota_usage.used + 1 WHERE quota_usage.used < ?
+		RETURNING used`)
+
+	used := make([]int, len(periods))
+	for i, period := range periods {
+		if err := tx.GetContext(ctx, &used[i], query, subject, period.Key, period.Limit); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, &QuotaExceededError{Period: period}
+			}
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return used, nil
+}
+
+func (s *sqlQuotaRepo) ListQuotaUsage(ctx context.Context, subject string) ([]entity.QuotaUsage, error) {
+	usage := []entity.QuotaUsage{}
+
+	err := s.db.SelectContext(ctx, &usage, s.db.Rebind(`SELECT period, used FROM quota_usage WHERE subject = ? ORDER BY period`), subject)
+	if err != nil {
+		return nil, err
+	}
+
+	return usage, nil
+}
+
+func (s *sqlQuotaRepo) DeleteQuotaUsage(ctx context.Context, subject string) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(`DELETE FROM quota_usage WHERE subject = ?`), subject)
+	return err
+}
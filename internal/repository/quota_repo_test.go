@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_QuotaRepository(t *testing.T) {
+	testCases := []struct {
+		name string
+		repo func(t *testing.T) QuotaRepository
+	}{
+		{"Memory", func(t *testing.T) QuotaRepository { return NewInMemoryQuotaRepo() }},
+		{"SQLite", func(t *testing.T) QuotaRepository {
+			repo, err := NewQuotaRepository(newSQLiteRepo(t))
+			require.NoError(t, err)
+			return repo
+		}},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			repo := test.repo(t)
+			ctx := context.Background()
+			periods := []QuotaPeriod{{Key: "day:2023-04-01", Limit: 2}, {Key: "month:2023-04", Limit: 3}}
+
+			used, err := repo.UseQuota(ctx, "key_1", periods)
+			require.NoError(t, err)
+			assert.Equal(t, []int{1, 1}, used)
+
+			used, err = repo.UseQuota(ctx, "key_1", periods)
+			require.NoError(t, err)
+			assert.Equal(t, []int{2, 2}, used)
+
+			// the day is at its limit, so the month isn't counted either
+			_, err = repo.UseQuota(ctx, "key_1", periods)
+			assert.ErrorIs(t, err, ErrQuotaExceeded)
+			assert.Equal(t, &QuotaExceededError{Period: periods[0]}, err)
+
+			// the next day starts again, until the month runs out
+			periods[0].Key = "day:2023-04-02"
+			used, err = repo.UseQuota(ctx, "key_1", periods)
+			require.NoError(t, err)
+			assert.Equal(t, []int{1, 3}, used)
+
+			_, err = repo.UseQuota(ctx, "key_1", periods)
+			assert.Equal(t, &QuotaExceededError{Period: periods[1]}, err)
+
+			// subjects are counted separately
+			used, err = repo.UseQuota(ctx, "ip:192.0.2.1", periods)
+			require.NoError(t, err)
+			assert.Equal(t, []int{1, 1}, used)
+
+			usage, err := repo.ListQuotaUsage(ctx, "key_1")
+			require.NoError(t, err)
+			assert.Equal(t, []entity.QuotaUsage{{Period: "day:2023-04-01", Used: 2}, {Period: "day:2023-04-02", Used: 1}, {Period: "month:2023-04", Used: 3}}, usage)
+
+			// deleting the usage of a subject leaves the others alone
+			require.NoError(t, repo.DeleteQuotaUsage(ctx, "key_1"))
+			usage, err = repo.ListQuotaUsage(ctx, "key_1")
+			require.NoError(t, err)
+			assert.Empty(t, usage)
+			usage, err = repo.ListQuotaUsage(ctx, "ip:192.0.2.1")
+			require.NoError(t, err)
+			assert.Len(t, usage, 2)
+		})
+	}
+}
+
+func Test_QuotaRepository_Concurrent(t *testing.T) {
+	repo, err := NewQuotaRepository(newSQLiteRepo(t))
+	require.NoError(t, err)
+
+	const limit = 5
+	periods := []QuotaPeriod{{Key: "day:2023-04-01", Limit: limit}}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		accepted int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := repo.UseQuota(context.Background(), "key_1", periods); err == nil {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, limit, accepted)
+}
+
+func Test_NewQuotaRepository_Unsupported(t *testing.T) {
+	_, err := NewQuotaRepository(newRedisRepo(t))
+	assert.ErrorIs(t, err, ErrQuotasUnsupported)
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRedisRepo(t *testing.T) *redisRepository {
+	server := miniredis.RunT(t)
+
+	repo, err := NewRedisRepository("redis://" + server.Addr())
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.(*redisRepository).Close() })
+
+	return repo.(*redisRepository)
+}
+
+func Test_RedisRepository(t *testing.T) {
+	repo := newRedisRepo(t)
+	ctx := context.Background()
+	createdAt := time.Now().UTC()
+
+	assert.NoError(t, repo.Create(ctx, &entity.Url{Token: "123456", TargetUrl: "https://example.com", CreatedAt: createdAt}))
+	assert.NoError(t, repo.Create(ctx, &entity.Url{Token: "987654", TargetUrl: "https://example.com/path", CreatedAt: createdAt}))
+	assert.ErrorIs(t, repo.Create(ctx, &entity.Url{Token: "123456"}), ErrTokenAlreadyExists)
+
+	url, err := repo.FindByToken(ctx, "123456")
+	assert.NoError(t, err)
+	assert.Equal(t, &entity.Url{Token: "123456", TargetUrl: "https://example.com", CreatedAt: createdAt}, url)
+
+	_, err = repo.FindByToken(ctx, "qwerty")
+	assert.ErrorIs(t, err, ErrUrlNotFound)
+
+	url.Visits = 42
+	assert.NoError(t, repo.Update(ctx, url))
+	assert.ErrorIs(t, repo.Update(ctx, &entity.Url{Token: "qwerty"}), ErrUrlNotFound)
+
+	url, err = repo.FindByToken(ctx, "123456")
+	assert.NoError(t, err)
+	assert.Equal(t, 42, url.Visits)
+
+	urls, _, err := repo.ListUrls(ctx, ListFilter{}, "", 1000)
+	assert.NoError(t, err)
+	assert.Len(t, urls, 2)
+
+	count, err := repo.Count(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	first, err := repo.NextSequence(ctx)
+	assert.NoError(t, err)
+	second, err := repo.NextSequence(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, first+1, second)
+}
+
+func Test_RedisRepository_IncrementVisits(t *testing.T) {
+	repo := newRedisRepo(t)
+	ctx := context.Background()
+
+	assert.NoError(t, repo.Create(ctx, &entity.Url{Token: "123456", TargetUrl: "https://example.com"}))
+
+	// concurrent visits shouldn't be lost
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.IncrementVisits(ctx, "123456")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	url, err := repo.FindByToken(ctx, "123456")
+	assert.NoError(t, err)
+	assert.Equal(t, 50, url.Visits)
+
+	// visiting a missing url shouldn't create it
+	_, err = repo.IncrementVisits(ctx, "qwerty")
+	assert.ErrorIs(t, err, ErrUrlNotFound)
+	_, err = repo.FindByToken(ctx, "qwerty")
+	assert.ErrorIs(t, err, ErrUrlNotFound)
+}
+
+func Test_RedisRepository_ListUrls(t *testing.T) {
+	testListUrls(t, newRedisRepo(t))
+}
+
+func Test_RedisRepository_ListUrlsOnlyReadsThePage(t *testing.T) {
+	server := miniredis.RunT(t)
+	repo, err := NewRedisRepository("redis://" + server.Addr())
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.(*redisRepository).Close() })
+	ctx := context.Background()
+
+	for _, token := range []string{"aaa", "bbb", "ccc", "ddd"} {
+		require.NoError(t, repo.Create(ctx, &entity.Url{Token: token, TargetUrl: "https://example.com", CreatedAt: listEpoch}))
+	}
+
+	// a url after the page that can't be decoded, so the page fails if it's read
+	server.HSet(redisUrlPrefix+"ddd", "visits", "many")
+
+	urls, next, err := repo.ListUrls(ctx, ListFilter{Sort: SortTokenAsc}, "", 2)
+	assert.NoError(t, err)
+	assert.Len(t, urls, 2)
+
+	_, _, err = repo.ListUrls(ctx, ListFilter{Sort: SortTokenAsc}, next, 2)
+	assert.Error(t, err)
+
+	// a url deleted without being unindexed is skipped, and paging carries on past it
+	server.HSet(redisUrlPrefix+"ddd", "visits", "0")
+	server.Del(redisUrlPrefix + "bbb")
+	assert.Equal(t, []string{"aaa", "ccc", "ddd"}, listAll(t, func(cursor string) ([]entity.Url, string, error) {
+		return repo.ListUrls(ctx, ListFilter{Sort: SortCreatedAsc}, cursor, 1)
+	}, 1))
+}
+
+func Test_RedisRepository_IndexesExistingUrls(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	// more urls than a single SCAN returns, stored before the indexes existed
+	for i := 0; i < redisScanCount*3+7; i++ {
+		server.HSet(fmt.Sprintf("%s%06d", redisUrlPrefix, i), "target_url", "https://example.com", "visits", "0", "created_at", listEpoch.Format(time.RFC3339Nano))
+	}
+	_, err := server.Incr(redisSequenceKey, 1)
+	require.NoError(t, err)
+
+	repo, err := NewRedisRepository("redis://" + server.Addr())
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.(*redisRepository).Close() })
+
+	urls, _, err := repo.ListUrls(ctx, ListFilter{}, "", 1000)
+	assert.NoError(t, err)
+	assert.Len(t, urls, redisScanCount*3+7)
+
+	// deleting an indexed url should remove it from both indexes
+	require.NoError(t, repo.Delete(ctx, "000000"))
+	tokens, err := server.ZMembers(redisTokenIndexKey)
+	assert.NoError(t, err)
+	assert.NotContains(t, tokens, "000000")
+	members, err := server.ZMembers(redisCreatedIndexKey)
+	assert.NoError(t, err)
+	assert.Len(t, members, redisScanCount*3+6)
+}
@@ -0,0 +1,444 @@
+// Package repotest is a conformance suite that every repository.UrlRepository implementation should pass,
+// so the backends can be swapped without the rest of the api noticing.
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/stretchr/testify/suite"
+)
+
+// CONCURRENCY is how many goroutines the concurrency tests use.
+const CONCURRENCY = 20
+
+// Suite runs the conformance tests against the repositories returned by NewRepository.
+// Embed it in a suite that needs its own setup, calling Suite.SetupTest from that suite's SetupTest,
+// or use Run.
+type Suite struct {
+	suite.Suite
+
+	// NewRepository returns an empty repository. It's called before every test.
+	NewRepository func(t *testing.T) repository.UrlRepository
+
+	repo repository.UrlRepository
+}
+
+// Run runs the conformance suite against the repositories returned by newRepository.
+func Run(t *testing.T, newRepository func(t *testing.T) repository.UrlRepository) {
+	suite.Run(t, &Suite{NewRepository: newRepository})
+}
+
+func (s *Suite) SetupTest() {
+	s.Require().NotNil(s.NewRepository, "repotest.Suite needs a NewRepository function")
+	s.repo = s.NewRepository(s.T())
+}
+
+// createdAt returns a creation time every repository can store exactly.
+// Postgres stores microseconds, and some repositories return times in UTC.
+func createdAt(offset time.Duration) time.Time {
+	return time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC).Add(offset).Truncate(time.Microsecond)
+}
+
+// create creates a url, failing the test if it can't.
+func (s *Suite) create(token, targetUrl string, offset time.Duration) *entity.Url {
+	url := &entity.Url{Token: token, TargetUrl: targetUrl, Visits: 1, CreatedAt: createdAt(offset), Owner: "key-" + token, WorkspaceID: "ws-" + token[:1]}
+	s.Require().NoError(s.repo.Create(context.Background(), url))
+	return url
+}
+
+// assertUrl asserts that found is the same url as expected.
+func (s *Suite) assertUrl(expected, found *entity.Url) {
+	s.Equal(expected.Token, found.Token)
+	s.Equal(expected.TargetUrl, found.TargetUrl)
+	s.Equal(expected.Visits, found.Visits)
+	s.Equal(expected.Owner, found.Owner)
+	s.Equal(expected.Workspace(), found.Workspace())
+	s.Equal(expected.Disabled, found.Disabled)
+	s.True(expected.CreatedAt.Equal(found.CreatedAt), "expected created at %s, got %s", expected.CreatedAt, found.CreatedAt)
+}
+
+func (s *Suite) TestCreateAndFind() {
+	url := s.create("123456", "https://example.com/path?query=1", time.Millisecond+time.Microsecond)
+
+	found, err := s.repo.FindByToken(context.Background(), "123456")
+	s.Require().NoError(err)
+	s.assertUrl(url, found)
+}
+
+func (s *Suite) TestNotFound() {
+	ctx := context.Background()
+
+	_, err := s.repo.FindByToken(ctx, "qwerty")
+	s.ErrorIs(err, repository.ErrUrlNotFound)
+
+	s.ErrorIs(s.repo.Update(ctx, &entity.Url{Token: "qwerty", TargetUrl: "https://example.com"}), repository.ErrUrlNotFound)
+
+	// a failed update mustn't create the url
+	_, err = s.repo.FindByToken(ctx, "qwerty")
+	s.ErrorIs(err, repository.ErrUrlNotFound)
+}
+
+func (s *Suite) TestCreateDuplicateToken() {
+	ctx := context.Background()
+	url := s.create("123456", "https://example.com", 0)
+
+	err := s.repo.Create(ctx, &entity.Url{Token: "123456", TargetUrl: "https://google.com", CreatedAt: createdAt(time.Hour)})
+	s.ErrorIs(err, repository.ErrTokenAlreadyExists)
+
+	// the existing url is left alone
+	found, err := s.repo.FindByToken(ctx, "123456")
+	s.Require().NoError(err)
+	s.assertUrl(url, found)
+
+	count, err := s.repo.Count(ctx)
+	s.NoError(err)
+	s.Equal(1, count)
+}
+
+func (s *Suite) TestUpdate() {
+	ctx := context.Background()
+	url := s.create("123456", "https://example.com", 0)
+	s.create("987654", "https://example.com", 0)
+
+	// update replaces the target url, visits and whether it's disabled, but keeps the creation time, owner and workspace
+	s.Require().NoError(s.repo.Update(ctx, &entity.Url{Token: "123456", TargetUrl: "https://google.com", Visits: 42, Disabled: true, CreatedAt: createdAt(time.Hour), Owner: "someone-else", WorkspaceID: "another"}))
+
+	found, err := s.repo.FindByToken(ctx, "123456")
+	s.Require().NoError(err)
+	s.assertUrl(&entity.Url{Token: "123456", TargetUrl: "https://google.com", Visits: 42, Disabled: true, CreatedAt: url.CreatedAt, Owner: url.Owner, WorkspaceID: url.WorkspaceID}, found)
+
+	// other urls aren't touched
+	other, err := s.repo.FindByToken(ctx, "987654")
+	s.Require().NoError(err)
+	s.Equal("https://example.com", other.TargetUrl)
+	s.Equal(1, other.Visits)
+
+	count, err := s.repo.Count(ctx)
+	s.NoError(err)
+	s.Equal(2, count)
+}
+
+func (s *Suite) TestDefaultWorkspace() {
+	ctx := context.Background()
+	s.Require().NoError(s.repo.Create(ctx, &entity.Url{Token: "123456", TargetUrl: "https://example.com", CreatedAt: createdAt(0)}))
+
+	// urls created without a workspace belong to the default workspace
+	found, err := s.repo.FindByToken(ctx, "123456")
+	s.Require().NoError(err)
+	s.Equal(entity.DEFAULT_WORKSPACE, found.Workspace())
+
+	urls, _, err := s.repo.ListUrls(ctx, repository.ListFilter{WorkspaceID: entity.DEFAULT_WORKSPACE}, "", 10)
+	s.Require().NoError(err)
+	s.Len(urls, 1)
+}
+
+func (s *Suite) TestDelete() {
+	ctx := context.Background()
+	s.create("123456", "https://example.com", 0)
+	s.create("987654", "https://example.com", 0)
+
+	s.Require().NoError(s.repo.Delete(ctx, "123456"))
+	s.ErrorIs(s.repo.Delete(ctx, "123456"), repository.ErrUrlNotFound)
+
+	_, err := s.repo.FindByToken(ctx, "123456")
+	s.ErrorIs(err, repository.ErrUrlNotFound)
+
+	// other urls aren't touched
+	_, err = s.repo.FindByToken(ctx, "987654")
+	s.NoError(err)
+
+	count, err := s.repo.Count(ctx)
+	s.NoError(err)
+	s.Equal(1, count)
+
+	urls, _, err := s.repo.ListUrls(ctx, repository.ListFilter{}, "", 10)
+	s.Require().NoError(err)
+	s.Require().Len(urls, 1)
+	s.Equal("987654", urls[0].Token)
+
+	// the token can be used again
+	s.create("123456", "https://google.com", 0)
+}
+
+func (s *Suite) TestDomains() {
+	repo, ok := s.repo.(repository.DomainUrlRepository)
+	if !ok {
+		s.T().Skip("repository doesn't store urls on other domains")
+	}
+
+	ctx := context.Background()
+	s.create("123456", "https://example.com", 0)
+
+	// the same token can be used on another domain
+	onDomain := &entity.Url{Domain: "go.example.com", Token: "123456", TargetUrl: "https://example.org", CreatedAt: createdAt(0), WorkspaceID: "ws-1"}
+	s.Require().NoError(s.repo.Create(ctx, onDomain))
+	s.ErrorIs(s.repo.Create(ctx, onDomain), repository.ErrTokenAlreadyExists)
+
+	found, err := repo.FindByDomainToken(ctx, "go.example.com", "123456")
+	s.Require().NoError(err)
+	s.assertUrl(onDomain, found)
+	s.Equal("go.example.com", found.Domain)
+
+	_, err = repo.FindByDomainToken(ctx, "other.example.com", "123456")
+	s.ErrorIs(err, repository.ErrUrlNotFound)
+
+	// updates only change the url on its domain
+	onDomain.TargetUrl = "https://example.net"
+	s.Require().NoError(s.repo.Update(ctx, onDomain))
+
+	found, err = s.repo.FindByToken(ctx, "123456")
+	s.Require().NoError(err)
+	s.Equal("https://example.com", found.TargetUrl)
+	s.Equal("", found.Domain)
+
+	// both are listed, in a stable order
+	urls, _, err := s.repo.ListUrls(ctx, repository.ListFilter{Sort: repository.SortTokenAsc}, "", 1)
+	s.Require().NoError(err)
+	s.Require().Len(urls, 1)
+	s.Equal("", urls[0].Domain)
+
+	urls, _, err = s.repo.ListUrls(ctx, repository.ListFilter{Sort: repository.SortTokenAsc}, repository.TokenCursor("123456"), 10)
+	s.Require().NoError(err)
+	s.Require().Len(urls, 1)
+	s.Equal("go.example.com", urls[0].Domain)
+
+	s.Require().NoError(repo.DeleteFromDomain(ctx, "go.example.com", "123456"))
+	s.ErrorIs(repo.DeleteFromDomain(ctx, "go.example.com", "123456"), repository.ErrUrlNotFound)
+
+	_, err = s.repo.FindByToken(ctx, "123456")
+	s.NoError(err)
+}
+
+func (s *Suite) TestUrlsAreCopied() {
+	ctx := context.Background()
+	url := s.create("123456", "https://example.com", 0)
+
+	// changing a url after creating or finding it mustn't change the stored url
+	url.TargetUrl = "https://google.com"
+
+	found, err := s.repo.FindByToken(ctx, "123456")
+	s.Require().NoError(err)
+	found.Visits = 100
+
+	found, err = s.repo.FindByToken(ctx, "123456")
+	s.Require().NoError(err)
+	s.Equal("https://example.com", found.TargetUrl)
+	s.Equal(1, found.Visits)
+}
+
+func (s *Suite) TestConcurrentCreates() {
+	ctx := context.Background()
+
+	// every goroutine races to create the same token, only one can win
+	errs := make(chan error, CONCURRENCY)
+	s.parallel(func(i int) {
+		errs <- s.repo.Create(ctx, &entity.Url{Token: "123456", TargetUrl: fmt.Sprintf("https://example.com/%d", i), CreatedAt: createdAt(0)})
+	})
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		if err == nil {
+			created++
+			continue
+		}
+		s.ErrorIs(err, repository.ErrTokenAlreadyExists)
+	}
+	s.Equal(1, created)
+
+	// creates of different tokens all succeed
+	s.parallel(func(i int) {
+		s.NoError(s.repo.Create(ctx, &entity.Url{Token: fmt.Sprintf("token%02d", i), TargetUrl: "https://example.com", CreatedAt: createdAt(0)}))
+	})
+
+	count, err := s.repo.Count(ctx)
+	s.NoError(err)
+	s.Equal(CONCURRENCY+1, count)
+}
+
+func (s *Suite) TestConcurrentUpdates() {
+	ctx := context.Background()
+	s.create("123456", "https://example.com", 0)
+
+	s.parallel(func(i int) {
+		s.NoError(s.repo.Update(ctx, &entity.Url{Token: "123456", TargetUrl: fmt.Sprintf("https://example.com/%d", i), Visits: i}))
+	})
+
+	// the last update wins, and it's written whole
+	found, err := s.repo.FindByToken(ctx, "123456")
+	s.Require().NoError(err)
+	s.Equal(fmt.Sprintf("https://example.com/%d", found.Visits), found.TargetUrl)
+	s.True(createdAt(0).Equal(found.CreatedAt))
+}
+
+func (s *Suite) TestIncrementVisits() {
+	repo, ok := s.repo.(repository.VisitIncrementer)
+	if !ok {
+		s.T().Skip("repository doesn't increment visits itself")
+	}
+
+	ctx := context.Background()
+	s.create("123456", "https://example.com", 0)
+
+	s.parallel(func(i int) {
+		_, err := repo.IncrementVisits(ctx, "123456")
+		s.NoError(err)
+	})
+
+	// a visit only changes visits, so it can't undo a retarget or disable
+	s.Require().NoError(s.repo.Update(ctx, &entity.Url{Token: "123456", TargetUrl: "https://example.org", Visits: 1 + CONCURRENCY, Disabled: true}))
+	visits, err := repo.IncrementVisits(ctx, "123456")
+	s.Require().NoError(err)
+	s.Equal(CONCURRENCY+2, visits)
+
+	found, err := s.repo.FindByToken(ctx, "123456")
+	s.Require().NoError(err)
+	s.Equal(CONCURRENCY+2, found.Visits)
+	s.Equal("https://example.org", found.TargetUrl)
+	s.True(found.Disabled)
+
+	_, err = repo.IncrementVisits(ctx, "qwerty")
+	s.ErrorIs(err, repository.ErrUrlNotFound)
+
+	domainRepo, ok := s.repo.(repository.DomainVisitIncrementer)
+	if !ok {
+		return
+	}
+
+	// visits on another domain are counted separately
+	s.Require().NoError(s.repo.Create(ctx, &entity.Url{Domain: "go.example.com", Token: "123456", TargetUrl: "https://example.net", CreatedAt: createdAt(0)}))
+	visits, err = domainRepo.IncrementDomainVisits(ctx, "go.example.com", "123456")
+	s.Require().NoError(err)
+	s.Equal(1, visits)
+
+	_, err = domainRepo.IncrementDomainVisits(ctx, "other.example.com", "123456")
+	s.ErrorIs(err, repository.ErrUrlNotFound)
+}
+
+func (s *Suite) TestListUrls() {
+	ctx := context.Background()
+
+	urls, next, err := s.repo.ListUrls(ctx, repository.ListFilter{}, "", 10)
+	s.NoError(err)
+	s.Empty(urls)
+	s.Empty(next)
+
+	// created out of token order, so the sorts differ
+	s.create("ccc", "https://example.com", time.Hour)
+	s.create("aaa", "https://docs.example.com/a", 3*time.Hour)
+	s.create("eee", "https://google.com", 2*time.Hour)
+	s.create("bbb", "https://notexample.com", 0)
+	s.create("ddd", "https://example.com/d", 4*time.Hour)
+
+	testCases := []struct {
+		name     string
+		filter   repository.ListFilter
+		expected []string
+	}{
+		{"Default Sort", repository.ListFilter{}, []string{"ddd", "aaa", "eee", "ccc", "bbb"}},
+		{"Created Asc", repository.ListFilter{Sort: repository.SortCreatedAsc}, []string{"bbb", "ccc", "eee", "aaa", "ddd"}},
+		{"Token Asc", repository.ListFilter{Sort: repository.SortTokenAsc}, []string{"aaa", "bbb", "ccc", "ddd", "eee"}},
+		{"Domain", repository.ListFilter{Domain: "example.com", Sort: repository.SortTokenAsc}, []string{"aaa", "ccc", "ddd"}},
+		{"Created Range", repository.ListFilter{CreatedAfter: createdAt(time.Hour), CreatedBefore: createdAt(3 * time.Hour)}, []string{"eee", "ccc"}},
+		{"Workspace", repository.ListFilter{WorkspaceID: "ws-d", Sort: repository.SortTokenAsc}, []string{"ddd"}},
+		{"Owner", repository.ListFilter{Owner: "key-eee"}, []string{"eee"}},
+		{"Search Target", repository.ListFilter{Search: "Example.COM/", Sort: repository.SortTokenAsc}, []string{"aaa", "ddd"}},
+		{"Search Token", repository.ListFilter{Search: "cc"}, []string{"ccc"}},
+	}
+
+	for _, test := range testCases {
+		s.Run(test.name, func() {
+			tokens := []string{}
+			cursor := ""
+			for pages := 0; pages < 10; pages++ {
+				urls, next, err := s.repo.ListUrls(ctx, test.filter, cursor, 2)
+				s.Require().NoError(err)
+				s.LessOrEqual(len(urls), 2)
+
+				for _, url := range urls {
+					tokens = append(tokens, url.Token)
+				}
+
+				if next == "" {
+					break
+				}
+				cursor = next
+			}
+
+			s.Equal(test.expected, tokens)
+		})
+	}
+
+	_, _, err = s.repo.ListUrls(ctx, repository.ListFilter{}, "not a cursor", 2)
+	s.ErrorIs(err, repository.ErrInvalidCursor)
+
+	_, _, err = s.repo.ListUrls(ctx, repository.ListFilter{Sort: "visits"}, "", 2)
+	s.ErrorIs(err, repository.ErrInvalidSort)
+}
+
+func (s *Suite) TestCount() {
+	ctx := context.Background()
+
+	count, err := s.repo.Count(ctx)
+	s.NoError(err)
+	s.Equal(0, count)
+
+	s.create("123456", "https://example.com", 0)
+	s.create("987654", "https://example.com", 0)
+
+	count, err = s.repo.Count(ctx)
+	s.NoError(err)
+	s.Equal(2, count)
+}
+
+func (s *Suite) TestCancelledContext() {
+	s.create("123456", "https://example.com", 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := s.repo.FindByToken(ctx, "123456")
+	s.ErrorIs(err, context.Canceled)
+
+	s.ErrorIs(s.repo.Create(ctx, &entity.Url{Token: "987654", TargetUrl: "https://example.com"}), context.Canceled)
+	s.ErrorIs(s.repo.Update(ctx, &entity.Url{Token: "123456", TargetUrl: "https://google.com"}), context.Canceled)
+	s.ErrorIs(s.repo.Delete(ctx, "123456"), context.Canceled)
+
+	_, _, err = s.repo.ListUrls(ctx, repository.ListFilter{}, "", 10)
+	s.ErrorIs(err, context.Canceled)
+
+	_, err = s.repo.Count(ctx)
+	s.ErrorIs(err, context.Canceled)
+
+	// nothing was written
+	_, err = s.repo.FindByToken(context.Background(), "987654")
+	s.ErrorIs(err, repository.ErrUrlNotFound)
+
+	found, err := s.repo.FindByToken(context.Background(), "123456")
+	s.Require().NoError(err)
+	s.Equal("https://example.com", found.TargetUrl)
+}
+
+// parallel calls fn from CONCURRENCY goroutines at once, and waits for them to finish.
+func (s *Suite) parallel(fn func(i int)) {
+	start := make(chan struct{})
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < CONCURRENCY; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			fn(i)
+		}(i)
+	}
+
+	close(start)
+	wg.Wait()
+}
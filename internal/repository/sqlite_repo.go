@@ -5,9 +5,12 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 
+	"github.com/Jaytpa01/url-shortener-api/config"
 	"github.com/Jaytpa01/url-shortener-api/internal/entity"
 	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
 )
 
 // sqliteRepository is the struct used for an SQLite implementation of our UrlRepository
@@ -15,14 +18,20 @@ type sqliteRepository struct {
 	db *sqlx.DB
 }
 
+// sqliteBusyTimeout is how long, in milliseconds, a write waits for another connection's write to finish
+// before failing with "database is locked".
+const sqliteBusyTimeout = 5000
+
 // NewSQLiteRepository attempts to connect to an SQLite file supplied by "dsn".
 // If successful, creates and returns &sqliteRepositpry
-func NewSQLiteRepository(dsn string) (UrlRepository, error) {
-	db, err := sqlx.Connect("sqlite3", dsn)
+func NewSQLiteRepository(dsn string, pool config.PoolConfig) (UrlRepository, error) {
+	db, err := sqlx.Connect("sqlite3", withBusyTimeout(dsn))
 	if err != nil {
 		return nil, fmt.Errorf("couldn't connect to sqlite database: %w", err)
 	}
 
+	applyPoolConfig(db, pool)
+
 	repo := &sqliteRepository{
 		db: db,
 	}
@@ -31,9 +40,13 @@ func NewSQLiteRepository(dsn string) (UrlRepository, error) {
 }
 
 func (s *sqliteRepository) FindByToken(ctx context.Context, token string) (*entity.Url, error) {
+	return s.FindByDomainToken(ctx, "", token)
+}
+
+func (s *sqliteRepository) FindByDomainToken(ctx context.Context, domain, token string) (*entity.Url, error) {
 	url := &entity.Url{}
 
-	err := s.db.GetContext(ctx, url, "SELECT * FROM url WHERE token = ?", token)
+	err := s.db.GetContext(ctx, url, "SELECT * FROM url WHERE domain = ? AND token = ?", domain, token)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrUrlNotFound
@@ -46,15 +59,19 @@ func (s *sqliteRepository) FindByToken(ctx context.Context, token string) (*enti
 }
 
 func (s *sqliteRepository) Create(ctx context.Context, url *entity.Url) error {
-	tx, err := s.db.Begin()
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	// Defer a rollback in case anything fails.
 	defer tx.Rollback()
 
-	result, err := tx.ExecContext(ctx, `INSERT INTO url (token, target_url, created_at) VALUES (?, ?, ?)`, url.Token, url.TargetUrl, url.CreatedAt)
+	result, err := tx.ExecContext(ctx, `INSERT INTO url (domain, token, target_url, visits, created_at, owner, workspace_id, disabled) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		url.Domain, url.Token, url.TargetUrl, url.Visits, url.CreatedAt, url.Owner, url.Workspace(), url.Disabled)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrTokenAlreadyExists
+		}
 		return err
 	}
 
@@ -75,14 +92,15 @@ func (s *sqliteRepository) Create(ctx context.Context, url *entity.Url) error {
 }
 
 func (s *sqliteRepository) Update(ctx context.Context, url *entity.Url) error {
-	tx, err := s.db.Begin()
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	// Defer a rollback in case anything fails.
 	defer tx.Rollback()
 
-	result, err := tx.ExecContext(ctx, `UPDATE url SET target_url = ?, visits = ? WHERE token = ?`, url.TargetUrl, url.Visits, url.Token)
+	result, err := tx.ExecContext(ctx, `UPDATE url SET target_url = ?, visits = ?, disabled = ? WHERE domain = ? AND token = ?`,
+		url.TargetUrl, url.Visits, url.Disabled, url.Domain, url.Token)
 	if err != nil {
 		return err
 	}
@@ -92,8 +110,8 @@ func (s *sqliteRepository) Update(ctx context.Context, url *entity.Url) error {
 		return err
 	}
 
-	if rowsAffected != 1 {
-		return fmt.Errorf("%d rows affected, expected 1", rowsAffected)
+	if rowsAffected == 0 {
+		return ErrUrlNotFound
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -103,12 +121,66 @@ func (s *sqliteRepository) Update(ctx context.Context, url *entity.Url) error {
 	return nil
 }
 
-func (s *sqliteRepository) GetAllUrls(ctx context.Context) ([]entity.Url, error) {
-	urls := []entity.Url{}
+func (s *sqliteRepository) Delete(ctx context.Context, token string) error {
+	return deleteSQL(ctx, s.db, "", token)
+}
+
+func (s *sqliteRepository) DeleteFromDomain(ctx context.Context, domain, token string) error {
+	return deleteSQL(ctx, s.db, domain, token)
+}
 
-	if err := s.db.SelectContext(ctx, &urls, "SELECT * FROM url"); err != nil {
-		return nil, err
+// IncrementVisits atomically increments the visits of the url with the token on the default domain.
+func (s *sqliteRepository) IncrementVisits(ctx context.Context, token string) (int, error) {
+	return incrementVisitsSQL(ctx, s.db, "", token)
+}
+
+// IncrementDomainVisits atomically increments the visits of the url with the token on the short domain.
+func (s *sqliteRepository) IncrementDomainVisits(ctx context.Context, domain, token string) (int, error) {
+	return incrementVisitsSQL(ctx, s.db, domain, token)
+}
+
+func (s *sqliteRepository) ListUrls(ctx context.Context, filter ListFilter, cursor string, limit int) ([]entity.Url, string, error) {
+	return listSQL(ctx, s.db, filter, cursor, limit)
+}
+
+func (s *sqliteRepository) Count(ctx context.Context) (int, error) {
+	var count int
+
+	if err := s.db.GetContext(ctx, &count, "SELECT COUNT(*) FROM url"); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// NextSequence increments and returns the url sequence, which the sequence token strategy encodes into tokens.
+func (s *sqliteRepository) NextSequence(ctx context.Context) (uint64, error) {
+	var value uint64
+
+	err := s.db.GetContext(ctx, &value, `UPDATE sequence SET value = value + 1 WHERE name = 'url' RETURNING value`)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't increment url sequence: %w", err)
+	}
+
+	return value, nil
+}
+
+// Close closes the sqlite database.
+func (s *sqliteRepository) Close() error {
+	return s.db.Close()
+}
+
+// withBusyTimeout adds a busy timeout to dsn, unless it already sets one, so concurrent writes queue instead of failing.
+func withBusyTimeout(dsn string) string {
+	// mattn/go-sqlite3 accepts both _busy_timeout and _timeout
+	if strings.Contains(dsn, "_timeout=") {
+		return dsn
+	}
+
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
 	}
 
-	return urls, nil
+	return fmt.Sprintf("%s%s_busy_timeout=%d", dsn, separator, sqliteBusyTimeout)
 }
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jaytpa01/url-shortener-api/config"
+	"github.com/Jaytpa01/url-shortener-api/db"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSQLiteRepo returns a sqlite repository migrated by the embedded migrations. They're run with golang-migrate
+// directly, as the migration package imports this one.
+func newSQLiteRepo(t *testing.T) *sqliteRepository {
+	dsn := filepath.Join(t.TempDir(), "url.db")
+
+	src, err := iofs.New(db.Migrations, "migrations")
+	require.NoError(t, err)
+	m, err := migrate.NewWithSourceInstance("iofs", src, "sqlite3://"+dsn)
+	require.NoError(t, err)
+	require.NoError(t, m.Up())
+	srcErr, dbErr := m.Close()
+	require.NoError(t, srcErr)
+	require.NoError(t, dbErr)
+
+	repo, err := NewSQLiteRepository(dsn, config.PoolConfig{})
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.(io.Closer).Close() })
+
+	return repo.(*sqliteRepository)
+}
+
+func Test_SQLiteRepository_CreateDuplicateToken(t *testing.T) {
+	repo := newSQLiteRepo(t)
+
+	ctx := context.Background()
+	assert.NoError(t, repo.Create(ctx, &entity.Url{Token: "123456", TargetUrl: "https://example.com", Visits: 3}))
+	assert.ErrorIs(t, repo.Create(ctx, &entity.Url{Token: "123456", TargetUrl: "https://example.com"}), ErrTokenAlreadyExists)
+
+	url, err := repo.FindByToken(ctx, "123456")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, url.Visits)
+}
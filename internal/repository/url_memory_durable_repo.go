@@ -0,0 +1,657 @@
+package repository
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
+)
+
+// SyncPolicy controls when writes to the log are fsynced to disk.
+type SyncPolicy string
+
+const (
+	SyncAlways   SyncPolicy = "always"   // fsync after every write, nothing acknowledged is lost on a crash
+	SyncInterval SyncPolicy = "interval" // fsync every SyncInterval, up to SyncInterval of writes can be lost on a crash
+	SyncNever    SyncPolicy = "never"    // leave it to the operating system
+)
+
+const (
+	walPrefix      = "wal-"
+	walSuffix      = ".log"
+	snapshotPrefix = "snapshot-"
+	snapshotSuffix = ".snap"
+
+	recordHeaderSize = 8       // 4 byte payload length followed by a 4 byte crc32 of the payload
+	maxRecordSize    = 1 << 20 // a record bigger than this is treated as corrupt
+
+	opCreate   = "create"
+	opUpdate   = "update"
+	opDelete   = "delete"
+	opSequence = "sequence"
+)
+
+var ErrCorruptLog = errors.New("log is corrupt")
+
+// DurableConfig configures the persistence of a durable in memory repository.
+type DurableConfig struct {
+	Dir              string        // directory holding the log and snapshots
+	Sync             SyncPolicy    // defaults to SyncAlways
+	SyncInterval     time.Duration // how often to fsync with SyncInterval, defaults to 1 second
+	SnapshotInterval time.Duration // how often to write a snapshot and compact the log, defaults to 5 minutes
+	Logger           logger.Logger // logs background syncs and snapshots that fail, defaults to a production logger
+}
+
+// durableMemoryRepo is an in memory UrlRepository that survives a restart, along with its domains, workspaces and audit log.
+// Every Create, Update, Delete and NextSequence, and every change to the others, is appended to a write-ahead log before it is applied,
+// and the log is periodically compacted into a snapshot. At startup the latest snapshot
+// and the logs written since are replayed. A write is only cancelled by its context before it is logged,
+// once logged it's applied just as it will be on replay, so the map never disagrees with the log.
+//
+// The log is split into numbered segments. Snapshot N holds everything written before
+// segment N, so once it has been written every earlier file can be removed.
+type durableMemoryRepo struct {
+	*memoryRepo
+	domains    *memoryDomainRepo
+	workspaces *memoryWorkspaceRepo
+	audit      *memoryAuditRepo
+	cfg        DurableConfig
+
+	mu      sync.Mutex // serialises writes, so the log is in the same order as the map
+	wal     *os.File
+	segment int
+	dirty   bool // whether there are writes that haven't been fsynced
+	records int  // records written to the current segment
+
+	stop      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewDurableInMemoryRepo replays the log in cfg.Dir and returns an in memory repository that persists to it.
+// The repository should be closed to flush the log on shutdown.
+func NewDurableInMemoryRepo(cfg DurableConfig) (UrlRepository, error) {
+	if cfg.Dir == "" {
+		return nil, errors.New("durable in memory repository needs a directory")
+	}
+
+	switch cfg.Sync {
+	case "":
+		cfg.Sync = SyncAlways
+	case SyncAlways, SyncInterval, SyncNever:
+	default:
+		return nil, fmt.Errorf("unknown sync policy (%s)", cfg.Sync)
+	}
+
+	if cfg.SyncInterval <= 0 {
+		cfg.SyncInterval = time.Second
+	}
+
+	if cfg.SnapshotInterval <= 0 {
+		cfg.SnapshotInterval = 5 * time.Minute
+	}
+
+	if cfg.Logger == nil {
+		cfg.Logger = logger.NewApiLogger("production")
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("couldn't create directory for durable in memory repository: %w", err)
+	}
+
+	repo := &durableMemoryRepo{
+		memoryRepo: NewInMemoryRepo().(*memoryRepo),
+		domains:    NewInMemoryDomainRepo().(*memoryDomainRepo),
+		workspaces: NewInMemoryWorkspaceRepo().(*memoryWorkspaceRepo),
+		audit:      NewInMemoryAuditRepo().(*memoryAuditRepo),
+		cfg:        cfg,
+		stop:       make(chan struct{}),
+	}
+
+	if err := repo.recover(); err != nil {
+		return nil, err
+	}
+
+	repo.wg.Add(1)
+	go repo.background()
+
+	return repo, nil
+}
+
+// Create is a durable in memory implementation of UrlRepository.Create
+func (r *durableMemoryRepo) Create(ctx context.Context, url *entity.Url) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.memoryRepo.FindByDomainToken(ctx, url.Domain, url.Token); err == nil {
+		return ErrTokenAlreadyExists
+	}
+
+	return r.write(&walRecord{Op: opCreate, Url: url})
+}
+
+// Update is a durable in memory implementation of UrlRepository.Update
+func (r *durableMemoryRepo) Update(ctx context.Context, url *entity.Url) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, err := r.memoryRepo.FindByDomainToken(ctx, url.Domain, url.Token)
+	if err != nil {
+		return err
+	}
+
+	// log the url as it'll be stored, with its original creation time, owner and workspace
+	updated := *url
+	updated.CreatedAt = existing.CreatedAt
+	updated.Owner = existing.Owner
+	updated.WorkspaceID = existing.WorkspaceID
+
+	return r.write(&walRecord{Op: opUpdate, Url: &updated})
+}
+
+// IncrementVisits is a durable in memory implementation of VisitIncrementer.IncrementVisits
+func (r *durableMemoryRepo) IncrementVisits(ctx context.Context, token string) (int, error) {
+	return r.IncrementDomainVisits(ctx, "", token)
+}
+
+// IncrementDomainVisits is a durable in memory implementation of DomainVisitIncrementer.IncrementDomainVisits.
+// The incremented url is logged as an update, which is safe as writes are serialised.
+func (r *durableMemoryRepo) IncrementDomainVisits(ctx context.Context, domain, token string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	updated, err := r.memoryRepo.FindByDomainToken(ctx, domain, token)
+	if err != nil {
+		return 0, err
+	}
+	updated.Visits++
+
+	if err := r.write(&walRecord{Op: opUpdate, Url: updated}); err != nil {
+		return 0, err
+	}
+
+	return updated.Visits, nil
+}
+
+// Delete is a durable in memory implementation of UrlRepository.Delete
+func (r *durableMemoryRepo) Delete(ctx context.Context, token string) error {
+	return r.DeleteFromDomain(ctx, "", token)
+}
+
+// DeleteFromDomain is a durable in memory implementation of DomainUrlRepository.DeleteFromDomain
+func (r *durableMemoryRepo) DeleteFromDomain(ctx context.Context, domain, token string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.memoryRepo.FindByDomainToken(ctx, domain, token); err != nil {
+		return err
+	}
+
+	return r.write(&walRecord{Op: opDelete, Url: &entity.Url{Domain: domain, Token: token}})
+}
+
+// NextSequence is a durable in memory implementation of utils.Sequence
+func (r *durableMemoryRepo) NextSequence(ctx context.Context) (uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next := r.memoryRepo.seq + 1
+	if err := r.write(&walRecord{Op: opSequence, Sequence: next}); err != nil {
+		return 0, err
+	}
+
+	return next, nil
+}
+
+// Close stops the background snapshots and flushes the log to disk.
+// Closing it again returns the result of the first close.
+func (r *durableMemoryRepo) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.stop)
+		r.wg.Wait()
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		if err := r.wal.Sync(); err != nil {
+			r.wal.Close()
+			r.closeErr = err
+			return
+		}
+
+		r.closeErr = r.wal.Close()
+	})
+
+	return r.closeErr
+}
+
+// walRecord is a single entry in the log or a snapshot
+type walRecord struct {
+	Op         string             `json:"op"`
+	Url        *entity.Url        `json:"url,omitempty"`
+	Sequence   uint64             `json:"sequence,omitempty"`
+	Domain     *entity.Domain     `json:"domain,omitempty"`
+	Workspace  *entity.Workspace  `json:"workspace,omitempty"`
+	User       *entity.User       `json:"user,omitempty"`
+	Membership *entity.Membership `json:"membership,omitempty"`
+	Audit      *entity.AuditEntry `json:"audit,omitempty"`
+	Subject    string             `json:"subject,omitempty"`   // the subject anonymized in the audit log
+	Pseudonym  string             `json:"pseudonym,omitempty"` // and what replaces it
+
+	changed int // how many domains or audit entries applying the record changed
+}
+
+// append writes a record to the current log segment. r.mu must be held.
+func (r *durableMemoryRepo) append(record *walRecord) error {
+	if err := writeRecord(r.wal, record); err != nil {
+		return fmt.Errorf("couldn't write to log: %w", err)
+	}
+	r.records++
+
+	if r.cfg.Sync == SyncAlways {
+		if err := r.wal.Sync(); err != nil {
+			return fmt.Errorf("couldn't sync log: %w", err)
+		}
+		return nil
+	}
+
+	r.dirty = true
+	return nil
+}
+
+// write logs a record, then applies it. It doesn't take a context, as once a record is logged it has to be applied,
+// or it would reappear on replay. r.mu must be held.
+func (r *durableMemoryRepo) write(record *walRecord) error {
+	if err := r.append(record); err != nil {
+		return err
+	}
+
+	return r.apply(record)
+}
+
+// apply applies a record to the in memory state, both when it's written and when it's replayed.
+func (r *durableMemoryRepo) apply(record *walRecord) error {
+	switch record.Op {
+	case opCreate, opUpdate, opDelete, opSequence:
+	default:
+		return r.applyState(record)
+	}
+
+	r.memoryRepo.mu.Lock()
+	defer r.memoryRepo.mu.Unlock()
+
+	switch record.Op {
+	case opCreate, opUpdate:
+		if record.Url == nil {
+			return fmt.Errorf("%w: %s record without a url", ErrCorruptLog, record.Op)
+		}
+		stored := *record.Url
+		r.memoryRepo.urls[record.Url.Address()] = &stored
+	case opDelete:
+		if record.Url == nil {
+			return fmt.Errorf("%w: %s record without a url", ErrCorruptLog, record.Op)
+		}
+		delete(r.memoryRepo.urls, record.Url.Address())
+	case opSequence:
+		if record.Sequence > r.memoryRepo.seq {
+			r.memoryRepo.seq = record.Sequence
+		}
+	}
+
+	return nil
+}
+
+// recover loads the latest snapshot, replays every log segment written since,
+// and opens a new segment to append to.
+func (r *durableMemoryRepo) recover() error {
+	snapshots, err := r.list(snapshotPrefix, snapshotSuffix)
+	if err != nil {
+		return err
+	}
+
+	segments, err := r.list(walPrefix, walSuffix)
+	if err != nil {
+		return err
+	}
+
+	// snapshots are written to a temporary file and renamed, so the latest one is always complete
+	start := 0
+	if len(snapshots) > 0 {
+		start = snapshots[len(snapshots)-1]
+		if err := r.replay(r.path(snapshotPrefix, start, snapshotSuffix), false); err != nil {
+			return err
+		}
+	}
+
+	r.segment = start
+	for i, segment := range segments {
+		if segment < start {
+			continue
+		}
+
+		// only the last segment can have a torn record from a crash mid-write
+		last := i == len(segments)-1
+		if err := r.replay(r.path(walPrefix, segment, walSuffix), last); err != nil {
+			return err
+		}
+		r.segment = segment
+	}
+
+	// we always start a new segment, rather than appending after a possibly truncated record
+	if err := r.rotate(); err != nil {
+		return err
+	}
+
+	r.removeBefore(start)
+	return nil
+}
+
+// replay applies every record in the file at path. If truncate is set, a torn or corrupt record
+// at the end of the file is assumed to be from a crash mid-write, and is truncated.
+func (r *durableMemoryRepo) replay(path string, truncate bool) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("couldn't open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	var offset int64
+	for {
+		record, size, err := readRecord(reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			if !truncate {
+				return fmt.Errorf("couldn't replay %s: %w", path, err)
+			}
+
+			if err := f.Truncate(offset); err != nil {
+				return fmt.Errorf("couldn't truncate torn record from %s: %w", path, err)
+			}
+			return f.Sync()
+		}
+
+		// operations that failed when they were written, such as adding a domain that exists, fail again
+		if err := r.apply(record); errors.Is(err, ErrCorruptLog) {
+			return fmt.Errorf("couldn't replay %s: %w", path, err)
+		}
+		offset += size
+	}
+}
+
+// rotate closes the current log segment and starts the next one. r.mu must be held, or r not yet shared.
+func (r *durableMemoryRepo) rotate() error {
+	if r.wal != nil {
+		if err := r.wal.Sync(); err != nil {
+			return err
+		}
+		if err := r.wal.Close(); err != nil {
+			return err
+		}
+	}
+
+	r.segment++
+	wal, err := os.OpenFile(r.path(walPrefix, r.segment, walSuffix), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("couldn't create log segment: %w", err)
+	}
+
+	r.wal = wal
+	r.dirty = false
+	r.records = 0
+	return syncDir(r.cfg.Dir)
+}
+
+// Snapshot writes the current state to a snapshot and removes the log segments it replaces.
+func (r *durableMemoryRepo) Snapshot() error {
+	// copy the state and start a new segment while holding the lock,
+	// then write the snapshot without blocking writes.
+	r.mu.Lock()
+	urls := make([]entity.Url, 0, len(r.memoryRepo.urls))
+	r.memoryRepo.mu.RLock()
+	for _, url := range r.memoryRepo.urls {
+		urls = append(urls, *url)
+	}
+	r.memoryRepo.mu.RUnlock()
+	seq := r.memoryRepo.seq
+	state := r.stateRecords()
+
+	err := r.rotate()
+	segment := r.segment
+	r.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	path := r.path(snapshotPrefix, segment, snapshotSuffix)
+	tmp, err := os.Create(path + ".tmp")
+	if err != nil {
+		return fmt.Errorf("couldn't create snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	w := bufio.NewWriter(tmp)
+	if err := writeRecord(w, &walRecord{Op: opSequence, Sequence: seq}); err != nil {
+		return err
+	}
+	for i := range urls {
+		if err := writeRecord(w, &walRecord{Op: opCreate, Url: &urls[i]}); err != nil {
+			return err
+		}
+	}
+	for i := range state {
+		if err := writeRecord(w, &state[i]); err != nil {
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	if err := syncDir(r.cfg.Dir); err != nil {
+		return err
+	}
+
+	r.removeBefore(segment)
+	return nil
+}
+
+// background fsyncs the log and writes snapshots on the configured intervals.
+func (r *durableMemoryRepo) background() {
+	defer r.wg.Done()
+
+	snapshot := time.NewTicker(r.cfg.SnapshotInterval)
+	defer snapshot.Stop()
+
+	// only the interval policy needs syncing, but a nil channel never fires
+	var syncTick <-chan time.Time
+	if r.cfg.Sync == SyncInterval {
+		ticker := time.NewTicker(r.cfg.SyncInterval)
+		defer ticker.Stop()
+		syncTick = ticker.C
+	}
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-syncTick:
+			r.mu.Lock()
+			if r.dirty {
+				if err := r.wal.Sync(); err != nil {
+					r.cfg.Logger.Errorf("Couldn't sync the log in %s, trying again next interval: %v", r.cfg.Dir, err)
+				} else {
+					r.dirty = false
+				}
+			}
+			r.mu.Unlock()
+		case <-snapshot.C:
+			r.mu.Lock()
+			written := r.records > 0
+			r.mu.Unlock()
+
+			// if a snapshot fails, the log segments are kept and we try again next interval
+			if written {
+				if err := r.Snapshot(); err != nil {
+					r.cfg.Logger.Errorf("Couldn't snapshot %s, keeping the log and trying again next interval: %v", r.cfg.Dir, err)
+				}
+			}
+		}
+	}
+}
+
+// list returns the numbers of the files in the directory with the given prefix and suffix, in ascending order.
+func (r *durableMemoryRepo) list(prefix, suffix string) ([]int, error) {
+	entries, err := os.ReadDir(r.cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	numbers := []int{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+			continue
+		}
+
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, prefix), suffix))
+		if err != nil {
+			continue
+		}
+		numbers = append(numbers, n)
+	}
+
+	sort.Ints(numbers)
+	return numbers, nil
+}
+
+// removeBefore removes the snapshots and log segments replaced by snapshot n.
+func (r *durableMemoryRepo) removeBefore(n int) {
+	for _, kind := range [][2]string{{walPrefix, walSuffix}, {snapshotPrefix, snapshotSuffix}} {
+		numbers, err := r.list(kind[0], kind[1])
+		if err != nil {
+			return
+		}
+
+		for _, number := range numbers {
+			if number < n {
+				os.Remove(r.path(kind[0], number, kind[1]))
+			}
+		}
+	}
+}
+
+func (r *durableMemoryRepo) path(prefix string, n int, suffix string) string {
+	return filepath.Join(r.cfg.Dir, fmt.Sprintf("%s%08d%s", prefix, n, suffix))
+}
+
+// writeRecord writes a length and checksum prefixed record to w.
+func writeRecord(w io.Writer, record *walRecord) error {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+	copy(buf[recordHeaderSize:], payload)
+
+	_, err = w.Write(buf)
+	return err
+}
+
+// readRecord reads a record written by writeRecord, returning it and how many bytes it took up.
+// io.EOF is only returned if there are no more records, a partial record is ErrCorruptLog.
+func readRecord(r io.Reader) (*walRecord, int64, error) {
+	header := make([]byte, recordHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, 0, io.EOF
+		}
+		return nil, 0, fmt.Errorf("%w: torn record header", ErrCorruptLog)
+	}
+
+	size := binary.BigEndian.Uint32(header[0:4])
+	if size > maxRecordSize {
+		return nil, 0, fmt.Errorf("%w: record size %d is too large", ErrCorruptLog, size)
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, 0, fmt.Errorf("%w: torn record", ErrCorruptLog)
+	}
+
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, 0, fmt.Errorf("%w: checksum mismatch", ErrCorruptLog)
+	}
+
+	record := &walRecord{}
+	if err := json.Unmarshal(payload, record); err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrCorruptLog, err)
+	}
+
+	return record, int64(recordHeaderSize + len(payload)), nil
+}
+
+// syncDir fsyncs a directory, so newly created and renamed files in it survive a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	// not every platform supports syncing a directory, but if it doesn't there's nothing more we can do
+	d.Sync()
+	return nil
+}
@@ -0,0 +1,253 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDurableRepo(t *testing.T, dir string) *durableMemoryRepo {
+	repo, err := NewDurableInMemoryRepo(DurableConfig{Dir: dir})
+	assert.NoError(t, err)
+	return repo.(*durableMemoryRepo)
+}
+
+func Test_DurableMemoryRepo_Replay(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	createdAt := time.Now().UTC().Truncate(time.Second)
+
+	repo := newDurableRepo(t, dir)
+	assert.NoError(t, repo.Create(ctx, &entity.Url{Token: "123456", TargetUrl: "https://example.com", CreatedAt: createdAt}))
+	assert.NoError(t, repo.Create(ctx, &entity.Url{Token: "987654", TargetUrl: "https://google.com", CreatedAt: createdAt}))
+	assert.NoError(t, repo.Update(ctx, &entity.Url{Token: "123456", TargetUrl: "https://example.com", Visits: 3, CreatedAt: createdAt}))
+	assert.NoError(t, repo.Create(ctx, &entity.Url{Token: "deleted", TargetUrl: "https://example.org", CreatedAt: createdAt}))
+	assert.NoError(t, repo.Delete(ctx, "deleted"))
+	_, err := repo.NextSequence(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Close())
+	assert.NoError(t, repo.Close(), "closing again is a no-op")
+
+	repo = newDurableRepo(t, dir)
+	defer repo.Close()
+
+	url, err := repo.FindByToken(ctx, "123456")
+	assert.NoError(t, err)
+	assert.Equal(t, &entity.Url{Token: "123456", TargetUrl: "https://example.com", Visits: 3, CreatedAt: createdAt}, url)
+
+	count, err := repo.Count(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	_, err = repo.FindByToken(ctx, "deleted")
+	assert.ErrorIs(t, err, ErrUrlNotFound)
+
+	seq, err := repo.NextSequence(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), seq)
+
+	assert.ErrorIs(t, repo.Create(ctx, &entity.Url{Token: "987654"}), ErrTokenAlreadyExists)
+	assert.ErrorIs(t, repo.Update(ctx, &entity.Url{Token: "qwerty"}), ErrUrlNotFound)
+}
+
+// cancelledAfter is a context that's cancelled once Err has been checked checks times,
+// so a write can be cancelled part way through.
+type cancelledAfter struct {
+	context.Context
+	checks int
+}
+
+func (c *cancelledAfter) Err() error {
+	if c.checks == 0 {
+		return context.Canceled
+	}
+	c.checks--
+	return nil
+}
+
+func Test_DurableMemoryRepo_CancelledAfterLogging(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	// the create and update are cancelled once they've checked their context before logging,
+	// so they have to be applied, as they'll be replayed
+	repo := newDurableRepo(t, dir)
+	assert.NoError(t, repo.Create(&cancelledAfter{Context: ctx, checks: 2}, &entity.Url{Token: "123456", TargetUrl: "https://example.com"}))
+	assert.NoError(t, repo.Update(&cancelledAfter{Context: ctx, checks: 2}, &entity.Url{Token: "123456", TargetUrl: "https://example.org"}))
+
+	url, err := repo.FindByToken(ctx, "123456")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.org", url.TargetUrl)
+	assert.NoError(t, repo.Close())
+
+	repo = newDurableRepo(t, dir)
+	defer repo.Close()
+
+	replayed, err := repo.FindByToken(ctx, "123456")
+	assert.NoError(t, err)
+	assert.Equal(t, url, replayed)
+}
+
+func Test_DurableMemoryRepo_ReplayState(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	createdAt := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	open := func() (*durableMemoryRepo, DomainRepository, WorkspaceRepository, AuditRepository) {
+		repo := newDurableRepo(t, dir)
+		domains, err := NewDomainRepository(repo)
+		require.NoError(t, err)
+		workspaces, err := NewWorkspaceRepository(repo)
+		require.NoError(t, err)
+		audit, err := NewAuditRepository(repo)
+		require.NoError(t, err)
+		return repo, domains, workspaces, audit
+	}
+
+	repo, domains, workspaces, audit := open()
+	domain := &entity.Domain{Name: "go.example.com", WorkspaceID: "ws_1", VerificationToken: "abc", CreatedAt: createdAt}
+	require.NoError(t, workspaces.CreateWorkspace(ctx, &entity.Workspace{ID: "ws_1", Name: "Team", CreatedAt: createdAt},
+		&entity.Membership{UserID: "user_1", Role: entity.RoleOwner, CreatedAt: createdAt}))
+	require.NoError(t, workspaces.AddMember(ctx, &entity.Membership{WorkspaceID: "ws_1", UserID: "user_2", Role: entity.RoleViewer, CreatedAt: createdAt}))
+	require.NoError(t, workspaces.UpdateMemberRole(ctx, "ws_1", "user_2", entity.RoleEditor))
+	require.NoError(t, workspaces.SaveUser(ctx, &entity.User{ID: "user_2", Name: "Bob"}))
+	require.NoError(t, domains.CreateDomain(ctx, domain))
+	assert.ErrorIs(t, domains.CreateDomain(ctx, domain), ErrDomainExists, "a failed write is logged, and fails again on replay")
+	require.NoError(t, domains.CreateDomain(ctx, &entity.Domain{Name: "old.example.com", WorkspaceID: "ws_1", CreatedAt: createdAt}))
+	deleted, err := domains.DeleteUnverifiedDomain(ctx, "old.example.com", createdAt.Add(time.Hour))
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	for _, actor := range []string{"user_1", "user_2"} {
+		require.NoError(t, audit.AppendAuditEntry(ctx, &entity.AuditEntry{Action: entity.AuditMemberAdd, Actor: actor, WorkspaceID: "ws_1", CreatedAt: createdAt}))
+	}
+	changed, err := audit.AnonymizeAuditEntries(ctx, "user_1", "erased_1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, changed)
+
+	check := func(domains DomainRepository, workspaces WorkspaceRepository, audit AuditRepository) {
+		found, err := domains.ListDomains(ctx, "ws_1")
+		require.NoError(t, err)
+		assert.Equal(t, []entity.Domain{*domain}, found)
+
+		members, err := workspaces.ListMembers(ctx, "ws_1")
+		require.NoError(t, err)
+		assert.Equal(t, []entity.Membership{
+			{WorkspaceID: "ws_1", UserID: "user_1", Role: entity.RoleOwner, CreatedAt: createdAt},
+			{WorkspaceID: "ws_1", UserID: "user_2", Role: entity.RoleEditor, CreatedAt: createdAt},
+		}, members)
+
+		user, err := workspaces.FindUser(ctx, "user_2")
+		require.NoError(t, err)
+		assert.Equal(t, "Bob", user.Name)
+
+		entries, _, err := audit.ListAuditEntries(ctx, AuditFilter{}, "", 10)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, []string{"erased_1", "user_2"}, []string{entries[0].Actor, entries[1].Actor})
+		assert.Equal(t, []int64{1, 2}, []int64{entries[0].ID, entries[1].ID})
+	}
+
+	// replayed from the log
+	require.NoError(t, repo.Close())
+	repo, domains, workspaces, audit = open()
+	check(domains, workspaces, audit)
+
+	// and from a snapshot, which replaces it
+	require.NoError(t, repo.Snapshot())
+	require.NoError(t, repo.Close())
+	repo, domains, workspaces, audit = open()
+	defer repo.Close()
+	check(domains, workspaces, audit)
+}
+
+func Test_DurableMemoryRepo_Snapshot(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	repo := newDurableRepo(t, dir)
+	assert.NoError(t, repo.Create(ctx, &entity.Url{Token: "123456", TargetUrl: "https://example.com"}))
+	assert.NoError(t, repo.Snapshot())
+	assert.NoError(t, repo.Create(ctx, &entity.Url{Token: "987654", TargetUrl: "https://google.com"}))
+	assert.NoError(t, repo.Close())
+
+	// the snapshot should replace every log segment written before it
+	segments, err := repo.list(walPrefix, walSuffix)
+	assert.NoError(t, err)
+	snapshots, err := repo.list(snapshotPrefix, snapshotSuffix)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2}, snapshots)
+	assert.Equal(t, []int{2}, segments)
+
+	repo = newDurableRepo(t, dir)
+	defer repo.Close()
+
+	urls, _, err := repo.ListUrls(ctx, ListFilter{}, "", 1000)
+	assert.NoError(t, err)
+	assert.Len(t, urls, 2)
+}
+
+func Test_DurableMemoryRepo_TornRecord(t *testing.T) {
+	testCases := []struct {
+		name string
+		torn []byte
+	}{
+		{"Torn Header", []byte{0, 0, 0}},
+		{"Torn Payload", []byte{0, 0, 0, 42, 1, 2, 3, 4, '{'}},
+		{"Checksum Mismatch", []byte{0, 0, 0, 2, 1, 2, 3, 4, '{', '}'}},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			ctx := context.Background()
+
+			repo := newDurableRepo(t, dir)
+			assert.NoError(t, repo.Create(ctx, &entity.Url{Token: "123456", TargetUrl: "https://example.com"}))
+			assert.NoError(t, repo.Close())
+
+			// simulate a crash part way through writing a record
+			path := filepath.Join(dir, "wal-00000001.log")
+			before, err := os.Stat(path)
+			assert.NoError(t, err)
+
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+			assert.NoError(t, err)
+			_, err = f.Write(test.torn)
+			assert.NoError(t, err)
+			assert.NoError(t, f.Close())
+
+			repo = newDurableRepo(t, dir)
+			defer repo.Close()
+
+			_, err = repo.FindByToken(ctx, "123456")
+			assert.NoError(t, err)
+
+			after, err := os.Stat(path)
+			assert.NoError(t, err)
+			assert.Equal(t, before.Size(), after.Size())
+		})
+	}
+}
+
+func Test_DurableMemoryRepo_CorruptSnapshot(t *testing.T) {
+	dir := t.TempDir()
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "snapshot-00000001.snap"), []byte{0, 0, 0, 2, 1, 2, 3, 4, '{', '}'}, 0o644))
+
+	_, err := NewDurableInMemoryRepo(DurableConfig{Dir: dir})
+	assert.ErrorIs(t, err, ErrCorruptLog)
+}
+
+func Test_DurableMemoryRepo_InvalidConfig(t *testing.T) {
+	_, err := NewDurableInMemoryRepo(DurableConfig{})
+	assert.Error(t, err)
+
+	_, err = NewDurableInMemoryRepo(DurableConfig{Dir: t.TempDir(), Sync: "sometimes"})
+	assert.Error(t, err)
+}
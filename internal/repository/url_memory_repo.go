@@ -3,13 +3,17 @@ package repository
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 
 	"github.com/Jaytpa01/url-shortener-api/internal/entity"
 )
 
+// memoryRepo stores copies of urls, so callers can't change a stored url without calling Update.
+// Urls are keyed by their address, which is their token on the default domain.
 type memoryRepo struct {
 	urls map[string]*entity.Url
 	mu   sync.RWMutex
+	seq  uint64
 }
 
 func NewInMemoryRepo() UrlRepository {
@@ -21,55 +25,149 @@ func NewInMemoryRepo() UrlRepository {
 
 // Create is an in memory implementation of UrlRepository.Create
 func (r *memoryRepo) Create(ctx context.Context, url *entity.Url) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.urls[url.Token]; exists {
+	if _, exists := r.urls[url.Address()]; exists {
 		return ErrTokenAlreadyExists
 	}
 
-	r.urls[url.Token] = url
+	stored := *url
+	r.urls[url.Address()] = &stored
 	return nil
 }
 
 // FindByToken is an in memory implementation of UrlRepository.FindByToken
 func (r *memoryRepo) FindByToken(ctx context.Context, token string) (*entity.Url, error) {
+	return r.FindByDomainToken(ctx, "", token)
+}
+
+// FindByDomainToken is an in memory implementation of DomainUrlRepository.FindByDomainToken
+func (r *memoryRepo) FindByDomainToken(ctx context.Context, domain, token string) (*entity.Url, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	url, ok := r.urls[token]
+	url, ok := r.urls[(&entity.Url{Domain: domain, Token: token}).Address()]
 	if !ok {
 		return nil, ErrUrlNotFound
 	}
 
-	return url, nil
+	found := *url
+	return &found, nil
 }
 
-// Update is an in memory implementation of UrlRepository.Update
+// Update is an in memory implementation of UrlRepository.Update.
+// Like the sql repositories, it keeps the url's original creation time, owner and workspace.
 func (r *memoryRepo) Update(ctx context.Context, url *entity.Url) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	_, ok := r.urls[url.Token]
+	existing, ok := r.urls[url.Address()]
 	if !ok {
 		return ErrUrlNotFound
 	}
 
-	r.urls[url.Token] = url
+	stored := *url
+	stored.CreatedAt = existing.CreatedAt
+	stored.Owner = existing.Owner
+	stored.WorkspaceID = existing.WorkspaceID
+	r.urls[url.Address()] = &stored
+
+	return nil
+}
+
+// IncrementVisits is an in memory implementation of VisitIncrementer.IncrementVisits
+func (r *memoryRepo) IncrementVisits(ctx context.Context, token string) (int, error) {
+	return r.IncrementDomainVisits(ctx, "", token)
+}
+
+// IncrementDomainVisits is an in memory implementation of DomainVisitIncrementer.IncrementDomainVisits.
+// It only changes visits, so it can't undo a concurrent Update.
+func (r *memoryRepo) IncrementDomainVisits(ctx context.Context, domain, token string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	url, ok := r.urls[(&entity.Url{Domain: domain, Token: token}).Address()]
+	if !ok {
+		return 0, ErrUrlNotFound
+	}
+
+	url.Visits++
+	return url.Visits, nil
+}
+
+// Delete is an in memory implementation of UrlRepository.Delete
+func (r *memoryRepo) Delete(ctx context.Context, token string) error {
+	return r.DeleteFromDomain(ctx, "", token)
+}
+
+// DeleteFromDomain is an in memory implementation of DomainUrlRepository.DeleteFromDomain
+func (r *memoryRepo) DeleteFromDomain(ctx context.Context, domain, token string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	address := (&entity.Url{Domain: domain, Token: token}).Address()
+	if _, ok := r.urls[address]; !ok {
+		return ErrUrlNotFound
+	}
 
+	delete(r.urls, address)
 	return nil
 }
 
-func (r *memoryRepo) GetAllUrls(ctx context.Context) ([]entity.Url, error) {
+// ListUrls is an in memory implementation of UrlRepository.ListUrls
+func (r *memoryRepo) ListUrls(ctx context.Context, filter ListFilter, cursor string, limit int) ([]entity.Url, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+
+	r.mu.RLock()
+	urls := make([]entity.Url, 0, len(r.urls))
+	for _, url := range r.urls {
+		urls = append(urls, *url)
+	}
+	r.mu.RUnlock()
+
+	return listSlice(urls, filter, cursor, limit)
+}
+
+// Count is an in memory implementation of UrlRepository.Count
+func (r *memoryRepo) Count(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	idx := 0
-	urls := make([]entity.Url, len(r.urls))
-	for _, url := range r.urls {
-		urls[idx] = *url
-		idx++
+	return len(r.urls), nil
+}
+
+// NextSequence is an in memory implementation of utils.Sequence
+func (r *memoryRepo) NextSequence(ctx context.Context) (uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
 	}
 
-	return urls, nil
+	return atomic.AddUint64(&r.seq, 1), nil
 }
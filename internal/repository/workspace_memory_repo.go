@@ -0,0 +1,242 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+)
+
+// memoryWorkspaceRepo keeps workspaces in memory, for use with the memory url repository.
+// Workspaces are lost on restart, unless they belong to a durable memory repository.
+type memoryWorkspaceRepo struct {
+	workspaces  map[string]*entity.Workspace
+	users       map[string]*entity.User
+	memberships map[string]map[string]*entity.Membership // workspace id -> user id -> membership
+	mu          sync.RWMutex
+}
+
+// NewInMemoryWorkspaceRepo returns a WorkspaceRepository holding only the default workspace,
+// like a freshly migrated database.
+func NewInMemoryWorkspaceRepo() WorkspaceRepository {
+	return &memoryWorkspaceRepo{
+		workspaces: map[string]*entity.Workspace{
+			entity.DEFAULT_WORKSPACE: {ID: entity.DEFAULT_WORKSPACE, Name: "Default", CreatedAt: time.Now().UTC()},
+		},
+		users:       make(map[string]*entity.User),
+		memberships: make(map[string]map[string]*entity.Membership),
+	}
+}
+
+// ensureUser creates the user if they don't exist yet. r.mu must be held.
+func (r *memoryWorkspaceRepo) ensureUser(id string, createdAt time.Time) {
+	if _, ok := r.users[id]; !ok {
+		r.users[id] = &entity.User{ID: id, CreatedAt: createdAt}
+	}
+}
+
+// CreateWorkspace is an in memory implementation of WorkspaceRepository.CreateWorkspace
+func (r *memoryWorkspaceRepo) CreateWorkspace(ctx context.Context, workspace *entity.Workspace, owner *entity.Membership) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.workspaces[workspace.ID]; exists {
+		return ErrWorkspaceExists
+	}
+
+	stored := *workspace
+	r.workspaces[workspace.ID] = &stored
+
+	r.ensureUser(owner.UserID, owner.CreatedAt)
+	membership := *owner
+	membership.WorkspaceID = workspace.ID
+	r.memberships[workspace.ID] = map[string]*entity.Membership{owner.UserID: &membership}
+
+	return nil
+}
+
+// FindWorkspace is an in memory implementation of WorkspaceRepository.FindWorkspace
+func (r *memoryWorkspaceRepo) FindWorkspace(ctx context.Context, id string) (*entity.Workspace, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	workspace, ok := r.workspaces[id]
+	if !ok {
+		return nil, ErrWorkspaceNotFound
+	}
+
+	found := *workspace
+	return &found, nil
+}
+
+// SaveUser is an in memory implementation of WorkspaceRepository.SaveUser
+func (r *memoryWorkspaceRepo) SaveUser(ctx context.Context, user *entity.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.ensureUser(user.ID, user.CreatedAt)
+	if user.Name != "" {
+		r.users[user.ID].Name = user.Name
+	}
+
+	return nil
+}
+
+// FindUser is an in memory implementation of WorkspaceRepository.FindUser
+func (r *memoryWorkspaceRepo) FindUser(ctx context.Context, id string) (*entity.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+
+	found := *user
+	return &found, nil
+}
+
+// DeleteUser is an in memory implementation of WorkspaceRepository.DeleteUser
+func (r *memoryWorkspaceRepo) DeleteUser(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, members := range r.memberships {
+		delete(members, id)
+	}
+	delete(r.users, id)
+
+	return nil
+}
+
+// FindMembership is an in memory implementation of WorkspaceRepository.FindMembership
+func (r *memoryWorkspaceRepo) FindMembership(ctx context.Context, workspaceID, userID string) (*entity.Membership, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	membership, ok := r.memberships[workspaceID][userID]
+	if !ok {
+		return nil, ErrMembershipNotFound
+	}
+
+	found := *membership
+	return &found, nil
+}
+
+// ListMemberships is an in memory implementation of WorkspaceRepository.ListMemberships
+func (r *memoryWorkspaceRepo) ListMemberships(ctx context.Context, userID string) ([]entity.Membership, error) {
+	r.mu.RLock()
+	memberships := []entity.Membership{}
+	for _, members := range r.memberships {
+		if membership, ok := members[userID]; ok {
+			memberships = append(memberships, *membership)
+		}
+	}
+	r.mu.RUnlock()
+
+	sortMemberships(memberships, func(m *entity.Membership) string { return m.WorkspaceID })
+	return memberships, nil
+}
+
+// ListMembers is an in memory implementation of WorkspaceRepository.ListMembers
+func (r *memoryWorkspaceRepo) ListMembers(ctx context.Context, workspaceID string) ([]entity.Membership, error) {
+	r.mu.RLock()
+	members := []entity.Membership{}
+	for _, membership := range r.memberships[workspaceID] {
+		members = append(members, *membership)
+	}
+	r.mu.RUnlock()
+
+	sortMemberships(members, func(m *entity.Membership) string { return m.UserID })
+	return members, nil
+}
+
+// AddMember is an in memory implementation of WorkspaceRepository.AddMember
+func (r *memoryWorkspaceRepo) AddMember(ctx context.Context, membership *entity.Membership) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.workspaces[membership.WorkspaceID]; !ok {
+		return ErrWorkspaceNotFound
+	}
+
+	members, ok := r.memberships[membership.WorkspaceID]
+	if !ok {
+		members = make(map[string]*entity.Membership)
+		r.memberships[membership.WorkspaceID] = members
+	}
+
+	if _, exists := members[membership.UserID]; exists {
+		return ErrMembershipExists
+	}
+
+	r.ensureUser(membership.UserID, membership.CreatedAt)
+	stored := *membership
+	members[membership.UserID] = &stored
+
+	return nil
+}
+
+// UpdateMemberRole is an in memory implementation of WorkspaceRepository.UpdateMemberRole
+func (r *memoryWorkspaceRepo) UpdateMemberRole(ctx context.Context, workspaceID, userID string, role entity.Role) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	membership, ok := r.memberships[workspaceID][userID]
+	if !ok {
+		return ErrMembershipNotFound
+	}
+
+	if role != entity.RoleOwner && r.isLastOwner(membership) {
+		return ErrLastOwner
+	}
+
+	membership.Role = role
+	return nil
+}
+
+// RemoveMember is an in memory implementation of WorkspaceRepository.RemoveMember
+func (r *memoryWorkspaceRepo) RemoveMember(ctx context.Context, workspaceID, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	membership, ok := r.memberships[workspaceID][userID]
+	if !ok {
+		return ErrMembershipNotFound
+	}
+
+	if r.isLastOwner(membership) {
+		return ErrLastOwner
+	}
+
+	delete(r.memberships[workspaceID], userID)
+	return nil
+}
+
+// isLastOwner reports whether membership is the only owner of its workspace. r.mu must be held.
+func (r *memoryWorkspaceRepo) isLastOwner(membership *entity.Membership) bool {
+	if membership.Role != entity.RoleOwner {
+		return false
+	}
+
+	owners := 0
+	for _, m := range r.memberships[membership.WorkspaceID] {
+		if m.Role == entity.RoleOwner {
+			owners++
+		}
+	}
+
+	return owners == 1
+}
+
+// sortMemberships sorts memberships by creation time, then by key, like the sql repositories.
+func sortMemberships(memberships []entity.Membership, key func(*entity.Membership) string) {
+	sort.Slice(memberships, func(i, j int) bool {
+		if !memberships[i].CreatedAt.Equal(memberships[j].CreatedAt) {
+			return memberships[i].CreatedAt.Before(memberships[j].CreatedAt)
+		}
+		return key(&memberships[i]) < key(&memberships[j])
+	})
+}
@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/jmoiron/sqlx"
+)
+
+// NewWorkspaceRepository returns the WorkspaceRepository that stores workspaces alongside the urls in urlRepo.
+// The sqlite and postgres repositories keep them in the workspace, app_user and membership tables,
+// the memory repository keeps them in memory, and the durable memory repository also logs them to its directory.
+// The other repositories return ErrWorkspacesUnsupported.
+func NewWorkspaceRepository(urlRepo UrlRepository) (WorkspaceRepository, error) {
+	switch repo := urlRepo.(type) {
+	case *sqliteRepository:
+		return &sqlWorkspaceRepo{db: repo.db}, nil
+	case *postgresRepository:
+		return &sqlWorkspaceRepo{db: repo.db}, nil
+	case *memoryRepo:
+		return NewInMemoryWorkspaceRepo(), nil
+	case *durableMemoryRepo:
+		return &durableWorkspaceRepo{memoryWorkspaceRepo: repo.workspaces, repo: repo}, nil
+	default:
+		return nil, ErrWorkspacesUnsupported
+	}
+}
+
+// sqlWorkspaceRepo stores workspaces in the sqlite or postgres database.
+type sqlWorkspaceRepo struct {
+	db *sqlx.DB
+}
+
+// ensureUser creates the user if they don't exist yet.
+func ensureUser(ctx context.Context, tx *sqlx.Tx, id string, createdAt time.Time) error {
+	_, err := tx.ExecContext(ctx, tx.Rebind(`INSERT INTO app_user (id, name, created_at) VALUES (?, '', ?) ON CONFLICT (id) DO NOTHING`), id, createdAt)
+	return err
+}
+
+func (s *sqlWorkspaceRepo) CreateWorkspace(ctx context.Context, workspace *entity.Workspace, owner *entity.Membership) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Defer a rollback in case anything fails.
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, tx.Rebind(`INSERT INTO workspace (id, name, created_at) VALUES (?, ?, ?)`), workspace.ID, workspace.Name, workspace.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrWorkspaceExists
+		}
+		return err
+	}
+
+	if err := ensureUser(ctx, tx, owner.UserID, owner.CreatedAt); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, tx.Rebind(`INSERT INTO membership (workspace_id, user_id, role, created_at) VALUES (?, ?, ?, ?)`),
+		workspace.ID, owner.UserID, owner.Role, owner.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *sqlWorkspaceRepo) FindWorkspace(ctx context.Context, id string) (*entity.Workspace, error) {
+	workspace := &entity.Workspace{}
+
+	err := s.db.GetContext(ctx, workspace, s.db.Rebind(`SELECT id, name, created_at FROM workspace WHERE id = ?`), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWorkspaceNotFound
+		}
+
+		return nil, err
+	}
+
+	return workspace, nil
+}
+
+func (s *sqlWorkspaceRepo) SaveUser(ctx context.Context, user *entity.User) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(`INSERT INTO app_user (id, name, created_at) VALUES (?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET name = excluded.name WHERE excluded.name <> ''`), user.ID, user.Name, user.CreatedAt)
+	return err
+}
+
+func (s *sqlWorkspaceRepo) FindUser(ctx context.Context, id string) (*entity.User, error) {
+	user := &entity.User{}
+
+	err := s.db.GetContext(ctx, user, s.db.Rebind(`SELECT id, name, created_at FROM app_user WHERE id = ?`), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *sqlWorkspaceRepo) DeleteUser(ctx context.Context, id string) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Defer a rollback in case anything fails.
+	defer tx.Rollback()
+
+	// sqlite only cascades deletes with foreign keys enabled, so the memberships are deleted first
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM membership WHERE user_id = ?`), id); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM app_user WHERE id = ?`), id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *sqlWorkspaceRepo) FindMembership(ctx context.Context, workspaceID, userID string) (*entity.Membership, error) {
+	membership := &entity.Membership{}
+
+	err := s.db.GetContext(ctx, membership, s.db.Rebind(`SELECT workspace_id, user_id, role, created_at FROM membership WHERE workspace_id = ? AND user_id = ?`),
+		workspaceID, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrMembershipNotFound
+		}
+
+		return nil, err
+	}
+
+	return membership, nil
+}
+
+func (s *sqlWorkspaceRepo) ListMemberships(ctx context.Context, userID string) ([]entity.Membership, error) {
+	memberships := []entity.Membership{}
+
+	err := s.db.SelectContext(ctx, &memberships, s.db.Rebind(`SELECT workspace_id, user_id, role, created_at FROM membership WHERE user_id = ? ORDER BY created_at, workspace_id`), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return memberships, nil
+}
+
+func (s *sqlWorkspaceRepo) ListMembers(ctx context.Context, workspaceID string) ([]entity.Membership, error) {
+	members := []entity.Membership{}
+
+	err := s.db.SelectContext(ctx, &members, s.db.Rebind(`SELECT workspace_id, user_id, role, created_at FROM membership WHERE workspace_id = ? ORDER BY created_at, user_id`), workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+func (s *sqlWorkspaceRepo) AddMember(ctx context.Context, membership *entity.Membership) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Defer a rollback in case anything fails.
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.GetContext(ctx, &exists, tx.Rebind(`SELECT COUNT(*) FROM workspace WHERE id = ?`), membership.WorkspaceID); err != nil {
+		return err
+	}
+	if exists == 0 {
+		return ErrWorkspaceNotFound
+	}
+
+	if err := ensureUser(ctx, tx, membership.UserID, membership.CreatedAt); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, tx.Rebind(`INSERT INTO membership (workspace_id, user_id, role, created_at) VALUES (?, ?, ?, ?)`),
+		membership.WorkspaceID, membership.UserID, membership.Role, membership.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrMembershipExists
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// lastOwnerCondition only matches a member if they aren't an owner, or aren't the workspace's only owner.
+// It takes the workspace id as an argument.
+const lastOwnerCondition = `(role <> 'owner' OR (SELECT COUNT(*) FROM membership WHERE workspace_id = ? AND role = 'owner') > 1)`
+
+func (s *sqlWorkspaceRepo) UpdateMemberRole(ctx context.Context, workspaceID, userID string, role entity.Role) error {
+	query := `UPDATE membership SET role = ? WHERE workspace_id = ? AND user_id = ?`
+	args := []interface{}{role, workspaceID, userID}
+	if role != entity.RoleOwner {
+		query += " AND " + lastOwnerCondition
+		args = append(args, workspaceID)
+	}
+
+	result, err := s.db.ExecContext(ctx, s.db.Rebind(query), args...)
+	if err != nil {
+		return err
+	}
+
+	return s.checkMemberChanged(ctx, result, workspaceID, userID)
+}
+
+func (s *sqlWorkspaceRepo) RemoveMember(ctx context.Context, workspaceID, userID string) error {
+	result, err := s.db.ExecContext(ctx, s.db.Rebind(`DELETE FROM membership WHERE workspace_id = ? AND user_id = ? AND `+lastOwnerCondition),
+		workspaceID, userID, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	return s.checkMemberChanged(ctx, result, workspaceID, userID)
+}
+
+// checkMemberChanged works out why an update or delete of a membership didn't change anything.
+// Either the membership doesn't exist, or the change would have left the workspace without an owner.
+func (s *sqlWorkspaceRepo) checkMemberChanged(ctx context.Context, result sql.Result, workspaceID, userID string) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	if _, err := s.FindMembership(ctx, workspaceID, userID); err != nil {
+		return err
+	}
+
+	return ErrLastOwner
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WorkspaceRepository(t *testing.T) {
+	testCases := []struct {
+		name string
+		repo func(t *testing.T) WorkspaceRepository
+	}{
+		{"Memory", func(t *testing.T) WorkspaceRepository { return NewInMemoryWorkspaceRepo() }},
+		{"Durable Memory", func(t *testing.T) WorkspaceRepository {
+			urlRepo := newDurableRepo(t, t.TempDir())
+			t.Cleanup(func() { urlRepo.Close() })
+			repo, err := NewWorkspaceRepository(urlRepo)
+			require.NoError(t, err)
+			return repo
+		}},
+		{"SQLite", func(t *testing.T) WorkspaceRepository {
+			repo, err := NewWorkspaceRepository(newSQLiteRepo(t))
+			require.NoError(t, err)
+			return repo
+		}},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			repo := test.repo(t)
+			ctx := context.Background()
+			createdAt := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+
+			// the default workspace always exists
+			_, err := repo.FindWorkspace(ctx, entity.DEFAULT_WORKSPACE)
+			require.NoError(t, err)
+
+			require.NoError(t, repo.SaveUser(ctx, &entity.User{ID: "alice", Name: "Alice", CreatedAt: createdAt}))
+			require.NoError(t, repo.SaveUser(ctx, &entity.User{ID: "alice", CreatedAt: createdAt}))
+
+			workspace := &entity.Workspace{ID: "ws_1", Name: "Marketing", CreatedAt: createdAt}
+			require.NoError(t, repo.CreateWorkspace(ctx, workspace, &entity.Membership{WorkspaceID: "ws_1", UserID: "alice", Role: entity.RoleOwner, CreatedAt: createdAt}))
+			assert.ErrorIs(t, repo.CreateWorkspace(ctx, workspace, &entity.Membership{WorkspaceID: "ws_1", UserID: "bob", Role: entity.RoleOwner, CreatedAt: createdAt}), ErrWorkspaceExists)
+
+			found, err := repo.FindWorkspace(ctx, "ws_1")
+			require.NoError(t, err)
+			assert.Equal(t, "Marketing", found.Name)
+
+			_, err = repo.FindWorkspace(ctx, "unknown")
+			assert.ErrorIs(t, err, ErrWorkspaceNotFound)
+
+			// bob hasn't signed in yet, adding him creates his user
+			require.NoError(t, repo.AddMember(ctx, &entity.Membership{WorkspaceID: "ws_1", UserID: "bob", Role: entity.RoleViewer, CreatedAt: createdAt.Add(time.Hour)}))
+			assert.ErrorIs(t, repo.AddMember(ctx, &entity.Membership{WorkspaceID: "ws_1", UserID: "bob", Role: entity.RoleEditor, CreatedAt: createdAt}), ErrMembershipExists)
+			assert.ErrorIs(t, repo.AddMember(ctx, &entity.Membership{WorkspaceID: "unknown", UserID: "bob", Role: entity.RoleEditor, CreatedAt: createdAt}), ErrWorkspaceNotFound)
+
+			membership, err := repo.FindMembership(ctx, "ws_1", "bob")
+			require.NoError(t, err)
+			assert.Equal(t, entity.RoleViewer, membership.Role)
+
+			_, err = repo.FindMembership(ctx, entity.DEFAULT_WORKSPACE, "bob")
+			assert.ErrorIs(t, err, ErrMembershipNotFound)
+
+			members, err := repo.ListMembers(ctx, "ws_1")
+			require.NoError(t, err)
+			require.Len(t, members, 2)
+			assert.Equal(t, "alice", members[0].UserID)
+			assert.Equal(t, "bob", members[1].UserID)
+
+			memberships, err := repo.ListMemberships(ctx, "bob")
+			require.NoError(t, err)
+			require.Len(t, memberships, 1)
+			assert.Equal(t, "ws_1", memberships[0].WorkspaceID)
+
+			// the only owner can't be demoted or removed
+			assert.ErrorIs(t, repo.UpdateMemberRole(ctx, "ws_1", "alice", entity.RoleEditor), ErrLastOwner)
+			assert.ErrorIs(t, repo.RemoveMember(ctx, "ws_1", "alice"), ErrLastOwner)
+			assert.ErrorIs(t, repo.UpdateMemberRole(ctx, "ws_1", "carol", entity.RoleEditor), ErrMembershipNotFound)
+			assert.ErrorIs(t, repo.RemoveMember(ctx, "ws_1", "carol"), ErrMembershipNotFound)
+
+			// once there's another owner, they can
+			require.NoError(t, repo.UpdateMemberRole(ctx, "ws_1", "bob", entity.RoleOwner))
+			require.NoError(t, repo.UpdateMemberRole(ctx, "ws_1", "alice", entity.RoleEditor))
+			assert.ErrorIs(t, repo.RemoveMember(ctx, "ws_1", "bob"), ErrLastOwner)
+			require.NoError(t, repo.RemoveMember(ctx, "ws_1", "alice"))
+
+			_, err = repo.FindMembership(ctx, "ws_1", "alice")
+			assert.ErrorIs(t, err, ErrMembershipNotFound)
+
+			// deleting a user deletes their memberships, even of the only owner
+			user, err := repo.FindUser(ctx, "bob")
+			require.NoError(t, err)
+			assert.Equal(t, "bob", user.ID)
+
+			require.NoError(t, repo.DeleteUser(ctx, "bob"))
+			require.NoError(t, repo.DeleteUser(ctx, "bob"))
+			_, err = repo.FindUser(ctx, "bob")
+			assert.ErrorIs(t, err, ErrUserNotFound)
+
+			memberships, err = repo.ListMemberships(ctx, "bob")
+			require.NoError(t, err)
+			assert.Empty(t, memberships)
+			members, err = repo.ListMembers(ctx, "ws_1")
+			require.NoError(t, err)
+			assert.Empty(t, members)
+		})
+	}
+}
+
+func Test_NewWorkspaceRepository_Unsupported(t *testing.T) {
+	_, err := NewWorkspaceRepository(newRedisRepo(t))
+	assert.ErrorIs(t, err, ErrWorkspacesUnsupported)
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/audit"
+	"github.com/Jaytpa01/url-shortener-api/internal/auth"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
+	"github.com/Jaytpa01/url-shortener-api/pkg/utils"
+)
+
+const (
+	ACTOR_ANONYMOUS = "anonymous" // actor of changes made by anonymous requests
+	ACTOR_SERVER    = "server"    // actor of config changes, which are recorded when the server starts
+)
+
+// auditor records the changes a service makes in the audit log.
+type auditor struct {
+	logger    logger.Logger
+	auditRepo repository.AuditRepository // nil if the database can't store the audit log
+}
+
+// actor returns who the request in ctx was made by: the id of its api key or user, the name of the admin principal, or anonymous.
+func actor(ctx context.Context) string {
+	principal, ok := auth.FromContext(ctx)
+	switch {
+	case !ok:
+		return ACTOR_ANONYMOUS
+	case principal.Owner() != "":
+		return principal.Owner()
+	default:
+		return principal.Name
+	}
+}
+
+// record appends an entry for a change made by the request in ctx. The change has already been made,
+// so an entry that can't be appended is logged rather than failing the request, and the entry is appended
+// even if the request is cancelled meanwhile.
+func (a *auditor) record(ctx context.Context, action entity.AuditAction, workspaceID, target string, changes entity.AuditChanges) {
+	if a.auditRepo == nil {
+		return
+	}
+
+	source := audit.FromContext(ctx)
+	entry := &entity.AuditEntry{
+		Action:      action,
+		Actor:       actor(ctx),
+		WorkspaceID: workspaceID,
+		Target:      target,
+		IP:          source.IP,
+		RequestID:   source.RequestID,
+		Changes:     changes,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	if err := a.auditRepo.AppendAuditEntry(detachedContext{ctx}, entry); err != nil {
+		a.logger.Errorf("Couldn't record %s of %s in the audit log: %v", action, target, err)
+	}
+}
+
+// detachedContext keeps the values of its parent, but is never cancelled and has no deadline.
+// It stands in for context.WithoutCancel, which needs a newer Go.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (d detachedContext) Value(key interface{}) interface{} { return d.parent.Value(key) }
+
+type AuditConfig struct {
+	Logger    logger.Logger
+	AuditRepo repository.AuditRepository
+
+	// WorkspaceRepo holds the memberships users are authorized by. It's nil if the database can't store workspaces.
+	WorkspaceRepo repository.WorkspaceRepository
+}
+
+// auditService reads the audit log, and records changes to the server's config in it
+type auditService struct {
+	authorizer
+	auditor
+}
+
+func NewAuditService(c *AuditConfig) AuditService {
+	return &auditService{
+		authorizer: authorizer{workspaceRepo: c.WorkspaceRepo},
+		auditor:    auditor{logger: c.Logger, auditRepo: c.AuditRepo},
+	}
+}
+
+// ListAuditEntries returns a page of entries matching the filter, oldest first, and a cursor for the next page.
+// Only entries in the workspace the request acts in are listed, to its owners, unless it was made with the admin token.
+// The limit defaults to DEFAULT_LIST_LIMIT, and can't be more than MAX_LIST_LIMIT.
+func (s *auditService) ListAuditEntries(ctx context.Context, filter repository.AuditFilter, cursor string, limit int) ([]entity.AuditEntry, string, error) {
+	if filter.Action != "" && !filter.Action.Valid() {
+		actions := make([]string, len(entity.AUDIT_ACTIONS))
+		for i, action := range entity.AUDIT_ACTIONS {
+			actions[i] = string(action)
+		}
+
+		return nil, "", api.NewBadRequest("audit/invalid-action", fmt.Sprintf("Unknown action (%s).", filter.Action),
+			api.WithAction(fmt.Sprintf("Use one of %s.", strings.Join(actions, ", "))))
+	}
+
+	if principal, ok := auth.FromContext(ctx); !ok || !principal.Admin {
+		filter.WorkspaceID = activeWorkspace(ctx)
+		if err := s.authorize(ctx, filter.WorkspaceID, actionViewAudit); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if limit <= 0 {
+		limit = DEFAULT_LIST_LIMIT
+	}
+	limit = utils.Min(limit, MAX_LIST_LIMIT)
+
+	entries, next, err := s.auditRepo.ListAuditEntries(ctx, filter, cursor, limit)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return nil, "", api.NewBadRequest("audit/invalid-cursor", "The provided cursor is invalid.",
+				api.WithAction("Use the next_cursor from a previous page."))
+		}
+
+		apiErr := api.NewInternal("audit/couldnt-list", api.WithDebug(err.Error()))
+		s.logger.Info("Failed to list audit entries.", apiErr)
+		return nil, "", apiErr
+	}
+
+	return entries, next, nil
+}
+
+// RecordConfig compares the settings with those recorded by previous config changes, and records the ones that changed.
+// It returns the entry it recorded, or nil if nothing changed.
+func (s *auditService) RecordConfig(ctx context.Context, settings map[string]string) (*entity.AuditEntry, error) {
+	previous, err := s.recordedConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(settings)+len(previous))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	for key := range previous {
+		if _, ok := settings[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := entity.AuditChanges{}
+	for _, key := range keys {
+		if previous[key] != settings[key] {
+			changes[key] = entity.AuditChange{Before: previous[key], After: settings[key]}
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	entry := &entity.AuditEntry{
+		Action:    entity.AuditConfigChange,
+		Actor:     ACTOR_SERVER,
+		Changes:   changes,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if err := s.auditRepo.AppendAuditEntry(ctx, entry); err != nil {
+		return nil, fmt.Errorf("couldn't record config change: %w", err)
+	}
+
+	return entry, nil
+}
+
+// recordedConfig replays every config change in the audit log, to find the settings they last recorded.
+func (s *auditService) recordedConfig(ctx context.Context) (map[string]string, error) {
+	settings := map[string]string{}
+	filter := repository.AuditFilter{Action: entity.AuditConfigChange}
+
+	cursor := ""
+	for {
+		entries, next, err := s.auditRepo.ListAuditEntries(ctx, filter, cursor, MAX_LIST_LIMIT)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read recorded config: %w", err)
+		}
+
+		for _, entry := range entries {
+			for key, change := range entry.Changes {
+				if change.After == "" {
+					delete(settings, key)
+				} else {
+					settings[key] = change.After
+				}
+			}
+		}
+
+		if next == "" {
+			return settings, nil
+		}
+		cursor = next
+	}
+}
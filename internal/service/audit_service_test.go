@@ -0,0 +1,342 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/audit"
+	"github.com/Jaytpa01/url-shortener-api/internal/auth"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// auditEntries returns every entry in the audit log, ignoring when they were created.
+func auditEntries(t *testing.T, repo repository.AuditRepository) []entity.AuditEntry {
+	entries, _, err := repo.ListAuditEntries(context.Background(), repository.AuditFilter{}, "", MAX_LIST_LIMIT)
+	require.NoError(t, err)
+
+	for i := range entries {
+		assert.False(t, entries[i].CreatedAt.IsZero())
+		entries[i].CreatedAt = time.Time{}
+	}
+
+	return entries
+}
+
+func Test_UrlService_Audit(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	workspaces := repository.NewInMemoryWorkspaceRepo()
+	require.NoError(t, workspaces.CreateWorkspace(ctx, &entity.Workspace{ID: "ws_1", Name: "Team", CreatedAt: now},
+		&entity.Membership{WorkspaceID: "ws_1", UserID: "user_editor", Role: entity.RoleEditor, CreatedAt: now}))
+	require.NoError(t, workspaces.AddMember(ctx, &entity.Membership{WorkspaceID: "ws_1", UserID: "user_viewer", Role: entity.RoleViewer, CreatedAt: now}))
+
+	auditRepo := repository.NewInMemoryAuditRepo()
+	service := NewUrlService(&Config{
+		Logger:        logger.NewApiLogger("development"),
+		UrlRepo:       repository.NewInMemoryRepo(),
+		WorkspaceRepo: workspaces,
+		AuditRepo:     auditRepo,
+	})
+
+	editor := audit.NewContext(auth.NewContext(ctx, &auth.Principal{UserID: "user_editor", WorkspaceID: "ws_1"}),
+		audit.Source{IP: "192.0.2.1", RequestID: "req-1"})
+
+	url, err := service.ShortenUrl(editor, "https://example.com")
+	require.NoError(t, err)
+	_, err = service.UpdateUrl(editor, url.Token, "https://example.org")
+	require.NoError(t, err)
+
+	// changes that aren't made aren't recorded
+	viewer := auth.NewContext(ctx, &auth.Principal{UserID: "user_viewer", WorkspaceID: "ws_1"})
+	_, err = service.UpdateUrl(viewer, url.Token, "https://attacker.example")
+	require.Error(t, err)
+
+	anonymous, err := service.ShortenUrl(audit.NewContext(ctx, audit.Source{IP: "192.0.2.2", RequestID: "req-2"}), "https://example.net")
+	require.NoError(t, err)
+
+	admin := auth.NewContext(ctx, &auth.Principal{Name: "admin", Admin: true})
+	require.NoError(t, service.DeleteUrl(admin, url.Token))
+
+	assert.Equal(t, []entity.AuditEntry{
+		{
+			ID: 1, Action: entity.AuditLinkCreate, Actor: "user_editor", WorkspaceID: "ws_1", Target: url.Token, IP: "192.0.2.1", RequestID: "req-1",
+			Changes: entity.AuditChanges{"target_url": {After: "https://example.com"}},
+		},
+		{
+			ID: 2, Action: entity.AuditLinkRetarget, Actor: "user_editor", WorkspaceID: "ws_1", Target: url.Token, IP: "192.0.2.1", RequestID: "req-1",
+			Changes: entity.AuditChanges{"target_url": {Before: "https://example.com", After: "https://example.org"}},
+		},
+		{
+			ID: 3, Action: entity.AuditLinkCreate, Actor: ACTOR_ANONYMOUS, WorkspaceID: entity.DEFAULT_WORKSPACE, Target: anonymous.Token, IP: "192.0.2.2", RequestID: "req-2",
+			Changes: entity.AuditChanges{"target_url": {After: "https://example.net"}},
+		},
+		{
+			ID: 4, Action: entity.AuditLinkDelete, Actor: "admin", WorkspaceID: "ws_1", Target: url.Token,
+			Changes: entity.AuditChanges{"target_url": {Before: "https://example.org"}},
+		},
+	}, auditEntries(t, auditRepo))
+}
+
+// contextCheckingAuditRepo is an AuditRepository that refuses to append entries with a cancelled context, like a database would.
+type contextCheckingAuditRepo struct {
+	repository.AuditRepository
+}
+
+func (r contextCheckingAuditRepo) AppendAuditEntry(ctx context.Context, entry *entity.AuditEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return r.AuditRepository.AppendAuditEntry(ctx, entry)
+}
+
+func Test_Record_CancelledRequest(t *testing.T) {
+	auditRepo := repository.NewInMemoryAuditRepo()
+	auditor := auditor{logger: logger.NewApiLogger("development"), auditRepo: contextCheckingAuditRepo{auditRepo}}
+
+	// the client went away after the change was made, but it's still recorded with the request's source
+	ctx, cancel := context.WithCancel(audit.NewContext(userContext("user_1"), audit.Source{IP: "192.0.2.1", RequestID: "req-1"}))
+	cancel()
+	auditor.record(ctx, entity.AuditLinkDelete, entity.DEFAULT_WORKSPACE, "abcdef", nil)
+
+	assert.Equal(t, []entity.AuditEntry{
+		{ID: 1, Action: entity.AuditLinkDelete, Actor: "user_1", WorkspaceID: entity.DEFAULT_WORKSPACE, Target: "abcdef", IP: "192.0.2.1", RequestID: "req-1"},
+	}, auditEntries(t, auditRepo))
+}
+
+func Test_KeyService_Audit(t *testing.T) {
+	auditRepo := repository.NewInMemoryAuditRepo()
+	keys := NewKeyService(&KeyConfig{
+		Logger:     logger.NewApiLogger("development"),
+		ApiKeyRepo: repository.NewInMemoryApiKeyRepo(),
+		AuditRepo:  auditRepo,
+	})
+
+	ctx := auth.NewContext(context.Background(), &auth.Principal{Name: "cli", Admin: true})
+	key, _, err := keys.CreateKey(ctx, "dashboard", "", entity.Scopes{entity.ScopeAnalyticsRead})
+	require.NoError(t, err)
+	require.NoError(t, keys.RevokeKey(ctx, key.ID))
+
+	assert.Equal(t, []entity.AuditEntry{
+		{
+			ID: 1, Action: entity.AuditKeyCreate, Actor: "cli", WorkspaceID: entity.DEFAULT_WORKSPACE, Target: key.ID,
+			Changes: entity.AuditChanges{"name": {After: "dashboard"}, "scopes": {After: "analytics:read"}},
+		},
+		{ID: 2, Action: entity.AuditKeyRevoke, Actor: "cli", Target: key.ID},
+	}, auditEntries(t, auditRepo))
+}
+
+func Test_WorkspaceService_Audit(t *testing.T) {
+	auditRepo := repository.NewInMemoryAuditRepo()
+	workspaces := NewWorkspaceService(&WorkspaceConfig{
+		Logger:        logger.NewApiLogger("development"),
+		WorkspaceRepo: repository.NewInMemoryWorkspaceRepo(),
+		AuditRepo:     auditRepo,
+	})
+
+	owner := userContext("user_owner")
+	workspace, err := workspaces.CreateWorkspace(owner, "Team")
+	require.NoError(t, err)
+	id := workspace.ID
+
+	_, err = workspaces.AddMember(owner, id, "user_2", entity.RoleViewer)
+	require.NoError(t, err)
+	_, err = workspaces.UpdateMember(owner, id, "user_2", entity.RoleEditor)
+	require.NoError(t, err)
+	require.NoError(t, workspaces.RemoveMember(owner, id, "user_2"))
+
+	assert.Equal(t, []entity.AuditEntry{
+		{ID: 1, Action: entity.AuditWorkspaceCreate, Actor: "user_owner", WorkspaceID: id, Target: id, Changes: entity.AuditChanges{"name": {After: "Team"}}},
+		{ID: 2, Action: entity.AuditMemberAdd, Actor: "user_owner", WorkspaceID: id, Target: "user_2", Changes: entity.AuditChanges{"role": {After: "viewer"}}},
+		{ID: 3, Action: entity.AuditMemberUpdate, Actor: "user_owner", WorkspaceID: id, Target: "user_2", Changes: entity.AuditChanges{"role": {Before: "viewer", After: "editor"}}},
+		{ID: 4, Action: entity.AuditMemberRemove, Actor: "user_owner", WorkspaceID: id, Target: "user_2", Changes: entity.AuditChanges{"role": {Before: "editor"}}},
+	}, auditEntries(t, auditRepo))
+}
+
+func Test_DomainService_Audit(t *testing.T) {
+	workspaceRepo := repository.NewInMemoryWorkspaceRepo()
+	auditRepo := repository.NewInMemoryAuditRepo()
+	resolver := &stubResolver{records: map[string][]string{}}
+	domains := NewDomainService(&DomainConfig{
+		Logger:        logger.NewApiLogger("development"),
+		DomainRepo:    repository.NewInMemoryDomainRepo(),
+		WorkspaceRepo: workspaceRepo,
+		AuditRepo:     auditRepo,
+		Resolver:      resolver,
+	})
+
+	// the admin token owns every workspace
+	admin := auth.NewContext(context.Background(), &auth.Principal{Name: "admin", Admin: true})
+	domain, err := domains.AddDomain(admin, entity.DEFAULT_WORKSPACE, &entity.Domain{Name: "go.example.com", DefaultRedirect: "https://example.com"})
+	require.NoError(t, err)
+
+	resolver.records[domain.VerificationRecord()] = []string{domain.VerificationValue()}
+	_, err = domains.VerifyDomain(admin, entity.DEFAULT_WORKSPACE, "go.example.com")
+	require.NoError(t, err)
+	_, err = domains.UpdateDomain(admin, entity.DEFAULT_WORKSPACE, &entity.Domain{Name: "go.example.com", DefaultRedirect: "https://example.com", NotFoundUrl: "https://example.com/404"})
+	require.NoError(t, err)
+	require.NoError(t, domains.RemoveDomain(admin, entity.DEFAULT_WORKSPACE, "go.example.com"))
+
+	assert.Equal(t, []entity.AuditEntry{
+		{ID: 1, Action: entity.AuditDomainAdd, Actor: "admin", WorkspaceID: entity.DEFAULT_WORKSPACE, Target: "go.example.com",
+			Changes: entity.AuditChanges{"default_redirect": {After: "https://example.com"}}},
+		{ID: 2, Action: entity.AuditDomainVerify, Actor: "admin", WorkspaceID: entity.DEFAULT_WORKSPACE, Target: "go.example.com",
+			Changes: entity.AuditChanges{"verified": {Before: "false", After: "true"}}},
+		{ID: 3, Action: entity.AuditDomainUpdate, Actor: "admin", WorkspaceID: entity.DEFAULT_WORKSPACE, Target: "go.example.com",
+			Changes: entity.AuditChanges{"not_found_url": {After: "https://example.com/404"}}},
+		{ID: 4, Action: entity.AuditDomainRemove, Actor: "admin", WorkspaceID: entity.DEFAULT_WORKSPACE, Target: "go.example.com",
+			Changes: entity.AuditChanges{"default_redirect": {Before: "https://example.com"}, "not_found_url": {Before: "https://example.com/404"}}},
+	}, auditEntries(t, auditRepo))
+}
+
+func Test_ListAuditEntries(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	workspaces := repository.NewInMemoryWorkspaceRepo()
+	require.NoError(t, workspaces.CreateWorkspace(ctx, &entity.Workspace{ID: "ws_1", Name: "Team", CreatedAt: now},
+		&entity.Membership{WorkspaceID: "ws_1", UserID: "user_owner", Role: entity.RoleOwner, CreatedAt: now}))
+	require.NoError(t, workspaces.AddMember(ctx, &entity.Membership{WorkspaceID: "ws_1", UserID: "user_editor", Role: entity.RoleEditor, CreatedAt: now}))
+
+	auditRepo := repository.NewInMemoryAuditRepo()
+	for _, entry := range []entity.AuditEntry{
+		{Action: entity.AuditLinkCreate, Actor: "user_editor", WorkspaceID: "ws_1", Target: "abcdef", CreatedAt: now},
+		{Action: entity.AuditLinkCreate, Actor: ACTOR_ANONYMOUS, WorkspaceID: entity.DEFAULT_WORKSPACE, Target: "ghijkl", CreatedAt: now},
+		{Action: entity.AuditLinkRetarget, Actor: "user_editor", WorkspaceID: "ws_1", Target: "abcdef", CreatedAt: now},
+	} {
+		entry := entry
+		require.NoError(t, auditRepo.AppendAuditEntry(ctx, &entry))
+	}
+
+	audits := NewAuditService(&AuditConfig{
+		Logger:        logger.NewApiLogger("development"),
+		AuditRepo:     auditRepo,
+		WorkspaceRepo: workspaces,
+	})
+
+	testCases := []struct {
+		name        string
+		principal   *auth.Principal
+		filter      repository.AuditFilter
+		cursor      string
+		expectedIDs []int64
+		expectedErr error
+	}{
+		{"Admin Sees Everything", &auth.Principal{Admin: true}, repository.AuditFilter{}, "", []int64{1, 2, 3}, nil},
+		{"Admin Filters", &auth.Principal{Admin: true}, repository.AuditFilter{Action: entity.AuditLinkRetarget}, "", []int64{3}, nil},
+		{"Owner Sees Their Workspace", &auth.Principal{UserID: "user_owner", WorkspaceID: "ws_1"}, repository.AuditFilter{}, "", []int64{1, 3}, nil},
+		{
+			"Owner Can't See Other Workspaces",
+			&auth.Principal{UserID: "user_owner", WorkspaceID: "ws_1"},
+			repository.AuditFilter{WorkspaceID: entity.DEFAULT_WORKSPACE},
+			"",
+			[]int64{1, 3},
+			nil,
+		},
+		{"Admin Key Sees Its Workspace", &auth.Principal{KeyID: "key_1", WorkspaceID: "ws_1", Scopes: entity.Scopes{entity.ScopeAdmin}}, repository.AuditFilter{}, "", []int64{1, 3}, nil},
+		{
+			"Editor",
+			&auth.Principal{UserID: "user_editor", WorkspaceID: "ws_1"},
+			repository.AuditFilter{},
+			"",
+			nil,
+			api.NewForbidden("workspace/forbidden", "You need the owner role to view the audit log of workspace (ws_1)."),
+		},
+		{
+			"Anonymous",
+			nil,
+			repository.AuditFilter{},
+			"",
+			nil,
+			api.NewUnauthorized("auth/required", "You need to authenticate to view the audit log of workspace (default).",
+				api.WithAction("Send an api key or token in the Authorization header, as 'Bearer <key>'.")),
+		},
+		{
+			"Unknown Action",
+			&auth.Principal{Admin: true},
+			repository.AuditFilter{Action: "link.steal"},
+			"",
+			nil,
+			api.NewBadRequest("audit/invalid-action", "Unknown action (link.steal).",
+				api.WithAction("Use one of link.create, link.retarget, link.delete, link.disable, link.enable, key.create, key.revoke, workspace.create, member.add, member.update, member.remove, domain.add, domain.verify, domain.update, domain.remove, config.change, subject.export, subject.erase.")),
+		},
+		{
+			"Invalid Cursor",
+			&auth.Principal{Admin: true},
+			repository.AuditFilter{},
+			"not a cursor",
+			nil,
+			api.NewBadRequest("audit/invalid-cursor", "The provided cursor is invalid.", api.WithAction("Use the next_cursor from a previous page.")),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			if test.principal != nil {
+				ctx = auth.NewContext(ctx, test.principal)
+			}
+
+			entries, _, err := audits.ListAuditEntries(ctx, test.filter, test.cursor, 0)
+			if test.expectedErr != nil {
+				assert.Equal(t, test.expectedErr, err)
+				return
+			}
+
+			require.NoError(t, err)
+			ids := make([]int64, len(entries))
+			for i, entry := range entries {
+				ids[i] = entry.ID
+			}
+			assert.Equal(t, test.expectedIDs, ids)
+		})
+	}
+}
+
+func Test_RecordConfig(t *testing.T) {
+	ctx := context.Background()
+	auditRepo := repository.NewInMemoryAuditRepo()
+	audits := NewAuditService(&AuditConfig{
+		Logger:    logger.NewApiLogger("development"),
+		AuditRepo: auditRepo,
+	})
+
+	// the first run records every setting
+	entry, err := audits.RecordConfig(ctx, map[string]string{"server.port": "8080", "rate_limit.groups.api.requests": "10"})
+	require.NoError(t, err)
+	require.NotNil(t, entry)
+	assert.Equal(t, entity.AuditConfigChange, entry.Action)
+	assert.Equal(t, ACTOR_SERVER, entry.Actor)
+	assert.Equal(t, entity.AuditChanges{
+		"server.port":                    {After: "8080"},
+		"rate_limit.groups.api.requests": {After: "10"},
+	}, entry.Changes)
+
+	// nothing is recorded when nothing changed
+	entry, err = audits.RecordConfig(ctx, map[string]string{"server.port": "8080", "rate_limit.groups.api.requests": "10"})
+	require.NoError(t, err)
+	assert.Nil(t, entry)
+
+	// only the settings that changed, were added or were removed are recorded
+	entry, err = audits.RecordConfig(ctx, map[string]string{"server.port": "8080", "rate_limit.groups.api.requests": "50", "admin.token": "hmac-sha256:abc"})
+	require.NoError(t, err)
+	require.NotNil(t, entry)
+	assert.Equal(t, entity.AuditChanges{
+		"rate_limit.groups.api.requests": {Before: "10", After: "50"},
+		"admin.token":                    {After: "hmac-sha256:abc"},
+	}, entry.Changes)
+
+	entry, err = audits.RecordConfig(ctx, map[string]string{"server.port": "8080", "rate_limit.groups.api.requests": "50"})
+	require.NoError(t, err)
+	require.NotNil(t, entry)
+	assert.Equal(t, entity.AuditChanges{"admin.token": {Before: "hmac-sha256:abc"}}, entry.Changes)
+
+	assert.Len(t, auditEntries(t, auditRepo), 3)
+}
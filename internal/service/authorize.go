@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/auth"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+)
+
+// action is something a principal can do in a workspace, and the role it needs to do it.
+type action struct {
+	description string
+	role        entity.Role
+	anonymous   bool // whether anonymous requests can do it in the default workspace
+}
+
+var (
+	actionViewAnalytics = action{"view the analytics of links in", entity.RoleViewer, true}
+	actionListLinks     = action{"list the links in", entity.RoleViewer, false}
+	actionCreateLink    = action{"create links in", entity.RoleEditor, true}
+	actionImportLinks   = action{"import links into", entity.RoleEditor, false}
+	actionEditLink      = action{"edit links in", entity.RoleEditor, false}
+	actionDeleteLink    = action{"delete links in", entity.RoleEditor, false}
+	actionDisableLink   = action{"disable links in", entity.RoleOwner, false}
+	actionViewMembers   = action{"view the members of", entity.RoleViewer, false}
+	actionManageMembers = action{"manage the members of", entity.RoleOwner, false}
+	actionViewAudit     = action{"view the audit log of", entity.RoleOwner, false}
+	actionViewDomains   = action{"view the domains of", entity.RoleViewer, false}
+	actionManageDomains = action{"manage the domains of", entity.RoleOwner, false}
+)
+
+// authorizer checks the principal of a request can act in a workspace.
+//
+// Api keys are editors of the workspace they were created for, or owners with the admin scope, the admin token is an owner of every workspace,
+// and users have the role their membership gives them. Users without a membership of the default workspace are its editors.
+// As anyone can create links in the default workspace, its editors can only edit and delete the links they created there.
+// Anonymous requests can only create links in, and view analytics of, the default workspace,
+// and authenticating never stops a request doing what it could do anonymously.
+type authorizer struct {
+	workspaceRepo repository.WorkspaceRepository // nil if the database can't store workspaces
+}
+
+// activeWorkspace returns the workspace the request acts in.
+func activeWorkspace(ctx context.Context) string {
+	principal, _ := auth.FromContext(ctx)
+	return principalWorkspace(principal)
+}
+
+// principalWorkspace returns the workspace principal acts in, which is the default workspace for anonymous requests.
+func principalWorkspace(principal *auth.Principal) string {
+	if principal == nil || principal.WorkspaceID == "" {
+		return entity.DEFAULT_WORKSPACE
+	}
+
+	return principal.WorkspaceID
+}
+
+// authorize returns an error unless the principal of the request can do the action in the workspace.
+func (a *authorizer) authorize(ctx context.Context, workspaceID string, do action) error {
+	if do.anonymous && workspaceID == entity.DEFAULT_WORKSPACE {
+		return nil
+	}
+
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return api.NewUnauthorized("auth/required", fmt.Sprintf("You need to authenticate to %s workspace (%s).", do.description, workspaceID),
+			api.WithAction("Send an api key or token in the Authorization header, as 'Bearer <key>'."))
+	}
+
+	role, err := a.role(ctx, principal, workspaceID)
+	if err != nil {
+		return api.NewInternal("workspace/couldnt-authorize", api.WithDebug(err.Error()))
+	}
+
+	if !role.Includes(do.role) {
+		return api.NewForbidden("workspace/forbidden", fmt.Sprintf("You need the %s role to %s workspace (%s).", do.role, do.description, workspaceID))
+	}
+
+	return nil
+}
+
+// authorizeLink returns an error unless the request can do the action to url.
+// In the default workspace, only the principal that created the link or an owner of the workspace can do actions that aren't anonymous.
+func (a *authorizer) authorizeLink(ctx context.Context, url *entity.Url, do action) error {
+	workspaceID := url.Workspace()
+	if err := a.authorize(ctx, workspaceID, do); err != nil {
+		return err
+	}
+
+	if do.anonymous || workspaceID != entity.DEFAULT_WORKSPACE {
+		return nil
+	}
+
+	// authorize has already checked there's a principal
+	principal, _ := auth.FromContext(ctx)
+	if url.Owner != "" && url.Owner == principal.Owner() {
+		return nil
+	}
+
+	role, err := a.role(ctx, principal, workspaceID)
+	if err != nil {
+		return api.NewInternal("workspace/couldnt-authorize", api.WithDebug(err.Error()))
+	}
+
+	if !role.Includes(entity.RoleOwner) {
+		return api.NewForbidden("workspace/not-link-owner", fmt.Sprintf("You can only %s workspace (%s) that you created.", do.description, workspaceID))
+	}
+
+	return nil
+}
+
+// authorizeWorkspace checks the workspace exists, and that the request can do the action in it.
+// It needs a workspace repository.
+func (a *authorizer) authorizeWorkspace(ctx context.Context, workspaceID string, do action) error {
+	if _, err := a.workspaceRepo.FindWorkspace(ctx, workspaceID); err != nil {
+		if errors.Is(err, repository.ErrWorkspaceNotFound) {
+			return api.NewNotFound("workspace/not-found", fmt.Sprintf("There is no workspace with the id %s.", workspaceID))
+		}
+
+		return api.NewInternal("workspace/internal", api.WithDebug(err.Error()))
+	}
+
+	return a.authorize(ctx, workspaceID, do)
+}
+
+// role returns the role of principal in the workspace, or "" if it has none.
+func (a *authorizer) role(ctx context.Context, principal *auth.Principal, workspaceID string) (entity.Role, error) {
+	switch {
+	case principal.Admin:
+		return entity.RoleOwner, nil
+	case principal.KeyID != "":
+		if principalWorkspace(principal) != workspaceID {
+			return "", nil
+		}
+		if principal.Scopes.Has(entity.ScopeAdmin) {
+			return entity.RoleOwner, nil
+		}
+		return entity.RoleEditor, nil
+	case a.workspaceRepo == nil:
+		if workspaceID == entity.DEFAULT_WORKSPACE {
+			return entity.RoleEditor, nil
+		}
+		return "", nil
+	}
+
+	membership, err := a.workspaceRepo.FindMembership(ctx, workspaceID, principal.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrMembershipNotFound) {
+			if workspaceID == entity.DEFAULT_WORKSPACE {
+				return entity.RoleEditor, nil
+			}
+			return "", nil
+		}
+		return "", err
+	}
+
+	return membership.Role, nil
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/pkg/utils"
+)
+
+const (
+	DEFAULT_CHALLENGE_TTL       = 5 * time.Minute
+	DEFAULT_MIN_DIFFICULTY      = 16 // leading zero bits, about 65 thousand hashes
+	DEFAULT_MAX_DIFFICULTY      = 24 // about 16 million hashes
+	DEFAULT_CHALLENGE_LOAD_STEP = 60 // challenges solved in CHALLENGE_LOAD_WINDOW that raise the difficulty by a bit
+	CHALLENGE_LOAD_WINDOW       = time.Minute
+	CHALLENGE_ID_BYTES          = 16
+	MAX_DIFFICULTY              = 32 // beyond this, challenges couldn't be solved in a browser
+)
+
+type ChallengeConfig struct {
+	// Secret signs challenges, so they can't be forged.
+	Secret []byte
+
+	// TTL is how long a challenge can be solved for. Defaults to DEFAULT_CHALLENGE_TTL.
+	TTL time.Duration
+
+	// MinDifficulty and MaxDifficulty bound the leading zero bits a solution needs,
+	// and default to DEFAULT_MIN_DIFFICULTY and DEFAULT_MAX_DIFFICULTY.
+	MinDifficulty int
+	MaxDifficulty int
+
+	// LoadStep is how many challenges solved in CHALLENGE_LOAD_WINDOW raise the difficulty by a bit.
+	// Defaults to DEFAULT_CHALLENGE_LOAD_STEP.
+	LoadStep int
+
+	// Now defaults to time.Now, and is replaced in tests.
+	Now func() time.Time
+}
+
+// challengeService issues proof of work challenges, and verifies their solutions.
+// The challenges themselves are signed rather than stored, but solved challenges are remembered until they expire,
+// so a solution can't be replayed. They're only remembered by this instance.
+type challengeService struct {
+	secret   []byte
+	ttl      time.Duration
+	min, max int
+	loadStep int
+	now      func() time.Time
+
+	mu sync.Mutex
+	// the challenges solved in the current and previous load window, which the load is estimated from.
+	// Issued challenges aren't counted, as requesting them is free, so anyone could raise the difficulty for everyone.
+	window             time.Time
+	verified, previous int
+	solved             map[string]time.Time // ids of solved challenges, and when they expire
+}
+
+func NewChallengeService(c *ChallengeConfig) ChallengeService {
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+	if c.TTL <= 0 {
+		c.TTL = DEFAULT_CHALLENGE_TTL
+	}
+	if c.MinDifficulty <= 0 {
+		c.MinDifficulty = DEFAULT_MIN_DIFFICULTY
+	}
+	if c.MaxDifficulty <= 0 {
+		c.MaxDifficulty = DEFAULT_MAX_DIFFICULTY
+	}
+	if c.LoadStep <= 0 {
+		c.LoadStep = DEFAULT_CHALLENGE_LOAD_STEP
+	}
+
+	max := utils.Min(c.MaxDifficulty, MAX_DIFFICULTY)
+
+	return &challengeService{
+		secret:   c.Secret,
+		ttl:      c.TTL,
+		min:      utils.Min(c.MinDifficulty, max),
+		max:      max,
+		loadStep: c.LoadStep,
+		now:      c.Now,
+		solved:   map[string]time.Time{},
+	}
+}
+
+// IssueChallenge returns a new signed challenge. Its difficulty depends on how many challenges were solved recently.
+func (c *challengeService) IssueChallenge(ctx context.Context) (*entity.Challenge, error) {
+	idBytes := make([]byte, CHALLENGE_ID_BYTES)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, api.NewInternal("challenge/couldnt-issue", api.WithDebug(err.Error()))
+	}
+
+	now := c.now()
+	challenge := &entity.Challenge{
+		Difficulty: c.difficulty(now),
+		ExpiresAt:  now.Add(c.ttl).UTC().Truncate(time.Second),
+	}
+
+	payload := fmt.Sprintf("%s.%d.%d", hex.EncodeToString(idBytes), challenge.Difficulty, challenge.ExpiresAt.Unix())
+	challenge.Token = payload + "." + c.sign(payload)
+
+	return challenge, nil
+}
+
+// difficulty returns the difficulty a challenge issued now should have.
+// The load is the challenges solved in the last CHALLENGE_LOAD_WINDOW, estimated from the current
+// and previous windows, like a sliding window rate limiter.
+func (c *challengeService) difficulty(now time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.advance(now)
+
+	elapsed := float64(now.Sub(c.window)) / float64(CHALLENGE_LOAD_WINDOW)
+	load := float64(c.previous)*(1-elapsed) + float64(c.verified)
+
+	return utils.Min(c.min+int(load)/c.loadStep, c.max)
+}
+
+// advance moves the load window on to the one now is in. It needs the lock.
+func (c *challengeService) advance(now time.Time) {
+	window := now.Truncate(CHALLENGE_LOAD_WINDOW)
+	switch {
+	case !window.After(c.window):
+		return
+	case window.Sub(c.window) == CHALLENGE_LOAD_WINDOW:
+		c.window, c.previous, c.verified = window, c.verified, 0
+	default:
+		c.window, c.previous, c.verified = window, 0, 0
+	}
+	c.forgetExpired(now)
+}
+
+// forgetExpired forgets solved challenges that have expired, as they can't be replayed anyway. It needs the lock.
+func (c *challengeService) forgetExpired(now time.Time) {
+	for id, expiresAt := range c.solved {
+		if !now.Before(expiresAt) {
+			delete(c.solved, id)
+		}
+	}
+}
+
+// sign returns the signature of a challenge's payload.
+func (c *challengeService) sign(payload string) string {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySolution checks the challenge was issued by us and hasn't expired, and that nonce solves it.
+// Each challenge can only be solved once, and each solution adds to the load the difficulty is based on.
+func (c *challengeService) VerifySolution(ctx context.Context, token, nonce string) error {
+	invalid := api.NewForbidden("challenge/invalid", "The provided challenge is invalid.",
+		api.WithAction("Solve a challenge from GET /challenge."))
+
+	// id.difficulty.expires.signature
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 {
+		return invalid
+	}
+
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(c.sign(payload))) {
+		return invalid
+	}
+
+	difficulty, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return invalid
+	}
+
+	expires, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return invalid
+	}
+
+	now := c.now()
+	expiresAt := time.Unix(expires, 0)
+	if !now.Before(expiresAt) {
+		return api.NewForbidden("challenge/expired", "The provided challenge has expired.",
+			api.WithAction("Solve a new challenge from GET /challenge."))
+	}
+
+	challenge := &entity.Challenge{Token: token, Difficulty: difficulty, ExpiresAt: expiresAt}
+	if !challenge.SolvedBy(nonce) {
+		return api.NewForbidden("challenge/unsolved", fmt.Sprintf("The provided solution doesn't solve the challenge, whose difficulty is %d.", difficulty),
+			api.WithAction(fmt.Sprintf("Find a nonce, such that the SHA-256 hash of '<challenge>:<nonce>' starts with %d zero bits.", difficulty)))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := parts[0]
+	if _, ok := c.solved[id]; ok {
+		return api.NewForbidden("challenge/used", "The provided challenge has already been used.",
+			api.WithAction("Solve a new challenge from GET /challenge."))
+	}
+	c.solved[id] = expiresAt
+
+	c.advance(now)
+	c.verified++
+
+	return nil
+}
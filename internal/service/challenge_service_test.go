@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// solve finds the first nonce that solves the challenge.
+func solve(t *testing.T, challenge *entity.Challenge) string {
+	for i := 0; i < 1<<24; i++ {
+		if nonce := strconv.Itoa(i); challenge.SolvedBy(nonce) {
+			return nonce
+		}
+	}
+
+	t.Fatalf("couldn't solve challenge of difficulty %d", challenge.Difficulty)
+	return ""
+}
+
+// unsolved finds a nonce that doesn't solve the challenge.
+func unsolved(challenge *entity.Challenge) string {
+	for i := 0; ; i++ {
+		if nonce := strconv.Itoa(i); !challenge.SolvedBy(nonce) {
+			return nonce
+		}
+	}
+}
+
+func newTestChallengeService(now *time.Time) ChallengeService {
+	return NewChallengeService(&ChallengeConfig{
+		Secret:        []byte("s3cret"),
+		MinDifficulty: 4,
+		MaxDifficulty: 6,
+		LoadStep:      2,
+		Now:           func() time.Time { return *now },
+	})
+}
+
+func Test_VerifySolution(t *testing.T) {
+	now := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	challenges := newTestChallengeService(&now)
+	ctx := context.Background()
+
+	issue := func() *entity.Challenge {
+		challenge, err := challenges.IssueChallenge(ctx)
+		require.NoError(t, err)
+		return challenge
+	}
+
+	t.Run("Solved", func(t *testing.T) {
+		challenge := issue()
+		assert.Equal(t, now.Add(DEFAULT_CHALLENGE_TTL), challenge.ExpiresAt)
+		assert.NoError(t, challenges.VerifySolution(ctx, challenge.Token, solve(t, challenge)))
+	})
+
+	t.Run("Used", func(t *testing.T) {
+		challenge := issue()
+		nonce := solve(t, challenge)
+		assert.NoError(t, challenges.VerifySolution(ctx, challenge.Token, nonce))
+		assert.Equal(t, api.NewForbidden("challenge/used", "The provided challenge has already been used.",
+			api.WithAction("Solve a new challenge from GET /challenge.")), challenges.VerifySolution(ctx, challenge.Token, nonce))
+	})
+
+	t.Run("Unsolved", func(t *testing.T) {
+		challenge := issue()
+		err := challenges.VerifySolution(ctx, challenge.Token, unsolved(challenge))
+		assert.Equal(t, "challenge/unsolved", api.EnsureApiError(err).Code)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		challenge := issue()
+		nonce := solve(t, challenge)
+
+		expired := challenge.ExpiresAt
+		later := newTestChallengeService(&expired)
+		assert.Equal(t, api.NewForbidden("challenge/expired", "The provided challenge has expired.",
+			api.WithAction("Solve a new challenge from GET /challenge.")), later.VerifySolution(ctx, challenge.Token, nonce))
+	})
+
+	invalid := api.NewForbidden("challenge/invalid", "The provided challenge is invalid.", api.WithAction("Solve a challenge from GET /challenge."))
+
+	t.Run("Forged Difficulty", func(t *testing.T) {
+		challenge := issue()
+		parts := strings.Split(challenge.Token, ".")
+		parts[1] = "0"
+		assert.Equal(t, invalid, challenges.VerifySolution(ctx, strings.Join(parts, "."), "0"))
+	})
+
+	t.Run("Other Secret", func(t *testing.T) {
+		other := NewChallengeService(&ChallengeConfig{Secret: []byte("other"), MinDifficulty: 1, MaxDifficulty: 1, Now: func() time.Time { return now }})
+		challenge, err := other.IssueChallenge(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, invalid, challenges.VerifySolution(ctx, challenge.Token, solve(t, challenge)))
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		assert.Equal(t, invalid, challenges.VerifySolution(ctx, "not a challenge", "0"))
+	})
+}
+
+func Test_IssueChallenge_AdaptsToLoad(t *testing.T) {
+	now := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	challenges := newTestChallengeService(&now)
+	ctx := context.Background()
+
+	issue := func() *entity.Challenge {
+		challenge, err := challenges.IssueChallenge(ctx)
+		require.NoError(t, err)
+		return challenge
+	}
+
+	// challenges that are only requested, or solved wrongly, don't count
+	for i := 0; i < 6; i++ {
+		challenge := issue()
+		assert.Equal(t, 4, challenge.Difficulty)
+		assert.Error(t, challenges.VerifySolution(ctx, challenge.Token, unsolved(challenge)))
+	}
+
+	difficulties := []int{}
+	for i := 0; i < 6; i++ {
+		challenge := issue()
+		difficulties = append(difficulties, challenge.Difficulty)
+		require.NoError(t, challenges.VerifySolution(ctx, challenge.Token, solve(t, challenge)))
+	}
+
+	// a bit harder every 2 solved challenges, up to the max
+	assert.Equal(t, []int{4, 4, 5, 5, 6, 6}, difficulties)
+	assert.Equal(t, 6, issue().Difficulty)
+
+	// the load of the previous window fades out over the next one
+	now = now.Add(CHALLENGE_LOAD_WINDOW + CHALLENGE_LOAD_WINDOW/2)
+	assert.Equal(t, 5, issue().Difficulty) // 6 * 0.5 = 3 recent solutions
+
+	now = now.Add(2 * CHALLENGE_LOAD_WINDOW)
+	assert.Equal(t, 4, issue().Difficulty)
+}
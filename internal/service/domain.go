@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
+	"github.com/Jaytpa01/url-shortener-api/pkg/validation"
+)
+
+const (
+	MAX_DOMAIN_LENGTH         = 253
+	DOMAIN_VERIFICATION_BYTES = 16 // random bytes in a domain's verification token
+
+	// DOMAIN_CLAIM_TTL is how long a workspace has to verify a domain it added before another workspace can add it,
+	// so an unverified claim can't keep a domain from the workspace that owns it.
+	DOMAIN_CLAIM_TTL = 7 * 24 * time.Hour
+)
+
+// domainPattern matches lowercase host names with at least two labels, such as go.example.com.
+var domainPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])$`)
+
+// Resolver looks up the TXT records that verify domains. *net.Resolver implements it, and tests stub it.
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+type DomainConfig struct {
+	Logger        logger.Logger
+	DomainRepo    repository.DomainRepository
+	WorkspaceRepo repository.WorkspaceRepository
+
+	// AuditRepo records who added, verified, changed and removed domains. It's nil if the database can't store the audit log.
+	AuditRepo repository.AuditRepository
+
+	// Resolver verifies domains. Defaults to net.DefaultResolver.
+	Resolver Resolver
+
+	// DefaultHost is the host of the default domain, from server.base_url, which can't be registered by a workspace.
+	DefaultHost string
+}
+
+// domainService manages the short domains registered by workspaces
+type domainService struct {
+	authorizer
+	auditor
+
+	logger      logger.Logger
+	domainRepo  repository.DomainRepository
+	resolver    Resolver
+	defaultHost string
+}
+
+func NewDomainService(c *DomainConfig) DomainService {
+	if c.Resolver == nil {
+		c.Resolver = net.DefaultResolver
+	}
+
+	return &domainService{
+		authorizer:  authorizer{workspaceRepo: c.WorkspaceRepo},
+		auditor:     auditor{logger: c.Logger, auditRepo: c.AuditRepo},
+		logger:      c.Logger,
+		domainRepo:  c.DomainRepo,
+		resolver:    c.Resolver,
+		defaultHost: normaliseDomain(c.DefaultHost),
+	}
+}
+
+// normaliseDomain lowercases a domain and removes the trailing dot of a fully qualified name.
+func normaliseDomain(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
+
+// ListDomains returns the domains of a workspace, if the request can view them.
+func (d *domainService) ListDomains(ctx context.Context, workspaceID string) ([]entity.Domain, error) {
+	if err := d.authorizeWorkspace(ctx, workspaceID, actionViewDomains); err != nil {
+		return nil, err
+	}
+
+	domains, err := d.domainRepo.ListDomains(ctx, workspaceID)
+	if err != nil {
+		return nil, api.NewInternal("domain/couldnt-list", api.WithDebug(err.Error()))
+	}
+
+	return domains, nil
+}
+
+// AddDomain registers a domain for a workspace, with its default redirect and not found url.
+// The domain isn't served until it's verified. A domain another workspace added can be added once its claim
+// has gone unverified for DOMAIN_CLAIM_TTL, which removes that claim. Only owners can add domains.
+func (d *domainService) AddDomain(ctx context.Context, workspaceID string, domain *entity.Domain) (*entity.Domain, error) {
+	name := normaliseDomain(domain.Name)
+	if len(name) > MAX_DOMAIN_LENGTH || !domainPattern.MatchString(name) {
+		return nil, api.NewBadRequest("domain/invalid", fmt.Sprintf("The provided domain (%s) isn't a valid host name.", domain.Name),
+			api.WithAction("Use a host name such as go.example.com, without a scheme or path."))
+	}
+
+	if name == d.defaultHost {
+		return nil, api.NewConflict("domain/reserved", fmt.Sprintf("%s is the default domain, so can't be added to a workspace.", name))
+	}
+
+	if err := validateDomainUrls(domain); err != nil {
+		return nil, err
+	}
+
+	if err := d.authorizeWorkspace(ctx, workspaceID, actionManageDomains); err != nil {
+		return nil, err
+	}
+
+	tokenBytes := make([]byte, DOMAIN_VERIFICATION_BYTES)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return nil, api.NewInternal("domain/couldnt-add", api.WithDebug(err.Error()))
+	}
+
+	added := &entity.Domain{
+		Name:              name,
+		WorkspaceID:       workspaceID,
+		VerificationToken: hex.EncodeToString(tokenBytes),
+		DefaultRedirect:   domain.DefaultRedirect,
+		NotFoundUrl:       domain.NotFoundUrl,
+		CreatedAt:         time.Now().UTC(),
+	}
+
+	if err := d.createDomain(ctx, added); err != nil {
+		if errors.Is(err, repository.ErrDomainExists) {
+			return nil, api.NewConflict("domain/exists", fmt.Sprintf("%s has already been added to a workspace.", name),
+				api.WithAction(fmt.Sprintf("If it isn't verified within %d days of being added, it can be added again.", DOMAIN_CLAIM_TTL/(24*time.Hour))))
+		}
+
+		apiErr := api.NewInternal("domain/couldnt-add", api.WithDebug(err.Error()))
+		d.logger.Info("Failed to add domain.", apiErr)
+		return nil, apiErr
+	}
+
+	d.record(ctx, entity.AuditDomainAdd, workspaceID, name, settingsChange(&entity.Domain{}, added))
+	return added, nil
+}
+
+// createDomain creates the domain, replacing another workspace's claim to it if that has expired.
+// The expired claim is audited as removed from its workspace.
+func (d *domainService) createDomain(ctx context.Context, domain *entity.Domain) error {
+	err := d.domainRepo.CreateDomain(ctx, domain)
+	if !errors.Is(err, repository.ErrDomainExists) {
+		return err
+	}
+
+	existing, err := d.domainRepo.FindDomain(ctx, domain.Name)
+	if err != nil {
+		return err
+	}
+
+	// the claim is only deleted if it's still unverified, so a domain verified meanwhile is kept
+	expired, err := d.domainRepo.DeleteUnverifiedDomain(ctx, domain.Name, domain.CreatedAt.Add(-DOMAIN_CLAIM_TTL))
+	if err != nil {
+		return err
+	}
+	if !expired {
+		return repository.ErrDomainExists
+	}
+	d.record(ctx, entity.AuditDomainRemove, existing.WorkspaceID, existing.Name, settingsChange(existing, &entity.Domain{}))
+
+	return d.domainRepo.CreateDomain(ctx, domain)
+}
+
+// VerifyDomain checks the domain's TXT record, and starts serving it once the record has the verification value.
+// Verifying a verified domain checks the record again, but keeps it verified. Only owners can verify domains.
+func (d *domainService) VerifyDomain(ctx context.Context, workspaceID, name string) (*entity.Domain, error) {
+	if err := d.authorizeWorkspace(ctx, workspaceID, actionManageDomains); err != nil {
+		return nil, err
+	}
+
+	domain, err := d.findDomain(ctx, workspaceID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if domain.Verified() {
+		return domain, nil
+	}
+
+	unverified := api.NewConflict("domain/unverified", fmt.Sprintf("Couldn't find the verification record of %s.", domain.Name),
+		api.WithAction(fmt.Sprintf("Add a TXT record named %s with the value %s, then try again once it has propagated.", domain.VerificationRecord(), domain.VerificationValue())))
+
+	records, err := d.resolver.LookupTXT(ctx, domain.VerificationRecord())
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return nil, unverified
+		}
+
+		apiErr := api.NewInternal("domain/couldnt-verify", api.WithDebug(err.Error()))
+		d.logger.Info("Failed to look up domain verification record.", apiErr)
+		return nil, apiErr
+	}
+
+	if !containsRecord(records, domain.VerificationValue()) {
+		return nil, unverified
+	}
+
+	verifiedAt := time.Now().UTC()
+	domain.VerifiedAt = &verifiedAt
+	if err := d.domainRepo.UpdateDomain(ctx, domain); err != nil {
+		return nil, d.domainError(err, workspaceID, domain.Name, "domain/couldnt-verify")
+	}
+
+	d.record(ctx, entity.AuditDomainVerify, workspaceID, domain.Name, entity.AuditChanges{"verified": {Before: "false", After: "true"}})
+	return domain, nil
+}
+
+// containsRecord reports whether one of the TXT records has the value, ignoring surrounding whitespace.
+func containsRecord(records []string, value string) bool {
+	for _, record := range records {
+		if strings.TrimSpace(record) == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// UpdateDomain changes the default redirect and not found url of a domain. Only owners can change them.
+func (d *domainService) UpdateDomain(ctx context.Context, workspaceID string, settings *entity.Domain) (*entity.Domain, error) {
+	if err := validateDomainUrls(settings); err != nil {
+		return nil, err
+	}
+
+	if err := d.authorizeWorkspace(ctx, workspaceID, actionManageDomains); err != nil {
+		return nil, err
+	}
+
+	domain, err := d.findDomain(ctx, workspaceID, settings.Name)
+	if err != nil {
+		return nil, err
+	}
+	before := *domain
+
+	domain.DefaultRedirect = settings.DefaultRedirect
+	domain.NotFoundUrl = settings.NotFoundUrl
+	if err := d.domainRepo.UpdateDomain(ctx, domain); err != nil {
+		return nil, d.domainError(err, workspaceID, domain.Name, "domain/couldnt-update")
+	}
+
+	if changes := settingsChange(&before, domain); len(changes) > 0 {
+		d.record(ctx, entity.AuditDomainUpdate, workspaceID, domain.Name, changes)
+	}
+	return domain, nil
+}
+
+// RemoveDomain removes a domain from a workspace, which stops it being served. Its links are kept,
+// and are served again if the domain is added and verified again. Only owners can remove domains.
+func (d *domainService) RemoveDomain(ctx context.Context, workspaceID, name string) error {
+	if err := d.authorizeWorkspace(ctx, workspaceID, actionManageDomains); err != nil {
+		return err
+	}
+
+	domain, err := d.findDomain(ctx, workspaceID, name)
+	if err != nil {
+		return err
+	}
+
+	if err := d.domainRepo.DeleteDomain(ctx, workspaceID, domain.Name); err != nil {
+		return d.domainError(err, workspaceID, domain.Name, "domain/couldnt-remove")
+	}
+
+	d.record(ctx, entity.AuditDomainRemove, workspaceID, domain.Name, settingsChange(domain, &entity.Domain{}))
+	return nil
+}
+
+// ResolveDomain returns the verified domain a request's host names, or nil if it isn't one.
+// It isn't authorized, as every request needs to know which domain it was made to.
+func (d *domainService) ResolveDomain(ctx context.Context, host string) (*entity.Domain, error) {
+	name := normaliseDomain(host)
+	if name == "" || name == d.defaultHost {
+		return nil, nil
+	}
+
+	domain, err := d.domainRepo.FindDomain(ctx, name)
+	if err != nil {
+		if errors.Is(err, repository.ErrDomainNotFound) {
+			return nil, nil
+		}
+
+		return nil, api.NewInternal("domain/couldnt-resolve", api.WithDebug(err.Error()))
+	}
+
+	if !domain.Verified() {
+		return nil, nil
+	}
+
+	return domain, nil
+}
+
+// findDomain finds a domain of the workspace. Domains of other workspaces aren't found.
+func (d *domainService) findDomain(ctx context.Context, workspaceID, name string) (*entity.Domain, error) {
+	domain, err := d.domainRepo.FindDomain(ctx, normaliseDomain(name))
+	if err == nil && domain.WorkspaceID != workspaceID {
+		err = repository.ErrDomainNotFound
+	}
+
+	if err != nil {
+		return nil, d.domainError(err, workspaceID, name, "domain/internal")
+	}
+
+	return domain, nil
+}
+
+// domainError maps a repository error from finding or changing a domain to an api error.
+func (d *domainService) domainError(err error, workspaceID, name, code string) error {
+	if errors.Is(err, repository.ErrDomainNotFound) {
+		return api.NewNotFound("domain/not-found", fmt.Sprintf("Workspace (%s) has no domain %s.", workspaceID, name))
+	}
+
+	apiErr := api.NewInternal(code, api.WithDebug(err.Error()))
+	d.logger.Info("Failed to change domain.", apiErr)
+	return apiErr
+}
+
+// validateDomainUrls checks the default redirect and not found url of a domain, which are optional.
+func validateDomainUrls(domain *entity.Domain) error {
+	for field, url := range map[string]string{"default_redirect": domain.DefaultRedirect, "not_found_url": domain.NotFoundUrl} {
+		if url != "" && !validation.IsValidUrl(url) {
+			return api.NewBadRequest("domain/invalid-url", fmt.Sprintf("The provided %s (%s) is invalid.", field, url))
+		}
+	}
+
+	return nil
+}
+
+// settingsChange is the audited change of a domain's default redirect and not found url. Unchanged settings are left out.
+func settingsChange(before, after *entity.Domain) entity.AuditChanges {
+	changes := entity.AuditChanges{}
+	if before.DefaultRedirect != after.DefaultRedirect {
+		changes["default_redirect"] = entity.AuditChange{Before: before.DefaultRedirect, After: after.DefaultRedirect}
+	}
+	if before.NotFoundUrl != after.NotFoundUrl {
+		changes["not_found_url"] = entity.AuditChange{Before: before.NotFoundUrl, After: after.NotFoundUrl}
+	}
+
+	return changes
+}
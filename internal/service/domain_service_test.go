@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/host"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubResolver answers TXT lookups from a map, so tests don't need DNS. Names without records aren't found.
+type stubResolver struct {
+	records map[string][]string
+	err     error
+}
+
+func (s *stubResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	records, ok := s.records[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+
+	return records, nil
+}
+
+// newDomainTest returns a domain service, and a workspace ws_1 owned by user_owner, with user_viewer as a viewer.
+func newDomainTest(t *testing.T) (DomainService, *stubResolver, repository.DomainRepository) {
+	workspaceRepo := repository.NewInMemoryWorkspaceRepo()
+	ctx := context.Background()
+	now := time.Now().UTC()
+	require.NoError(t, workspaceRepo.CreateWorkspace(ctx, &entity.Workspace{ID: "ws_1", Name: "Team", CreatedAt: now},
+		&entity.Membership{WorkspaceID: "ws_1", UserID: "user_owner", Role: entity.RoleOwner, CreatedAt: now}))
+	require.NoError(t, workspaceRepo.AddMember(ctx, &entity.Membership{WorkspaceID: "ws_1", UserID: "user_viewer", Role: entity.RoleViewer, CreatedAt: now}))
+
+	resolver := &stubResolver{records: map[string][]string{}}
+	domainRepo := repository.NewInMemoryDomainRepo()
+	domains := NewDomainService(&DomainConfig{
+		Logger:        logger.NewApiLogger("development"),
+		DomainRepo:    domainRepo,
+		WorkspaceRepo: workspaceRepo,
+		Resolver:      resolver,
+		DefaultHost:   "sho.rt",
+	})
+
+	return domains, resolver, domainRepo
+}
+
+func Test_AddDomain(t *testing.T) {
+	domains, _, _ := newDomainTest(t)
+	owner := userContext("user_owner")
+
+	added, err := domains.AddDomain(owner, "ws_1", &entity.Domain{Name: " Go.Example.com. ", NotFoundUrl: "https://example.com/404"})
+	require.NoError(t, err)
+	assert.Equal(t, "go.example.com", added.Name)
+	assert.Equal(t, "ws_1", added.WorkspaceID)
+	assert.Equal(t, "https://example.com/404", added.NotFoundUrl)
+	assert.Len(t, added.VerificationToken, DOMAIN_VERIFICATION_BYTES*2)
+	assert.False(t, added.Verified())
+
+	testCases := []struct {
+		name        string
+		ctx         context.Context
+		workspaceID string
+		domain      *entity.Domain
+		expectedErr error
+	}{
+		{
+			"Invalid Name", owner, "ws_1", &entity.Domain{Name: "https://go.example.com/"},
+			api.NewBadRequest("domain/invalid", "The provided domain (https://go.example.com/) isn't a valid host name.",
+				api.WithAction("Use a host name such as go.example.com, without a scheme or path.")),
+		},
+		{
+			"Single Label", owner, "ws_1", &entity.Domain{Name: "localhost"},
+			api.NewBadRequest("domain/invalid", "The provided domain (localhost) isn't a valid host name.",
+				api.WithAction("Use a host name such as go.example.com, without a scheme or path.")),
+		},
+		{
+			"Default Domain", owner, "ws_1", &entity.Domain{Name: "SHO.RT"},
+			api.NewConflict("domain/reserved", "sho.rt is the default domain, so can't be added to a workspace."),
+		},
+		{
+			"Invalid Redirect", owner, "ws_1", &entity.Domain{Name: "links.example.com", DefaultRedirect: "not a url"},
+			api.NewBadRequest("domain/invalid-url", "The provided default_redirect (not a url) is invalid."),
+		},
+		{
+			"Already Added", owner, "ws_1", &entity.Domain{Name: "go.example.com"},
+			api.NewConflict("domain/exists", "go.example.com has already been added to a workspace.",
+				api.WithAction("If it isn't verified within 7 days of being added, it can be added again.")),
+		},
+		{
+			"Viewer", userContext("user_viewer"), "ws_1", &entity.Domain{Name: "links.example.com"},
+			api.NewForbidden("workspace/forbidden", "You need the owner role to manage the domains of workspace (ws_1)."),
+		},
+		{
+			"Unknown Workspace", owner, "ws_2", &entity.Domain{Name: "links.example.com"},
+			api.NewNotFound("workspace/not-found", "There is no workspace with the id ws_2."),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := domains.AddDomain(test.ctx, test.workspaceID, test.domain)
+			assert.Equal(t, test.expectedErr, err)
+		})
+	}
+
+	// viewers can list the domains
+	listed, err := domains.ListDomains(userContext("user_viewer"), "ws_1")
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, "go.example.com", listed[0].Name)
+}
+
+func Test_AddDomain_ExpiredClaim(t *testing.T) {
+	domains, _, domainRepo := newDomainTest(t)
+	owner := userContext("user_owner")
+	ctx := context.Background()
+	expired := time.Now().UTC().Add(-DOMAIN_CLAIM_TTL - time.Minute)
+	verifiedAt := expired.Add(time.Hour)
+
+	// another workspace added these domains long ago, but only verified one of them
+	require.NoError(t, domainRepo.CreateDomain(ctx, &entity.Domain{Name: "go.example.com", WorkspaceID: "ws_2", VerificationToken: "abc", CreatedAt: expired}))
+	require.NoError(t, domainRepo.CreateDomain(ctx, &entity.Domain{Name: "links.example.com", WorkspaceID: "ws_2", VerificationToken: "def",
+		VerifiedAt: &verifiedAt, CreatedAt: expired}))
+
+	added, err := domains.AddDomain(owner, "ws_1", &entity.Domain{Name: "go.example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "ws_1", added.WorkspaceID)
+	assert.NotEqual(t, "abc", added.VerificationToken)
+
+	found, err := domainRepo.FindDomain(ctx, "go.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "ws_1", found.WorkspaceID)
+
+	// while the verified domain stays with its workspace, and a fresh claim can't be taken over
+	for _, name := range []string{"links.example.com", "go.example.com"} {
+		_, err = domains.AddDomain(owner, "ws_1", &entity.Domain{Name: name})
+		assert.Equal(t, api.NewConflict("domain/exists", name+" has already been added to a workspace.",
+			api.WithAction("If it isn't verified within 7 days of being added, it can be added again.")), err)
+	}
+
+	found, err = domainRepo.FindDomain(ctx, "links.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "ws_2", found.WorkspaceID)
+}
+
+func Test_VerifyDomain(t *testing.T) {
+	domains, resolver, _ := newDomainTest(t)
+	owner := userContext("user_owner")
+
+	added, err := domains.AddDomain(owner, "ws_1", &entity.Domain{Name: "go.example.com"})
+	require.NoError(t, err)
+
+	unverified := api.NewConflict("domain/unverified", "Couldn't find the verification record of go.example.com.",
+		api.WithAction("Add a TXT record named _url-shortener.go.example.com with the value "+added.VerificationValue()+", then try again once it has propagated."))
+
+	// no record yet
+	_, err = domains.VerifyDomain(owner, "ws_1", "go.example.com")
+	assert.Equal(t, unverified, err)
+
+	// a record with the wrong value
+	resolver.records["_url-shortener.go.example.com"] = []string{"v=spf1 -all", "url-shortener-verification=wrong"}
+	_, err = domains.VerifyDomain(owner, "ws_1", "go.example.com")
+	assert.Equal(t, unverified, err)
+
+	// the domain isn't served until it's verified
+	resolved, err := domains.ResolveDomain(context.Background(), "go.example.com")
+	require.NoError(t, err)
+	assert.Nil(t, resolved)
+
+	// dns failures aren't reported as a missing record
+	resolver.err = errors.New("i/o timeout")
+	_, err = domains.VerifyDomain(owner, "ws_1", "go.example.com")
+	assert.Equal(t, api.NewInternal("domain/couldnt-verify", api.WithDebug("i/o timeout")), err)
+	resolver.err = nil
+
+	resolver.records["_url-shortener.go.example.com"] = append(resolver.records["_url-shortener.go.example.com"], added.VerificationValue())
+	verified, err := domains.VerifyDomain(owner, "ws_1", "go.example.com")
+	require.NoError(t, err)
+	assert.True(t, verified.Verified())
+
+	// verifying again keeps it verified, even if the record has gone
+	delete(resolver.records, "_url-shortener.go.example.com")
+	verified, err = domains.VerifyDomain(owner, "ws_1", "GO.example.com")
+	require.NoError(t, err)
+	assert.True(t, verified.Verified())
+
+	for _, name := range []string{"go.example.com", "Go.Example.com."} {
+		resolved, err = domains.ResolveDomain(context.Background(), name)
+		require.NoError(t, err)
+		require.NotNil(t, resolved)
+		assert.Equal(t, "go.example.com", resolved.Name)
+	}
+
+	// the default domain and unknown hosts aren't short domains
+	for _, name := range []string{"sho.rt", "localhost", ""} {
+		resolved, err = domains.ResolveDomain(context.Background(), name)
+		require.NoError(t, err)
+		assert.Nil(t, resolved)
+	}
+}
+
+func Test_UpdateAndRemoveDomain(t *testing.T) {
+	domains, _, domainRepo := newDomainTest(t)
+	owner := userContext("user_owner")
+	ctx := context.Background()
+
+	_, err := domains.AddDomain(owner, "ws_1", &entity.Domain{Name: "go.example.com"})
+	require.NoError(t, err)
+	// another workspace's domain
+	require.NoError(t, domainRepo.CreateDomain(ctx, &entity.Domain{Name: "other.example.com", WorkspaceID: entity.DEFAULT_WORKSPACE}))
+
+	updated, err := domains.UpdateDomain(owner, "ws_1", &entity.Domain{Name: "go.example.com", DefaultRedirect: "https://example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", updated.DefaultRedirect)
+	assert.Empty(t, updated.NotFoundUrl)
+
+	found, err := domainRepo.FindDomain(ctx, "go.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", found.DefaultRedirect)
+
+	_, err = domains.UpdateDomain(owner, "ws_1", &entity.Domain{Name: "go.example.com", NotFoundUrl: "nope"})
+	assert.Equal(t, api.NewBadRequest("domain/invalid-url", "The provided not_found_url (nope) is invalid."), err)
+
+	notFound := api.NewNotFound("domain/not-found", "Workspace (ws_1) has no domain other.example.com.")
+	_, err = domains.UpdateDomain(owner, "ws_1", &entity.Domain{Name: "other.example.com"})
+	assert.Equal(t, notFound, err)
+	assert.Equal(t, notFound, domains.RemoveDomain(owner, "ws_1", "other.example.com"))
+
+	assert.Equal(t, api.NewForbidden("workspace/forbidden", "You need the owner role to manage the domains of workspace (ws_1)."),
+		domains.RemoveDomain(userContext("user_viewer"), "ws_1", "go.example.com"))
+
+	require.NoError(t, domains.RemoveDomain(owner, "ws_1", "go.example.com"))
+	_, err = domainRepo.FindDomain(ctx, "go.example.com")
+	assert.ErrorIs(t, err, repository.ErrDomainNotFound)
+}
+
+func Test_UrlService_Domains(t *testing.T) {
+	urlRepo := repository.NewInMemoryRepo()
+	workspaceRepo := repository.NewInMemoryWorkspaceRepo()
+	ctx := context.Background()
+	now := time.Now().UTC()
+	require.NoError(t, workspaceRepo.CreateWorkspace(ctx, &entity.Workspace{ID: "ws_1", Name: "Team", CreatedAt: now},
+		&entity.Membership{WorkspaceID: "ws_1", UserID: "user_1", Role: entity.RoleOwner, CreatedAt: now}))
+
+	urls := NewUrlService(&Config{
+		Logger:        logger.NewApiLogger("development"),
+		UrlRepo:       urlRepo,
+		WorkspaceRepo: workspaceRepo,
+	})
+
+	verifiedAt := now
+	domain := &entity.Domain{Name: "go.example.com", WorkspaceID: "ws_1", VerifiedAt: &verifiedAt}
+	onDomain := host.NewContext(userContext("user_1"), domain)
+
+	// links on a domain belong to the domain's workspace, even when the request acts in another
+	created, err := urls.ShortenUrl(onDomain, "https://example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "go.example.com", created.Domain)
+	assert.Equal(t, "ws_1", created.WorkspaceID)
+
+	// the same token can be used on the default domain
+	require.NoError(t, urlRepo.Create(ctx, &entity.Url{Token: created.Token, TargetUrl: "https://example.org", CreatedAt: now}))
+
+	found, err := urls.FindUrlByToken(onDomain, created.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", found.TargetUrl)
+
+	found, err = urls.FindUrlByToken(ctx, created.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.org", found.TargetUrl)
+
+	// anonymous requests can't create links on a workspace's domain
+	_, err = urls.ShortenUrl(host.NewContext(ctx, domain), "https://example.com")
+	assert.Equal(t, api.NewUnauthorized("auth/required", "You need to authenticate to create links in workspace (ws_1).",
+		api.WithAction("Send an api key or token in the Authorization header, as 'Bearer <key>'.")), err)
+
+	updated, err := urls.UpdateUrl(onDomain, created.Token, "https://example.net")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.net", updated.TargetUrl)
+
+	found, err = urls.FindUrlByToken(onDomain, created.Token)
+	require.NoError(t, err)
+	require.NoError(t, urls.IncrementUrlVisits(onDomain, found))
+	assert.Equal(t, 1, found.Visits)
+
+	require.NoError(t, urls.DeleteUrl(onDomain, created.Token))
+	_, err = urls.FindUrlByToken(onDomain, created.Token)
+	assert.Equal(t, api.NewNotFound("url/not-found", "Couldn't find URL with token ("+created.Token+")."), err)
+
+	// the default domain's link is untouched
+	found, err = urls.FindUrlByToken(ctx, created.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.org", found.TargetUrl)
+	assert.Zero(t, found.Visits)
+}
@@ -3,7 +3,10 @@ package service
 import (
 	"context"
 
+	"github.com/Jaytpa01/url-shortener-api/internal/auth"
 	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/importer"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
 )
 
 // UrlService defines the methods the handler layer
@@ -12,6 +15,88 @@ type UrlService interface {
 	ShortenUrl(ctx context.Context, url string) (*entity.Url, error)
 	LengthenUrl(ctx context.Context, url string) (*entity.Url, error)
 	FindUrlByToken(ctx context.Context, token string) (*entity.Url, error)
+	// GetUrlAnalytics, UpdateUrl, DeleteUrl and SetUrlDisabled check the request is authorized to act in the url's workspace,
+	// unlike FindUrlByToken, which is used for public redirects, so doesn't find disabled urls.
+	GetUrlAnalytics(ctx context.Context, token string) (*entity.Url, error)
+	UpdateUrl(ctx context.Context, token, targetUrl string) (*entity.Url, error)
+	DeleteUrl(ctx context.Context, token string) error
+	SetUrlDisabled(ctx context.Context, token string, disabled bool) (*entity.Url, error)
 	IncrementUrlVisits(ctx context.Context, url *entity.Url) error
-	GetAllUrls(ctx context.Context) ([]entity.Url, error)
+	ListUrls(ctx context.Context, filter repository.ListFilter, cursor string, limit int) ([]entity.Url, string, error)
+	KeyspaceStats(ctx context.Context) *entity.KeyspaceStats
+	ImportUrls(ctx context.Context, records []importer.Record, conflict importer.Conflict) (*importer.Report, error)
+}
+
+// KeyService defines the methods the handler layer and cli
+// expect any api key services they interact with to implement.
+type KeyService interface {
+	// CreateKey creates an api key for a workspace, returning it and the key itself, which can't be retrieved again.
+	CreateKey(ctx context.Context, name, workspaceID string, scopes entity.Scopes) (*entity.ApiKey, string, error)
+	ListKeys(ctx context.Context) ([]entity.ApiKey, error)
+	RevokeKey(ctx context.Context, id string) error
+	// Authenticate resolves an api key into the principal it authenticates.
+	Authenticate(ctx context.Context, key string) (*auth.Principal, error)
+}
+
+// WorkspaceService defines the methods the handler layer
+// expects any workspace services it interacts with to implement.
+type WorkspaceService interface {
+	// CreateWorkspace creates a workspace, owned by the user making the request.
+	CreateWorkspace(ctx context.Context, name string) (*entity.Workspace, error)
+	// ListWorkspaces returns the memberships of the user making the request.
+	ListWorkspaces(ctx context.Context) ([]entity.Membership, error)
+	ListMembers(ctx context.Context, workspaceID string) ([]entity.Membership, error)
+	AddMember(ctx context.Context, workspaceID, userID string, role entity.Role) (*entity.Membership, error)
+	UpdateMember(ctx context.Context, workspaceID, userID string, role entity.Role) (*entity.Membership, error)
+	RemoveMember(ctx context.Context, workspaceID, userID string) error
+}
+
+// DomainService defines the methods the handler layer
+// expects any domain services it interacts with to implement.
+type DomainService interface {
+	ListDomains(ctx context.Context, workspaceID string) ([]entity.Domain, error)
+	AddDomain(ctx context.Context, workspaceID string, domain *entity.Domain) (*entity.Domain, error)
+	// VerifyDomain checks the domain's TXT record, and starts serving the domain once it's verified.
+	VerifyDomain(ctx context.Context, workspaceID, name string) (*entity.Domain, error)
+	// UpdateDomain changes the default redirect and not found url of the domain named by settings.Name.
+	UpdateDomain(ctx context.Context, workspaceID string, settings *entity.Domain) (*entity.Domain, error)
+	RemoveDomain(ctx context.Context, workspaceID, name string) error
+	// ResolveDomain returns the verified domain a request's host names, or nil if it's the default domain or an unknown host.
+	ResolveDomain(ctx context.Context, host string) (*entity.Domain, error)
+}
+
+// QuotaService defines the methods the handler layer
+// expects any quota services it interacts with to implement.
+type QuotaService interface {
+	// UseCreateQuota counts a url created by the subject, an api key, user or anonymous ip, against its quotas.
+	UseCreateQuota(ctx context.Context, subject string) (*entity.QuotaStatus, error)
+}
+
+// ChallengeService defines the methods the handler layer
+// expects any proof of work challenge services it interacts with to implement.
+type ChallengeService interface {
+	// IssueChallenge returns a signed challenge, whose difficulty grows with the number of challenges recently issued.
+	IssueChallenge(ctx context.Context) (*entity.Challenge, error)
+	// VerifySolution checks the nonce solves the challenge, which can only be solved once.
+	VerifySolution(ctx context.Context, challenge, nonce string) error
+}
+
+// AuditService defines the methods the handler layer and cli
+// expect any audit services they interact with to implement.
+type AuditService interface {
+	ListAuditEntries(ctx context.Context, filter repository.AuditFilter, cursor string, limit int) ([]entity.AuditEntry, string, error)
+	// RecordConfig records the settings that changed since they were last recorded, returning nil if none did.
+	RecordConfig(ctx context.Context, settings map[string]string) (*entity.AuditEntry, error)
+}
+
+// PrivacyService defines the methods the handler layer and cli
+// expect any privacy services they interact with to implement.
+type PrivacyService interface {
+	// StartJob creates a job exporting or erasing the data of the subject, an api key or user id, and runs it in the background.
+	StartJob(ctx context.Context, kind entity.PrivacyJobKind, subject string) (*entity.PrivacyJob, error)
+	// RunJob creates a job like StartJob, but runs it until it finishes.
+	RunJob(ctx context.Context, kind entity.PrivacyJobKind, subject string) (*entity.PrivacyJob, error)
+	FindJob(ctx context.Context, id string) (*entity.PrivacyJob, error)
+	// ResumeJobs starts the jobs that hadn't finished when the server stopped, returning how many it started.
+	ResumeJobs(ctx context.Context) (int, error)
 }
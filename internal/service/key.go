@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/auth"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
+)
+
+const MAX_KEY_NAME_LENGTH = 100
+
+type KeyConfig struct {
+	Logger     logger.Logger
+	ApiKeyRepo repository.ApiKeyRepository
+
+	// WorkspaceRepo is used to check keys are created for a workspace that exists.
+	// It's nil if the database can't store workspaces, and keys can only be created for the default workspace.
+	WorkspaceRepo repository.WorkspaceRepository
+
+	// AuditRepo records who created and revoked keys. It's nil if the database can't store the audit log.
+	AuditRepo repository.AuditRepository
+}
+
+// keyService manages the api keys that authenticate requests
+type keyService struct {
+	auditor
+
+	logger        logger.Logger
+	apiKeyRepo    repository.ApiKeyRepository
+	workspaceRepo repository.WorkspaceRepository
+}
+
+func NewKeyService(c *KeyConfig) KeyService {
+	return &keyService{
+		auditor: auditor{logger: c.Logger, auditRepo: c.AuditRepo},
+
+		logger:        c.Logger,
+		apiKeyRepo:    c.ApiKeyRepo,
+		workspaceRepo: c.WorkspaceRepo,
+	}
+}
+
+// CreateKey generates a new api key for the workspace, with the scopes, storing only its hash.
+// An empty workspace id creates the key for the default workspace, and keys without scopes get entity.DEFAULT_SCOPES.
+func (k *keyService) CreateKey(ctx context.Context, name, workspaceID string, scopes entity.Scopes) (*entity.ApiKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > MAX_KEY_NAME_LENGTH {
+		return nil, "", api.NewBadRequest("key/invalid-name", fmt.Sprintf("An api key needs a name of at most %d characters.", MAX_KEY_NAME_LENGTH))
+	}
+
+	scopes, err := validateScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if workspaceID == "" {
+		workspaceID = entity.DEFAULT_WORKSPACE
+	}
+
+	if err := k.checkWorkspace(ctx, workspaceID); err != nil {
+		return nil, "", err
+	}
+
+	id, secret, err := auth.GenerateKey()
+	if err != nil {
+		return nil, "", api.NewInternal("key/couldnt-create", api.WithDebug(err.Error()))
+	}
+
+	key := &entity.ApiKey{
+		ID:        id,
+		Name:      name,
+		Hash:      auth.HashKey(secret),
+		CreatedAt: time.Now().UTC(),
+
+		WorkspaceID: workspaceID,
+		Scopes:      scopes,
+	}
+
+	if err := k.apiKeyRepo.CreateApiKey(ctx, key); err != nil {
+		apiErr := api.NewInternal("key/couldnt-create", api.WithDebug(err.Error()))
+		k.logger.Info("Failed to create api key.", apiErr)
+		return nil, "", apiErr
+	}
+
+	k.record(ctx, entity.AuditKeyCreate, workspaceID, key.ID, entity.AuditChanges{
+		"name":   {After: key.Name},
+		"scopes": {After: key.Scopes.String()},
+	})
+	return key, secret, nil
+}
+
+// checkWorkspace checks the workspace a key is being created for exists.
+func (k *keyService) checkWorkspace(ctx context.Context, workspaceID string) error {
+	notFound := api.NewNotFound("workspace/not-found", fmt.Sprintf("There is no workspace with the id %s.", workspaceID))
+
+	if k.workspaceRepo == nil {
+		if workspaceID != entity.DEFAULT_WORKSPACE {
+			return notFound
+		}
+		return nil
+	}
+
+	if _, err := k.workspaceRepo.FindWorkspace(ctx, workspaceID); err != nil {
+		if errors.Is(err, repository.ErrWorkspaceNotFound) {
+			return notFound
+		}
+
+		return api.NewInternal("key/couldnt-create", api.WithDebug(err.Error()))
+	}
+
+	return nil
+}
+
+// ListKeys returns every api key, including revoked ones.
+func (k *keyService) ListKeys(ctx context.Context) ([]entity.ApiKey, error) {
+	keys, err := k.apiKeyRepo.ListApiKeys(ctx)
+	if err != nil {
+		return nil, api.NewInternal("key/couldnt-list", api.WithDebug(err.Error()))
+	}
+
+	return keys, nil
+}
+
+// RevokeKey revokes an api key, so it no longer authenticates requests.
+func (k *keyService) RevokeKey(ctx context.Context, id string) error {
+	err := k.apiKeyRepo.RevokeApiKey(ctx, id, time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, repository.ErrApiKeyNotFound) {
+			return api.NewNotFound("key/not-found", fmt.Sprintf("There is no api key with the id %s.", id))
+		}
+
+		return api.NewInternal("key/couldnt-revoke", api.WithDebug(err.Error()))
+	}
+
+	k.record(ctx, entity.AuditKeyRevoke, "", id, nil)
+	return nil
+}
+
+// Authenticate looks up an api key by its hash. Unknown keys are unauthorized, and revoked keys are forbidden.
+func (k *keyService) Authenticate(ctx context.Context, key string) (*auth.Principal, error) {
+	found, err := k.apiKeyRepo.FindApiKeyByHash(ctx, auth.HashKey(key))
+	if err != nil {
+		if errors.Is(err, repository.ErrApiKeyNotFound) {
+			return nil, api.NewUnauthorized("auth/invalid-key", "The provided api key is invalid.")
+		}
+
+		return nil, api.NewInternal("auth/couldnt-authenticate", api.WithDebug(err.Error()))
+	}
+
+	if found.Revoked() {
+		return nil, api.NewForbidden("auth/key-revoked", "The provided api key has been revoked.",
+			api.WithAction("Ask an administrator for a new api key."))
+	}
+
+	return &auth.Principal{KeyID: found.ID, Name: found.Name, WorkspaceID: found.WorkspaceID, Scopes: found.Scopes}, nil
+}
+
+// validateScopes checks every scope is known, dropping duplicates. No scopes means entity.DEFAULT_SCOPES.
+func validateScopes(scopes entity.Scopes) (entity.Scopes, error) {
+	if len(scopes) == 0 {
+		return entity.DEFAULT_SCOPES, nil
+	}
+
+	valid := make(entity.Scopes, 0, len(scopes))
+	seen := make(map[entity.Scope]bool, len(scopes))
+	for _, scope := range scopes {
+		if !scope.Valid() {
+			return nil, api.NewBadRequest("key/invalid-scope", fmt.Sprintf("Unknown scope (%s).", scope),
+				api.WithAction(fmt.Sprintf("Use any of %s.", entity.ALL_SCOPES)))
+		}
+
+		if !seen[scope] {
+			seen[scope] = true
+			valid = append(valid, scope)
+		}
+	}
+
+	return valid, nil
+}
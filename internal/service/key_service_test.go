@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/auth"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newKeyService() KeyService {
+	return NewKeyService(&KeyConfig{
+		Logger:        logger.NewApiLogger("development"),
+		ApiKeyRepo:    repository.NewInMemoryApiKeyRepo(),
+		WorkspaceRepo: repository.NewInMemoryWorkspaceRepo(),
+	})
+}
+
+func Test_CreateKey(t *testing.T) {
+	keys := newKeyService()
+	ctx := context.Background()
+
+	key, secret, err := keys.CreateKey(ctx, "  ci  ", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "ci", key.Name)
+	assert.Equal(t, entity.DEFAULT_WORKSPACE, key.WorkspaceID)
+	assert.Equal(t, entity.DEFAULT_SCOPES, key.Scopes)
+	assert.True(t, strings.HasPrefix(secret, auth.KEY_PREFIX))
+
+	// only the hash is stored
+	assert.Equal(t, auth.HashKey(secret), key.Hash)
+	assert.NotContains(t, key.Hash, secret)
+
+	list, err := keys.ListKeys(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, key.ID, list[0].ID)
+
+	testCases := []struct {
+		name    string
+		keyName string
+	}{
+		{"Empty Name", "   "},
+		{"Long Name", strings.Repeat("a", MAX_KEY_NAME_LENGTH+1)},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := keys.CreateKey(ctx, test.keyName, "", nil)
+			assert.Equal(t, api.NewBadRequest("key/invalid-name", "An api key needs a name of at most 100 characters."), err)
+		})
+	}
+
+	_, _, err = keys.CreateKey(ctx, "ci", "ws_unknown", nil)
+	assert.Equal(t, api.NewNotFound("workspace/not-found", "There is no workspace with the id ws_unknown."), err)
+}
+
+func Test_CreateKey_Scopes(t *testing.T) {
+	keys := newKeyService()
+	ctx := context.Background()
+
+	testCases := []struct {
+		name     string
+		scopes   entity.Scopes
+		expected entity.Scopes
+		err      error
+	}{
+		{"Default Scopes", nil, entity.DEFAULT_SCOPES, nil},
+		{"Single Scope", entity.Scopes{entity.ScopeAnalyticsRead}, entity.Scopes{entity.ScopeAnalyticsRead}, nil},
+		{"Duplicate Scopes", entity.Scopes{entity.ScopeAdmin, entity.ScopeAdmin}, entity.Scopes{entity.ScopeAdmin}, nil},
+		{
+			"Unknown Scope",
+			entity.Scopes{entity.ScopeLinksCreate, "links:everything"},
+			nil,
+			api.NewBadRequest("key/invalid-scope", "Unknown scope (links:everything).", api.WithAction("Use any of links:create links:write links:delete analytics:read admin.")),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			key, secret, err := keys.CreateKey(ctx, "dashboard", "", test.scopes)
+			if test.err != nil {
+				assert.Equal(t, test.err, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, key.Scopes)
+
+			principal, err := keys.Authenticate(ctx, secret)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, principal.Scopes)
+		})
+	}
+}
+
+func Test_Authenticate(t *testing.T) {
+	keys := newKeyService()
+	ctx := context.Background()
+
+	key, secret, err := keys.CreateKey(ctx, "ci", "", nil)
+	require.NoError(t, err)
+
+	principal, err := keys.Authenticate(ctx, secret)
+	require.NoError(t, err)
+	assert.Equal(t, &auth.Principal{KeyID: key.ID, Name: "ci", WorkspaceID: entity.DEFAULT_WORKSPACE, Scopes: entity.DEFAULT_SCOPES}, principal)
+
+	_, err = keys.Authenticate(ctx, auth.KEY_PREFIX+"wrong")
+	assert.Equal(t, api.NewUnauthorized("auth/invalid-key", "The provided api key is invalid."), err)
+
+	require.NoError(t, keys.RevokeKey(ctx, key.ID))
+	_, err = keys.Authenticate(ctx, secret)
+	assert.Equal(t, api.Forbidden, api.EnsureApiError(err).Type)
+	assert.Equal(t, "auth/key-revoked", api.EnsureApiError(err).Code)
+
+	err = keys.RevokeKey(ctx, "key_unknown")
+	assert.Equal(t, api.NewNotFound("key/not-found", "There is no api key with the id key_unknown."), err)
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
+)
+
+const (
+	DEFAULT_COLLISION_THRESHOLD = 0.01            // grow tokens once 1% of generated tokens are expected to clash
+	MINIMUM_COLLISION_SAMPLE    = 100             // attempts needed before the observed collision rate is trusted
+	MAX_TOKEN_GROWTH            = 2               // how many characters a single request can grow the token length by
+	KEYSPACE_REFRESH_INTERVAL   = 1 * time.Minute // how often the link count is refreshed from the repository
+)
+
+// keyspace tracks how many links exist and how often generated tokens clash,
+// and grows the token length when the chance of a clash crosses threshold.
+type keyspace struct {
+	logger    logger.Logger
+	urlRepo   repository.UrlRepository
+	threshold float64
+
+	mu          sync.Mutex
+	length      int
+	links       int
+	refreshedAt time.Time
+	attempts    int // attempts at the current length
+	collisions  int // collisions at the current length
+}
+
+func newKeyspace(logger logger.Logger, urlRepo repository.UrlRepository, threshold float64) *keyspace {
+	if threshold <= 0 {
+		threshold = DEFAULT_COLLISION_THRESHOLD
+	}
+
+	return &keyspace{
+		logger:    logger,
+		urlRepo:   urlRepo,
+		threshold: threshold,
+		length:    TOKEN_LENGTH,
+	}
+}
+
+// TokenLength returns the length tokens should currently be generated with.
+func (k *keyspace) TokenLength(ctx context.Context) int {
+	k.refresh(ctx)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.adapt()
+	return k.length
+}
+
+// Grow is called when every attempt at length clashed. Unless the length has already
+// grown since, it grows the token length by one and returns it.
+func (k *keyspace) Grow(length int) int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.length <= length {
+		k.setLength(length + 1)
+	}
+
+	return k.length
+}
+
+// RecordAttempt records whether creating a url with a token of the given length clashed.
+func (k *keyspace) RecordAttempt(length int, collided bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if !collided {
+		k.links++
+	}
+
+	// only attempts at the current length say anything about its collision rate
+	if length != k.length {
+		return
+	}
+
+	k.attempts++
+	if collided {
+		k.collisions++
+	}
+}
+
+// Stats returns a snapshot of the keyspace at the current token length.
+func (k *keyspace) Stats(ctx context.Context) *entity.KeyspaceStats {
+	k.refresh(ctx)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.adapt()
+
+	stats := &entity.KeyspaceStats{
+		TokenLength: k.length,
+		Links:       k.links,
+		Capacity:    capacity(k.length),
+		Utilisation: float64(k.links) / capacity(k.length),
+		Attempts:    k.attempts,
+		Collisions:  k.collisions,
+	}
+	if k.attempts > 0 {
+		stats.CollisionRate = float64(k.collisions) / float64(k.attempts)
+	}
+
+	return stats
+}
+
+// refresh updates the link count from the repository if it is stale.
+// Links created by other api instances are only picked up here.
+func (k *keyspace) refresh(ctx context.Context) {
+	k.mu.Lock()
+	stale := time.Since(k.refreshedAt) > KEYSPACE_REFRESH_INTERVAL
+	k.mu.Unlock()
+
+	if !stale {
+		return
+	}
+
+	links, err := k.urlRepo.Count(ctx)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	// even if counting failed, we wait for the next interval rather than retrying every request
+	k.refreshedAt = time.Now()
+	if err != nil {
+		k.logger.Infof("couldn't count urls: %v", err)
+		return
+	}
+
+	k.links = links
+}
+
+// adapt grows the token length while the expected or observed collision rate is above threshold.
+// k.mu must be held.
+func (k *keyspace) adapt() {
+	for float64(k.links)/capacity(k.length) >= k.threshold {
+		k.setLength(k.length + 1)
+	}
+
+	if k.attempts >= MINIMUM_COLLISION_SAMPLE && float64(k.collisions)/float64(k.attempts) >= k.threshold {
+		k.setLength(k.length + 1)
+	}
+}
+
+// setLength changes the token length and resets the observed collisions. k.mu must be held.
+func (k *keyspace) setLength(length int) {
+	k.logger.Infof("growing token length from %d to %d", k.length, length)
+	k.length = length
+	k.attempts = 0
+	k.collisions = 0
+}
+
+// capacity returns the number of possible tokens of the given length
+func capacity(length int) float64 {
+	return math.Pow(62, float64(length))
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/mocks"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_Keyspace_GrowsWithLinkCount(t *testing.T) {
+	testCases := []struct {
+		name           string
+		links          int
+		expectedLength int
+	}{
+		{"Empty Keyspace", 0, TOKEN_LENGTH},
+		{"Below Threshold", int(capacity(TOKEN_LENGTH) * DEFAULT_COLLISION_THRESHOLD / 2), TOKEN_LENGTH},
+		{"Above Threshold", int(capacity(TOKEN_LENGTH)*DEFAULT_COLLISION_THRESHOLD) + 1, TOKEN_LENGTH + 1},
+		{"Well Above Threshold", int(capacity(TOKEN_LENGTH + 1)), TOKEN_LENGTH + 3},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			repo := mocks.NewMockUrlRepository()
+			repo.On("Count", mock.Anything).Return(test.links, nil)
+
+			ks := newKeyspace(logger.NewApiLogger("development"), repo, 0)
+			assert.Equal(t, test.expectedLength, ks.TokenLength(context.Background()))
+		})
+	}
+}
+
+func Test_Keyspace_GrowsWithObservedCollisions(t *testing.T) {
+	repo := mocks.NewMockUrlRepository()
+	repo.On("Count", mock.Anything).Return(0, nil)
+
+	ks := newKeyspace(logger.NewApiLogger("development"), repo, 0.1)
+	ctx := context.Background()
+	assert.Equal(t, TOKEN_LENGTH, ks.TokenLength(ctx))
+
+	for i := 0; i < MINIMUM_COLLISION_SAMPLE; i++ {
+		ks.RecordAttempt(TOKEN_LENGTH, i%5 == 0)
+	}
+	assert.Equal(t, TOKEN_LENGTH+1, ks.TokenLength(ctx))
+
+	// attempts at the old length don't count towards the new one
+	ks.RecordAttempt(TOKEN_LENGTH, true)
+	stats := ks.Stats(ctx)
+	assert.Equal(t, 0, stats.Attempts)
+	assert.Equal(t, MINIMUM_COLLISION_SAMPLE*4/5, stats.Links)
+}
+
+func Test_Keyspace_Grow(t *testing.T) {
+	repo := mocks.NewMockUrlRepository()
+	ks := newKeyspace(logger.NewApiLogger("development"), repo, 0)
+
+	assert.Equal(t, TOKEN_LENGTH+1, ks.Grow(TOKEN_LENGTH))
+	// a request still using the old length shouldn't grow it again
+	assert.Equal(t, TOKEN_LENGTH+1, ks.Grow(TOKEN_LENGTH))
+	assert.Equal(t, TOKEN_LENGTH+2, ks.Grow(TOKEN_LENGTH+1))
+}
+
+func Test_ShortenUrl_GrowsTokenAfterClashes(t *testing.T) {
+	repo := mocks.NewMockUrlRepository()
+	repo.On("Count", mock.Anything).Return(0, nil)
+	repo.On("Create", mock.Anything, mock.MatchedBy(func(url *entity.Url) bool {
+		return len(url.Token) == TOKEN_LENGTH
+	})).Return(repository.ErrTokenAlreadyExists)
+	repo.On("Create", mock.Anything, mock.AnythingOfType("*entity.Url")).Return(nil)
+
+	randomiser := mocks.NewMockRandomiser()
+	randomiser.On("GenerateRandomString", TOKEN_LENGTH).Return("123456")
+	randomiser.On("GenerateRandomString", TOKEN_LENGTH+1).Return("1234567")
+
+	urlService := NewUrlService(&Config{
+		UrlRepo: repo,
+		Logger:  logger.NewApiLogger("development"),
+		Random:  randomiser,
+	})
+
+	url, err := urlService.ShortenUrl(context.Background(), "https://example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "1234567", url.Token)
+	assert.Equal(t, TOKEN_LENGTH+1, urlService.KeyspaceStats(context.Background()).TokenLength)
+}
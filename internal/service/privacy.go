@@ -0,0 +1,391 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/audit"
+	"github.com/Jaytpa01/url-shortener-api/internal/auth"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
+)
+
+const (
+	PRIVACY_JOB_ID_BYTES  = 8 // random bytes in a privacy job's id
+	PRIVACY_JOB_ID_PREFIX = "job_"
+	PSEUDONYM_BYTES       = 8 // random bytes in the pseudonym an erased subject is replaced with
+	PSEUDONYM_PREFIX      = "erased_"
+)
+
+// reservedActors are the actors of the audit log that aren't api keys or users, so can't be the subject of a privacy job.
+var reservedActors = []string{ACTOR_ANONYMOUS, ACTOR_SERVER, "admin", "cli"}
+
+type PrivacyConfig struct {
+	Logger      logger.Logger
+	UrlRepo     repository.UrlRepository
+	AuditRepo   repository.AuditRepository
+	PrivacyRepo repository.PrivacyRepository
+
+	ApiKeyRepo    repository.ApiKeyRepository    // nil if the database can't store api keys
+	WorkspaceRepo repository.WorkspaceRepository // nil if the database can't store users
+	QuotaRepo     repository.QuotaRepository     // nil if the database can't store quota usage
+}
+
+// privacyService answers data protection requests, by exporting or erasing everything tied to an api key or user:
+// their links, audit entries, api key, user name, memberships and quota usage.
+//
+// Erasure anonymizes links and audit entries rather than deleting them: links are kept without an owner,
+// and audit entries are kept with a pseudonym in place of the subject, so the number of links, their visits
+// and the audit log's entries stay the same. The api key, user, memberships and quota usage are deleted,
+// and the privacy jobs for the subject are kept, with the pseudonym in place of the subject, as the record of the request.
+type privacyService struct {
+	auditor
+
+	logger        logger.Logger
+	urlRepo       repository.UrlRepository
+	privacyRepo   repository.PrivacyRepository
+	apiKeyRepo    repository.ApiKeyRepository
+	workspaceRepo repository.WorkspaceRepository
+	quotaRepo     repository.QuotaRepository
+}
+
+func NewPrivacyService(c *PrivacyConfig) PrivacyService {
+	return &privacyService{
+		auditor:       auditor{logger: c.Logger, auditRepo: c.AuditRepo},
+		logger:        c.Logger,
+		urlRepo:       c.UrlRepo,
+		privacyRepo:   c.PrivacyRepo,
+		apiKeyRepo:    c.ApiKeyRepo,
+		workspaceRepo: c.WorkspaceRepo,
+		quotaRepo:     c.QuotaRepo,
+	}
+}
+
+// requireAdmin returns an error unless the request was made by the admin, as data protection requests cover every workspace.
+func requireAdmin(ctx context.Context) error {
+	if principal, ok := auth.FromContext(ctx); !ok || !principal.Admin {
+		return api.NewForbidden("privacy/forbidden", "Only the admin can export or erase the data of an api key or user.")
+	}
+
+	return nil
+}
+
+// StartJob creates a job of the kind for the subject, and runs it in the background.
+// The job carries on after the request, so it's polled with FindJob until it finishes.
+func (p *privacyService) StartJob(ctx context.Context, kind entity.PrivacyJobKind, subject string) (*entity.PrivacyJob, error) {
+	job, err := p.createJob(ctx, kind, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	// the job outlives the request, but is still made by its principal and from its source
+	background := audit.NewContext(context.Background(), audit.FromContext(ctx))
+	if principal, ok := auth.FromContext(ctx); ok {
+		background = auth.NewContext(background, principal)
+	}
+
+	started := *job
+	go p.run(background, &started)
+
+	return job, nil
+}
+
+// RunJob creates a job of the kind for the subject, and runs it until it finishes. The job is returned whether it succeeded or failed.
+func (p *privacyService) RunJob(ctx context.Context, kind entity.PrivacyJobKind, subject string) (*entity.PrivacyJob, error) {
+	job, err := p.createJob(ctx, kind, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	p.run(ctx, job)
+	return job, nil
+}
+
+// createJob stores a pending job of the kind for the subject, if the request can start it.
+func (p *privacyService) createJob(ctx context.Context, kind entity.PrivacyJobKind, subject string) (*entity.PrivacyJob, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	if !kind.Valid() {
+		return nil, api.NewBadRequest("privacy/invalid-kind", fmt.Sprintf("Unknown kind of privacy job (%s).", kind),
+			api.WithAction(fmt.Sprintf("Use %s or %s.", entity.PrivacyExport, entity.PrivacyErase)))
+	}
+
+	subject = strings.TrimSpace(subject)
+	if err := validateSubject(subject); err != nil {
+		return nil, err
+	}
+
+	idBytes := make([]byte, PRIVACY_JOB_ID_BYTES)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, api.NewInternal("privacy/couldnt-create", api.WithDebug(err.Error()))
+	}
+
+	job := &entity.PrivacyJob{
+		ID:          PRIVACY_JOB_ID_PREFIX + hex.EncodeToString(idBytes),
+		Kind:        kind,
+		Subject:     subject,
+		Status:      entity.PrivacyJobPending,
+		RequestedBy: actor(ctx),
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	if err := p.privacyRepo.CreatePrivacyJob(ctx, job); err != nil {
+		apiErr := api.NewInternal("privacy/couldnt-create", api.WithDebug(err.Error()))
+		p.logger.Info("Failed to create privacy job.", apiErr)
+		return nil, apiErr
+	}
+
+	return job, nil
+}
+
+// validateSubject checks subject could be an api key or user id, rather than one of the other actors of the audit log.
+func validateSubject(subject string) error {
+	reserved := subject == "" || strings.HasPrefix(subject, "admin:") || strings.HasPrefix(subject, PSEUDONYM_PREFIX)
+	for _, actor := range reservedActors {
+		reserved = reserved || subject == actor
+	}
+
+	if reserved {
+		return api.NewBadRequest("privacy/invalid-subject", fmt.Sprintf("The provided subject (%s) isn't an api key or user id.", subject),
+			api.WithAction("Use the id of the api key, such as key_0123456789abcdef, or the user id from their token."))
+	}
+
+	return nil
+}
+
+// FindJob returns the job with the id, including the exported data once an export succeeds.
+func (p *privacyService) FindJob(ctx context.Context, id string) (*entity.PrivacyJob, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	job, err := p.privacyRepo.FindPrivacyJob(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrPrivacyJobNotFound) {
+			return nil, api.NewNotFound("privacy/not-found", fmt.Sprintf("There is no privacy job with the id %s.", id))
+		}
+
+		return nil, api.NewInternal("privacy/couldnt-find", api.WithDebug(err.Error()))
+	}
+
+	return job, nil
+}
+
+// ResumeJobs runs the jobs that hadn't finished when the server stopped, in the background, as whoever started them.
+// Exporting or erasing a subject again gives the same result, so a job that was stopped part way through is run from the start.
+func (p *privacyService) ResumeJobs(ctx context.Context) (int, error) {
+	jobs, err := p.privacyRepo.ListUnfinishedPrivacyJobs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range jobs {
+		background := auth.NewContext(context.Background(), &auth.Principal{Name: jobs[i].RequestedBy, Admin: true})
+		go p.run(background, &jobs[i])
+	}
+
+	return len(jobs), nil
+}
+
+// run exports or erases the subject of the job, saving the job as it starts and finishes.
+// Finished jobs are recorded in the audit log, with the job as their target, rather than the subject.
+func (p *privacyService) run(ctx context.Context, job *entity.PrivacyJob) {
+	startedAt := time.Now().UTC()
+	job.Status, job.StartedAt, job.FinishedAt, job.Error = entity.PrivacyJobRunning, &startedAt, nil, ""
+	if err := p.privacyRepo.UpdatePrivacyJob(ctx, job); err != nil {
+		p.logger.Errorf("Couldn't start privacy job %s: %v", job.ID, err)
+		return
+	}
+
+	var err error
+	action := entity.AuditSubjectExport
+	switch job.Kind {
+	case entity.PrivacyExport:
+		err = p.export(ctx, job)
+	case entity.PrivacyErase:
+		action = entity.AuditSubjectErase
+		err = p.erase(ctx, job)
+	}
+
+	finishedAt := time.Now().UTC()
+	job.Status, job.FinishedAt = entity.PrivacyJobSucceeded, &finishedAt
+	if err != nil {
+		p.logger.Errorf("Privacy job %s failed: %v", job.ID, err)
+		job.Status, job.Error = entity.PrivacyJobFailed, err.Error()
+	}
+
+	if err := p.privacyRepo.UpdatePrivacyJob(ctx, job); err != nil {
+		p.logger.Errorf("Couldn't save privacy job %s: %v", job.ID, err)
+		return
+	}
+
+	if job.Status == entity.PrivacyJobSucceeded {
+		p.record(ctx, action, "", job.ID, entity.AuditChanges{
+			"links":         {After: strconv.Itoa(job.Links)},
+			"audit_entries": {After: strconv.Itoa(job.AuditEntries)},
+		})
+	}
+}
+
+// export collects the links the subject owns, with their visits, the audit entries they made or that target them,
+// and their api key, user, memberships and quota usage.
+func (p *privacyService) export(ctx context.Context, job *entity.PrivacyJob) error {
+	links := []entity.Url{}
+	cursor := ""
+	for {
+		page, next, err := p.urlRepo.ListUrls(ctx, repository.ListFilter{Owner: job.Subject, Sort: repository.SortCreatedAsc}, cursor, MAX_LIST_LIMIT)
+		if err != nil {
+			return fmt.Errorf("couldn't list links: %w", err)
+		}
+
+		links = append(links, page...)
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	// an entry can be both made by and target the subject, such as a key that was revoked with itself
+	byID := map[int64]entity.AuditEntry{}
+	for _, filter := range []repository.AuditFilter{{Actor: job.Subject}, {Target: job.Subject}} {
+		cursor := ""
+		for {
+			page, next, err := p.auditRepo.ListAuditEntries(ctx, filter, cursor, MAX_LIST_LIMIT)
+			if err != nil {
+				return fmt.Errorf("couldn't list audit entries: %w", err)
+			}
+
+			for _, entry := range page {
+				byID[entry.ID] = entry
+			}
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+	}
+
+	entries := make([]entity.AuditEntry, 0, len(byID))
+	for _, entry := range byID {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+
+	job.Export = &entity.SubjectExport{Links: links, AuditEntries: entries}
+	if err := p.exportAccount(ctx, job.Subject, job.Export); err != nil {
+		return err
+	}
+
+	job.Links, job.AuditEntries = len(links), len(entries)
+	return nil
+}
+
+// exportAccount adds the subject's api key, or their user and memberships, and their quota usage to the export.
+// The key's hash is left out, as it's a secret rather than data about the subject.
+func (p *privacyService) exportAccount(ctx context.Context, subject string, export *entity.SubjectExport) error {
+	if p.apiKeyRepo != nil {
+		key, err := p.apiKeyRepo.FindApiKey(ctx, subject)
+		switch {
+		case err == nil:
+			key.Hash = ""
+			export.ApiKey = key
+		case !errors.Is(err, repository.ErrApiKeyNotFound):
+			return fmt.Errorf("couldn't find api key: %w", err)
+		}
+	}
+
+	if p.workspaceRepo != nil {
+		user, err := p.workspaceRepo.FindUser(ctx, subject)
+		switch {
+		case err == nil:
+			export.User = user
+		case !errors.Is(err, repository.ErrUserNotFound):
+			return fmt.Errorf("couldn't find user: %w", err)
+		}
+
+		memberships, err := p.workspaceRepo.ListMemberships(ctx, subject)
+		if err != nil {
+			return fmt.Errorf("couldn't list memberships: %w", err)
+		}
+		export.Memberships = memberships
+	}
+
+	if p.quotaRepo != nil {
+		usage, err := p.quotaRepo.ListQuotaUsage(ctx, subject)
+		if err != nil {
+			return fmt.Errorf("couldn't list quota usage: %w", err)
+		}
+		export.QuotaUsage = usage
+	}
+
+	return nil
+}
+
+// erase anonymizes the links the subject owns, replaces the subject with a new pseudonym in the audit log,
+// and deletes their api key, user, memberships and quota usage.
+// The jobs for the subject are given the pseudonym last, so a job that's stopped part way through can be run again.
+// Their exports are cleared, so they don't outlive the subject's data.
+func (p *privacyService) erase(ctx context.Context, job *entity.PrivacyJob) error {
+	pseudonymBytes := make([]byte, PSEUDONYM_BYTES)
+	if _, err := rand.Read(pseudonymBytes); err != nil {
+		return fmt.Errorf("couldn't generate pseudonym: %w", err)
+	}
+
+	links, err := p.privacyRepo.AnonymizeOwner(ctx, job.Subject)
+	if err != nil {
+		return fmt.Errorf("couldn't anonymize links: %w", err)
+	}
+
+	// the pseudonym is never stored with the subject, so the entries can't be tied back to them
+	pseudonym := PSEUDONYM_PREFIX + hex.EncodeToString(pseudonymBytes)
+	entries, err := p.auditRepo.AnonymizeAuditEntries(ctx, job.Subject, pseudonym)
+	if err != nil {
+		return fmt.Errorf("couldn't anonymize audit entries: %w", err)
+	}
+
+	if err := p.eraseAccount(ctx, job.Subject); err != nil {
+		return err
+	}
+
+	if err := p.privacyRepo.AnonymizePrivacyJobs(ctx, job.Subject, pseudonym); err != nil {
+		return fmt.Errorf("couldn't anonymize privacy jobs: %w", err)
+	}
+
+	job.Subject, job.Export = pseudonym, nil
+	job.Links, job.AuditEntries = links, entries
+	return nil
+}
+
+// eraseAccount deletes the subject's api key, or their user and memberships, and their quota usage.
+func (p *privacyService) eraseAccount(ctx context.Context, subject string) error {
+	if p.apiKeyRepo != nil {
+		if err := p.apiKeyRepo.DeleteApiKey(ctx, subject); err != nil {
+			return fmt.Errorf("couldn't delete api key: %w", err)
+		}
+	}
+
+	if p.workspaceRepo != nil {
+		if err := p.workspaceRepo.DeleteUser(ctx, subject); err != nil {
+			return fmt.Errorf("couldn't delete user: %w", err)
+		}
+	}
+
+	if p.quotaRepo != nil {
+		if err := p.quotaRepo.DeleteQuotaUsage(ctx, subject); err != nil {
+			return fmt.Errorf("couldn't delete quota usage: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/audit"
+	"github.com/Jaytpa01/url-shortener-api/internal/auth"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// privacyFixture is a privacy service over memory repositories holding the links and audit entries of key_1 and key_2.
+type privacyFixture struct {
+	service   PrivacyService
+	urlRepo   repository.UrlRepository
+	auditRepo repository.AuditRepository
+	jobs      repository.PrivacyRepository
+}
+
+func newPrivacyFixture(t *testing.T) *privacyFixture {
+	ctx := context.Background()
+	createdAt := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	urlRepo := repository.NewInMemoryRepo()
+	for _, url := range []entity.Url{
+		{Token: "aaaaaa", TargetUrl: "https://example.com/a", Visits: 3, Owner: "key_1", CreatedAt: createdAt},
+		{Token: "bbbbbb", TargetUrl: "https://example.com/b", Visits: 5, Owner: "key_1", CreatedAt: createdAt.Add(time.Minute)},
+		{Token: "cccccc", TargetUrl: "https://example.com/c", Visits: 7, Owner: "key_2", CreatedAt: createdAt},
+	} {
+		require.NoError(t, urlRepo.Create(ctx, &url))
+	}
+
+	auditRepo := repository.NewInMemoryAuditRepo()
+	for _, entry := range []entity.AuditEntry{
+		{Action: entity.AuditKeyCreate, Actor: "admin", Target: "key_1", IP: "192.0.2.1", CreatedAt: createdAt},
+		{Action: entity.AuditLinkCreate, Actor: "key_1", Target: "aaaaaa", IP: "192.0.2.2", CreatedAt: createdAt},
+		{Action: entity.AuditLinkCreate, Actor: "key_2", Target: "cccccc", IP: "192.0.2.3", CreatedAt: createdAt},
+		{Action: entity.AuditLinkCreate, Actor: "key_1", Target: "bbbbbb", IP: "192.0.2.2", CreatedAt: createdAt.Add(time.Minute)},
+	} {
+		require.NoError(t, auditRepo.AppendAuditEntry(ctx, &entry))
+	}
+
+	jobs, err := repository.NewPrivacyRepository(urlRepo)
+	require.NoError(t, err)
+
+	return &privacyFixture{
+		service: NewPrivacyService(&PrivacyConfig{
+			Logger:      logger.NewApiLogger("development"),
+			UrlRepo:     urlRepo,
+			AuditRepo:   auditRepo,
+			PrivacyRepo: jobs,
+		}),
+		urlRepo:   urlRepo,
+		auditRepo: auditRepo,
+		jobs:      jobs,
+	}
+}
+
+var privacyAdmin = auth.NewContext(context.Background(), &auth.Principal{Name: "admin", Admin: true})
+
+func Test_PrivacyService_Export(t *testing.T) {
+	f := newPrivacyFixture(t)
+
+	job, err := f.service.RunJob(privacyAdmin, entity.PrivacyExport, " key_1 ")
+	require.NoError(t, err)
+
+	assert.Equal(t, entity.PrivacyJobSucceeded, job.Status, job.Error)
+	assert.Equal(t, "key_1", job.Subject)
+	assert.Equal(t, "admin", job.RequestedBy)
+	assert.Equal(t, 2, job.Links)
+	assert.Equal(t, 3, job.AuditEntries)
+	assert.NotNil(t, job.StartedAt)
+	assert.NotNil(t, job.FinishedAt)
+
+	// polling the job returns the export
+	found, err := f.service.FindJob(privacyAdmin, job.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found.Export)
+
+	tokens := []string{}
+	for _, url := range found.Export.Links {
+		tokens = append(tokens, url.Token)
+		assert.Equal(t, "key_1", url.Owner)
+	}
+	assert.Equal(t, []string{"aaaaaa", "bbbbbb"}, tokens)
+	assert.Equal(t, 3, found.Export.Links[0].Visits)
+
+	ids := []int64{}
+	for _, entry := range found.Export.AuditEntries {
+		ids = append(ids, entry.ID)
+	}
+	assert.Equal(t, []int64{1, 2, 4}, ids)
+
+	// the export is audited against the job, so the subject isn't in the entry
+	entries := auditEntries(t, f.auditRepo)
+	assert.Equal(t, entity.AuditEntry{ID: 5, Action: entity.AuditSubjectExport, Actor: "admin", Target: job.ID,
+		Changes: entity.AuditChanges{"links": {After: "2"}, "audit_entries": {After: "3"}}}, entries[4])
+}
+
+func Test_PrivacyService_Erase(t *testing.T) {
+	f := newPrivacyFixture(t)
+	ctx := context.Background()
+
+	export, err := f.service.RunJob(privacyAdmin, entity.PrivacyExport, "key_1")
+	require.NoError(t, err)
+
+	job, err := f.service.RunJob(privacyAdmin, entity.PrivacyErase, "key_1")
+	require.NoError(t, err)
+	assert.Equal(t, entity.PrivacyJobSucceeded, job.Status, job.Error)
+	assert.Equal(t, 2, job.Links)
+	assert.Equal(t, 3, job.AuditEntries)
+
+	// links are kept with their visits, but without an owner
+	count, err := f.urlRepo.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	for token, expected := range map[string]struct {
+		owner  string
+		visits int
+	}{"aaaaaa": {"", 3}, "bbbbbb": {"", 5}, "cccccc": {"key_2", 7}} {
+		url, err := f.urlRepo.FindByToken(ctx, token)
+		require.NoError(t, err)
+		assert.Equal(t, expected.owner, url.Owner, token)
+		assert.Equal(t, expected.visits, url.Visits, token)
+	}
+
+	// audit entries are kept, with the same pseudonym in place of the subject
+	entries := auditEntries(t, f.auditRepo)
+	require.Len(t, entries, 6)
+
+	pseudonym := entries[0].Target
+	assert.Regexp(t, "^erased_[0-9a-f]{16}$", pseudonym)
+	assert.Equal(t, "192.0.2.1", entries[0].IP)
+	assert.Equal(t, pseudonym, entries[1].Actor)
+	assert.Empty(t, entries[1].IP)
+	assert.Equal(t, "key_2", entries[2].Actor)
+	assert.Equal(t, pseudonym, entries[3].Actor)
+	assert.Equal(t, entity.AuditSubjectErase, entries[5].Action)
+	assert.Equal(t, job.ID, entries[5].Target)
+
+	remaining, _, err := f.auditRepo.ListAuditEntries(ctx, repository.AuditFilter{Actor: "key_1"}, "", 10)
+	require.NoError(t, err)
+	assert.Empty(t, remaining)
+
+	// and the earlier export doesn't outlive the subject
+	found, err := f.service.FindJob(privacyAdmin, export.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found.Export)
+	assert.Equal(t, 2, found.Links)
+
+	// and neither job names the subject any more
+	assert.Equal(t, pseudonym, found.Subject)
+	assert.Equal(t, pseudonym, job.Subject)
+}
+
+func Test_PrivacyService_StartJob(t *testing.T) {
+	f := newPrivacyFixture(t)
+
+	// the job carries on after the request that started it
+	ctx, cancel := context.WithCancel(audit.NewContext(privacyAdmin, audit.Source{IP: "192.0.2.9", RequestID: "req-1"}))
+	job, err := f.service.StartJob(ctx, entity.PrivacyExport, "key_2")
+	cancel()
+	require.NoError(t, err)
+	assert.Equal(t, entity.PrivacyJobPending, job.Status)
+
+	assert.Eventually(t, func() bool {
+		found, err := f.service.FindJob(privacyAdmin, job.ID)
+		return err == nil && found.Status == entity.PrivacyJobSucceeded && found.Links == 1
+	}, time.Second, 10*time.Millisecond)
+
+	entries, _, err := f.auditRepo.ListAuditEntries(context.Background(), repository.AuditFilter{Target: job.ID}, "", 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "192.0.2.9", entries[0].IP)
+	assert.Equal(t, "req-1", entries[0].RequestID)
+}
+
+func Test_PrivacyService_ResumeJobs(t *testing.T) {
+	f := newPrivacyFixture(t)
+	ctx := context.Background()
+
+	// the server stopped while the job was running
+	startedAt := time.Now().UTC()
+	require.NoError(t, f.jobs.CreatePrivacyJob(ctx, &entity.PrivacyJob{ID: "job_1", Kind: entity.PrivacyErase, Subject: "key_2",
+		Status: entity.PrivacyJobRunning, RequestedBy: "admin:ops", CreatedAt: startedAt, StartedAt: &startedAt}))
+
+	resumed, err := f.service.ResumeJobs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, resumed)
+
+	assert.Eventually(t, func() bool {
+		found, err := f.service.FindJob(privacyAdmin, "job_1")
+		return err == nil && found.Status == entity.PrivacyJobSucceeded
+	}, time.Second, 10*time.Millisecond)
+
+	entries, _, err := f.auditRepo.ListAuditEntries(ctx, repository.AuditFilter{Target: "job_1"}, "", 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "admin:ops", entries[0].Actor)
+}
+
+func Test_PrivacyService_Errors(t *testing.T) {
+	f := newPrivacyFixture(t)
+	user := auth.NewContext(context.Background(), &auth.Principal{UserID: "user_1"})
+
+	testCases := []struct {
+		name    string
+		ctx     context.Context
+		kind    entity.PrivacyJobKind
+		subject string
+
+		expectedErr error
+	}{
+		{"Not Admin", user, entity.PrivacyExport, "user_1", api.NewForbidden("privacy/forbidden", "Only the admin can export or erase the data of an api key or user.")},
+		{"Anonymous", context.Background(), entity.PrivacyErase, "key_1", api.NewForbidden("privacy/forbidden", "Only the admin can export or erase the data of an api key or user.")},
+		{"Unknown Kind", privacyAdmin, "delete", "key_1", api.NewBadRequest("privacy/invalid-kind", "Unknown kind of privacy job (delete).", api.WithAction("Use export or erase."))},
+		{"No Subject", privacyAdmin, entity.PrivacyExport, " ", api.NewBadRequest("privacy/invalid-subject", "The provided subject () isn't an api key or user id.",
+			api.WithAction("Use the id of the api key, such as key_0123456789abcdef, or the user id from their token."))},
+		{"Reserved Subject", privacyAdmin, entity.PrivacyErase, "anonymous", api.NewBadRequest("privacy/invalid-subject", "The provided subject (anonymous) isn't an api key or user id.",
+			api.WithAction("Use the id of the api key, such as key_0123456789abcdef, or the user id from their token."))},
+		{"Pseudonym", privacyAdmin, entity.PrivacyErase, "erased_0123456789abcdef", api.NewBadRequest("privacy/invalid-subject", "The provided subject (erased_0123456789abcdef) isn't an api key or user id.",
+			api.WithAction("Use the id of the api key, such as key_0123456789abcdef, or the user id from their token."))},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			job, err := f.service.StartJob(test.ctx, test.kind, test.subject)
+			assert.Nil(t, job)
+			assert.Equal(t, test.expectedErr, err)
+		})
+	}
+
+	t.Run("Unknown Job", func(t *testing.T) {
+		_, err := f.service.FindJob(privacyAdmin, "job_unknown")
+		assert.Equal(t, api.NewNotFound("privacy/not-found", "There is no privacy job with the id job_unknown."), err)
+	})
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
+)
+
+// Quota is how many urls a subject can create in a UTC day and month. Zero means no limit.
+type Quota struct {
+	Daily   int
+	Monthly int
+}
+
+type QuotaConfig struct {
+	Logger    logger.Logger
+	QuotaRepo repository.QuotaRepository
+
+	// Default is the quota of every subject without one in Overrides, which is keyed by api key or user id.
+	Default   Quota
+	Overrides map[string]Quota
+
+	// Now defaults to time.Now, and is replaced in tests.
+	Now func() time.Time
+}
+
+// quotaService counts the urls each subject creates against its quota
+type quotaService struct {
+	logger    logger.Logger
+	quotaRepo repository.QuotaRepository
+	quota     Quota
+	overrides map[string]Quota
+	now       func() time.Time
+}
+
+func NewQuotaService(c *QuotaConfig) QuotaService {
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+
+	return &quotaService{
+		logger:    c.Logger,
+		quotaRepo: c.QuotaRepo,
+		quota:     c.Default,
+		overrides: c.Overrides,
+		now:       c.Now,
+	}
+}
+
+// quotaPeriod is a day or month a subject's uses are counted in.
+type quotaPeriod struct {
+	repository.QuotaPeriod
+	name  string // "daily" or "monthly"
+	reset time.Time
+}
+
+// UseCreateQuota counts a url created by the subject against its daily and monthly quotas.
+// It returns the status of the period closest to running out, or nil if the subject has no quota.
+// If a quota has run out, the status of that period is returned alongside the error.
+func (q *quotaService) UseCreateQuota(ctx context.Context, subject string) (*entity.QuotaStatus, error) {
+	quota, ok := q.overrides[subject]
+	if !ok {
+		quota = q.quota
+	}
+
+	now := q.now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	periods := make([]quotaPeriod, 0, 2)
+	if quota.Daily > 0 {
+		periods = append(periods, quotaPeriod{
+			QuotaPeriod: repository.QuotaPeriod{Key: "day:" + today.Format("2006-01-02"), Limit: quota.Daily},
+			name:        "daily",
+			reset:       today.AddDate(0, 0, 1),
+		})
+	}
+	if quota.Monthly > 0 {
+		periods = append(periods, quotaPeriod{
+			QuotaPeriod: repository.QuotaPeriod{Key: "month:" + thisMonth.Format("2006-01"), Limit: quota.Monthly},
+			name:        "monthly",
+			reset:       thisMonth.AddDate(0, 1, 0),
+		})
+	}
+
+	if len(periods) == 0 {
+		return nil, nil
+	}
+
+	repoPeriods := make([]repository.QuotaPeriod, len(periods))
+	for i, period := range periods {
+		repoPeriods[i] = period.QuotaPeriod
+	}
+
+	used, err := q.quotaRepo.UseQuota(ctx, subject, repoPeriods)
+	if err != nil {
+		var exceeded *repository.QuotaExceededError
+		if !errors.As(err, &exceeded) {
+			apiErr := api.NewInternal("quota/couldnt-count", api.WithDebug(err.Error()))
+			q.logger.Info("Failed to count quota.", apiErr)
+			return nil, apiErr
+		}
+
+		for _, period := range periods {
+			if period.Key != exceeded.Period.Key {
+				continue
+			}
+
+			return &entity.QuotaStatus{Limit: period.Limit, Remaining: 0, Reset: period.reset},
+				api.NewTooManyRequests("quota/exceeded", fmt.Sprintf("You have created the %d urls your %s quota allows.", period.Limit, period.name),
+					api.WithAction(fmt.Sprintf("Try again after %s.", period.reset.Format(time.RFC3339))))
+		}
+
+		return nil, api.NewInternal("quota/couldnt-count", api.WithDebug(err.Error()))
+	}
+
+	var status *entity.QuotaStatus
+	for i, period := range periods {
+		remaining := period.Limit - used[i]
+		if status == nil || remaining < status.Remaining {
+			status = &entity.QuotaStatus{Limit: period.Limit, Remaining: remaining, Reset: period.reset}
+		}
+	}
+
+	return status, nil
+}
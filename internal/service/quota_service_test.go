@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_UseCreateQuota(t *testing.T) {
+	now := time.Date(2023, 4, 30, 15, 0, 0, 0, time.UTC)
+	tomorrow := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	quotas := NewQuotaService(&QuotaConfig{
+		Logger:    logger.NewApiLogger("development"),
+		QuotaRepo: repository.NewInMemoryQuotaRepo(),
+		Default:   Quota{Daily: 2, Monthly: 10},
+		Overrides: map[string]Quota{"key_ci": {}},
+		Now:       func() time.Time { return now },
+	})
+	ctx := context.Background()
+
+	status, err := quotas.UseCreateQuota(ctx, "ip:192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, &entity.QuotaStatus{Limit: 2, Remaining: 1, Reset: tomorrow}, status)
+
+	status, err = quotas.UseCreateQuota(ctx, "ip:192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, &entity.QuotaStatus{Limit: 2, Remaining: 0, Reset: tomorrow}, status)
+
+	status, err = quotas.UseCreateQuota(ctx, "ip:192.0.2.1")
+	assert.Equal(t, &entity.QuotaStatus{Limit: 2, Remaining: 0, Reset: tomorrow}, status)
+	assert.Equal(t, api.NewTooManyRequests("quota/exceeded", "You have created the 2 urls your daily quota allows.",
+		api.WithAction("Try again after 2023-05-01T00:00:00Z.")), err)
+
+	// the override has no quota at all
+	for i := 0; i < 5; i++ {
+		status, err = quotas.UseCreateQuota(ctx, "key_ci")
+		require.NoError(t, err)
+		assert.Nil(t, status)
+	}
+}
+
+func Test_UseCreateQuota_Monthly(t *testing.T) {
+	now := time.Date(2023, 4, 30, 15, 0, 0, 0, time.UTC)
+
+	quotas := NewQuotaService(&QuotaConfig{
+		Logger:    logger.NewApiLogger("development"),
+		QuotaRepo: repository.NewInMemoryQuotaRepo(),
+		Default:   Quota{Daily: 5, Monthly: 1},
+		Now:       func() time.Time { return now },
+	})
+
+	_, err := quotas.UseCreateQuota(context.Background(), "key_1")
+	require.NoError(t, err)
+
+	status, err := quotas.UseCreateQuota(context.Background(), "key_1")
+	assert.Equal(t, &entity.QuotaStatus{Limit: 1, Remaining: 0, Reset: time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)}, status)
+	assert.Equal(t, "quota/exceeded", api.EnsureApiError(err).Code)
+}
+
+// failingQuotaRepo is a QuotaRepository that always fails to use quota
+type failingQuotaRepo struct {
+	repository.QuotaRepository
+}
+
+func (failingQuotaRepo) UseQuota(ctx context.Context, subject string, periods []repository.QuotaPeriod) ([]int, error) {
+	return nil, errors.New("some error")
+}
+
+func Test_UseCreateQuota_RepoError(t *testing.T) {
+	quotas := NewQuotaService(&QuotaConfig{
+		Logger:    logger.NewApiLogger("development"),
+		QuotaRepo: failingQuotaRepo{},
+		Default:   Quota{Daily: 1},
+	})
+
+	_, err := quotas.UseCreateQuota(context.Background(), "key_1")
+	assert.Equal(t, api.NewInternal("quota/couldnt-count", api.WithDebug("some error")), err)
+}
@@ -4,10 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/auth"
 	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/host"
+	"github.com/Jaytpa01/url-shortener-api/internal/importer"
 	"github.com/Jaytpa01/url-shortener-api/internal/repository"
 	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
 	"github.com/Jaytpa01/url-shortener-api/pkg/utils"
@@ -18,19 +22,37 @@ const (
 	TOKEN_LENGTH                = 6  // length of tokens we generate
 	LENGTHEN_TOKEN_SCALE_FACTOR = 2  // double the length of URLs
 	MINIMUM_LONG_TOKEN_LENGTH   = 42 // the mimimum length that a lengthened token should be
+	TOKEN_ATTEMPTS              = 3  // how many times we try to create a url when random tokens clash
+	DEFAULT_LIST_LIMIT          = 100
+	MAX_LIST_LIMIT              = 1000
 )
 
 type Config struct {
 	Logger  logger.Logger
 	UrlRepo repository.UrlRepository
 	Random  utils.Random
+	Tokens  utils.TokenGenerator
+
+	// WorkspaceRepo holds the memberships users are authorized by. It's nil if the database can't store workspaces.
+	WorkspaceRepo repository.WorkspaceRepository
+
+	// AuditRepo records who created, retargeted and deleted links. It's nil if the database can't store the audit log.
+	AuditRepo repository.AuditRepository
+
+	// CollisionThreshold is the collision probability at which the token length grows.
+	// Defaults to DEFAULT_COLLISION_THRESHOLD.
+	CollisionThreshold float64
 }
 
 // urlService is used for the actual service implementation of this api
 type urlService struct {
-	logger  logger.Logger
-	urlRepo repository.UrlRepository
-	random  utils.Random
+	authorizer
+	auditor
+
+	logger   logger.Logger
+	urlRepo  repository.UrlRepository
+	tokens   utils.TokenGenerator
+	keyspace *keyspace
 }
 
 func NewUrlService(c *Config) UrlService {
@@ -38,52 +60,59 @@ func NewUrlService(c *Config) UrlService {
 		c.Random = utils.NewRandomiser()
 	}
 
+	// if we haven't been given a token generator, generate random tokens
+	if c.Tokens == nil {
+		c.Tokens = utils.NewRandomTokenGenerator(c.Random)
+	}
+
 	return &urlService{
-		urlRepo: c.UrlRepo,
-		logger:  c.Logger,
-		random:  c.Random,
+		authorizer: authorizer{workspaceRepo: c.WorkspaceRepo},
+		auditor:    auditor{logger: c.Logger, auditRepo: c.AuditRepo},
+
+		urlRepo:  c.UrlRepo,
+		logger:   c.Logger,
+		tokens:   c.Tokens,
+		keyspace: newKeyspace(c.Logger, c.UrlRepo, c.CollisionThreshold),
 	}
 }
 
 // ShortenUrl validates the url, then attempts to create it in the repository.
+// If the request was authenticated, the url is owned by its api key or user.
+// The url is created on the short domain the request was made to, in the workspace given by linkWorkspace.
 func (u *urlService) ShortenUrl(ctx context.Context, url string) (*entity.Url, error) {
 	if !validation.IsValidUrl(url) {
 		return nil, api.NewBadRequest("url/invalid", fmt.Sprintf("The provided URL (%s) is invalid.", url))
 	}
 
-	newUrl := &entity.Url{
-		Token:     u.random.GenerateRandomString(TOKEN_LENGTH),
-		TargetUrl: url,
-		CreatedAt: time.Now().UTC(),
+	workspace := linkWorkspace(ctx)
+	if err := u.authorize(ctx, workspace, actionCreateLink); err != nil {
+		return nil, err
 	}
 
-	var err error
-	// because there's a very slim chance a generated token may clash, we give it up to 3 attempts
-	attempts := 3
-	for i := 0; i < attempts; i++ {
-		err = u.urlRepo.Create(ctx, newUrl)
-		if err != nil {
-			if errors.Is(err, repository.ErrTokenAlreadyExists) {
-				newUrl.Token = u.random.GenerateRandomString(TOKEN_LENGTH)
-				continue
-			} else {
-				u.logger.Infof("couldnt create url: %v", err)
-				return nil, api.NewInternal("url/couldnt-shorten", api.WithDebug(err.Error()))
-			}
-		}
+	newUrl := &entity.Url{
+		Domain:      host.DomainName(ctx),
+		TargetUrl:   url,
+		CreatedAt:   time.Now().UTC(),
+		Owner:       owner(ctx),
+		WorkspaceID: workspace,
+	}
 
-		// no error, so we break
-		break
+	// if every attempt clashes, the keyspace is fuller than we thought,
+	// so we'd rather hand out a longer token than an error
+	length := u.keyspace.TokenLength(ctx)
+	err := u.createUrl(ctx, newUrl, length)
+	for grown := 0; errors.Is(err, repository.ErrTokenAlreadyExists) && grown < MAX_TOKEN_GROWTH; grown++ {
+		length = u.keyspace.Grow(length)
+		err = u.createUrl(ctx, newUrl, length)
 	}
 
 	if err != nil {
-		// Because we try 3 times to prevent a token clash, we simply return an internal
-		// error here after several attempts if we keep clashing
 		apiErr := api.NewInternal("url/couldnt-shorten", api.WithDebug(err.Error()))
 		u.logger.Info("Failed to shorten URL.", apiErr)
 		return nil, apiErr
 	}
 
+	u.record(ctx, entity.AuditLinkCreate, workspace, newUrl.Address(), targetChange("", newUrl.TargetUrl))
 	return newUrl, nil
 }
 
@@ -93,44 +122,125 @@ func (u *urlService) LengthenUrl(ctx context.Context, url string) (*entity.Url,
 		return nil, api.NewBadRequest("url/invalid", fmt.Sprintf("The provided URL (%s) is invalid.", url))
 	}
 
+	workspace := linkWorkspace(ctx)
+	if err := u.authorize(ctx, workspace, actionCreateLink); err != nil {
+		return nil, err
+	}
+
 	newUrl := &entity.Url{
-		Token:     u.random.GenerateRandomString(utils.Max(MINIMUM_LONG_TOKEN_LENGTH, len(url)*LENGTHEN_TOKEN_SCALE_FACTOR)),
-		TargetUrl: url,
-		CreatedAt: time.Now().UTC(),
+		Domain:      host.DomainName(ctx),
+		TargetUrl:   url,
+		CreatedAt:   time.Now().UTC(),
+		Owner:       owner(ctx),
+		WorkspaceID: workspace,
+	}
+
+	if err := u.createUrl(ctx, newUrl, utils.Max(MINIMUM_LONG_TOKEN_LENGTH, len(url)*LENGTHEN_TOKEN_SCALE_FACTOR)); err != nil {
+		apiErr := api.NewInternal("url/couldnt-lengthen", api.WithDebug(err.Error()))
+		u.logger.Info("Failed to lengthen URL.", apiErr)
+		return nil, apiErr
+	}
+
+	u.record(ctx, entity.AuditLinkCreate, workspace, newUrl.Address(), targetChange("", newUrl.TargetUrl))
+	return newUrl, nil
+}
+
+// linkWorkspace returns the workspace links created by the request belong to.
+// Links on a short domain belong to the domain's workspace, and other links to the workspace the request acts in.
+func linkWorkspace(ctx context.Context) string {
+	if domain := host.FromContext(ctx); domain != nil {
+		return domain.WorkspaceID
 	}
 
+	return activeWorkspace(ctx)
+}
+
+// targetChange is the audited change of a url's target.
+func targetChange(before, after string) entity.AuditChanges {
+	return entity.AuditChanges{"target_url": {Before: before, After: after}}
+}
+
+// owner returns the id of the api key or user the request was authenticated as, or "" for anonymous requests.
+func owner(ctx context.Context) string {
+	if principal, ok := auth.FromContext(ctx); ok {
+		return principal.Owner()
+	}
+
+	return ""
+}
+
+// createUrl generates a token of the given length for url and creates it in the repository.
+// Random tokens have a very slim chance of clashing with an existing token, so a clash is
+// retried up to TOKEN_ATTEMPTS times. Sequence tokens never clash, so they're created on the first attempt.
+func (u *urlService) createUrl(ctx context.Context, url *entity.Url, length int) error {
 	var err error
-	// because there's a very slim chance a generated token may clash, we give it up to 3 attempts
-	attempts := 3
-	for i := 0; i < attempts; i++ {
-		err = u.urlRepo.Create(ctx, newUrl)
+	for i := 0; i < TOKEN_ATTEMPTS; i++ {
+		url.Token, err = u.tokens.GenerateToken(ctx, length)
 		if err != nil {
-			if errors.Is(err, repository.ErrTokenAlreadyExists) {
-				newUrl.Token = u.random.GenerateRandomString(utils.Max(MINIMUM_LONG_TOKEN_LENGTH, len(url)*LENGTHEN_TOKEN_SCALE_FACTOR))
-				continue
-			} else {
-				return nil, api.NewInternal("url/couldnt-lengthen", api.WithDebug(err.Error()))
-			}
+			return err
+		}
+
+		err = u.urlRepo.Create(ctx, url)
+		collided := errors.Is(err, repository.ErrTokenAlreadyExists)
+		if err == nil || collided {
+			u.keyspace.RecordAttempt(length, collided)
 		}
 
-		// no error, so we break
-		break
+		if !collided {
+			return err
+		}
 	}
 
-	if err != nil {
-		// Because we try 3 times to prevent a token clash, we simply return an internal
-		// error here after several attempts if we keep clashing
-		apiErr := api.NewInternal("url/couldnt-lengthen", api.WithDebug(err.Error()))
-		u.logger.Info("Failed to lengthen URL.", apiErr)
-		return nil, apiErr
+	return err
+}
+
+// findUrl finds the url with the token on the short domain the request was made to.
+func (u *urlService) findUrl(ctx context.Context, token string) (*entity.Url, error) {
+	domain := host.DomainName(ctx)
+	if domain == "" {
+		return u.urlRepo.FindByToken(ctx, token)
 	}
 
-	return newUrl, nil
+	repo, ok := u.urlRepo.(repository.DomainUrlRepository)
+	if !ok {
+		return nil, repository.ErrUrlNotFound
+	}
+
+	return repo.FindByDomainToken(ctx, domain, token)
 }
 
-// FindUrlByToken attempts to find the URL associated to provided token.
+// deleteUrl deletes the url from its short domain.
+func (u *urlService) deleteUrl(ctx context.Context, url *entity.Url) error {
+	if url.Domain == "" {
+		return u.urlRepo.Delete(ctx, url.Token)
+	}
+
+	repo, ok := u.urlRepo.(repository.DomainUrlRepository)
+	if !ok {
+		return repository.ErrUrlNotFound
+	}
+
+	return repo.DeleteFromDomain(ctx, url.Domain, url.Token)
+}
+
+// FindUrlByToken attempts to find the URL associated to provided token, on the short domain the request was made to.
+// Disabled urls are found, but return a Gone error, as they mustn't be redirected to.
 func (u *urlService) FindUrlByToken(ctx context.Context, token string) (*entity.Url, error) {
-	url, err := u.urlRepo.FindByToken(ctx, token)
+	url, err := u.lookupUrl(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if url.Disabled {
+		return nil, api.NewGone("url/disabled", fmt.Sprintf("The URL with token (%s) has been disabled.", token))
+	}
+
+	return url, nil
+}
+
+// lookupUrl finds the url with the token like FindUrlByToken, including disabled urls, which can still be managed.
+func (u *urlService) lookupUrl(ctx context.Context, token string) (*entity.Url, error) {
+	url, err := u.findUrl(ctx, token)
 	if err != nil {
 		if errors.Is(err, repository.ErrUrlNotFound) {
 			return nil, api.NewNotFound("url/not-found", fmt.Sprintf("Couldn't find URL with token (%s).", token))
@@ -145,6 +255,113 @@ func (u *urlService) FindUrlByToken(ctx context.Context, token string) (*entity.
 	return url, nil
 }
 
+// GetUrlAnalytics finds the url with the token, if the request can view the analytics of its workspace.
+func (u *urlService) GetUrlAnalytics(ctx context.Context, token string) (*entity.Url, error) {
+	url, err := u.lookupUrl(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.authorize(ctx, url.Workspace(), actionViewAnalytics); err != nil {
+		return nil, err
+	}
+
+	return url, nil
+}
+
+// UpdateUrl changes the target of the url with the token, if the request can edit links in its workspace.
+func (u *urlService) UpdateUrl(ctx context.Context, token, targetUrl string) (*entity.Url, error) {
+	if !validation.IsValidUrl(targetUrl) {
+		return nil, api.NewBadRequest("url/invalid", fmt.Sprintf("The provided URL (%s) is invalid.", targetUrl))
+	}
+
+	url, err := u.lookupUrl(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.authorizeLink(ctx, url, actionEditLink); err != nil {
+		return nil, err
+	}
+
+	before := url.TargetUrl
+	url.TargetUrl = targetUrl
+	if err := u.urlRepo.Update(ctx, url); err != nil {
+		if errors.Is(err, repository.ErrUrlNotFound) {
+			return nil, api.NewNotFound("url/not-found", fmt.Sprintf("Couldn't find URL with token (%s).", token))
+		}
+
+		apiErr := api.NewInternal("url/couldnt-update", api.WithDebug(err.Error()))
+		u.logger.Info("Failed to update URL.", apiErr)
+		return nil, apiErr
+	}
+
+	u.record(ctx, entity.AuditLinkRetarget, url.Workspace(), url.Address(), targetChange(before, targetUrl))
+	return url, nil
+}
+
+// DeleteUrl deletes the url with the token, if the request can delete links in its workspace.
+func (u *urlService) DeleteUrl(ctx context.Context, token string) error {
+	url, err := u.lookupUrl(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	if err := u.authorizeLink(ctx, url, actionDeleteLink); err != nil {
+		return err
+	}
+
+	if err := u.deleteUrl(ctx, url); err != nil {
+		if errors.Is(err, repository.ErrUrlNotFound) {
+			return api.NewNotFound("url/not-found", fmt.Sprintf("Couldn't find URL with token (%s).", token))
+		}
+
+		apiErr := api.NewInternal("url/couldnt-delete", api.WithDebug(err.Error()))
+		u.logger.Info("Failed to delete URL.", apiErr)
+		return apiErr
+	}
+
+	u.record(ctx, entity.AuditLinkDelete, url.Workspace(), url.Address(), targetChange(url.TargetUrl, ""))
+	return nil
+}
+
+// SetUrlDisabled disables or enables the url with the token, if the request can disable links in its workspace.
+// Disabled urls aren't redirected to, but keep their token, so it can't be reused.
+func (u *urlService) SetUrlDisabled(ctx context.Context, token string, disabled bool) (*entity.Url, error) {
+	url, err := u.lookupUrl(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.authorize(ctx, url.Workspace(), actionDisableLink); err != nil {
+		return nil, err
+	}
+
+	if url.Disabled == disabled {
+		return url, nil
+	}
+
+	url.Disabled = disabled
+	if err := u.urlRepo.Update(ctx, url); err != nil {
+		if errors.Is(err, repository.ErrUrlNotFound) {
+			return nil, api.NewNotFound("url/not-found", fmt.Sprintf("Couldn't find URL with token (%s).", token))
+		}
+
+		apiErr := api.NewInternal("url/couldnt-disable", api.WithDebug(err.Error()))
+		u.logger.Info("Failed to disable URL.", apiErr)
+		return nil, apiErr
+	}
+
+	action := entity.AuditLinkEnable
+	if disabled {
+		action = entity.AuditLinkDisable
+	}
+	u.record(ctx, action, url.Workspace(), url.Address(), entity.AuditChanges{
+		"disabled": {Before: strconv.FormatBool(!disabled), After: strconv.FormatBool(disabled)},
+	})
+	return url, nil
+}
+
 var ErrNilUrlPointer = errors.New("received nil url pointer")
 
 // IncrementUrlVisits increments the visits of the url and persists that to our UrlRepository
@@ -153,6 +370,33 @@ func (u *urlService) IncrementUrlVisits(ctx context.Context, url *entity.Url) er
 		return ErrNilUrlPointer
 	}
 
+	// let the repository increment visits itself if it can, so concurrent visits aren't lost,
+	// and a visit can't write back a target or disabled flag that was changed since the url was found
+	var (
+		visits int
+		err    error
+	)
+	incrementer, ok := u.urlRepo.(repository.VisitIncrementer)
+	domainIncrementer, domainOk := u.urlRepo.(repository.DomainVisitIncrementer)
+	switch {
+	case domainOk:
+		visits, err = domainIncrementer.IncrementDomainVisits(ctx, url.Domain, url.Token)
+	case ok && url.Domain == "":
+		visits, err = incrementer.IncrementVisits(ctx, url.Token)
+	default:
+		return u.updateVisits(ctx, url)
+	}
+	if err != nil {
+		return err
+	}
+
+	url.Visits = visits
+	return nil
+}
+
+// updateVisits increments the visits of the url by updating the whole url, for repositories that can't increment them.
+func (u *urlService) updateVisits(ctx context.Context, url *entity.Url) error {
+
 	url.Visits++
 	err := u.urlRepo.Update(ctx, url)
 	if err != nil {
@@ -164,6 +408,158 @@ func (u *urlService) IncrementUrlVisits(ctx context.Context, url *entity.Url) er
 	return nil
 }
 
-func (u *urlService) GetAllUrls(ctx context.Context) ([]entity.Url, error) {
-	return u.urlRepo.GetAllUrls(ctx)
+// ListUrls returns a page of urls matching the filter, and a cursor for the next page.
+// Only urls in the workspace the request acts in are listed, unless it was made with the admin token.
+// The limit defaults to DEFAULT_LIST_LIMIT, and can't be more than MAX_LIST_LIMIT.
+func (u *urlService) ListUrls(ctx context.Context, filter repository.ListFilter, cursor string, limit int) ([]entity.Url, string, error) {
+	if principal, ok := auth.FromContext(ctx); !ok || !principal.Admin {
+		filter.WorkspaceID = activeWorkspace(ctx)
+		if err := u.authorize(ctx, filter.WorkspaceID, actionListLinks); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if limit <= 0 {
+		limit = DEFAULT_LIST_LIMIT
+	}
+	limit = utils.Min(limit, MAX_LIST_LIMIT)
+
+	urls, next, err := u.urlRepo.ListUrls(ctx, filter, cursor, limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrInvalidCursor):
+			return nil, "", api.NewBadRequest("url/invalid-cursor", "The provided cursor is invalid.",
+				api.WithAction("Use the next_cursor from a previous page, with the same sort order."))
+		case errors.Is(err, repository.ErrInvalidSort):
+			return nil, "", api.NewBadRequest("url/invalid-sort", err.Error())
+		}
+
+		apiErr := api.NewInternal("url/couldnt-list", api.WithDebug(err.Error()))
+		u.logger.Info("Failed to list URLs.", apiErr)
+		return nil, "", apiErr
+	}
+
+	return urls, next, nil
+}
+
+// KeyspaceStats returns how full the keyspace of the current token length is.
+func (u *urlService) KeyspaceStats(ctx context.Context) *entity.KeyspaceStats {
+	return u.keyspace.Stats(ctx)
+}
+
+// ImportUrls creates each of the imported urls, keeping their tokens, visits and created dates.
+// Urls without a token are given a generated one. What happens when a token already exists depends on conflict.
+// Every row is reported on, so one url failing doesn't stop the rest being imported.
+// The urls are imported onto the short domain the request was made to, in the workspace given by linkWorkspace.
+func (u *urlService) ImportUrls(ctx context.Context, records []importer.Record, conflict importer.Conflict) (*importer.Report, error) {
+	switch conflict {
+	case importer.ConflictSkip, importer.ConflictOverwrite, importer.ConflictRename:
+	default:
+		return nil, api.NewBadRequest("import/invalid-conflict", fmt.Sprintf("Unknown conflict policy (%s).", conflict),
+			api.WithAction("Use one of skip, overwrite or rename."))
+	}
+
+	workspace := linkWorkspace(ctx)
+	if err := u.authorize(ctx, workspace, actionImportLinks); err != nil {
+		return nil, err
+	}
+
+	report := &importer.Report{}
+	for _, record := range records {
+		if err := ctx.Err(); err != nil {
+			return report, api.NewInternal("import/cancelled", api.WithDebug(err.Error()))
+		}
+
+		report.Add(u.importUrl(ctx, record, conflict, workspace))
+	}
+
+	return report, nil
+}
+
+// authorizeOverwrite checks the request can edit the existing url with the token, and returns it.
+func (u *urlService) authorizeOverwrite(ctx context.Context, token string) (*entity.Url, error) {
+	existing, err := u.findUrl(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	return existing, u.authorizeLink(ctx, existing, actionEditLink)
+}
+
+// importUrl imports a single record.
+func (u *urlService) importUrl(ctx context.Context, record importer.Record, conflict importer.Conflict, workspace string) importer.RowResult {
+	result := importer.RowResult{
+		Row:   record.Row,
+		Token: record.Token,
+	}
+
+	if record.Err != nil {
+		result.Status = importer.StatusFailed
+		result.Error = record.Err.Error()
+		return result
+	}
+
+	url := &entity.Url{
+		Domain:    host.DomainName(ctx),
+		Token:     record.Token,
+		TargetUrl: record.TargetUrl,
+		Visits:    record.Visits,
+		CreatedAt: record.CreatedAt,
+
+		WorkspaceID: workspace,
+	}
+	if url.CreatedAt.IsZero() {
+		url.CreatedAt = time.Now().UTC()
+	}
+
+	var (
+		err      error
+		existing *entity.Url // the url that was overwritten, if any
+	)
+	switch {
+	case url.Token == "":
+		err = u.createUrl(ctx, url, u.keyspace.TokenLength(ctx))
+		result.Status = importer.StatusCreated
+	default:
+		err = u.urlRepo.Create(ctx, url)
+		result.Status = importer.StatusCreated
+		if !errors.Is(err, repository.ErrTokenAlreadyExists) {
+			break
+		}
+
+		switch conflict {
+		case importer.ConflictSkip:
+			err = nil
+			result.Status = importer.StatusSkipped
+		case importer.ConflictOverwrite:
+			// the existing url may be in another workspace
+			existing, err = u.authorizeOverwrite(ctx, url.Token)
+			if err == nil {
+				err = u.urlRepo.Update(ctx, url)
+			}
+			result.Status = importer.StatusOverwritten
+		case importer.ConflictRename:
+			err = u.createUrl(ctx, url, u.keyspace.TokenLength(ctx))
+			result.OriginalToken = record.Token
+			result.Status = importer.StatusRenamed
+		}
+	}
+
+	if err != nil {
+		result.Status = importer.StatusFailed
+		result.Error = err.Error()
+		result.OriginalToken = ""
+		result.Token = record.Token
+		return result
+	}
+
+	switch result.Status {
+	case importer.StatusCreated, importer.StatusRenamed:
+		u.record(ctx, entity.AuditLinkCreate, workspace, url.Address(), targetChange("", url.TargetUrl))
+	case importer.StatusOverwritten:
+		u.record(ctx, entity.AuditLinkRetarget, workspace, url.Address(), targetChange(existing.TargetUrl, url.TargetUrl))
+	}
+
+	result.Token = url.Token
+	return result
 }
@@ -8,7 +8,9 @@ import (
 	"time"
 
 	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/auth"
 	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/importer"
 	"github.com/Jaytpa01/url-shortener-api/internal/mocks"
 	"github.com/Jaytpa01/url-shortener-api/internal/repository"
 	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
@@ -29,8 +31,9 @@ func Test_ShortenUrl(t *testing.T) {
 			"Create Valid Url",
 			"https://example.com",
 			&entity.Url{
-				Token:     "123456",
-				TargetUrl: "https://example.com",
+				Token:       "123456",
+				TargetUrl:   "https://example.com",
+				WorkspaceID: entity.DEFAULT_WORKSPACE,
 			},
 			nil,
 			nil,
@@ -72,10 +75,11 @@ func Test_ShortenUrl(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 
 			repo := mocks.NewMockUrlRepository()
-			repo.On("Create", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*entity.Url")).Return(test.repoError)
+			repo.On("Create", mock.Anything, mock.AnythingOfType("*entity.Url")).Return(test.repoError)
+			repo.On("Count", mock.Anything).Return(0, nil)
 
 			randomiser := mocks.NewMockRandomiser()
-			randomiser.On("GenerateRandomString", 6).Return("123456")
+			randomiser.On("GenerateRandomString", mock.Anything).Return("123456")
 			urlService := NewUrlService(&Config{
 				UrlRepo: repo,
 				Logger:  logger.NewApiLogger("development"),
@@ -104,6 +108,27 @@ func Test_ShortenUrl(t *testing.T) {
 	}
 }
 
+func Test_ShortenUrl_RecordsOwner(t *testing.T) {
+	repo := mocks.NewMockUrlRepository()
+	repo.On("Create", mock.Anything, mock.AnythingOfType("*entity.Url")).Return(nil)
+	repo.On("Count", mock.Anything).Return(0, nil)
+
+	urlService := NewUrlService(&Config{
+		UrlRepo: repo,
+		Logger:  logger.NewApiLogger("development"),
+	})
+
+	url, err := urlService.ShortenUrl(context.Background(), "https://example.com")
+	assert.NoError(t, err)
+	assert.Empty(t, url.Owner)
+
+	ctx := auth.NewContext(context.Background(), &auth.Principal{KeyID: "key_1", Name: "ci"})
+	url, err = urlService.ShortenUrl(ctx, "https://example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "key_1", url.Owner)
+	repo.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(u *entity.Url) bool { return u.Owner == "key_1" }))
+}
+
 func Test_LengthenUrl(t *testing.T) {
 	testCases := []struct {
 		name           string
@@ -117,8 +142,9 @@ func Test_LengthenUrl(t *testing.T) {
 			"Successfully Lengthen URL",
 			"https://example.com",
 			&entity.Url{
-				Token:     "ThisIsMeantToRepresentAReallyReallyReallyReallyLongToken",
-				TargetUrl: "https://example.com",
+				Token:       "ThisIsMeantToRepresentAReallyReallyReallyReallyLongToken",
+				TargetUrl:   "https://example.com",
+				WorkspaceID: entity.DEFAULT_WORKSPACE,
 			},
 			nil,
 			nil,
@@ -148,7 +174,7 @@ func Test_LengthenUrl(t *testing.T) {
 
 	for _, test := range testCases {
 		repo := mocks.NewMockUrlRepository()
-		repo.On("Create", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*entity.Url")).Return(test.repoError)
+		repo.On("Create", mock.Anything, mock.AnythingOfType("*entity.Url")).Return(test.repoError)
 
 		randomiser := mocks.NewMockRandomiser()
 		randomiser.On("GenerateRandomString", utils.Max(MINIMUM_LONG_TOKEN_LENGTH, len(test.inputUrl)*LENGTHEN_TOKEN_SCALE_FACTOR)).Return("ThisIsMeantToRepresentAReallyReallyReallyReallyLongToken")
@@ -216,7 +242,7 @@ func Test_FindUrlByToken(t *testing.T) {
 
 	for _, test := range testCases {
 		repo := mocks.NewMockUrlRepository()
-		repo.On("FindByToken", mock.AnythingOfType("*context.emptyCtx"), test.token).Return(test.returnUrl, test.repoError)
+		repo.On("FindByToken", mock.Anything, test.token).Return(test.returnUrl, test.repoError)
 
 		urlService := NewUrlService(&Config{
 			UrlRepo: repo,
@@ -275,7 +301,7 @@ func Test_IncrementUrlVisits(t *testing.T) {
 	for _, test := range testCases {
 		t.Run(test.name, func(t *testing.T) {
 			repo := mocks.NewMockUrlRepository()
-			repo.On("Update", mock.AnythingOfType("*context.emptyCtx"), test.inputUrl).Return(test.repoError)
+			repo.On("Update", mock.Anything, test.inputUrl).Return(test.repoError)
 
 			service := NewUrlService(&Config{
 				UrlRepo: repo,
@@ -295,60 +321,534 @@ func Test_IncrementUrlVisits(t *testing.T) {
 	}
 }
 
-func Test_GetAllUrls(t *testing.T) {
+func Test_IncrementUrlVisits_VisitIncrementer(t *testing.T) {
 	testCases := []struct {
-		name            string
-		repoUrlResponse []entity.Url
-		repoErr         error
-		expectedErr     error
+		name                            string
+		inputUrl                        *entity.Url
+		repoVisits                      int
+		repoError                       error
+		expectedVisitsAfterIncrementing int
 	}{
 		{
-			"Success",
-			[]entity.Url{
-				{
-					Token:     "123456",
-					TargetUrl: "https://example.com",
-				},
-				{
-					Token:     "987654",
-					TargetUrl: "https://google.com",
-				},
-			},
-			nil,
+			"Successfully Incremented Visits",
+			&entity.Url{Token: "987654", Visits: 1},
+			// other visits may have been counted since the url was read
+			5,
 			nil,
+			5,
 		},
 		{
-			"Failure",
+			"Couldn't Increment Visits",
+			&entity.Url{Token: "987654", Visits: 1},
+			0,
+			errors.New("couldn't increment"),
+			1,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			repo := mocks.NewMockVisitIncrementingUrlRepository()
+			repo.On("IncrementVisits", mock.Anything, test.inputUrl.Token).Return(test.repoVisits, test.repoError)
+
+			service := NewUrlService(&Config{
+				UrlRepo: repo,
+				Logger:  logger.NewApiLogger("development"),
+			})
+
+			err := service.IncrementUrlVisits(context.Background(), test.inputUrl)
+			assert.Equal(t, test.repoError, err)
+			assert.Equal(t, test.expectedVisitsAfterIncrementing, test.inputUrl.Visits)
+
+			// the whole url shouldn't be rewritten
+			repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func Test_IncrementUrlVisits_DomainVisitIncrementer(t *testing.T) {
+	url := &entity.Url{Domain: "go.example.com", Token: "987654", Visits: 1}
+
+	repo := mocks.NewMockDomainVisitIncrementingUrlRepository()
+	repo.On("IncrementDomainVisits", mock.Anything, "go.example.com", "987654").Return(3, nil)
+
+	service := NewUrlService(&Config{
+		UrlRepo: repo,
+		Logger:  logger.NewApiLogger("development"),
+	})
+
+	err := service.IncrementUrlVisits(context.Background(), url)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, url.Visits)
+
+	// a visit to a short domain mustn't rewrite the whole url either
+	repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func Test_ListUrls(t *testing.T) {
+	testCases := []struct {
+		name          string
+		limit         int
+		expectedLimit int
+		repoErr       error
+		expectedErr   error
+	}{
+		{"Success", 10, 10, nil, nil},
+		{"Default Limit", 0, DEFAULT_LIST_LIMIT, nil, nil},
+		{"Limit Too Large", MAX_LIST_LIMIT + 1, MAX_LIST_LIMIT, nil, nil},
+		{
+			"Invalid Cursor",
+			10,
+			10,
+			repository.ErrInvalidCursor,
+			api.NewBadRequest("url/invalid-cursor", "The provided cursor is invalid.",
+				api.WithAction("Use the next_cursor from a previous page, with the same sort order.")),
+		},
+		{"Unknown Repo Error", 10, 10, errors.New("some error"), api.NewInternal("url/couldnt-list", api.WithDebug("some error"))},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			page := []entity.Url{{Token: "123456", TargetUrl: "https://example.com"}}
+			filter := repository.ListFilter{Domain: "example.com"}
+
+			repo := mocks.NewMockUrlRepository()
+			repo.On("ListUrls", mock.Anything, filter, "cursor", test.expectedLimit).Return(page, "next", test.repoErr)
+
+			service := NewUrlService(&Config{
+				UrlRepo: repo,
+				Logger:  logger.NewApiLogger("development"),
+			})
+
+			ctx := auth.NewContext(context.Background(), &auth.Principal{Admin: true})
+			urls, next, err := service.ListUrls(ctx, filter, "cursor", test.limit)
+			if test.expectedErr != nil {
+				assert.Equal(t, test.expectedErr, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, page, urls)
+			assert.Equal(t, "next", next)
+		})
+	}
+}
+
+func Test_ShortenUrl_SequenceTokens(t *testing.T) {
+	repo := repository.NewInMemoryRepo()
+	urlService := NewUrlService(&Config{
+		UrlRepo: repo,
+		Logger:  logger.NewApiLogger("development"),
+		Tokens:  utils.NewSequenceTokenGenerator(repo.(utils.Sequence), []byte("secret")),
+	})
+
+	first, err := urlService.ShortenUrl(context.Background(), "https://example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, TOKEN_LENGTH, len(first.Token))
+
+	second, err := urlService.ShortenUrl(context.Background(), "https://example.com")
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.Token, second.Token)
+}
+
+func Test_ImportUrls(t *testing.T) {
+	createdAt := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	testCases := []struct {
+		name           string
+		conflict       importer.Conflict
+		expectedStatus importer.Status
+		expectedTarget string
+	}{
+		{"Skip", importer.ConflictSkip, importer.StatusSkipped, "https://example.com"},
+		{"Overwrite", importer.ConflictOverwrite, importer.StatusOverwritten, "https://example.com/imported"},
+		{"Rename", importer.ConflictRename, importer.StatusRenamed, "https://example.com"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			repo := repository.NewInMemoryRepo()
+			assert.NoError(t, repo.Create(context.Background(), &entity.Url{Token: "abc123", TargetUrl: "https://example.com"}))
+
+			service := NewUrlService(&Config{
+				UrlRepo: repo,
+				Logger:  logger.NewApiLogger("development"),
+			})
+
+			ctx := auth.NewContext(context.Background(), &auth.Principal{Admin: true})
+			report, err := service.ImportUrls(ctx, []importer.Record{
+				{Row: 1, Token: "xyz789", TargetUrl: "https://example.com/new", Visits: 4, CreatedAt: createdAt},
+				{Row: 2, Token: "abc123", TargetUrl: "https://example.com/imported", Visits: 9},
+				{Row: 3, TargetUrl: "https://example.com/bookmark"},
+				{Row: 4, Token: "bad", Err: errors.New("invalid target url (nope)")},
+			}, test.conflict)
+			assert.NoError(t, err)
+			assert.Len(t, report.Rows, 4)
+			assert.Equal(t, 2, report.Created)
+			assert.Equal(t, 1, report.Failed)
+
+			// the token, visits and created date of imported urls are kept
+			assert.Equal(t, importer.RowResult{Row: 1, Token: "xyz789", Status: importer.StatusCreated}, report.Rows[0])
+			url, err := repo.FindByToken(context.Background(), "xyz789")
+			assert.NoError(t, err)
+			assert.Equal(t, 4, url.Visits)
+			assert.Equal(t, createdAt, url.CreatedAt)
+
+			assert.Equal(t, test.expectedStatus, report.Rows[1].Status)
+			url, err = repo.FindByToken(context.Background(), "abc123")
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedTarget, url.TargetUrl)
+
+			if test.conflict == importer.ConflictRename {
+				assert.Equal(t, "abc123", report.Rows[1].OriginalToken)
+				url, err = repo.FindByToken(context.Background(), report.Rows[1].Token)
+				assert.NoError(t, err)
+				assert.Equal(t, "https://example.com/imported", url.TargetUrl)
+			}
+
+			// urls without a token are given one
+			assert.Equal(t, importer.StatusCreated, report.Rows[2].Status)
+			assert.Len(t, report.Rows[2].Token, TOKEN_LENGTH)
+
+			assert.Equal(t, importer.RowResult{Row: 4, Token: "bad", Status: importer.StatusFailed, Error: "invalid target url (nope)"}, report.Rows[3])
+		})
+	}
+}
+
+func Test_ImportUrls_InvalidConflict(t *testing.T) {
+	service := NewUrlService(&Config{
+		UrlRepo: repository.NewInMemoryRepo(),
+		Logger:  logger.NewApiLogger("development"),
+	})
+
+	_, err := service.ImportUrls(context.Background(), nil, "merge")
+	assert.Equal(t, api.BadRequest, api.EnsureApiError(err).Type)
+}
+
+// newWorkspaceUrlService returns a url service with an in-memory workspace ws_1, where user_owner is an owner,
+// user_editor an editor and user_viewer a viewer, holding the url with token "abcdef".
+func newWorkspaceUrlService(t *testing.T) UrlService {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	workspaces := repository.NewInMemoryWorkspaceRepo()
+	assert.NoError(t, workspaces.CreateWorkspace(ctx, &entity.Workspace{ID: "ws_1", Name: "Team", CreatedAt: now},
+		&entity.Membership{WorkspaceID: "ws_1", UserID: "user_owner", Role: entity.RoleOwner, CreatedAt: now}))
+	for user, role := range map[string]entity.Role{"user_editor": entity.RoleEditor, "user_viewer": entity.RoleViewer} {
+		assert.NoError(t, workspaces.AddMember(ctx, &entity.Membership{WorkspaceID: "ws_1", UserID: user, Role: role, CreatedAt: now}))
+	}
+
+	urls := repository.NewInMemoryRepo()
+	assert.NoError(t, urls.Create(ctx, &entity.Url{Token: "abcdef", TargetUrl: "https://example.com", WorkspaceID: "ws_1", CreatedAt: now}))
+
+	return NewUrlService(&Config{
+		UrlRepo:       urls,
+		WorkspaceRepo: workspaces,
+		Logger:        logger.NewApiLogger("development"),
+	})
+}
+
+func Test_ShortenUrl_UserWithoutDefaultMembership(t *testing.T) {
+	service := newWorkspaceUrlService(t)
+
+	// a user who isn't a member of the default workspace can do everything there an anonymous request can, and more
+	ctx := auth.NewContext(context.Background(), &auth.Principal{UserID: "user_new"})
+	url, err := service.ShortenUrl(ctx, "https://example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "user_new", url.Owner)
+	assert.Equal(t, entity.DEFAULT_WORKSPACE, url.Workspace())
+
+	_, err = service.UpdateUrl(ctx, url.Token, "https://example.org")
+	assert.NoError(t, err)
+
+	_, err = service.GetUrlAnalytics(ctx, url.Token)
+	assert.NoError(t, err)
+
+	// they're still not a member of other workspaces
+	_, err = service.UpdateUrl(ctx, "abcdef", "https://example.org")
+	assert.Equal(t, api.Forbidden, api.EnsureApiError(err).Type)
+}
+
+func Test_DefaultWorkspaceLinks(t *testing.T) {
+	service := newWorkspaceUrlService(t)
+
+	alice := auth.NewContext(context.Background(), &auth.Principal{UserID: "user_alice"})
+	url, err := service.ShortenUrl(alice, "https://example.com")
+	assert.NoError(t, err)
+
+	anonymous, err := service.ShortenUrl(context.Background(), "https://example.com")
+	assert.NoError(t, err)
+
+	// principals without a membership can't change links other principals created in the default workspace
+	forbidden := api.NewForbidden("workspace/not-link-owner", "You can only edit links in workspace (default) that you created.")
+	for _, principal := range []*auth.Principal{
+		{UserID: "user_bob"},
+		{KeyID: "key_1", WorkspaceID: entity.DEFAULT_WORKSPACE},
+	} {
+		ctx := auth.NewContext(context.Background(), principal)
+
+		_, err = service.UpdateUrl(ctx, url.Token, "https://attacker.example")
+		assert.Equal(t, forbidden, err)
+
+		_, err = service.UpdateUrl(ctx, anonymous.Token, "https://attacker.example")
+		assert.Equal(t, forbidden, err)
+
+		err = service.DeleteUrl(ctx, url.Token)
+		assert.Equal(t, api.NewForbidden("workspace/not-link-owner", "You can only delete links in workspace (default) that you created."), err)
+	}
+
+	found, err := service.FindUrlByToken(context.Background(), url.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", found.TargetUrl)
+
+	// the admin token owns every workspace, so can change any of them
+	admin := auth.NewContext(context.Background(), &auth.Principal{Admin: true})
+	_, err = service.UpdateUrl(admin, anonymous.Token, "https://example.org")
+	assert.NoError(t, err)
+	assert.NoError(t, service.DeleteUrl(admin, anonymous.Token))
+
+	assert.NoError(t, service.DeleteUrl(alice, url.Token))
+}
+
+func Test_UpdateUrl(t *testing.T) {
+	testCases := []struct {
+		name        string
+		principal   *auth.Principal
+		token       string
+		targetUrl   string
+		expectedErr error
+	}{
+		{"Owner", &auth.Principal{UserID: "user_owner", WorkspaceID: "ws_1"}, "abcdef", "https://example.org", nil},
+		{"Editor", &auth.Principal{UserID: "user_editor", WorkspaceID: "ws_1"}, "abcdef", "https://example.org", nil},
+		{"Key In Workspace", &auth.Principal{KeyID: "key_1", WorkspaceID: "ws_1"}, "abcdef", "https://example.org", nil},
+		{"Admin", &auth.Principal{Admin: true}, "abcdef", "https://example.org", nil},
+		{
+			"Viewer",
+			&auth.Principal{UserID: "user_viewer", WorkspaceID: "ws_1"},
+			"abcdef",
+			"https://example.org",
+			api.NewForbidden("workspace/forbidden", "You need the editor role to edit links in workspace (ws_1)."),
+		},
+		{
+			"Non Member",
+			&auth.Principal{UserID: "user_stranger"},
+			"abcdef",
+			"https://example.org",
+			api.NewForbidden("workspace/forbidden", "You need the editor role to edit links in workspace (ws_1)."),
+		},
+		{
+			"Key In Other Workspace",
+			&auth.Principal{KeyID: "key_2", WorkspaceID: entity.DEFAULT_WORKSPACE},
+			"abcdef",
+			"https://example.org",
+			api.NewForbidden("workspace/forbidden", "You need the editor role to edit links in workspace (ws_1)."),
+		},
+		{
+			"Anonymous",
 			nil,
-			errors.New("some error"),
-			errors.New("some error"),
+			"abcdef",
+			"https://example.org",
+			api.NewUnauthorized("auth/required", "You need to authenticate to edit links in workspace (ws_1).",
+				api.WithAction("Send an api key or token in the Authorization header, as 'Bearer <key>'.")),
+		},
+		{
+			"Invalid Url",
+			&auth.Principal{Admin: true},
+			"abcdef",
+			"not a url",
+			api.NewBadRequest("url/invalid", "The provided URL (not a url) is invalid."),
+		},
+		{
+			"Not Found",
+			&auth.Principal{Admin: true},
+			"zzzzzz",
+			"https://example.org",
+			api.NewNotFound("url/not-found", "Couldn't find URL with token (zzzzzz)."),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			service := newWorkspaceUrlService(t)
+
+			ctx := context.Background()
+			if test.principal != nil {
+				ctx = auth.NewContext(ctx, test.principal)
+			}
+
+			url, err := service.UpdateUrl(ctx, test.token, test.targetUrl)
+			if test.expectedErr != nil {
+				assert.Equal(t, test.expectedErr, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.targetUrl, url.TargetUrl)
+			assert.Equal(t, "ws_1", url.WorkspaceID)
+		})
+	}
+}
+
+func Test_DeleteUrl(t *testing.T) {
+	testCases := []struct {
+		name        string
+		principal   *auth.Principal
+		expectedErr error
+	}{
+		{"Editor", &auth.Principal{UserID: "user_editor", WorkspaceID: "ws_1"}, nil},
+		{
+			"Viewer",
+			&auth.Principal{UserID: "user_viewer", WorkspaceID: "ws_1"},
+			api.NewForbidden("workspace/forbidden", "You need the editor role to delete links in workspace (ws_1)."),
 		},
 		{
-			"Success But No URLS in Repo",
-			[]entity.Url{},
+			"Anonymous",
 			nil,
+			api.NewUnauthorized("auth/required", "You need to authenticate to delete links in workspace (ws_1).",
+				api.WithAction("Send an api key or token in the Authorization header, as 'Bearer <key>'.")),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			service := newWorkspaceUrlService(t)
+
+			ctx := context.Background()
+			if test.principal != nil {
+				ctx = auth.NewContext(ctx, test.principal)
+			}
+
+			err := service.DeleteUrl(ctx, "abcdef")
+			assert.Equal(t, test.expectedErr, err)
+
+			_, findErr := service.FindUrlByToken(context.Background(), "abcdef")
+			if test.expectedErr == nil {
+				assert.Equal(t, api.NotFound, api.EnsureApiError(findErr).Type)
+			} else {
+				assert.NoError(t, findErr)
+			}
+		})
+	}
+}
+
+func Test_SetUrlDisabled(t *testing.T) {
+	testCases := []struct {
+		name        string
+		principal   *auth.Principal
+		expectedErr error
+	}{
+		{"Admin", &auth.Principal{Admin: true}, nil},
+		{"Owner", &auth.Principal{UserID: "user_owner", WorkspaceID: "ws_1"}, nil},
+		{
+			"Editor",
+			&auth.Principal{UserID: "user_editor", WorkspaceID: "ws_1"},
+			api.NewForbidden("workspace/forbidden", "You need the owner role to disable links in workspace (ws_1)."),
+		},
+		{
+			"Anonymous",
 			nil,
+			api.NewUnauthorized("auth/required", "You need to authenticate to disable links in workspace (ws_1).",
+				api.WithAction("Send an api key or token in the Authorization header, as 'Bearer <key>'.")),
 		},
 	}
 
 	for _, test := range testCases {
-		repo := mocks.NewMockUrlRepository()
-		repo.On("GetAllUrls", mock.AnythingOfType("*context.emptyCtx")).Return(test.repoUrlResponse, test.repoErr)
+		t.Run(test.name, func(t *testing.T) {
+			service := newWorkspaceUrlService(t)
 
-		service := NewUrlService(&Config{
-			UrlRepo: repo,
-			Logger:  logger.NewApiLogger("development"),
+			ctx := context.Background()
+			if test.principal != nil {
+				ctx = auth.NewContext(ctx, test.principal)
+			}
+
+			url, err := service.SetUrlDisabled(ctx, "abcdef", true)
+			if test.expectedErr != nil {
+				assert.Equal(t, test.expectedErr, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.True(t, url.Disabled)
+
+			// disabled urls aren't redirected to, but can still be managed
+			_, err = service.FindUrlByToken(context.Background(), "abcdef")
+			assert.Equal(t, api.NewGone("url/disabled", "The URL with token (abcdef) has been disabled."), err)
+
+			_, err = service.GetUrlAnalytics(ctx, "abcdef")
+			assert.NoError(t, err)
+
+			url, err = service.SetUrlDisabled(ctx, "abcdef", false)
+			assert.NoError(t, err)
+			assert.False(t, url.Disabled)
+
+			_, err = service.FindUrlByToken(context.Background(), "abcdef")
+			assert.NoError(t, err)
 		})
+	}
+}
+
+func Test_GetUrlAnalytics(t *testing.T) {
+	testCases := []struct {
+		name        string
+		principal   *auth.Principal
+		expectedErr error
+	}{
+		{"Viewer", &auth.Principal{UserID: "user_viewer", WorkspaceID: "ws_1"}, nil},
+		{
+			"Non Member",
+			&auth.Principal{UserID: "user_stranger"},
+			api.NewForbidden("workspace/forbidden", "You need the viewer role to view the analytics of links in workspace (ws_1)."),
+		},
+		{
+			"Anonymous",
+			nil,
+			api.NewUnauthorized("auth/required", "You need to authenticate to view the analytics of links in workspace (ws_1).",
+				api.WithAction("Send an api key or token in the Authorization header, as 'Bearer <key>'.")),
+		},
+	}
 
-		urls, err := service.GetAllUrls(context.Background())
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			service := newWorkspaceUrlService(t)
 
-		if test.expectedErr != nil {
-			assert.Error(t, err)
-			assert.Equal(t, test.expectedErr, err)
-		}
+			ctx := context.Background()
+			if test.principal != nil {
+				ctx = auth.NewContext(ctx, test.principal)
+			}
 
-		assert.Equal(t, test.repoUrlResponse, urls)
+			url, err := service.GetUrlAnalytics(ctx, "abcdef")
+			if test.expectedErr != nil {
+				assert.Equal(t, test.expectedErr, err)
+				return
+			}
 
+			assert.NoError(t, err)
+			assert.Equal(t, "abcdef", url.Token)
+		})
 	}
+}
 
+func Test_ListUrls_ScopedToWorkspace(t *testing.T) {
+	repo := mocks.NewMockUrlRepository()
+	repo.On("ListUrls", mock.Anything, repository.ListFilter{WorkspaceID: "ws_1"}, "", DEFAULT_LIST_LIMIT).Return([]entity.Url{}, "", nil)
+
+	workspaces := repository.NewInMemoryWorkspaceRepo()
+	now := time.Now().UTC()
+	assert.NoError(t, workspaces.CreateWorkspace(context.Background(), &entity.Workspace{ID: "ws_1", Name: "Team", CreatedAt: now},
+		&entity.Membership{WorkspaceID: "ws_1", UserID: "user_1", Role: entity.RoleOwner, CreatedAt: now}))
+
+	service := NewUrlService(&Config{
+		UrlRepo:       repo,
+		WorkspaceRepo: workspaces,
+		Logger:        logger.NewApiLogger("development"),
+	})
+
+	// a user can't list another workspace's links by asking for it in the filter
+	ctx := auth.NewContext(context.Background(), &auth.Principal{UserID: "user_1", WorkspaceID: "ws_1"})
+	_, _, err := service.ListUrls(ctx, repository.ListFilter{WorkspaceID: "ws_2"}, "", 0)
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+
+	_, _, err = service.ListUrls(context.Background(), repository.ListFilter{}, "", 0)
+	assert.Equal(t, api.Unauthorized, api.EnsureApiError(err).Type)
 }
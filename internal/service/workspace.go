@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/auth"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
+)
+
+const (
+	MAX_WORKSPACE_NAME_LENGTH = 100
+	WORKSPACE_ID_BYTES        = 8 // random bytes in a workspace's id
+	WORKSPACE_ID_PREFIX       = "ws_"
+)
+
+type WorkspaceConfig struct {
+	Logger        logger.Logger
+	WorkspaceRepo repository.WorkspaceRepository
+
+	// AuditRepo records who created workspaces and changed their members. It's nil if the database can't store the audit log.
+	AuditRepo repository.AuditRepository
+}
+
+// workspaceService manages workspaces and their members
+type workspaceService struct {
+	authorizer
+	auditor
+
+	logger        logger.Logger
+	workspaceRepo repository.WorkspaceRepository
+}
+
+func NewWorkspaceService(c *WorkspaceConfig) WorkspaceService {
+	return &workspaceService{
+		authorizer:    authorizer{workspaceRepo: c.WorkspaceRepo},
+		auditor:       auditor{logger: c.Logger, auditRepo: c.AuditRepo},
+		logger:        c.Logger,
+		workspaceRepo: c.WorkspaceRepo,
+	}
+}
+
+// currentUser returns the user making the request. Workspaces belong to users,
+// so api keys, the admin token and anonymous requests can't create or list them.
+func currentUser(ctx context.Context) (*auth.Principal, error) {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil, api.NewUnauthorized("auth/required", "You need to sign in to use workspaces.",
+			api.WithAction("Send a token in the Authorization header, as 'Bearer <token>'."))
+	}
+
+	if principal.UserID == "" {
+		return nil, api.NewForbidden("workspace/users-only", "Only users can create or list their workspaces.",
+			api.WithAction("Sign in with a token rather than an api key."))
+	}
+
+	return principal, nil
+}
+
+// CreateWorkspace creates a workspace, with the user making the request as its owner.
+func (w *workspaceService) CreateWorkspace(ctx context.Context, name string) (*entity.Workspace, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > MAX_WORKSPACE_NAME_LENGTH {
+		return nil, api.NewBadRequest("workspace/invalid-name", fmt.Sprintf("A workspace needs a name of at most %d characters.", MAX_WORKSPACE_NAME_LENGTH))
+	}
+
+	principal, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	idBytes := make([]byte, WORKSPACE_ID_BYTES)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, api.NewInternal("workspace/couldnt-create", api.WithDebug(err.Error()))
+	}
+
+	now := time.Now().UTC()
+	workspace := &entity.Workspace{
+		ID:        WORKSPACE_ID_PREFIX + hex.EncodeToString(idBytes),
+		Name:      name,
+		CreatedAt: now,
+	}
+
+	if err := w.workspaceRepo.SaveUser(ctx, &entity.User{ID: principal.UserID, Name: principal.Name, CreatedAt: now}); err != nil {
+		apiErr := api.NewInternal("workspace/couldnt-create", api.WithDebug(err.Error()))
+		w.logger.Info("Failed to save user.", apiErr)
+		return nil, apiErr
+	}
+
+	owner := &entity.Membership{WorkspaceID: workspace.ID, UserID: principal.UserID, Role: entity.RoleOwner, CreatedAt: now}
+	if err := w.workspaceRepo.CreateWorkspace(ctx, workspace, owner); err != nil {
+		apiErr := api.NewInternal("workspace/couldnt-create", api.WithDebug(err.Error()))
+		w.logger.Info("Failed to create workspace.", apiErr)
+		return nil, apiErr
+	}
+
+	w.record(ctx, entity.AuditWorkspaceCreate, workspace.ID, workspace.ID, entity.AuditChanges{"name": {After: workspace.Name}})
+	return workspace, nil
+}
+
+// ListWorkspaces returns the memberships of the user making the request.
+func (w *workspaceService) ListWorkspaces(ctx context.Context) ([]entity.Membership, error) {
+	principal, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	memberships, err := w.workspaceRepo.ListMemberships(ctx, principal.UserID)
+	if err != nil {
+		return nil, api.NewInternal("workspace/couldnt-list", api.WithDebug(err.Error()))
+	}
+
+	return memberships, nil
+}
+
+// ListMembers returns the members of a workspace, if the request can view them.
+func (w *workspaceService) ListMembers(ctx context.Context, workspaceID string) ([]entity.Membership, error) {
+	if err := w.authorizeWorkspace(ctx, workspaceID, actionViewMembers); err != nil {
+		return nil, err
+	}
+
+	members, err := w.workspaceRepo.ListMembers(ctx, workspaceID)
+	if err != nil {
+		return nil, api.NewInternal("workspace/couldnt-list-members", api.WithDebug(err.Error()))
+	}
+
+	return members, nil
+}
+
+// AddMember gives a user a role in a workspace. Only owners can add members.
+func (w *workspaceService) AddMember(ctx context.Context, workspaceID, userID string, role entity.Role) (*entity.Membership, error) {
+	if err := validateMember(userID, role); err != nil {
+		return nil, err
+	}
+
+	if err := w.authorizeWorkspace(ctx, workspaceID, actionManageMembers); err != nil {
+		return nil, err
+	}
+
+	membership := &entity.Membership{WorkspaceID: workspaceID, UserID: userID, Role: role, CreatedAt: time.Now().UTC()}
+	if err := w.workspaceRepo.AddMember(ctx, membership); err != nil {
+		return nil, w.memberError(err, workspaceID, userID, "workspace/couldnt-add-member")
+	}
+
+	w.record(ctx, entity.AuditMemberAdd, workspaceID, userID, roleChange("", role))
+	return membership, nil
+}
+
+// UpdateMember changes the role of a member. Only owners can change roles, and a workspace's only owner can't be demoted.
+func (w *workspaceService) UpdateMember(ctx context.Context, workspaceID, userID string, role entity.Role) (*entity.Membership, error) {
+	if err := validateMember(userID, role); err != nil {
+		return nil, err
+	}
+
+	if err := w.authorizeWorkspace(ctx, workspaceID, actionManageMembers); err != nil {
+		return nil, err
+	}
+
+	membership, err := w.workspaceRepo.FindMembership(ctx, workspaceID, userID)
+	if err != nil {
+		return nil, w.memberError(err, workspaceID, userID, "workspace/couldnt-update-member")
+	}
+	before := membership.Role
+
+	if err := w.workspaceRepo.UpdateMemberRole(ctx, workspaceID, userID, role); err != nil {
+		return nil, w.memberError(err, workspaceID, userID, "workspace/couldnt-update-member")
+	}
+
+	membership, err = w.workspaceRepo.FindMembership(ctx, workspaceID, userID)
+	if err != nil {
+		return nil, w.memberError(err, workspaceID, userID, "workspace/couldnt-update-member")
+	}
+
+	w.record(ctx, entity.AuditMemberUpdate, workspaceID, userID, roleChange(before, role))
+	return membership, nil
+}
+
+// RemoveMember removes a member from a workspace. Only owners can remove members, and a workspace's only owner can't be removed.
+func (w *workspaceService) RemoveMember(ctx context.Context, workspaceID, userID string) error {
+	if err := w.authorizeWorkspace(ctx, workspaceID, actionManageMembers); err != nil {
+		return err
+	}
+
+	membership, err := w.workspaceRepo.FindMembership(ctx, workspaceID, userID)
+	if err != nil {
+		return w.memberError(err, workspaceID, userID, "workspace/couldnt-remove-member")
+	}
+
+	if err := w.workspaceRepo.RemoveMember(ctx, workspaceID, userID); err != nil {
+		return w.memberError(err, workspaceID, userID, "workspace/couldnt-remove-member")
+	}
+
+	w.record(ctx, entity.AuditMemberRemove, workspaceID, userID, roleChange(membership.Role, ""))
+	return nil
+}
+
+// roleChange is the audited change of a member's role.
+func roleChange(before, after entity.Role) entity.AuditChanges {
+	return entity.AuditChanges{"role": {Before: string(before), After: string(after)}}
+}
+
+// validateMember checks the user id and role of a membership.
+func validateMember(userID string, role entity.Role) error {
+	if strings.TrimSpace(userID) == "" {
+		return api.NewBadRequest("workspace/invalid-user", "A member needs a user id.")
+	}
+
+	if !role.Valid() {
+		return api.NewBadRequest("workspace/invalid-role", fmt.Sprintf("Unknown role (%s).", role),
+			api.WithAction(fmt.Sprintf("Use one of %s, %s or %s.", entity.RoleOwner, entity.RoleEditor, entity.RoleViewer)))
+	}
+
+	return nil
+}
+
+// memberError maps a repository error from changing a membership to an api error.
+func (w *workspaceService) memberError(err error, workspaceID, userID, code string) error {
+	switch {
+	case errors.Is(err, repository.ErrWorkspaceNotFound):
+		return api.NewNotFound("workspace/not-found", fmt.Sprintf("There is no workspace with the id %s.", workspaceID))
+	case errors.Is(err, repository.ErrMembershipNotFound):
+		return api.NewNotFound("workspace/member-not-found", fmt.Sprintf("%s isn't a member of workspace (%s).", userID, workspaceID))
+	case errors.Is(err, repository.ErrMembershipExists):
+		return api.NewConflict("workspace/member-exists", fmt.Sprintf("%s is already a member of workspace (%s).", userID, workspaceID),
+			api.WithAction("Change their role instead."))
+	case errors.Is(err, repository.ErrLastOwner):
+		return api.NewConflict("workspace/last-owner", fmt.Sprintf("Workspace (%s) must keep at least one owner.", workspaceID),
+			api.WithAction("Make another member an owner first."))
+	}
+
+	apiErr := api.NewInternal(code, api.WithDebug(err.Error()))
+	w.logger.Info("Failed to change workspace member.", apiErr)
+	return apiErr
+}
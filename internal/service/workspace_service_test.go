@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Jaytpa01/url-shortener-api/api"
+	"github.com/Jaytpa01/url-shortener-api/internal/auth"
+	"github.com/Jaytpa01/url-shortener-api/internal/entity"
+	"github.com/Jaytpa01/url-shortener-api/internal/repository"
+	"github.com/Jaytpa01/url-shortener-api/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newWorkspaceService() WorkspaceService {
+	return NewWorkspaceService(&WorkspaceConfig{
+		Logger:        logger.NewApiLogger("development"),
+		WorkspaceRepo: repository.NewInMemoryWorkspaceRepo(),
+	})
+}
+
+func userContext(userID string) context.Context {
+	return auth.NewContext(context.Background(), &auth.Principal{UserID: userID, Name: userID})
+}
+
+func Test_CreateWorkspace(t *testing.T) {
+	workspaces := newWorkspaceService()
+	ctx := userContext("user_1")
+
+	workspace, err := workspaces.CreateWorkspace(ctx, "  Team  ")
+	require.NoError(t, err)
+	assert.Equal(t, "Team", workspace.Name)
+	assert.True(t, strings.HasPrefix(workspace.ID, WORKSPACE_ID_PREFIX))
+
+	// the creator is its owner
+	memberships, err := workspaces.ListWorkspaces(ctx)
+	require.NoError(t, err)
+	require.Len(t, memberships, 1)
+	assert.Equal(t, workspace.ID, memberships[0].WorkspaceID)
+	assert.Equal(t, entity.RoleOwner, memberships[0].Role)
+
+	testCases := []struct {
+		name        string
+		ctx         context.Context
+		wsName      string
+		expectedErr error
+	}{
+		{"Empty Name", ctx, "   ", api.NewBadRequest("workspace/invalid-name", "A workspace needs a name of at most 100 characters.")},
+		{"Long Name", ctx, strings.Repeat("a", MAX_WORKSPACE_NAME_LENGTH+1), api.NewBadRequest("workspace/invalid-name", "A workspace needs a name of at most 100 characters.")},
+		{
+			"Anonymous",
+			context.Background(),
+			"Team",
+			api.NewUnauthorized("auth/required", "You need to sign in to use workspaces.",
+				api.WithAction("Send a token in the Authorization header, as 'Bearer <token>'.")),
+		},
+		{
+			"Api Key",
+			auth.NewContext(context.Background(), &auth.Principal{KeyID: "key_1"}),
+			"Team",
+			api.NewForbidden("workspace/users-only", "Only users can create or list their workspaces.",
+				api.WithAction("Sign in with a token rather than an api key.")),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := workspaces.CreateWorkspace(test.ctx, test.wsName)
+			assert.Equal(t, test.expectedErr, err)
+		})
+	}
+}
+
+func Test_WorkspaceMembers(t *testing.T) {
+	workspaces := newWorkspaceService()
+	owner := userContext("user_owner")
+
+	workspace, err := workspaces.CreateWorkspace(owner, "Team")
+	require.NoError(t, err)
+	id := workspace.ID
+
+	_, err = workspaces.AddMember(owner, id, "user_editor", entity.RoleEditor)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name        string
+		do          func() error
+		expectedErr error
+	}{
+		{
+			"Add Existing Member",
+			func() error {
+				_, err := workspaces.AddMember(owner, id, "user_editor", entity.RoleViewer)
+				return err
+			},
+			api.NewConflict("workspace/member-exists", "user_editor is already a member of workspace ("+id+").",
+				api.WithAction("Change their role instead.")),
+		},
+		{
+			"Invalid Role",
+			func() error {
+				_, err := workspaces.AddMember(owner, id, "user_2", "superuser")
+				return err
+			},
+			api.NewBadRequest("workspace/invalid-role", "Unknown role (superuser).", api.WithAction("Use one of owner, editor or viewer.")),
+		},
+		{
+			"Editor Can't Add Members",
+			func() error {
+				_, err := workspaces.AddMember(userContext("user_editor"), id, "user_2", entity.RoleViewer)
+				return err
+			},
+			api.NewForbidden("workspace/forbidden", "You need the owner role to manage the members of workspace ("+id+")."),
+		},
+		{
+			"Key Can't Add Members",
+			func() error {
+				key := auth.NewContext(context.Background(), &auth.Principal{KeyID: "key_1", WorkspaceID: id, Scopes: entity.DEFAULT_SCOPES})
+				_, err := workspaces.AddMember(key, id, "user_2", entity.RoleViewer)
+				return err
+			},
+			api.NewForbidden("workspace/forbidden", "You need the owner role to manage the members of workspace ("+id+")."),
+		},
+		{
+			"Admin Key Can Add Members",
+			func() error {
+				key := auth.NewContext(context.Background(), &auth.Principal{KeyID: "key_1", WorkspaceID: id, Scopes: entity.Scopes{entity.ScopeAdmin}})
+				_, err := workspaces.AddMember(key, id, "user_viewer", entity.RoleViewer)
+				return err
+			},
+			nil,
+		},
+		{
+			"Editor Can List Members",
+			func() error {
+				members, err := workspaces.ListMembers(userContext("user_editor"), id)
+				assert.Len(t, members, 3)
+				return err
+			},
+			nil,
+		},
+		{
+			"Stranger Can't List Members",
+			func() error {
+				_, err := workspaces.ListMembers(userContext("user_stranger"), id)
+				return err
+			},
+			api.NewForbidden("workspace/forbidden", "You need the viewer role to view the members of workspace ("+id+")."),
+		},
+		{
+			"Unknown Workspace",
+			func() error {
+				_, err := workspaces.ListMembers(owner, "ws_unknown")
+				return err
+			},
+			api.NewNotFound("workspace/not-found", "There is no workspace with the id ws_unknown."),
+		},
+		{
+			"Demote Last Owner",
+			func() error {
+				_, err := workspaces.UpdateMember(owner, id, "user_owner", entity.RoleEditor)
+				return err
+			},
+			api.NewConflict("workspace/last-owner", "Workspace ("+id+") must keep at least one owner.",
+				api.WithAction("Make another member an owner first.")),
+		},
+		{
+			"Remove Last Owner",
+			func() error {
+				return workspaces.RemoveMember(owner, id, "user_owner")
+			},
+			api.NewConflict("workspace/last-owner", "Workspace ("+id+") must keep at least one owner.",
+				api.WithAction("Make another member an owner first.")),
+		},
+		{
+			"Remove Unknown Member",
+			func() error {
+				return workspaces.RemoveMember(owner, id, "user_stranger")
+			},
+			api.NewNotFound("workspace/member-not-found", "user_stranger isn't a member of workspace ("+id+")."),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expectedErr, test.do())
+		})
+	}
+
+	// once there's another owner, the first can step down
+	membership, err := workspaces.UpdateMember(owner, id, "user_editor", entity.RoleOwner)
+	require.NoError(t, err)
+	assert.Equal(t, entity.RoleOwner, membership.Role)
+
+	require.NoError(t, workspaces.RemoveMember(owner, id, "user_owner"))
+
+	members, err := workspaces.ListMembers(userContext("user_editor"), id)
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	assert.Equal(t, "user_editor", members[0].UserID)
+}
@@ -9,3 +9,11 @@ func Max[T constraints.Ordered](a, b T) T {
 	}
 	return b
 }
+
+// Min is a generic function that returns the smaller of two numbers
+func Min[T constraints.Ordered](a, b T) T {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,211 @@
+package utils
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+const (
+	feistelRounds = 4
+
+	// maxPermutedLength is the longest token whose keyspace (62^n) still fits in a uint64.
+	// Anything longer permutes the full 64 bit space and pads the remaining characters.
+	maxPermutedLength = 10
+)
+
+// TokenGenerator generates the tokens used to identify urls.
+// It is an interface so the strategy can be selected in config and mocked in tests.
+type TokenGenerator interface {
+	GenerateToken(ctx context.Context, length int) (string, error)
+}
+
+// Sequence hands out monotonically increasing ids. Repositories implement it
+// so that ids survive a restart and are shared between api instances.
+type Sequence interface {
+	NextSequence(ctx context.Context) (uint64, error)
+}
+
+// randomTokenGenerator generates tokens using a Random.
+// Tokens can clash with existing ones, so callers need to handle a clash.
+type randomTokenGenerator struct {
+	random Random
+}
+
+func NewRandomTokenGenerator(random Random) TokenGenerator {
+	return &randomTokenGenerator{
+		random: random,
+	}
+}
+
+// GenerateToken generates a random alphanumeric token of the given length.
+func (g *randomTokenGenerator) GenerateToken(ctx context.Context, length int) (string, error) {
+	return g.random.GenerateRandomString(length), nil
+}
+
+// sequenceTokenGenerator generates tokens by encoding ids from a Sequence into base62.
+// Each id is passed through a keyed permutation first, so consecutive ids
+// don't produce consecutive (and easily guessable) tokens.
+type sequenceTokenGenerator struct {
+	seq Sequence
+	key []byte
+}
+
+func NewSequenceTokenGenerator(seq Sequence, key []byte) TokenGenerator {
+	return &sequenceTokenGenerator{
+		seq: seq,
+		key: key,
+	}
+}
+
+var ErrTokenLength = errors.New("token length must be greater than zero")
+
+// GenerateToken takes the next id from the sequence and encodes it as a token.
+// The token is at least length characters long, and grows by a character
+// whenever the ids outgrow the keyspace of the current length.
+// Because every id is only handed out once, tokens never clash.
+func (g *sequenceTokenGenerator) GenerateToken(ctx context.Context, length int) (string, error) {
+	if length <= 0 {
+		return "", ErrTokenLength
+	}
+
+	id, err := g.seq.NextSequence(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return g.encode(id, length), nil
+}
+
+// encode maps id to a token of at least length characters.
+func (g *sequenceTokenGenerator) encode(id uint64, length int) string {
+	for length <= maxPermutedLength && id >= keyspace(length) {
+		length++
+	}
+
+	if length <= maxPermutedLength {
+		return encodeBase62(g.permute(id, keyspace(length)), length)
+	}
+
+	// the keyspace no longer fits in a uint64, so permute the whole 64 bit space
+	// and fill the remaining characters with a keyed pad. The permuted suffix is
+	// unique per id, which keeps the whole token unique.
+	suffix := encodeBase62(g.feistel(id, 64), maxPermutedLength+1)
+	return g.pad(id, length-len(suffix)) + suffix
+}
+
+// permute is a keyed permutation of [0, n). It cycle walks a Feistel network
+// over the smallest even number of bits that covers n until the result lands in range.
+func (g *sequenceTokenGenerator) permute(x, n uint64) uint64 {
+	width := feistelWidth(n)
+	for {
+		x = g.feistel(x, width)
+		if x < n {
+			return x
+		}
+	}
+}
+
+// unpermute is the inverse of permute.
+func (g *sequenceTokenGenerator) unpermute(x, n uint64) uint64 {
+	width := feistelWidth(n)
+	for {
+		x = g.unfeistel(x, width)
+		if x < n {
+			return x
+		}
+	}
+}
+
+// feistel runs a balanced Feistel network over the lowest width bits of x.
+func (g *sequenceTokenGenerator) feistel(x uint64, width int) uint64 {
+	half := width / 2
+	mask := uint64(1)<<half - 1
+	left, right := (x>>half)&mask, x&mask
+
+	for round := 0; round < feistelRounds; round++ {
+		left, right = right, left^(g.round(round, right)&mask)
+	}
+
+	return left<<half | right
+}
+
+// unfeistel is the inverse of feistel.
+func (g *sequenceTokenGenerator) unfeistel(x uint64, width int) uint64 {
+	half := width / 2
+	mask := uint64(1)<<half - 1
+	left, right := (x>>half)&mask, x&mask
+
+	for round := feistelRounds - 1; round >= 0; round-- {
+		left, right = right^(g.round(round, left)&mask), left
+	}
+
+	return left<<half | right
+}
+
+// round is the Feistel round function, a HMAC of the round number and half block.
+func (g *sequenceTokenGenerator) round(round int, half uint64) uint64 {
+	msg := make([]byte, 9)
+	msg[0] = byte(round)
+	binary.BigEndian.PutUint64(msg[1:], half)
+
+	mac := hmac.New(sha256.New, g.key)
+	mac.Write(msg)
+	return binary.BigEndian.Uint64(mac.Sum(nil))
+}
+
+// pad deterministically generates n characters for id.
+func (g *sequenceTokenGenerator) pad(id uint64, n int) string {
+	b := make([]byte, 0, n)
+	for block := uint64(0); len(b) < n; block++ {
+		msg := make([]byte, 16)
+		binary.BigEndian.PutUint64(msg, id)
+		binary.BigEndian.PutUint64(msg[8:], block)
+
+		mac := hmac.New(sha256.New, g.key)
+		mac.Write(msg)
+		for _, c := range mac.Sum(nil) {
+			if len(b) == n {
+				break
+			}
+			b = append(b, letterBytes[int(c)%len(letterBytes)])
+		}
+	}
+
+	return string(b)
+}
+
+// keyspace returns the number of tokens of the given length, 62^length.
+func keyspace(length int) uint64 {
+	n := uint64(1)
+	for i := 0; i < length; i++ {
+		n *= uint64(len(letterBytes))
+	}
+	return n
+}
+
+// feistelWidth returns the smallest even number of bits that can represent every value below n.
+func feistelWidth(n uint64) int {
+	width := bits.Len64(n - 1)
+	if width%2 != 0 {
+		width++
+	}
+	if width < 2 {
+		width = 2
+	}
+	return width
+}
+
+// encodeBase62 encodes x using the same alphabet as GenerateRandomString,
+// left padded to length characters.
+func encodeBase62(x uint64, length int) string {
+	b := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		b[i] = letterBytes[x%uint64(len(letterBytes))]
+		x /= uint64(len(letterBytes))
+	}
+	return string(b)
+}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// counter is a simple in memory Sequence used for testing
+type counter struct {
+	value uint64
+}
+
+func (c *counter) NextSequence(ctx context.Context) (uint64, error) {
+	c.value++
+	return c.value, nil
+}
+
+func Test_SequenceTokenGenerator_Unique(t *testing.T) {
+	tokens := NewSequenceTokenGenerator(&counter{}, []byte("secret"))
+	generated := map[string]struct{}{}
+
+	for i := 0; i < 100000; i++ {
+		token, err := tokens.GenerateToken(context.Background(), 6)
+		assert.NoError(t, err)
+		assert.Equal(t, 6, len(token))
+
+		_, exists := generated[token]
+		assert.Falsef(t, exists, "%s already exists", token)
+		generated[token] = struct{}{}
+	}
+}
+
+func Test_SequenceTokenGenerator_NotSequential(t *testing.T) {
+	tokens := NewSequenceTokenGenerator(&counter{}, []byte("secret"))
+
+	first, _ := tokens.GenerateToken(context.Background(), 6)
+	second, _ := tokens.GenerateToken(context.Background(), 6)
+
+	// consecutive ids should share no more than a coincidental prefix
+	assert.NotEqual(t, first[:4], second[:4])
+}
+
+func Test_SequenceTokenGenerator_DifferentKeys(t *testing.T) {
+	a := NewSequenceTokenGenerator(&counter{}, []byte("secret"))
+	b := NewSequenceTokenGenerator(&counter{}, []byte("another secret"))
+
+	tokenA, _ := a.GenerateToken(context.Background(), 6)
+	tokenB, _ := b.GenerateToken(context.Background(), 6)
+
+	assert.NotEqual(t, tokenA, tokenB)
+}
+
+func Test_SequenceTokenGenerator_GrowsWithKeyspace(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             uint64
+		length         int
+		expectedLength int
+	}{
+		{"Fits In Keyspace", 1, 6, 6},
+		{"Last Id In Keyspace", keyspace(6) - 1, 6, 6},
+		{"Outgrows Keyspace", keyspace(6), 6, 7},
+		{"Outgrows Several Lengths", keyspace(8), 6, 9},
+		{"Longer Than Uint64", 42, 42, 42},
+		{"Largest Id", ^uint64(0), 6, 11},
+	}
+
+	g := &sequenceTokenGenerator{key: []byte("secret")}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expectedLength, len(g.encode(test.id, test.length)))
+		})
+	}
+}
+
+func Test_SequenceTokenGenerator_InvalidLength(t *testing.T) {
+	tokens := NewSequenceTokenGenerator(&counter{}, []byte("secret"))
+
+	_, err := tokens.GenerateToken(context.Background(), 0)
+	assert.ErrorIs(t, err, ErrTokenLength)
+}
+
+func Test_Permute_IsReversible(t *testing.T) {
+	g := &sequenceTokenGenerator{key: []byte("secret")}
+
+	for _, n := range []uint64{2, 62, 1000, keyspace(6)} {
+		for x := uint64(0); x < 1000 && x < n; x++ {
+			permuted := g.permute(x, n)
+			assert.Less(t, permuted, n)
+			assert.Equal(t, x, g.unpermute(permuted, n))
+		}
+	}
+}
+
+func Test_RandomTokenGenerator(t *testing.T) {
+	tokens := NewRandomTokenGenerator(NewRandomiser())
+
+	token, err := tokens.GenerateToken(context.Background(), 8)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, len(token))
+}